@@ -0,0 +1,257 @@
+// Package apperror define los errores que la API devuelve a los clientes: un
+// código estable que el frontend puede interpretar, el estado HTTP y un
+// mensaje traducido según Accept-Language.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+	"gorm.io/gorm"
+)
+
+// Code identifica un tipo de error. Los códigos son parte de la API y no se
+// deben renombrar.
+type Code string
+
+const (
+	CodeInternal       Code = "INTERNAL_ERROR"
+	CodeInvalidRequest Code = "INVALID_REQUEST"
+	CodeValidation     Code = "VALIDATION_FAILED"
+	CodeRouteNotFound  Code = "ROUTE_NOT_FOUND"
+
+	CodeUnauthenticated    Code = "UNAUTHENTICATED"
+	CodeInvalidToken       Code = "INVALID_TOKEN"
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeInvalidResetToken  Code = "INVALID_RESET_TOKEN"
+	CodeTooManyAttempts    Code = "TOO_MANY_ATTEMPTS"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeSigningKeys        Code = "SIGNING_KEYS_UNAVAILABLE"
+	CodeProviderNotFound   Code = "AUTH_PROVIDER_NOT_FOUND"
+	CodeProviderDown       Code = "AUTH_PROVIDER_UNAVAILABLE"
+	CodeIdentityLinked     Code = "IDENTITY_ALREADY_LINKED"
+	CodeEmailFailed        Code = "EMAIL_DELIVERY_FAILED"
+	CodeUploadFailed       Code = "FILE_UPLOAD_FAILED"
+
+	CodeUserNotFound       Code = "USER_NOT_FOUND"
+	CodePostNotFound       Code = "POST_NOT_FOUND"
+	CodeCommentNotFound    Code = "COMMENT_NOT_FOUND"
+	CodeUniversityNotFound Code = "UNIVERSITY_NOT_FOUND"
+	CodeRoleNotFound       Code = "ROLE_NOT_FOUND"
+	CodeRoleAlreadyGranted Code = "ROLE_ALREADY_GRANTED"
+	CodeLastAdmin          Code = "LAST_ADMIN"
+
+	CodeChannelNotFound      Code = "CHANNEL_NOT_FOUND"
+	CodeChannelAccessDenied  Code = "CHANNEL_ACCESS_DENIED"
+	CodeChannelAdminRequired Code = "CHANNEL_ADMIN_REQUIRED"
+	CodeAlreadyChannelMember Code = "ALREADY_CHANNEL_MEMBER"
+	CodeInvitationNotFound   Code = "INVITATION_NOT_FOUND"
+	CodeInvitationPending    Code = "INVITATION_ALREADY_PENDING"
+	CodeInvitationProcessed  Code = "INVITATION_ALREADY_PROCESSED"
+	CodeChannelPrivate       Code = "CHANNEL_IS_PRIVATE"
+	CodeChannelPublic        Code = "CHANNEL_IS_PUBLIC"
+	CodeJoinRequestNotFound  Code = "JOIN_REQUEST_NOT_FOUND"
+	CodeJoinRequestPending   Code = "JOIN_REQUEST_ALREADY_PENDING"
+	CodeJoinRequestProcessed Code = "JOIN_REQUEST_ALREADY_PROCESSED"
+
+	CodeMemberNotFound          Code = "CHANNEL_MEMBER_NOT_FOUND"
+	CodeChannelOwnerRequired    Code = "CHANNEL_OWNER_REQUIRED"
+	CodeChannelOwnerProtected   Code = "CHANNEL_OWNER_PROTECTED"
+	CodeChannelOwnerCannotLeave Code = "CHANNEL_OWNER_CANNOT_LEAVE"
+	CodeLastChannelAdmin        Code = "LAST_CHANNEL_ADMIN"
+	CodeChannelBanned           Code = "CHANNEL_USER_BANNED"
+	CodeAlreadyBanned           Code = "USER_ALREADY_BANNED"
+	CodeBanNotFound             Code = "BAN_NOT_FOUND"
+	CodeChannelArchived         Code = "CHANNEL_ARCHIVED"
+	CodeChannelPermissionDenied Code = "CHANNEL_PERMISSION_DENIED"
+
+	CodeInviteLinkNotFound  Code = "INVITE_LINK_NOT_FOUND"
+	CodeInviteLinkExpired   Code = "INVITE_LINK_EXPIRED"
+	CodeInviteLinkExhausted Code = "INVITE_LINK_EXHAUSTED"
+	CodeInviteLinkEmail     Code = "INVITE_LINK_EMAIL_MISMATCH"
+
+	CodeFileNotFound    Code = "FILE_NOT_FOUND"
+	CodeFileLinkInvalid Code = "FILE_LINK_INVALID"
+	CodeFileLinkExpired Code = "FILE_LINK_EXPIRED"
+
+	CodeChannelPinLimit Code = "CHANNEL_PIN_LIMIT_REACHED"
+)
+
+// statuses asigna el estado HTTP de cada código
+var statuses = map[Code]int{
+	CodeInternal:       http.StatusInternalServerError,
+	CodeInvalidRequest: http.StatusBadRequest,
+	CodeValidation:     http.StatusBadRequest,
+	CodeRouteNotFound:  http.StatusNotFound,
+
+	CodeUnauthenticated:    http.StatusUnauthorized,
+	CodeInvalidToken:       http.StatusUnauthorized,
+	CodeInvalidCredentials: http.StatusUnauthorized,
+	CodeInvalidResetToken:  http.StatusBadRequest,
+	CodeTooManyAttempts:    http.StatusTooManyRequests,
+	CodeForbidden:          http.StatusForbidden,
+	CodeSigningKeys:        http.StatusServiceUnavailable,
+	CodeProviderNotFound:   http.StatusNotFound,
+	CodeProviderDown:       http.StatusBadGateway,
+	CodeIdentityLinked:     http.StatusConflict,
+	CodeEmailFailed:        http.StatusBadGateway,
+	CodeUploadFailed:       http.StatusBadGateway,
+
+	CodeUserNotFound:       http.StatusNotFound,
+	CodePostNotFound:       http.StatusNotFound,
+	CodeCommentNotFound:    http.StatusNotFound,
+	CodeUniversityNotFound: http.StatusNotFound,
+	CodeRoleNotFound:       http.StatusNotFound,
+	CodeRoleAlreadyGranted: http.StatusConflict,
+	CodeLastAdmin:          http.StatusConflict,
+
+	CodeChannelNotFound:      http.StatusNotFound,
+	CodeChannelAccessDenied:  http.StatusForbidden,
+	CodeChannelAdminRequired: http.StatusForbidden,
+	CodeAlreadyChannelMember: http.StatusConflict,
+	CodeInvitationNotFound:   http.StatusNotFound,
+	CodeInvitationPending:    http.StatusConflict,
+	CodeInvitationProcessed:  http.StatusConflict,
+	CodeChannelPrivate:       http.StatusForbidden,
+	CodeChannelPublic:        http.StatusConflict,
+	CodeJoinRequestNotFound:  http.StatusNotFound,
+	CodeJoinRequestPending:   http.StatusConflict,
+	CodeJoinRequestProcessed: http.StatusConflict,
+
+	CodeMemberNotFound:          http.StatusNotFound,
+	CodeChannelOwnerRequired:    http.StatusForbidden,
+	CodeChannelOwnerProtected:   http.StatusForbidden,
+	CodeChannelOwnerCannotLeave: http.StatusConflict,
+	CodeLastChannelAdmin:        http.StatusConflict,
+	CodeChannelBanned:           http.StatusForbidden,
+	CodeAlreadyBanned:           http.StatusConflict,
+	CodeBanNotFound:             http.StatusNotFound,
+	CodeChannelArchived:         http.StatusConflict,
+	CodeChannelPermissionDenied: http.StatusForbidden,
+
+	CodeInviteLinkNotFound:  http.StatusNotFound,
+	CodeInviteLinkExpired:   http.StatusGone,
+	CodeInviteLinkExhausted: http.StatusGone,
+	CodeInviteLinkEmail:     http.StatusForbidden,
+
+	CodeFileNotFound:    http.StatusNotFound,
+	CodeFileLinkInvalid: http.StatusForbidden,
+	CodeFileLinkExpired: http.StatusGone,
+
+	CodeChannelPinLimit: http.StatusConflict,
+}
+
+// Error es un error con código estable. Cause no se muestra al cliente, solo
+// se registra en el log.
+type Error struct {
+	Code   Code
+	Status int
+	// Params reemplaza los {nombre} del mensaje traducido
+	Params map[string]interface{}
+	// Fields son los errores por campo de VALIDATION_FAILED
+	Fields validation.Errors
+	// Details se agrega tal cual a la respuesta (por ejemplo retry_after)
+	Details map[string]interface{}
+	Cause   error
+}
+
+// New crea un error con el estado HTTP que corresponde al código
+func New(code Code) *Error {
+	status, ok := statuses[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &Error{Code: code, Status: status}
+}
+
+// Internal envuelve un error inesperado. El cliente solo ve INTERNAL_ERROR.
+func Internal(cause error) *Error {
+	return New(CodeInternal).Wrap(cause)
+}
+
+// Validation devuelve VALIDATION_FAILED con los errores por campo
+func Validation(fields validation.Errors) *Error {
+	e := New(CodeValidation)
+	e.Fields = fields
+	return e
+}
+
+// InvalidField devuelve VALIDATION_FAILED para un único campo. El mensaje se
+// toma del catálogo según el código del campo.
+func InvalidField(field, code string) *Error {
+	return Validation(validation.Errors{{Field: field, Code: code}})
+}
+
+// NotFound devuelve el código indicado si err es ErrRecordNotFound y un error
+// interno si la consulta falló por otro motivo
+func NotFound(code Code, err error) *Error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return New(code)
+	}
+	return Internal(err)
+}
+
+// WithStatus cambia el estado HTTP por defecto del código
+func (e *Error) WithStatus(status int) *Error {
+	e.Status = status
+	return e
+}
+
+// WithParam agrega un valor para el mensaje traducido
+func (e *Error) WithParam(key string, value interface{}) *Error {
+	if e.Params == nil {
+		e.Params = map[string]interface{}{}
+	}
+	e.Params[key] = value
+	return e
+}
+
+// WithDetail agrega un campo extra a la respuesta
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = map[string]interface{}{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+// Wrap guarda la causa para el log
+func (e *Error) Wrap(cause error) *Error {
+	e.Cause = cause
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.Cause)
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]string, 0, len(e.Fields))
+		for _, fe := range e.Fields {
+			fields = append(fields, fe.Field+"="+fe.Code)
+		}
+		return fmt.Sprintf("%s: %s", e.Code, strings.Join(fields, ", "))
+	}
+	return string(e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// From convierte cualquier error en un *Error. Los errores de validación se
+// devuelven como VALIDATION_FAILED y el resto como INTERNAL_ERROR.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		return Validation(fields)
+	}
+	return Internal(err)
+}
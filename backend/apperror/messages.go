@@ -0,0 +1,239 @@
+package apperror
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage se usa cuando Accept-Language no pide ningún idioma
+// soportado
+const DefaultLanguage = "es"
+
+var supported = []language.Tag{language.Spanish, language.English}
+
+var matcher = language.NewMatcher(supported)
+
+// catalogs tiene los mensajes por idioma. Las claves en mayúsculas son códigos
+// de error y las en minúsculas son códigos de errores por campo.
+var catalogs = map[string]map[string]string{
+	"es": {
+		string(CodeInternal):       "Error interno del servidor",
+		string(CodeInvalidRequest): "Datos inválidos",
+		string(CodeValidation):     "Datos inválidos",
+		string(CodeRouteNotFound):  "Ruta no encontrada",
+
+		string(CodeUnauthenticated):    "Se requiere iniciar sesión",
+		string(CodeInvalidToken):       "Token inválido o expirado",
+		string(CodeInvalidCredentials): "Email o contraseña inválidos",
+		string(CodeInvalidResetToken):  "Token inválido o expirado",
+		string(CodeTooManyAttempts):    "Demasiados intentos. Intentá de nuevo más tarde",
+		string(CodeForbidden):          "No tienes permiso para realizar esta acción",
+		string(CodeSigningKeys):        "Claves de firma no disponibles",
+		string(CodeProviderNotFound):   "Proveedor de autenticación no encontrado",
+		string(CodeProviderDown):       "No se pudo contactar al proveedor de autenticación",
+		string(CodeIdentityLinked):     "Esa cuenta externa ya está vinculada a otro usuario",
+		string(CodeEmailFailed):        "Error al enviar el email",
+		string(CodeUploadFailed):       "Error al subir el archivo {file}",
+
+		string(CodeUserNotFound):       "Usuario no encontrado",
+		string(CodePostNotFound):       "Post no encontrado",
+		string(CodeCommentNotFound):    "Comentario no encontrado",
+		string(CodeUniversityNotFound): "Universidad no encontrada",
+		string(CodeRoleNotFound):       "Rol no encontrado",
+		string(CodeRoleAlreadyGranted): "El usuario ya tiene ese rol",
+		string(CodeLastAdmin):          "No se puede quitar el último administrador",
+
+		string(CodeChannelNotFound):      "Canal no encontrado",
+		string(CodeChannelAccessDenied):  "No tienes acceso a este canal",
+		string(CodeChannelAdminRequired): "Solo los administradores del canal pueden hacer esto",
+		string(CodeAlreadyChannelMember): "El usuario ya es miembro del canal",
+		string(CodeInvitationNotFound):   "Invitación no encontrada",
+		string(CodeInvitationPending):    "Ya existe una invitación pendiente para este usuario",
+		string(CodeInvitationProcessed):  "Esta invitación ya ha sido procesada",
+		string(CodeChannelPrivate):       "Este canal es privado; envía una solicitud para unirte",
+		string(CodeChannelPublic):        "Este canal es público; puedes unirte directamente",
+		string(CodeJoinRequestNotFound):  "Solicitud no encontrada",
+		string(CodeJoinRequestPending):   "Ya tienes una solicitud pendiente para este canal",
+		string(CodeJoinRequestProcessed): "Esta solicitud ya ha sido procesada",
+
+		string(CodeMemberNotFound):          "El usuario no es miembro del canal",
+		string(CodeChannelOwnerRequired):    "Solo el creador del canal puede hacer esto",
+		string(CodeChannelOwnerProtected):   "No se puede expulsar, bloquear ni cambiar el rol del creador del canal",
+		string(CodeChannelOwnerCannotLeave): "Transfiere la propiedad del canal antes de salir",
+		string(CodeLastChannelAdmin):        "El canal debe tener al menos un administrador",
+		string(CodeChannelBanned):           "El usuario tiene bloqueado el acceso a este canal",
+		string(CodeAlreadyBanned):           "El usuario ya está bloqueado en este canal",
+		string(CodeBanNotFound):             "El usuario no está bloqueado en este canal",
+		string(CodeChannelArchived):         "El canal está archivado y es de solo lectura",
+		string(CodeChannelPermissionDenied): "Tu rol en el canal no permite esta acción",
+
+		string(CodeInviteLinkNotFound):  "Enlace de invitación no encontrado",
+		string(CodeInviteLinkExpired):   "El enlace de invitación venció o fue revocado",
+		string(CodeInviteLinkExhausted): "El enlace de invitación ya alcanzó su límite de usos",
+		string(CodeInviteLinkEmail):     "Esta invitación fue enviada a otro email",
+
+		string(CodeFileNotFound):    "Archivo no encontrado",
+		string(CodeFileLinkInvalid): "El enlace de descarga no es válido",
+		string(CodeFileLinkExpired): "El enlace de descarga venció; volvé a abrir el canal",
+
+		string(CodeChannelPinLimit): "El canal ya tiene {max} posts fijados; desfijá alguno antes",
+
+		"required":         "Este campo es obligatorio",
+		"invalid_id":       "Identificador inválido",
+		"invalid_format":   "Formato inválido",
+		"invalid_action":   "Acción inválida",
+		"invalid_role":     "Rol inválido",
+		"admin_not_scoped": "El rol de administrador no puede limitarse a una universidad",
+		"unknown_tag":      "Tag con ID {id} no encontrado",
+		"not_image":        "El archivo debe ser una imagen",
+		"unknown_career":   "La carrera no existe en la universidad del canal",
+		"not_positive":     "Debe ser mayor que cero",
+		"not_future":       "Debe ser una fecha futura",
+		"too_many_files":   "Se pueden adjuntar como máximo {max} archivos",
+
+		"username_required":               "El nombre de usuario es obligatorio",
+		"username_too_short":              "El nombre de usuario debe tener al menos 3 caracteres",
+		"username_too_long":               "El nombre de usuario puede tener como máximo 30 caracteres",
+		"username_invalid_chars":          "El nombre de usuario solo puede tener letras, números, puntos y guiones bajos, y no puede empezar con un símbolo",
+		"username_reserved":               "Ese nombre de usuario está reservado",
+		"username_taken":                  "Ese nombre de usuario ya está en uso",
+		"email_required":                  "El email es obligatorio",
+		"email_invalid":                   "Ingresá un email válido",
+		"email_taken":                     "Ya existe una cuenta con ese email",
+		"account_name_required":           "El nombre de la cuenta es obligatorio",
+		"account_name_too_long":           "El nombre de la cuenta puede tener como máximo 60 caracteres",
+		"password_required":               "La contraseña es obligatoria",
+		"password_too_short":              "La contraseña debe tener al menos 8 caracteres",
+		"password_too_long":               "La contraseña puede tener como máximo 72 caracteres",
+		"password_too_weak":               "La contraseña debe combinar letras con números o símbolos",
+		"password_contains_personal_data": "La contraseña no puede contener tu nombre de usuario o email",
+		"password_breached":               "Esa contraseña aparece en filtraciones conocidas, elegí otra",
+		"password_incorrect":              "La contraseña actual es incorrecta",
+	},
+	"en": {
+		string(CodeInternal):       "Internal server error",
+		string(CodeInvalidRequest): "Invalid request",
+		string(CodeValidation):     "Invalid data",
+		string(CodeRouteNotFound):  "Route not found",
+
+		string(CodeUnauthenticated):    "Authentication required",
+		string(CodeInvalidToken):       "Invalid or expired token",
+		string(CodeInvalidCredentials): "Invalid email or password",
+		string(CodeInvalidResetToken):  "Invalid or expired token",
+		string(CodeTooManyAttempts):    "Too many attempts. Please try again later",
+		string(CodeForbidden):          "You are not allowed to perform this action",
+		string(CodeSigningKeys):        "Signing keys unavailable",
+		string(CodeProviderNotFound):   "Authentication provider not found",
+		string(CodeProviderDown):       "Could not reach the authentication provider",
+		string(CodeIdentityLinked):     "That external account is already linked to another user",
+		string(CodeEmailFailed):        "Could not send the email",
+		string(CodeUploadFailed):       "Could not upload file {file}",
+
+		string(CodeUserNotFound):       "User not found",
+		string(CodePostNotFound):       "Post not found",
+		string(CodeCommentNotFound):    "Comment not found",
+		string(CodeUniversityNotFound): "University not found",
+		string(CodeRoleNotFound):       "Role not found",
+		string(CodeRoleAlreadyGranted): "The user already has that role",
+		string(CodeLastAdmin):          "The last administrator cannot be removed",
+
+		string(CodeChannelNotFound):      "Channel not found",
+		string(CodeChannelAccessDenied):  "You do not have access to this channel",
+		string(CodeChannelAdminRequired): "Only channel administrators can do this",
+		string(CodeAlreadyChannelMember): "The user is already a member of the channel",
+		string(CodeInvitationNotFound):   "Invitation not found",
+		string(CodeInvitationPending):    "There is already a pending invitation for this user",
+		string(CodeInvitationProcessed):  "This invitation has already been processed",
+		string(CodeChannelPrivate):       "This channel is private; send a request to join",
+		string(CodeChannelPublic):        "This channel is public; you can join directly",
+		string(CodeJoinRequestNotFound):  "Request not found",
+		string(CodeJoinRequestPending):   "You already have a pending request for this channel",
+		string(CodeJoinRequestProcessed): "This request has already been processed",
+
+		string(CodeMemberNotFound):          "The user is not a member of the channel",
+		string(CodeChannelOwnerRequired):    "Only the channel creator can do this",
+		string(CodeChannelOwnerProtected):   "The channel creator cannot be removed, banned or have their role changed",
+		string(CodeChannelOwnerCannotLeave): "Transfer ownership of the channel before leaving",
+		string(CodeLastChannelAdmin):        "The channel must have at least one administrator",
+		string(CodeChannelBanned):           "The user is banned from this channel",
+		string(CodeAlreadyBanned):           "The user is already banned from this channel",
+		string(CodeBanNotFound):             "The user is not banned from this channel",
+		string(CodeChannelArchived):         "The channel is archived and read-only",
+		string(CodeChannelPermissionDenied): "Your role in the channel does not allow this action",
+
+		string(CodeInviteLinkNotFound):  "Invite link not found",
+		string(CodeInviteLinkExpired):   "The invite link has expired or was revoked",
+		string(CodeInviteLinkExhausted): "The invite link has reached its usage limit",
+		string(CodeInviteLinkEmail):     "This invitation was sent to a different email",
+
+		string(CodeFileNotFound):    "File not found",
+		string(CodeFileLinkInvalid): "The download link is not valid",
+		string(CodeFileLinkExpired): "The download link has expired; open the channel again",
+
+		string(CodeChannelPinLimit): "The channel already has {max} pinned posts; unpin one first",
+
+		"required":         "This field is required",
+		"invalid_id":       "Invalid identifier",
+		"invalid_format":   "Invalid format",
+		"invalid_action":   "Invalid action",
+		"invalid_role":     "Invalid role",
+		"admin_not_scoped": "The administrator role cannot be limited to a university",
+		"unknown_tag":      "Tag with ID {id} not found",
+		"not_image":        "The file must be an image",
+		"unknown_career":   "The career does not exist in the channel's university",
+		"not_positive":     "Must be greater than zero",
+		"not_future":       "Must be a future date",
+		"too_many_files":   "At most {max} files can be attached",
+
+		"username_required":               "Username is required",
+		"username_too_short":              "Username must be at least 3 characters long",
+		"username_too_long":               "Username can be at most 30 characters long",
+		"username_invalid_chars":          "Username can only contain letters, numbers, dots and underscores, and cannot start with a symbol",
+		"username_reserved":               "That username is reserved",
+		"username_taken":                  "That username is already taken",
+		"email_required":                  "Email is required",
+		"email_invalid":                   "Enter a valid email",
+		"email_taken":                     "An account with that email already exists",
+		"account_name_required":           "Account name is required",
+		"account_name_too_long":           "Account name can be at most 60 characters long",
+		"password_required":               "Password is required",
+		"password_too_short":              "Password must be at least 8 characters long",
+		"password_too_long":               "Password can be at most 72 characters long",
+		"password_too_weak":               "Password must mix letters with numbers or symbols",
+		"password_contains_personal_data": "Password cannot contain your username or email",
+		"password_breached":               "That password appears in known breaches, choose another one",
+		"password_incorrect":              "Current password is incorrect",
+	},
+}
+
+// Language elige el idioma de los mensajes a partir de Accept-Language
+func Language(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLanguage
+	}
+	base, _ := supported[index].Base()
+	return base.String()
+}
+
+// Message traduce una clave del catálogo reemplazando los {nombre} por los
+// parámetros. Devuelve "" si la clave no existe.
+func Message(lang, key string, params map[string]interface{}) string {
+	message, ok := catalogs[lang][key]
+	if !ok {
+		message, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		return ""
+	}
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", fmt.Sprint(value))
+	}
+	return message
+}
@@ -0,0 +1,54 @@
+package apperror
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/tracing"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Abort registra el error en el pedido y corta la cadena de handlers. La
+// respuesta la escribe el middleware de errores al volver.
+func Abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// Render escribe la respuesta de error:
+//
+//	{"error": "Post no encontrado", "code": "POST_NOT_FOUND", "trace_id": "..."}
+//
+// error es el mensaje en el idioma pedido, code es estable, fields aparece
+// solo en VALIDATION_FAILED y los Details se agregan como campos extra. Los
+// errores 5xx se registran en el log con su causa.
+func Render(c *gin.Context, err error) {
+	appErr := From(err)
+	if appErr.Status >= 500 && appErr.Cause != nil {
+		logging.FromContext(c).Error("Error procesando el pedido", "code", appErr.Code, "error", appErr.Cause)
+	}
+
+	lang := Language(c.GetHeader("Accept-Language"))
+	body := gin.H{}
+	for key, value := range appErr.Details {
+		body[key] = value
+	}
+	body["error"] = Message(lang, string(appErr.Code), appErr.Params)
+	body["code"] = appErr.Code
+
+	if len(appErr.Fields) > 0 {
+		fields := make(validation.Errors, 0, len(appErr.Fields))
+		for _, fe := range appErr.Fields {
+			if message := Message(lang, fe.Code, appErr.Params); message != "" {
+				fe.Message = message
+			}
+			fields = append(fields, fe)
+		}
+		body["fields"] = fields
+	}
+	if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+		body["trace_id"] = traceID
+	}
+
+	c.Header("Content-Language", lang)
+	c.AbortWithStatusJSON(appErr.Status, body)
+}
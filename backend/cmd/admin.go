@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/config"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/ops"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+)
+
+const adminUsage = `uso: admin <tarea> [flags]
+
+Tareas:
+  create-user     --username --email [--name] [--university-id] [--career-id] [--role] [--password]
+  grant-role      --user <email o usuario> --role moderator|admin [--university-id]
+  reset-password  --user <email o usuario> [--password]
+  reindex-search  recalcula el índice de búsqueda de los posts
+  purge-deleted   [--older-than 720h] [--dry-run] borra tokens, claves e invitaciones sin uso
+
+Si no se indica --password se lee de la entrada estándar.
+`
+
+// runAdmin ejecuta una tarea de administración
+func runAdmin(cfg *config.Config, args []string) error {
+	task, args := args[0], args[1:]
+	switch task {
+	case "create-user":
+		return adminCreateUser(cfg, args)
+	case "grant-role":
+		return adminGrantRole(args)
+	case "reset-password":
+		return adminResetPassword(cfg, args)
+	case "reindex-search":
+		updated, err := ops.ReindexSearch()
+		if err != nil {
+			return err
+		}
+		slog.Info("Índice de búsqueda recalculado", "posts", updated)
+		return nil
+	case "purge-deleted":
+		return adminPurgeDeleted(args)
+	default:
+		fmt.Fprint(os.Stderr, adminUsage)
+		return fmt.Errorf("tarea desconocida: %s", task)
+	}
+}
+
+func adminCreateUser(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("admin create-user", flag.ContinueOnError)
+	input := ops.NewUser{}
+	fs.StringVar(&input.Username, "username", "", "nombre de usuario")
+	fs.StringVar(&input.Email, "email", "", "email")
+	fs.StringVar(&input.AccountName, "name", "", "nombre para mostrar")
+	fs.StringVar(&input.Password, "password", "", "contraseña (si se omite se lee de la entrada estándar)")
+	universityID := fs.Uint("university-id", 0, "universidad")
+	careerID := fs.Uint("career-id", 0, "carrera")
+	role := fs.String("role", "", "rol adicional: moderator o admin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	input.UniversityID = uint(*universityID)
+	input.CareerID = uint(*careerID)
+
+	if *role != "" && !rbac.ValidRole(*role) {
+		return rbac.ErrInvalidRole
+	}
+
+	if input.Password == "" {
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		input.Password = password
+	}
+
+	useBreachedPasswords(cfg)
+	user, err := ops.CreateUser(input)
+	if err != nil {
+		return err
+	}
+	slog.Info("Usuario creado", "username", user.Username, "user_id", user.UserID)
+
+	if *role != "" {
+		return grantRole(user, *role, nil)
+	}
+	return nil
+}
+
+func adminGrantRole(args []string) error {
+	fs := flag.NewFlagSet("admin grant-role", flag.ContinueOnError)
+	identifier := fs.String("user", "", "email o nombre de usuario")
+	role := fs.String("role", "", "moderator o admin")
+	universityID := fs.Uint("university-id", 0, "limitar el rol de moderador a una universidad")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *identifier == "" || *role == "" {
+		return errors.New("--user y --role son obligatorios")
+	}
+
+	user, err := ops.FindUser(*identifier)
+	if err != nil {
+		return err
+	}
+
+	var scope *uint
+	if *universityID != 0 {
+		id := uint(*universityID)
+		scope = &id
+	}
+	return grantRole(user, *role, scope)
+}
+
+func grantRole(user *models.User, role string, universityID *uint) error {
+	granted, err := rbac.Grant(context.Background(), user.UserID, role, universityID, nil)
+	if err != nil {
+		return err
+	}
+
+	details := "rol " + granted.Role + " otorgado desde la línea de comandos"
+	if universityID != nil {
+		details = fmt.Sprintf("rol %s (universidad %d) otorgado desde la línea de comandos", granted.Role, *universityID)
+	}
+	security.Audit(context.Background(), models.AuditLog{
+		UserID:  &user.UserID,
+		Action:  security.AuditRoleGranted,
+		Details: details,
+	})
+	slog.Info("Rol otorgado", "role", granted.Role, "username", user.Username)
+	return nil
+}
+
+func adminResetPassword(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("admin reset-password", flag.ContinueOnError)
+	identifier := fs.String("user", "", "email o nombre de usuario")
+	password := fs.String("password", "", "contraseña nueva (si se omite se lee de la entrada estándar)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *identifier == "" {
+		return errors.New("--user es obligatorio")
+	}
+
+	user, err := ops.FindUser(*identifier)
+	if err != nil {
+		return err
+	}
+
+	if *password == "" {
+		if *password, err = readPassword(); err != nil {
+			return err
+		}
+	}
+
+	useBreachedPasswords(cfg)
+	if err := ops.ResetPassword(context.Background(), user, *password); err != nil {
+		return err
+	}
+	slog.Info("Contraseña restablecida", "username", user.Username)
+	return nil
+}
+
+func adminPurgeDeleted(args []string) error {
+	fs := flag.NewFlagSet("admin purge-deleted", flag.ContinueOnError)
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "antigüedad mínima de las filas a borrar")
+	dryRun := fs.Bool("dry-run", false, "solo contar las filas")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	counts, err := ops.PurgeDeleted(*olderThan, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "borrados"
+	if *dryRun {
+		verb = "a borrar"
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s %s: %d\n", name, verb, counts[name])
+	}
+	return nil
+}
+
+// useBreachedPasswords aplica la configuración que usa la validación de contraseñas
+func useBreachedPasswords(cfg *config.Config) {
+	validation.UseBreachedPasswordsFile(cfg.BreachedPasswordsFile)
+}
+
+// readPassword lee la contraseña de la primera línea de la entrada estándar,
+// para no dejarla en el historial ni en la lista de procesos
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Contraseña: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("la contraseña es obligatoria")
+	}
+	return password, nil
+}
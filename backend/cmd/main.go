@@ -1,54 +1,95 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/LautaroRomano/repositorio-tecnologico/config"
 	"github.com/LautaroRomano/repositorio-tecnologico/database"
-	"github.com/LautaroRomano/repositorio-tecnologico/routes"
-	"github.com/LautaroRomano/repositorio-tecnologico/utils"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
 )
 
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error cargando .env")
-	}
-
-	database.Connect()
-	database.Migrate()
+const usage = `uso: backend [flags de configuración] <comando> [argumentos]
 
-	router := gin.Default()
-	router.RedirectTrailingSlash = false
+Comandos:
+  serve                      levanta el servidor HTTP (comando por defecto)
+  migrate up|down [n]        aplica o revierte migraciones
+  migrate status             lista las migraciones y su estado
+  seed [archivo]             carga universidades, carreras y tags desde YAML o CSV
+  admin <tarea> [flags]      tareas de administración (ver "admin help")
+  config print               muestra la configuración efectiva sin secretos
+`
 
-	routes.SetupAuthRoutes(router)
-	routes.UserRoutes(router)
-	routes.PostRoutes(router)
-	routes.UniversityRoutes(router)
-	routes.CareerRoutes(router)
-	routes.SetupChannelRoutes(router)
-
-	utils.InitResendClient(os.Getenv("RESEND_API_KEY"))
-
-	// Inicializar Cloudinary
-	err = config.SetupCloudinary()
+func main() {
+	cfg, args, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Fatalf("Error configurando Cloudinary: %v", err)
+		fmt.Fprintf(os.Stderr, "Error cargando la configuración: %v\n", err)
+		os.Exit(1)
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	// Aquí irán tus rutas (por ahora un ping)
-	router.GET("/ping", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "pong"})
-	})
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	switch command {
+	case "serve":
+		runServe(cfg)
+	case "migrate":
+		connectDatabase(cfg)
+		if err := runMigrate(args); err != nil {
+			fatal("Error en la migración", err)
+		}
+	case "seed":
+		connectDatabase(cfg)
+		if err := runSeed(args); err != nil {
+			fatal("Error cargando los datos", err)
+		}
+	case "admin":
+		if len(args) == 0 || args[0] == "help" {
+			fmt.Print(adminUsage)
+			return
+		}
+		connectDatabase(cfg)
+		if err := runAdmin(cfg, args); err != nil {
+			fatal("Error en la tarea de administración", err)
+		}
+	case "config":
+		if len(args) == 0 || args[0] != "print" {
+			fmt.Fprintln(os.Stderr, "uso: config print")
+			os.Exit(2)
+		}
+		cfg.Print(os.Stdout)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
+}
 
-	router.Run(":" + port)
+// connectDatabase conecta a la base para los comandos que no levantan el
+// servidor; solo exige la configuración de la base de datos
+func connectDatabase(cfg *config.Config) {
+	if err := cfg.ValidateDatabase(); err != nil {
+		fatal("Configuración de la base de datos inválida", err)
+	}
+	if err := database.Connect(cfg.Database); err != nil {
+		fatal("Error conectando a la base de datos", err)
+	}
+}
 
+// fatal registra el error y termina el proceso
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+)
+
+// runMigrate ejecuta el subcomando migrate. up y down aceptan opcionalmente la
+// cantidad de migraciones a aplicar o revertir.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("uso: migrate up [n] | down [n] | status")
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("cantidad de migraciones inválida: %s", args[1])
+		}
+		steps = n
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, steps)
+		if err != nil {
+			return err
+		}
+		slog.Info("Migraciones aplicadas", "count", applied)
+	case "down":
+		reverted, err := database.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		slog.Info("Migraciones revertidas", "count", reverted)
+	case "status":
+		status, err := database.MigrationsStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range status {
+			applied := "pendiente"
+			if m.AppliedAt != nil {
+				applied = "aplicada " + m.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, applied)
+		}
+	default:
+		return fmt.Errorf("subcomando desconocido: migrate %s", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/seed"
+)
+
+// runSeed carga el dataset indicado, o el incluido en el binario si no se
+// indica ninguno
+func runSeed(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("uso: seed [archivo.yaml|archivo.csv]")
+	}
+
+	var dataset *seed.Dataset
+	var err error
+	if len(args) == 1 {
+		dataset, err = seed.Load(args[0])
+	} else {
+		dataset, err = seed.Default()
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := seed.Apply(database.DB, dataset)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Datos cargados", "universities", result.Universities,
+		"careers", result.Careers, "tags", result.Tags)
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/config"
+	"github.com/LautaroRomano/repositorio-tecnologico/controllers"
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/health"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/realtime"
+	"github.com/LautaroRomano/repositorio-tecnologico/routes"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/LautaroRomano/repositorio-tecnologico/sso"
+	"github.com/LautaroRomano/repositorio-tecnologico/tracing"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+)
+
+// runServe aplica las migraciones pendientes y levanta el servidor HTTP
+func runServe(cfg *config.Config) {
+	if err := cfg.Validate(); err != nil {
+		fatal("Configuración inválida", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+		ServiceName:  cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		fatal("Error configurando las trazas", err)
+	}
+
+	if err := database.Connect(cfg.Database); err != nil {
+		fatal("Error conectando a la base de datos", err)
+	}
+	if err := database.Migrate(); err != nil {
+		fatal("Error aplicando las migraciones", err)
+	}
+
+	// Otorgar el rol de administrador a los emails de ADMIN_EMAILS
+	rbac.BootstrapAdmins(context.Background(), cfg.AdminEmails)
+
+	// Inicializar el llavero de claves para firmar los JWT
+	if err := setupKeyRing(cfg.JWT); err != nil {
+		fatal("Error configurando claves JWT", err)
+	}
+
+	validation.UseBreachedPasswordsFile(cfg.BreachedPasswordsFile)
+	controllers.FrontendURL = cfg.FrontendURL
+	controllers.MetricsToken = cfg.MetricsToken
+	events := newEventBroker(cfg.Realtime)
+	controllers.Services = services.New(database.DB, services.CloudinaryStorage{}, events)
+
+	router := routes.NewRouter(cfg.Tracing.ServiceName)
+
+	utils.InitResendClient(utils.EmailConfig{
+		APIKey:      cfg.Email.ResendAPIKey,
+		From:        cfg.Email.From,
+		FrontendURL: cfg.FrontendURL,
+	})
+
+	// Inicializar Cloudinary
+	if err := config.SetupCloudinary(cfg.Cloudinary); err != nil {
+		fatal("Error configurando Cloudinary", err)
+	}
+
+	// Registrar proveedores de login OIDC
+	setupOIDC(cfg.OIDC)
+
+	registerHealthChecks()
+
+	// SIGTERM (o Ctrl+C) inicia el apagado ordenado
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Tareas en segundo plano: terminan cuando se cancela ctx
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		utils.Keys.RunRotation(ctx, time.Minute)
+	}()
+	// Los avisos de anuncios no dependen de ctx: se cierran después de que
+	// terminan los pedidos que pueden encolarlos y se envían los pendientes
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		controllers.Services.Announcements.Run()
+	}()
+	if broker, ok := events.(*realtime.PostgresBroker); ok {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			broker.Run(ctx)
+		}()
+	}
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           router,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+	// Shutdown no corta las conexiones activas: los streams de eventos
+	// terminan al cerrar el broker
+	server.RegisterOnShutdown(func() {
+		if err := events.Close(); err != nil {
+			slog.Error("Error cerrando el broker de eventos", "error", err)
+		}
+	})
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Servidor escuchando", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal("Error en el servidor HTTP", err)
+	case <-ctx.Done():
+	}
+	// Una segunda señal corta el proceso sin esperar
+	stop()
+
+	slog.Info("Apagando el servidor")
+	health.SetShuttingDown()
+
+	// Shutdown deja de aceptar conexiones y espera a que terminen los pedidos
+	// en curso, incluidas las subidas de archivos a Cloudinary
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Algunos pedidos no terminaron a tiempo", "timeout", cfg.HTTP.ShutdownTimeout, "error", err)
+	}
+	controllers.Services.Announcements.Close()
+
+	workers.Wait()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Error enviando las últimas trazas", "error", err)
+	}
+	if err := database.Close(); err != nil {
+		slog.Error("Error cerrando la base de datos", "error", err)
+	}
+	slog.Info("Servidor apagado")
+}
+
+// memoryEventBacklog es cuántos eventos guarda el broker en memoria para los
+// clientes que se reconectan
+const memoryEventBacklog = 1000
+
+// newEventBroker crea el broker de eventos en tiempo real de los canales
+func newEventBroker(cfg config.RealtimeConfig) realtime.Broker {
+	if cfg.Broker == "postgres" {
+		return realtime.NewPostgresBroker(database.DB, cfg.Retention)
+	}
+	return realtime.NewMemoryBroker(memoryEventBacklog)
+}
+
+// setupKeyRing inicializa el llavero de claves JWT. La rotación periódica la
+// arranca runServe junto con las demás tareas en segundo plano.
+func setupKeyRing(cfg config.JWTConfig) error {
+	return utils.InitKeyRing(database.DB, utils.KeyRingConfig{
+		Algorithm:        cfg.Algorithm,
+		RotationInterval: cfg.RotationInterval,
+		TokenTTL:         utils.TokenTTL,
+		Issuer:           cfg.Issuer,
+		LegacySecret:     cfg.LegacySecret,
+		EncryptionKey:    cfg.KeyEncryptionKey,
+	})
+}
+
+// registerHealthChecks registra las dependencias que verifica /readyz. Los
+// servicios externos se consultan como mucho cada 30 segundos porque sus
+// APIs tienen límite de pedidos.
+func registerHealthChecks() {
+	health.Register(&health.Dependency{
+		Name:     "database",
+		Critical: true,
+		Check:    database.Ping,
+	})
+	health.Register(&health.Dependency{
+		Name:     "storage",
+		Critical: true,
+		CacheFor: 30 * time.Second,
+		Check: func(ctx context.Context) (map[string]interface{}, error) {
+			return map[string]interface{}{"backend": "cloudinary"}, config.PingCloudinary(ctx)
+		},
+	})
+	// Sin email solo fallan la recuperación de contraseñas y los avisos, el resto
+	// del sitio funciona
+	health.Register(&health.Dependency{
+		Name:     "email",
+		CacheFor: 30 * time.Second,
+		Check: func(ctx context.Context) (map[string]interface{}, error) {
+			return map[string]interface{}{"transport": "resend"}, utils.CheckEmailTransport(ctx)
+		},
+	})
+}
+
+func setupOIDC(cfg config.OIDCConfig) {
+	providers := make([]sso.ProviderConfig, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		providers = append(providers, sso.ProviderConfig{
+			Name:         p.Name,
+			DisplayName:  p.DisplayName,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			Scopes:       p.Scopes,
+			RedirectURL:  p.RedirectURL,
+			TrustEmail:   p.TrustEmail,
+		})
+	}
+	sso.Configure(providers)
+}
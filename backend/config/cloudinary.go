@@ -1,27 +1,177 @@
 package config
 
 import (
-	"os"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/tracing"
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var Cld *cloudinary.Cloudinary
 
 // SetupCloudinary inicializa la conexión con Cloudinary
-func SetupCloudinary() error {
+func SetupCloudinary(cfg CloudinaryConfig) error {
 	var err error
 
-	// Obtener credenciales desde variables de entorno
-	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
-	apiKey := os.Getenv("CLOUDINARY_API_KEY")
-	apiSecret := os.Getenv("CLOUDINARY_API_SECRET")
-
 	// Crear instancia de Cloudinary
-	Cld, err = cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
+	Cld, err = cloudinary.NewFromParams(cfg.CloudName, cfg.APIKey, cfg.APISecret)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// PingCloudinary verifica que la API de Cloudinary responda con las
+// credenciales configuradas
+func PingCloudinary(ctx context.Context) error {
+	if Cld == nil {
+		return errors.New("Cloudinary no fue inicializado")
+	}
+
+	result, err := Cld.Admin.Ping(ctx)
+	if err != nil {
+		return err
+	}
+	if result.Error.Message != "" {
+		return errors.New(result.Error.Message)
+	}
+	return nil
+}
+
+// StorageBackend identifica a Cloudinary en las métricas de subidas
+const StorageBackend = "cloudinary"
+
+// Upload sube un archivo a Cloudinary y registra bytes, duración y un span. Los errores
+// que la API devuelve en el cuerpo de la respuesta se devuelven como error.
+func Upload(ctx context.Context, file io.Reader, size int64, params uploader.UploadParams) (*uploader.UploadResult, error) {
+	ctx, span := tracing.Start(ctx, "storage.upload",
+		attribute.String("storage.backend", StorageBackend),
+		attribute.String("storage.folder", params.Folder),
+		attribute.Int64("storage.size", size),
+	)
+	start := time.Now()
+	result, err := Cld.Upload.Upload(ctx, file, params)
+	if err == nil && result.Error.Message != "" {
+		err = errors.New(result.Error.Message)
+	}
+	tracing.End(span, err)
+	metrics.ObserveUpload(StorageBackend, size, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Destroy borra de Cloudinary el archivo de una URL devuelta por Upload. Si el
+// archivo ya no existe no se considera un error.
+func Destroy(ctx context.Context, url string) error {
+	asset, err := parseAssetURL(url)
+	if err != nil {
+		return err
+	}
+
+	ctx, span := tracing.Start(ctx, "storage.delete",
+		attribute.String("storage.backend", StorageBackend),
+		attribute.String("storage.public_id", asset.publicID),
+	)
+	result, err := Cld.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     asset.publicID,
+		Type:         asset.deliveryType,
+		ResourceType: asset.resourceType,
+	})
+	if err == nil && result.Error.Message != "" {
+		err = errors.New(result.Error.Message)
+	}
+	if err == nil && result.Result != "ok" && result.Result != "not found" {
+		err = fmt.Errorf("Cloudinary respondió %q", result.Result)
+	}
+	tracing.End(span, err)
+	return err
+}
+
+// PrivateDownloadURL devuelve una URL firmada que descarga hasta expiresAt el
+// archivo de una URL devuelta por Upload con Type authenticated. Se firma acá
+// y no con Upload.PrivateDownloadURL porque el SDK envía expires_at como
+// fecha ISO y la API espera un timestamp Unix.
+func PrivateDownloadURL(assetURL string, expiresAt time.Time) (string, error) {
+	asset, err := parseAssetURL(assetURL)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("public_id", asset.publicID)
+	params.Set("type", asset.deliveryType)
+	params.Set("expires_at", strconv.FormatInt(expiresAt.Unix(), 10))
+	if asset.resourceType != "raw" {
+		params.Set("format", asset.format)
+	}
+	signature, err := api.SignParameters(params, Cld.Config.Cloud.APISecret)
+	if err != nil {
+		return "", err
+	}
+	params.Set("signature", signature)
+	params.Set("api_key", Cld.Config.Cloud.APIKey)
+
+	return fmt.Sprintf("%s/%s/%s/download?%s",
+		api.BaseURL(Cld.Config.API.UploadPrefix, ""), Cld.Config.Cloud.CloudName, asset.resourceType, params.Encode()), nil
+}
+
+// assetURL son las partes de una URL de entrega de Cloudinary
+type assetURL struct {
+	resourceType string
+	deliveryType string
+	publicID     string
+	format       string
+}
+
+// parseAssetURL obtiene el tipo de recurso, el tipo de entrega y el public ID
+// de una URL de entrega de Cloudinary:
+// https://res.cloudinary.com/<cloud>/<tipo>/<entrega>/v<versión>/<public id>.<ext>
+// donde la entrega es upload para los archivos públicos y authenticated para
+// los privados. Las imágenes y los videos se identifican sin la extensión;
+// los archivos raw la incluyen.
+func parseAssetURL(url string) (assetURL, error) {
+	parts := strings.Split(url, "/")
+	for i := 1; i < len(parts)-1; i++ {
+		if parts[i] != "upload" && parts[i] != "authenticated" {
+			continue
+		}
+		asset := assetURL{resourceType: parts[i-1], deliveryType: parts[i]}
+		rest := parts[i+1:]
+		if len(rest) > 1 && len(rest[0]) > 1 && rest[0][0] == 'v' && isDigits(rest[0][1:]) {
+			rest = rest[1:]
+		}
+		asset.publicID = strings.Join(rest, "/")
+		asset.format = strings.TrimPrefix(path.Ext(asset.publicID), ".")
+		if asset.resourceType != "raw" {
+			asset.publicID = strings.TrimSuffix(asset.publicID, path.Ext(asset.publicID))
+		}
+		if asset.publicID != "" {
+			return asset, nil
+		}
+	}
+	return assetURL{}, fmt.Errorf("URL de Cloudinary inválida: %s", url)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,622 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config es la configuración completa del backend. Se carga una sola vez al
+// iniciar (ver Load) y cada subsistema recibe la parte que necesita.
+type Config struct {
+	Port                  int
+	FrontendURL           string
+	AdminEmails           []string
+	BreachedPasswordsFile string
+	MetricsToken          string
+
+	Log        LogConfig
+	Tracing    TracingConfig
+	HTTP       HTTPConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Cloudinary CloudinaryConfig
+	Email      EmailConfig
+	OIDC       OIDCConfig
+	Realtime   RealtimeConfig
+
+	// values guarda el valor crudo de cada variable y de dónde salió, para
+	// poder mostrarlos con Print
+	values map[string]value
+}
+
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+// TracingConfig define a dónde se exportan las trazas de OpenTelemetry.
+// Exporter puede ser none, stdout u otlp.
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string
+	SampleRatio  float64
+	ServiceName  string
+}
+
+// HTTPConfig define los tiempos límite del servidor. La escritura es más larga
+// que la lectura porque los pedidos con archivos los suben a Cloudinary antes
+// de responder.
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout es cuánto se espera a los pedidos en curso al apagar
+	ShutdownTimeout time.Duration
+}
+
+type DatabaseConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	TimeZone string
+}
+
+// DSN arma la cadena de conexión para el driver de PostgreSQL
+func (c DatabaseConfig) DSN() string {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		c.Host, c.User, c.Password, c.Name, c.Port, c.SSLMode,
+	)
+	if c.TimeZone != "" {
+		dsn += " timezone=" + c.TimeZone
+	}
+	return dsn
+}
+
+type JWTConfig struct {
+	Algorithm        string
+	RotationInterval time.Duration
+	Issuer           string
+	// LegacySecret valida los tokens HS256 emitidos antes del llavero de claves
+	LegacySecret string
+	// KeyEncryptionKey cifra las claves privadas guardadas en la base de datos
+	KeyEncryptionKey []byte
+}
+
+type CloudinaryConfig struct {
+	CloudName string
+	APIKey    string
+	APISecret string
+}
+
+type EmailConfig struct {
+	ResendAPIKey string
+	From         string
+}
+
+type OIDCConfig struct {
+	RedirectBaseURL string
+	Providers       []OIDCProviderConfig
+}
+
+type OIDCProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+	TrustEmail   bool
+}
+
+// RealtimeConfig define cómo se comparten los eventos en tiempo real de los
+// canales. Broker memory sirve para un solo nodo; con varias réplicas hace
+// falta postgres.
+type RealtimeConfig struct {
+	Broker string
+	// Retention es cuánto se guardan los eventos para los clientes que se
+	// reconectan (solo postgres)
+	Retention time.Duration
+}
+
+// Orígenes posibles de un valor, de menor a mayor prioridad
+const (
+	sourceDefault = "default"
+	sourceFile    = "archivo"
+	sourceEnv     = "entorno"
+	sourceFlag    = "flag"
+)
+
+type value struct {
+	raw    string
+	source string
+}
+
+// setting describe una variable de configuración conocida. Cada una se puede
+// definir en el archivo, como variable de entorno o con un flag (DB_HOST se
+// pasa como --db-host).
+type setting struct {
+	key          string
+	defaultValue string
+	secret       bool
+	usage        string
+}
+
+var settings = []setting{
+	{key: "PORT", defaultValue: "8080", usage: "puerto HTTP del servidor"},
+	{key: "FRONTEND_URL", defaultValue: "http://localhost:3000", usage: "URL pública del frontend"},
+	{key: "ADMIN_EMAILS", usage: "emails separados por coma que reciben el rol de administrador"},
+	{key: "BREACHED_PASSWORDS_FILE", usage: "archivo adicional de contraseñas filtradas"},
+	{key: "METRICS_TOKEN", secret: true, usage: "si se define, /metrics exige Authorization: Bearer <token>"},
+
+	{key: "LOG_LEVEL", defaultValue: "info", usage: "nivel de log: debug, info, warn o error"},
+	{key: "LOG_FORMAT", defaultValue: "json", usage: "formato de log: json o text"},
+
+	{key: "TRACING_EXPORTER", defaultValue: "none", usage: "exportador de trazas: none, stdout u otlp"},
+	{key: "TRACING_OTLP_ENDPOINT", defaultValue: "http://localhost:4318", usage: "URL del colector OTLP (HTTP)"},
+	{key: "TRACING_SAMPLE_RATIO", defaultValue: "1", usage: "fracción de pedidos que se trazan, entre 0 y 1"},
+	{key: "TRACING_SERVICE_NAME", defaultValue: "repositorio-tecnologico", usage: "nombre del servicio en las trazas"},
+
+	{key: "HTTP_READ_HEADER_TIMEOUT", defaultValue: "10s", usage: "tiempo máximo para leer los encabezados"},
+	{key: "HTTP_READ_TIMEOUT", defaultValue: "2m", usage: "tiempo máximo para leer un pedido completo"},
+	{key: "HTTP_WRITE_TIMEOUT", defaultValue: "5m", usage: "tiempo máximo para responder un pedido"},
+	{key: "HTTP_IDLE_TIMEOUT", defaultValue: "2m", usage: "tiempo máximo de una conexión keep-alive inactiva"},
+	{key: "SHUTDOWN_TIMEOUT", defaultValue: "1m", usage: "tiempo de espera de los pedidos en curso al apagar"},
+
+	{key: "DB_HOST", usage: "host de PostgreSQL"},
+	{key: "DB_PORT", defaultValue: "5432", usage: "puerto de PostgreSQL"},
+	{key: "DB_USER", usage: "usuario de PostgreSQL"},
+	{key: "DB_PASSWORD", secret: true, usage: "contraseña de PostgreSQL"},
+	{key: "DB_NAME", usage: "base de datos"},
+	{key: "DB_SSLMODE", defaultValue: "require", usage: "sslmode de la conexión"},
+	{key: "DB_TIMEZONE", usage: "zona horaria de la conexión"},
+
+	{key: "JWT_ALGORITHM", defaultValue: "EdDSA", usage: "algoritmo de firma de los JWT (EdDSA o RS256)"},
+	{key: "JWT_ROTATION_INTERVAL", defaultValue: "720h", usage: "cada cuánto se rota la clave de firma"},
+	{key: "JWT_ISSUER", defaultValue: "repositorio-tecnologico", usage: "claim iss de los JWT"},
+	{key: "JWT_SECRET", secret: true, usage: "secreto de los tokens HS256 anteriores al llavero"},
+	{key: "JWT_KEY_ENCRYPTION_KEY", secret: true, usage: "clave AES de 32 bytes en base64 que cifra las claves de firma guardadas (openssl rand -base64 32)"},
+
+	{key: "CLOUDINARY_CLOUD_NAME", usage: "cloud name de Cloudinary"},
+	{key: "CLOUDINARY_API_KEY", secret: true, usage: "API key de Cloudinary"},
+	{key: "CLOUDINARY_API_SECRET", secret: true, usage: "API secret de Cloudinary"},
+
+	{key: "RESEND_API_KEY", secret: true, usage: "API key de Resend"},
+	{key: "EMAIL_FROM", defaultValue: "noreply@redapuntes.com", usage: "remitente de los emails"},
+
+	{key: "OIDC_PROVIDERS", usage: "proveedores OIDC separados por coma"},
+	{key: "OIDC_REDIRECT_BASE_URL", defaultValue: "http://localhost:8080", usage: "URL pública del backend para los callbacks OIDC"},
+
+	{key: "REALTIME_BROKER", defaultValue: "memory", usage: "pub/sub de eventos en tiempo real: memory (un nodo) o postgres (varias réplicas)"},
+	{key: "REALTIME_RETENTION", defaultValue: "24h", usage: "cuánto se guardan los eventos en tiempo real para reanudar"},
+}
+
+// Variables OIDC_<NOMBRE>_* de cada proveedor
+var oidcProviderKeys = []string{"DISPLAY_NAME", "ISSUER", "CLIENT_ID", "CLIENT_SECRET", "SCOPES", "TRUST_EMAIL"}
+
+// ValidationError reúne todos los problemas de la configuración para
+// mostrarlos juntos en lugar de fallar con el primero
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "configuración inválida:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+func (e *ValidationError) err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Load arma la configuración a partir de, en orden de prioridad, los flags, las
+// variables de entorno, un archivo opcional en formato .env y los valores por
+// defecto. El archivo se indica con --config o CONFIG_FILE; si no se indica se
+// usa .env cuando existe. Devuelve los argumentos que no son flags (el
+// subcomando).
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("repositorio-tecnologico", flag.ContinueOnError)
+	configFile := fs.String("config", "", "archivo de configuración en formato .env")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.key] = fs.String(flagName(s.key), "", s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	fileValues := map[string]string{}
+	if path != "" {
+		values, err := godotenv.Read(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("no se pudo leer el archivo de configuración %s: %w", path, err)
+		}
+		fileValues = values
+	} else if values, err := godotenv.Read(".env"); err == nil {
+		fileValues = values
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("no se pudo leer .env: %w", err)
+	}
+
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	resolve := func(key, defaultValue string) value {
+		if setFlags[flagName(key)] {
+			return value{raw: *flagValues[key], source: sourceFlag}
+		}
+		if raw, ok := os.LookupEnv(key); ok {
+			return value{raw: raw, source: sourceEnv}
+		}
+		if raw, ok := fileValues[key]; ok {
+			return value{raw: raw, source: sourceFile}
+		}
+		return value{raw: defaultValue, source: sourceDefault}
+	}
+
+	values := map[string]value{}
+	for _, s := range settings {
+		values[s.key] = resolve(s.key, s.defaultValue)
+	}
+	for _, name := range splitList(values["OIDC_PROVIDERS"].raw) {
+		for _, suffix := range oidcProviderKeys {
+			key := oidcPrefix(name) + suffix
+			if v := resolve(key, ""); v.source != sourceDefault {
+				values[key] = v
+			}
+		}
+	}
+
+	cfg, err := parse(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// parse convierte los valores crudos a los tipos de Config. Solo falla por
+// valores que no se pueden interpretar; las reglas de negocio están en Validate.
+func parse(values map[string]value) (*Config, error) {
+	get := func(key string) string { return strings.TrimSpace(values[key].raw) }
+	problems := &ValidationError{}
+
+	parseDuration := func(key string) time.Duration {
+		d, err := time.ParseDuration(get(key))
+		if err != nil {
+			problems.add("%s debe ser una duración (por ejemplo 30s): %q", key, get(key))
+		}
+		return d
+	}
+
+	parseInt := func(key string) int {
+		n, err := strconv.Atoi(get(key))
+		if err != nil {
+			problems.add("%s debe ser un número: %q", key, get(key))
+		}
+		return n
+	}
+
+	parseFloat := func(key string) float64 {
+		f, err := strconv.ParseFloat(get(key), 64)
+		if err != nil {
+			problems.add("%s debe ser un número: %q", key, get(key))
+		}
+		return f
+	}
+
+	cfg := &Config{
+		Port:                  parseInt("PORT"),
+		FrontendURL:           strings.TrimRight(get("FRONTEND_URL"), "/"),
+		AdminEmails:           splitList(get("ADMIN_EMAILS")),
+		BreachedPasswordsFile: get("BREACHED_PASSWORDS_FILE"),
+		MetricsToken:          get("METRICS_TOKEN"),
+		Log: LogConfig{
+			Level:  strings.ToLower(get("LOG_LEVEL")),
+			Format: strings.ToLower(get("LOG_FORMAT")),
+		},
+		Tracing: TracingConfig{
+			Exporter:     strings.ToLower(get("TRACING_EXPORTER")),
+			OTLPEndpoint: get("TRACING_OTLP_ENDPOINT"),
+			SampleRatio:  parseFloat("TRACING_SAMPLE_RATIO"),
+			ServiceName:  get("TRACING_SERVICE_NAME"),
+		},
+		HTTP: HTTPConfig{
+			ReadHeaderTimeout: parseDuration("HTTP_READ_HEADER_TIMEOUT"),
+			ReadTimeout:       parseDuration("HTTP_READ_TIMEOUT"),
+			WriteTimeout:      parseDuration("HTTP_WRITE_TIMEOUT"),
+			IdleTimeout:       parseDuration("HTTP_IDLE_TIMEOUT"),
+			ShutdownTimeout:   parseDuration("SHUTDOWN_TIMEOUT"),
+		},
+		Database: DatabaseConfig{
+			Host:     get("DB_HOST"),
+			Port:     parseInt("DB_PORT"),
+			User:     get("DB_USER"),
+			Password: values["DB_PASSWORD"].raw,
+			Name:     get("DB_NAME"),
+			SSLMode:  get("DB_SSLMODE"),
+			TimeZone: get("DB_TIMEZONE"),
+		},
+		JWT: JWTConfig{
+			Algorithm:    get("JWT_ALGORITHM"),
+			Issuer:       get("JWT_ISSUER"),
+			LegacySecret: values["JWT_SECRET"].raw,
+		},
+		Cloudinary: CloudinaryConfig{
+			CloudName: get("CLOUDINARY_CLOUD_NAME"),
+			APIKey:    get("CLOUDINARY_API_KEY"),
+			APISecret: get("CLOUDINARY_API_SECRET"),
+		},
+		Email: EmailConfig{
+			ResendAPIKey: get("RESEND_API_KEY"),
+			From:         get("EMAIL_FROM"),
+		},
+		OIDC: OIDCConfig{
+			RedirectBaseURL: strings.TrimRight(get("OIDC_REDIRECT_BASE_URL"), "/"),
+		},
+		Realtime: RealtimeConfig{
+			Broker:    strings.ToLower(get("REALTIME_BROKER")),
+			Retention: parseDuration("REALTIME_RETENTION"),
+		},
+		values: values,
+	}
+
+	cfg.JWT.RotationInterval = parseDuration("JWT_ROTATION_INTERVAL")
+	if key := get("JWT_KEY_ENCRYPTION_KEY"); key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			problems.add("JWT_KEY_ENCRYPTION_KEY debe estar en base64")
+		}
+		cfg.JWT.KeyEncryptionKey = decoded
+	}
+
+	for _, name := range splitList(get("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := oidcPrefix(name)
+
+		provider := OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  get(prefix + "DISPLAY_NAME"),
+			Issuer:       get(prefix + "ISSUER"),
+			ClientID:     get(prefix + "CLIENT_ID"),
+			ClientSecret: values[prefix+"CLIENT_SECRET"].raw,
+			Scopes:       strings.Fields(strings.ReplaceAll(get(prefix+"SCOPES"), ",", " ")),
+			RedirectURL:  cfg.OIDC.RedirectBaseURL + "/auth/oidc/" + name + "/callback",
+		}
+		if trust := get(prefix + "TRUST_EMAIL"); trust != "" {
+			trustEmail, err := strconv.ParseBool(trust)
+			if err != nil {
+				problems.add("%sTRUST_EMAIL debe ser true o false: %q", prefix, trust)
+			}
+			provider.TrustEmail = trustEmail
+		}
+		if provider.DisplayName == "" {
+			provider.DisplayName = name
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		cfg.OIDC.Providers = append(cfg.OIDC.Providers, provider)
+	}
+
+	return cfg, problems.err()
+}
+
+// Validate verifica toda la configuración necesaria para levantar el servidor
+func (c *Config) Validate() error {
+	problems := &ValidationError{}
+
+	if c.Port < 1 || c.Port > 65535 {
+		problems.add("PORT debe estar entre 1 y 65535")
+	}
+	if !validURL(c.FrontendURL) {
+		problems.add("FRONTEND_URL debe ser una URL http(s) válida")
+	}
+	if c.BreachedPasswordsFile != "" {
+		if _, err := os.Stat(c.BreachedPasswordsFile); err != nil {
+			problems.add("BREACHED_PASSWORDS_FILE no se puede leer: %v", err)
+		}
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems.add("LOG_LEVEL debe ser debug, info, warn o error")
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		problems.add("LOG_FORMAT debe ser json o text")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if !validURL(c.Tracing.OTLPEndpoint) {
+			problems.add("TRACING_OTLP_ENDPOINT debe ser una URL http(s) válida")
+		}
+	default:
+		problems.add("TRACING_EXPORTER debe ser none, stdout u otlp")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems.add("TRACING_SAMPLE_RATIO debe estar entre 0 y 1")
+	}
+
+	for key, d := range map[string]time.Duration{
+		"HTTP_READ_HEADER_TIMEOUT": c.HTTP.ReadHeaderTimeout,
+		"HTTP_READ_TIMEOUT":        c.HTTP.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":       c.HTTP.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        c.HTTP.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":         c.HTTP.ShutdownTimeout,
+	} {
+		if d <= 0 {
+			problems.add("%s debe ser mayor a 0", key)
+		}
+	}
+
+	c.Database.validate(problems)
+
+	switch c.JWT.Algorithm {
+	case "EdDSA", "RS256":
+	default:
+		problems.add("JWT_ALGORITHM debe ser EdDSA o RS256")
+	}
+	if c.JWT.RotationInterval < 2*time.Hour {
+		problems.add("JWT_ROTATION_INTERVAL debe ser de al menos 2h")
+	}
+	if c.JWT.Issuer == "" {
+		problems.add("JWT_ISSUER es obligatorio")
+	}
+	if len(c.JWT.KeyEncryptionKey) != 32 {
+		problems.add("JWT_KEY_ENCRYPTION_KEY es obligatoria y debe tener 32 bytes")
+	}
+
+	if c.Cloudinary.CloudName == "" || c.Cloudinary.APIKey == "" || c.Cloudinary.APISecret == "" {
+		problems.add("CLOUDINARY_CLOUD_NAME, CLOUDINARY_API_KEY y CLOUDINARY_API_SECRET son obligatorios")
+	}
+	if c.Email.From == "" {
+		problems.add("EMAIL_FROM es obligatorio")
+	}
+
+	if len(c.OIDC.Providers) > 0 && !validURL(c.OIDC.RedirectBaseURL) {
+		problems.add("OIDC_REDIRECT_BASE_URL debe ser una URL http(s) válida")
+	}
+	for _, p := range c.OIDC.Providers {
+		prefix := oidcPrefix(p.Name)
+		if !validURL(p.Issuer) {
+			problems.add("%sISSUER debe ser una URL http(s) válida", prefix)
+		}
+		if p.ClientID == "" {
+			problems.add("%sCLIENT_ID es obligatorio", prefix)
+		}
+	}
+
+	switch c.Realtime.Broker {
+	case "memory", "postgres":
+	default:
+		problems.add("REALTIME_BROKER debe ser memory o postgres")
+	}
+	if c.Realtime.Retention < time.Minute {
+		problems.add("REALTIME_RETENTION debe ser de al menos 1m")
+	}
+
+	return problems.err()
+}
+
+// ValidateDatabase verifica solo la conexión a la base de datos, para los
+// comandos que no levantan el servidor
+func (c *Config) ValidateDatabase() error {
+	problems := &ValidationError{}
+	c.Database.validate(problems)
+	return problems.err()
+}
+
+func (c DatabaseConfig) validate(problems *ValidationError) {
+	if c.Host == "" {
+		problems.add("DB_HOST es obligatorio")
+	}
+	if c.User == "" {
+		problems.add("DB_USER es obligatorio")
+	}
+	if c.Name == "" {
+		problems.add("DB_NAME es obligatorio")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		problems.add("DB_PORT debe estar entre 1 y 65535")
+	}
+	switch c.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		problems.add("DB_SSLMODE inválido: %q", c.SSLMode)
+	}
+}
+
+// Print escribe la configuración efectiva y el origen de cada valor. Los
+// secretos se reemplazan para poder compartir la salida sin exponerlos.
+func (c *Config) Print(w io.Writer) {
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+	var oidcKeys []string
+	for key := range c.values {
+		if strings.HasPrefix(key, "OIDC_") && !isSetting(key) {
+			oidcKeys = append(oidcKeys, key)
+		}
+	}
+	sort.Strings(oidcKeys)
+	keys = append(keys, oidcKeys...)
+
+	for _, key := range keys {
+		v := c.values[key]
+		raw := v.raw
+		if isSecret(key) && raw != "" {
+			raw = "********"
+		}
+		fmt.Fprintf(w, "%s=%s\t# %s\n", key, raw, v.source)
+	}
+}
+
+func isSetting(key string) bool {
+	for _, s := range settings {
+		if s.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isSecret(key string) bool {
+	for _, s := range settings {
+		if s.key == key {
+			return s.secret
+		}
+	}
+	return strings.HasSuffix(key, "_SECRET")
+}
+
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func oidcPrefix(name string) string {
+	return "OIDC_" + strings.ToUpper(name) + "_"
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserRoles lista los roles asignados a un usuario
+func GetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField("id", "invalid_id"))
+		return
+	}
+
+	roles, err := rbac.Roles(uint(userID))
+	if err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GrantRole otorga un rol a un usuario. Los moderadores pueden limitarse a una
+// universidad; los administradores siempre son globales.
+func GrantRole(c *gin.Context) {
+	userID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role         string `json:"role" binding:"required"`
+		UniversityID *uint  `json:"university_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	role, err := Services.Roles.Grant(c, actor(c), userID, input.Role, input.UniversityID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Rol otorgado exitosamente",
+		"role":    role,
+	})
+}
+
+// RevokeRole quita un rol a un usuario
+func RevokeRole(c *gin.Context) {
+	userID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	roleID, ok := paramID(c, "roleId")
+	if !ok {
+		return
+	}
+
+	if _, err := Services.Roles.Revoke(c, actor(c), userID, roleID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rol quitado exitosamente"})
+}
+
+// GetAuditLogs lista el log de auditoría, con filtros opcionales por acción y usuario
+func GetAuditLogs(c *gin.Context) {
+	page := 1
+	pageSize := 50
+
+	pageNum, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err == nil && pageNum > 0 {
+		page = pageNum
+	}
+
+	query := database.DB.WithContext(c).Model(&models.AuditLog{})
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ? OR actor_id = ?", userID, userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+
+	var logs []models.AuditLog
+	if err := query.Order("created_at DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&logs).Error; err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs": logs,
+		"pagination": gin.H{
+			"current_page": page,
+			"total_pages":  (int(total) + pageSize - 1) / pageSize,
+			"page_size":    pageSize,
+			"total_items":  total,
+		},
+	})
+}
@@ -1,15 +1,24 @@
 package controllers
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
 	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
 	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
 	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func Register(c *gin.Context) {
@@ -22,25 +31,60 @@ func Register(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	username := validation.NormalizeUsername(req.Username)
+	email, canonicalEmail, emailErr := validation.NormalizeEmail(req.Email)
+
+	var errs validation.Errors
+	errs.Merge(validation.Username(username))
+	errs.Merge(emailErr)
+	errs.Merge(validation.AccountName(req.AccountName))
+	errs.Merge(validation.Password("password", req.Password, username, email))
+	if errs.Has() {
+		apperror.Abort(c, apperror.Validation(errs))
+		return
+	}
+
+	// Verificar que el nombre de usuario y la casilla de email estén libres
+	var taken []models.User
+	if err := database.DB.WithContext(c).
+		Where("LOWER(username) = ? OR LOWER(email) = ? OR email_canonical = ?", username, email, canonicalEmail).
+		Find(&taken).Error; err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+	for _, u := range taken {
+		if strings.ToLower(u.Username) == username {
+			errs.Add("username", "username_taken", "Ese nombre de usuario ya está en uso")
+		} else {
+			errs.Add("email", "email_taken", "Ya existe una cuenta con ese email")
+		}
+	}
+	if errs.Has() {
+		apperror.Abort(c, apperror.Validation(errs).WithStatus(http.StatusConflict))
 		return
 	}
 
 	user := models.User{
-		Username:    req.Username,
-		Email:       req.Email,
-		AccountName: req.AccountName,
-		Img:         req.Img,
+		Username:       username,
+		Email:          email,
+		EmailCanonical: &canonicalEmail,
+		AccountName:    strings.TrimSpace(req.AccountName),
+		Img:            req.Img,
 	}
 	if err := user.SetPassword(req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la contraseña"})
+		apperror.Abort(c, apperror.Internal(err))
 		return
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No se pudo registrar el usuario"})
+	if err := database.DB.WithContext(c).Create(&user).Error; err != nil {
+		apperror.Abort(c, apperror.Internal(err))
 		return
 	}
+	metrics.Registrations.WithLabelValues("password").Inc()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Usuario registrado con éxito"})
 }
@@ -53,79 +97,184 @@ func Login(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 		return
 	}
 
+	identifier := strings.ToLower(strings.TrimSpace(req.Email))
+	if identifier == "" {
+		identifier = strings.ToLower(strings.TrimSpace(req.Username))
+	}
+
 	var user models.User
 	var err error
 
 	if req.Email != "" {
-		err = database.DB.Where("email = ?", req.Email).First(&user).Error
+		err = database.DB.WithContext(c).Where("LOWER(email) = ?", identifier).First(&user).Error
 	} else {
-		err = database.DB.Where("username = ?", req.Username).First(&user).Error
+		err = database.DB.WithContext(c).Where("LOWER(username) = ?", identifier).First(&user).Error
 	}
-
+	account := &user
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email o contraseña inválidos"})
-		return
+		account = nil
 	}
-
-	if !user.CheckPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email o contraseña inválidos"})
+	if !verifyPassword(c, account, identifier, req.Password) {
 		return
 	}
 
 	token, err := utils.GenerateJWT(user.UserID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar el token"})
+		apperror.Abort(c, apperror.Internal(err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"token": token, "me": user})
 }
 
+// absentAccount se usa para controlar la contraseña cuando la cuenta no
+// existe: bcrypt tarda lo mismo y el tiempo de respuesta no revela si el
+// identificador está registrado
+var absentAccount = models.User{PasswordHash: "$2a$10$NtIFOBL5tzqHlv2TYn6jWO62Zkw9//qJqF9ZTtuxejmNxwq7X.qr6"}
+
+// verifyPassword controla la contraseña de user, o de nadie si la cuenta no
+// existe, con límite de intentos por IP y por cuenta (o por identificador si
+// no existe). Si devuelve false ya respondió el pedido.
+func verifyPassword(c *gin.Context, user *models.User, identifier, password string) bool {
+	ip := c.ClientIP()
+	found := user != nil
+	var userID uint
+	ipKey := "login:ip:" + ip
+	accountKey := "login:ident:" + identifier
+	if found {
+		userID = user.UserID
+		accountKey = fmt.Sprintf("login:user:%d", user.UserID)
+	}
+
+	// El intento se cuenta como fallido antes de controlar la contraseña, y se
+	// devuelve si sale bien
+	attempt, wait := security.Reserve(c,
+		security.Limit{Key: ipKey, Policy: security.LoginIPPolicy},
+		security.Limit{Key: accountKey, Policy: security.LoginAccountPolicy},
+	)
+	if wait > 0 {
+		security.Audit(c, models.AuditLog{
+			UserID:  auditUserID(found, userID),
+			Action:  security.AuditLoginThrottled,
+			IP:      ip,
+			Details: "identificador: " + identifier,
+		})
+		tooManyAttempts(c, wait)
+		return false
+	}
+
+	account := user
+	if !found {
+		account = &absentAccount
+	}
+	if !account.CheckPassword(password) || !found {
+		security.Audit(c, models.AuditLog{
+			UserID:  auditUserID(found, userID),
+			Action:  security.AuditLoginFailed,
+			IP:      ip,
+			Details: "identificador: " + identifier,
+		})
+
+		if until, locked := attempt.Locked(accountKey); locked && found {
+			security.Audit(c, models.AuditLog{
+				UserID:  &user.UserID,
+				Action:  security.AuditAccountLocked,
+				IP:      ip,
+				Details: "bloqueada hasta " + until.Format(time.RFC3339),
+			})
+			if err := utils.SendAccountLockedEmail(c, user.Email, until); err != nil {
+				logging.FromContext(c).Error("Error enviando aviso de bloqueo", "account_id", user.UserID, "error", err)
+			}
+		}
+
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidCredentials))
+		return false
+	}
+
+	attempt.Release(c)
+	if err := security.Reset(accountKey); err != nil {
+		logging.FromContext(c).Error("Error reiniciando intentos de login", "error", err)
+	}
+	return true
+}
+
 func RequestPasswordReset(c *gin.Context) {
 	var req struct {
 		Email string `json:"email"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	ip := c.ClientIP()
+	ipKey := "reset-request:ip:" + ip
+	// Cada solicitud cuenta, salga bien o no
+	if _, wait := security.Reserve(c, security.Limit{Key: ipKey, Policy: security.ResetRequestIPPolicy}); wait > 0 {
+		tooManyAttempts(c, wait)
 		return
 	}
 
 	var user models.User
-	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	if err := database.DB.WithContext(c).Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(req.Email))).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Si el email existe, recibirás un correo con instrucciones"})
+		return
+	}
+
+	// Si la cuenta ya recibió demasiados correos se responde igual que siempre
+	// para no revelar si el email existe
+	accountKey := fmt.Sprintf("reset-request:user:%d", user.UserID)
+	if _, wait := security.Reserve(c, security.Limit{Key: accountKey, Policy: security.ResetRequestAccountPolicy}); wait > 0 {
+		security.Audit(c, models.AuditLog{
+			UserID:  &user.UserID,
+			Action:  security.AuditPasswordResetFailed,
+			IP:      ip,
+			Details: "solicitud limitada por exceso de pedidos",
+		})
 		c.JSON(http.StatusOK, gin.H{"message": "Si el email existe, recibirás un correo con instrucciones"})
 		return
 	}
 
 	// Generar token aleatorio
-	tokenBytes := make([]byte, 32)
-	if _, err := rand.Read(tokenBytes); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al generar el token"})
+	resetToken, err := security.NewToken()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal(err))
 		return
 	}
-	resetToken := hex.EncodeToString(tokenBytes)
 
-	// Guardar token en la base de datos
-	user.ResetPasswordToken = resetToken
-	user.ResetPasswordExpires = time.Now().Add(1 * time.Hour)
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la solicitud"})
+	// Guardar solo el hash del token en la base de datos
+	token := models.PasswordResetToken{
+		UserID:    user.UserID,
+		TokenHash: security.HashToken(resetToken),
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	}
+	if err := database.DB.WithContext(c).Create(&token).Error; err != nil {
+		apperror.Abort(c, apperror.Internal(err))
 		return
 	}
 
+	security.Audit(c, models.AuditLog{
+		UserID: &user.UserID,
+		Action: security.AuditPasswordResetRequest,
+		IP:     ip,
+	})
+
 	// Enviar email
-	if err := utils.SendPasswordResetEmail(user.Email, resetToken); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al enviar el email"})
+	if err := utils.SendPasswordResetEmail(c, user.Email, resetToken); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeEmailFailed).Wrap(err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Si el email existe, recibirás un correo con instrucciones"})
 }
 
+var errInvalidResetToken = errors.New("token inválido o expirado")
+
 func ResetPassword(c *gin.Context) {
 	var req struct {
 		Token    string `json:"token"`
@@ -133,30 +282,111 @@ func ResetPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 		return
 	}
 
-	var user models.User
-	if err := database.DB.Where("reset_password_token = ? AND reset_password_expires > ?",
-		req.Token, time.Now()).First(&user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido o expirado"})
+	ip := c.ClientIP()
+	ipKey := "reset:ip:" + ip
+	// Igual que en el login, el intento se cuenta antes de probar el token y se
+	// devuelve si el token era válido
+	attempt, wait := security.Reserve(c, security.Limit{Key: ipKey, Policy: security.ResetTokenIPPolicy})
+	if wait > 0 {
+		tooManyAttempts(c, wait)
 		return
 	}
 
-	if err := user.SetPassword(req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar la contraseña"})
+	var user models.User
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+			security.HashToken(req.Token), time.Now()).First(&token).Error; err != nil {
+			return errInvalidResetToken
+		}
+
+		// Marcar el token como usado de forma atómica: si dos pedidos llegan a la
+		// vez solo uno puede consumirlo
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("token_id = ? AND used_at IS NULL", token.TokenID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidResetToken
+		}
+
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return err
+		}
+		// La contraseña se valida acá porque no puede contener los datos del
+		// usuario; si no es válida la transacción se revierte y el token sigue
+		// sirviendo
+		if fe := validation.Password("password", req.Password, user.Username, user.Email); fe != nil {
+			return validation.Errors{*fe}
+		}
+		if err := user.SetPassword(req.Password); err != nil {
+			return err
+		}
+		// Usar el enlace enviado por email también prueba que controla el email
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password_hash":     user.PasswordHash,
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", now),
+		}).Error; err != nil {
+			return err
+		}
+
+		// Invalidar cualquier otro token pendiente del usuario
+		return tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.UserID).
+			Update("used_at", now).Error
+	})
+
+	if errors.Is(err, errInvalidResetToken) {
+		security.Audit(c, models.AuditLog{
+			Action:  security.AuditPasswordResetFailed,
+			IP:      ip,
+			Details: "token inválido o expirado",
+		})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidResetToken))
 		return
 	}
+	attempt.Release(c)
 
-	// Limpiar token de reset
-	user.ResetPasswordToken = ""
-	user.ResetPasswordExpires = time.Time{}
-
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al guardar los cambios"})
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		apperror.Abort(c, apperror.Validation(fieldErrs))
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal(err))
 		return
 	}
 
+	// Con la contraseña nueva la cuenta deja de estar bloqueada
+	if err := security.Reset(fmt.Sprintf("login:user:%d", user.UserID)); err != nil {
+		logging.FromContext(c).Error("Error reiniciando intentos de login", "error", err)
+	}
+	security.Audit(c, models.AuditLog{
+		UserID: &user.UserID,
+		Action: security.AuditPasswordReset,
+		IP:     ip,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Contraseña actualizada con éxito"})
 }
+
+// tooManyAttempts responde 429 indicando cuándo se puede reintentar
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	apperror.Abort(c, apperror.New(apperror.CodeTooManyAttempts).WithDetail("retry_after", seconds))
+}
+
+func auditUserID(found bool, userID uint) *uint {
+	if !found {
+		return nil
+	}
+	return &userID
+}
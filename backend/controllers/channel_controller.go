@@ -2,11 +2,14 @@ package controllers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/LautaroRomano/repositorio-tecnologico/database"
-	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,85 +24,240 @@ func CreateChannel(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 		return
 	}
 
-	userID := c.MustGet("userID").(uint)
-
-	channel := models.Channel{
+	channel, err := Services.Channels.Create(c, actor(c), services.NewChannel{
 		Name:         input.Name,
 		Description:  input.Description,
 		IsPrivate:    input.IsPrivate,
-		CreatedBy:    userID,
 		UniversityID: input.UniversityID,
 		CareerID:     input.CareerID,
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
 	}
 
-	if err := database.DB.Create(&channel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear el canal"})
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Canal creado exitosamente",
+		"channel": channel,
+	})
+}
+
+// GetChannels obtiene la lista de canales disponibles para el usuario, con
+// los posts y comentarios no leídos de cada uno y el total para el badge
+func GetChannels(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	channels, err := Services.Channels.ListForUser(c, userID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	counts, err := Services.Channels.UnreadCounts(c, userID)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Crear el primer miembro (creador) como administrador
-	member := models.ChannelMember{
-		ChannelID:  channel.ChannelID,
-		UserID:     userID,
-		IsAdmin:    true,
-		JoinedAt:   time.Now(),
-		LastSeenAt: time.Now(),
+	unread := make(map[uint]gin.H, len(counts))
+	var total int64
+	for _, count := range counts {
+		unread[count.ChannelID] = gin.H{
+			"posts":        count.Posts,
+			"comments":     count.Comments,
+			"last_seen_at": count.LastSeenAt,
+		}
+		total += count.Posts + count.Comments
 	}
 
-	if err := database.DB.Create(&member).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al agregar el creador como miembro"})
+	c.JSON(http.StatusOK, gin.H{
+		"channels":     channels,
+		"unread":       unread,
+		"unread_total": total,
+	})
+}
+
+// MarkChannelRead marca como leído todo lo publicado en el canal hasta ahora
+func MarkChannelRead(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Canal creado exitosamente",
+	member, err := Services.Channels.MarkRead(c, actor(c), channelID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"last_seen_at": member.LastSeenAt})
+}
+
+// UpdateChannel modifica nombre, descripción, privacidad o carrera del canal
+func UpdateChannel(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		IsPrivate   *bool   `json:"is_private"`
+		CareerID    *uint   `json:"career_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	channel, err := Services.Channels.Update(c, actor(c), channelID, services.ChannelUpdate{
+		Name:        input.Name,
+		Description: input.Description,
+		IsPrivate:   input.IsPrivate,
+		CareerID:    input.CareerID,
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Canal actualizado exitosamente",
 		"channel": channel,
 	})
 }
 
-// GetChannels obtiene la lista de canales disponibles para el usuario
-func GetChannels(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
+// ArchiveChannel deja el canal en solo lectura y lo saca del listado público
+func ArchiveChannel(c *gin.Context) {
+	setChannelArchived(c, true, "Canal archivado exitosamente")
+}
+
+// UnarchiveChannel reactiva un canal archivado
+func UnarchiveChannel(c *gin.Context) {
+	setChannelArchived(c, false, "Canal reactivado exitosamente")
+}
+
+func setChannelArchived(c *gin.Context, archived bool, message string) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	channel, err := Services.Channels.SetArchived(c, actor(c), channelID, archived)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"channel": channel,
+	})
+}
+
+// DeleteChannel borra el canal con todo su contenido
+func DeleteChannel(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
-	var channels []models.Channel
-	if err := database.DB.
-		Joins("JOIN channel_members ON channels.channel_id = channel_members.channel_id").
-		Where("channel_members.user_id = ?", userID).
-		Preload("Creator").
-		Preload("University").
-		Preload("Career").
-		Find(&channels).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener los canales"})
+	if err := Services.Channels.Delete(c, actor(c), channelID); err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"channels": channels})
+	c.JSON(http.StatusOK, gin.H{"message": "Canal eliminado exitosamente"})
+}
+
+// GetChannelAuditLog obtiene el registro de cambios del canal
+func GetChannelAuditLog(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	const pageSize = 50
+	page := 1
+	if pageNum, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && pageNum > 0 {
+		page = pageNum
+	}
+
+	entries, total, err := Services.Channels.AuditLog(c, actor(c), channelID, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"pagination": gin.H{
+			"current_page": page,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"page_size":    pageSize,
+			"total_items":  total,
+		},
+	})
+}
+
+// DiscoverChannels lista los canales públicos, filtrables por universidad,
+// carrera y texto, con su cantidad de miembros y última actividad
+func DiscoverChannels(c *gin.Context) {
+	const pageSize = 20
+	page := 1
+	if pageNum, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && pageNum > 0 {
+		page = pageNum
+	}
+
+	filter := repository.ChannelFilter{Query: c.Query("q")}
+	var ok bool
+	if filter.UniversityID, ok = queryID(c, "university"); !ok {
+		return
+	}
+	if filter.CareerID, ok = queryID(c, "career"); !ok {
+		return
+	}
+
+	channels, total, err := Services.Channels.Discover(c, filter, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	result := make([]gin.H, len(channels))
+	for i, summary := range channels {
+		result[i] = gin.H{
+			"channel":          summary.Channel,
+			"member_count":     summary.MemberCount,
+			"last_activity_at": summary.LastActivityAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels": result,
+		"pagination": gin.H{
+			"current_page": page,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"page_size":    pageSize,
+			"total_items":  total,
+		},
+	})
 }
 
 // GetChannel obtiene los detalles de un canal específico
 func GetChannel(c *gin.Context) {
-	channelID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
-
-	// Verificar si el usuario es miembro del canal
-	var member models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ?", channelID, userID).First(&member).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes acceso a este canal"})
+	channelID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	var channel models.Channel
-	if err := database.DB.
-		Preload("Creator").
-		Preload("University").
-		Preload("Career").
-		Preload("Members.User").
-		First(&channel, channelID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Canal no encontrado"})
+	channel, err := Services.Channels.Get(c, actor(c), channelID)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
@@ -108,147 +266,492 @@ func GetChannel(c *gin.Context) {
 
 // InviteToChannel invita a un usuario a un canal
 func InviteToChannel(c *gin.Context) {
-	channelID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
+	// Se invita a un usuario por ID o a cualquier dirección por email
 	var input struct {
-		InvitedUserID uint `json:"invited_user_id" binding:"required"`
+		InvitedUserID uint   `json:"invited_user_id"`
+		Email         string `json:"email"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+	if err := c.ShouldBindJSON(&input); err != nil || (input.InvitedUserID == 0) == (input.Email == "") {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 		return
 	}
 
-	// Verificar si el usuario que invita es administrador
-	var member models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ? AND is_admin = ?", channelID, userID, true).First(&member).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Solo los administradores pueden invitar usuarios"})
+	if input.Email != "" {
+		result, err := Services.Channels.InviteByEmail(c, actor(c), channelID, input.Email)
+		if err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		if result.Invitation == nil {
+			c.JSON(http.StatusCreated, gin.H{
+				"message": "Invitación enviada por email",
+				"link":    result.Link,
+			})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"message":    "Invitación enviada exitosamente",
+			"invitation": result.Invitation,
+		})
 		return
 	}
 
-	// Verificar si el usuario ya es miembro
-	var existingMember models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ?", channelID, input.InvitedUserID).First(&existingMember).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El usuario ya es miembro del canal"})
+	invitation, err := Services.Channels.Invite(c, actor(c), channelID, input.InvitedUserID)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Verificar si ya existe una invitación pendiente
-	var existingInvitation models.ChannelInvitation
-	if err := database.DB.Where("channel_id = ? AND invited_user = ? AND status = ?", channelID, input.InvitedUserID, "pending").First(&existingInvitation).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe una invitación pendiente para este usuario"})
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitación enviada exitosamente",
+		"invitation": invitation,
+	})
+}
+
+// CreateChannelInviteLink crea un enlace de invitación al canal
+func CreateChannelInviteLink(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	invitation := models.ChannelInvitation{
-		ChannelID:   uint(parseUint(channelID)),
-		InvitedBy:   userID,
-		InvitedUser: input.InvitedUserID,
-		Status:      "pending",
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	var input struct {
+		Role      string     `json:"role"`
+		MaxUses   *int       `json:"max_uses"`
+		ExpiresAt *time.Time `json:"expires_at"`
 	}
 
-	if err := database.DB.Create(&invitation).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear la invitación"})
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+			return
+		}
+	}
+
+	link, err := Services.Channels.CreateInviteLink(c, actor(c), channelID, services.NewInviteLink{
+		Role:      input.Role,
+		MaxUses:   input.MaxUses,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message":    "Invitación enviada exitosamente",
-		"invitation": invitation,
+		"message": "Enlace de invitación creado exitosamente",
+		"link":    link,
+	})
+}
+
+// GetChannelInviteLinks lista los enlaces de invitación vigentes del canal
+func GetChannelInviteLinks(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	links, err := Services.Channels.InviteLinks(c, actor(c), channelID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// RevokeChannelInviteLink invalida un enlace de invitación
+func RevokeChannelInviteLink(c *gin.Context) {
+	linkID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := Services.Channels.RevokeInviteLink(c, actor(c), linkID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Enlace de invitación revocado"})
+}
+
+// JoinChannelWithCode canjea un enlace de invitación
+func JoinChannelWithCode(c *gin.Context) {
+	member, err := Services.Channels.RedeemInviteLink(c, actor(c), c.Param("code"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Te uniste al canal",
+		"member":  member,
 	})
 }
 
 // HandleInvitation maneja la aceptación o rechazo de una invitación
 func HandleInvitation(c *gin.Context) {
-	invitationID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
+	invitationID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
 	var input struct {
 		Action string `json:"action" binding:"required"` // "accept" o "reject"
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+	if input.Action != "accept" && input.Action != "reject" {
+		apperror.Abort(c, apperror.InvalidField("action", "invalid_action"))
 		return
 	}
 
-	var invitation models.ChannelInvitation
-	if err := database.DB.First(&invitation, invitationID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invitación no encontrada"})
+	invitation, err := Services.Channels.RespondInvitation(c, actor(c), invitationID, input.Action == "accept")
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Verificar que el usuario sea el invitado
-	if invitation.InvitedUser != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes permiso para manejar esta invitación"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("Invitación %s exitosamente", input.Action),
+		"invitation": invitation,
+	})
+}
+
+// GetPendingInvitations obtiene las invitaciones pendientes del usuario
+func GetPendingInvitations(c *gin.Context) {
+	invitations, err := Services.Channels.PendingInvitations(c, c.MustGet("userID").(uint))
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Verificar que la invitación esté pendiente
-	if invitation.Status != "pending" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Esta invitación ya ha sido procesada"})
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// JoinChannel une al usuario a un canal público
+func JoinChannel(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	if input.Action == "accept" {
-		// Crear nuevo miembro
-		member := models.ChannelMember{
-			ChannelID:  invitation.ChannelID,
-			UserID:     userID,
-			IsAdmin:    false,
-			JoinedAt:   time.Now(),
-			LastSeenAt: time.Now(),
-		}
+	member, err := Services.Channels.Join(c, actor(c), channelID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Te uniste al canal",
+		"member":  member,
+	})
+}
 
-		if err := database.DB.Create(&member).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al agregar al usuario como miembro"})
+// RequestToJoinChannel envía una solicitud para entrar a un canal privado
+func RequestToJoinChannel(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Message string `json:"message"`
+	}
+	// El mensaje es opcional, así que el cuerpo puede venir vacío
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 			return
 		}
+	}
+
+	request, err := Services.Channels.RequestJoin(c, actor(c), channelID, input.Message)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
 
-		// Actualizar estado de la invitación
-		invitation.Status = "accepted"
-	} else if input.Action == "reject" {
-		invitation.Status = "rejected"
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Acción inválida"})
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Solicitud enviada exitosamente",
+		"request": request,
+	})
+}
+
+// GetJoinRequests obtiene las solicitudes pendientes de un canal
+func GetJoinRequests(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	invitation.UpdatedAt = time.Now()
-	if err := database.DB.Save(&invitation).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar la invitación"})
+	requests, err := Services.Channels.JoinRequests(c, actor(c), channelID)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
+}
+
+// HandleJoinRequest aprueba o rechaza una solicitud para entrar a un canal
+func HandleJoinRequest(c *gin.Context) {
+	requestID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Action string `json:"action" binding:"required"` // "approve" o "deny"
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+	if input.Action != "approve" && input.Action != "deny" {
+		apperror.Abort(c, apperror.InvalidField("action", "invalid_action"))
+		return
+	}
+
+	request, err := Services.Channels.RespondJoinRequest(c, actor(c), requestID, input.Action == "approve")
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	message := "Solicitud aprobada exitosamente"
+	if input.Action == "deny" {
+		message = "Solicitud rechazada exitosamente"
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message":    fmt.Sprintf("Invitación %s exitosamente", input.Action),
-		"invitation": invitation,
+		"message": message,
+		"request": request,
 	})
 }
 
-// GetPendingInvitations obtiene las invitaciones pendientes del usuario
-func GetPendingInvitations(c *gin.Context) {
-	userID := c.MustGet("userID").(uint)
+// LeaveChannel saca al usuario del canal
+func LeaveChannel(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
-	var invitations []models.ChannelInvitation
-	if err := database.DB.
-		Where("invited_user = ? AND status = ?", userID, "pending").
-		Preload("Channel").
-		Preload("Inviter").
-		Find(&invitations).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las invitaciones"})
+	if err := Services.Channels.Leave(c, actor(c), channelID); err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+	c.JSON(http.StatusOK, gin.H{"message": "Saliste del canal"})
+}
+
+// RemoveChannelMember expulsa a un miembro del canal
+func RemoveChannelMember(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := Services.Channels.RemoveMember(c, actor(c), channelID, userID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Miembro eliminado del canal"})
+}
+
+// SetChannelMemberRole cambia el rol de un miembro del canal
+func SetChannelMemberRole(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	member, err := Services.Channels.SetRole(c, actor(c), channelID, userID, input.Role)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Miembro actualizado exitosamente",
+		"member":  member,
+	})
 }
 
-// Función auxiliar para convertir string a uint
-func parseUint(s string) uint64 {
-	var result uint64
-	fmt.Sscanf(s, "%d", &result)
-	return result
+// TransferChannelOwnership pasa la propiedad del canal a otro miembro
+func TransferChannelOwnership(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	channel, err := Services.Channels.TransferOwnership(c, actor(c), channelID, input.UserID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Propiedad del canal transferida exitosamente",
+		"channel": channel,
+	})
+}
+
+// GetChannelPermissions devuelve el rol del usuario en el canal, la
+// configuración de permisos y qué puede hacer
+func GetChannelPermissions(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	summary, err := Services.Channels.Permissions(c, actor(c), channelID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":        summary.Role,
+		"settings":    summary.Settings,
+		"permissions": summary.Allowed,
+	})
+}
+
+// UpdateChannelPermissions cambia el rol mínimo de cada permiso del canal
+func UpdateChannelPermissions(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Post        *string `json:"post"`
+		Comment     *string `json:"comment"`
+		Invite      *string `json:"invite"`
+		Pin         *string `json:"pin"`
+		DeletePosts *string `json:"delete_posts"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	channel, err := Services.Channels.UpdatePermissions(c, actor(c), channelID, services.ChannelPermissionsUpdate{
+		Post:        input.Post,
+		Comment:     input.Comment,
+		Invite:      input.Invite,
+		Pin:         input.Pin,
+		DeletePosts: input.DeletePosts,
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Permisos del canal actualizados exitosamente",
+		"settings": channel.Permissions,
+	})
+}
+
+// BanFromChannel bloquea a un usuario en el canal
+func BanFromChannel(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserID uint   `json:"user_id" binding:"required"`
+		Reason string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	ban, err := Services.Channels.Ban(c, actor(c), channelID, input.UserID, input.Reason)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Usuario bloqueado en el canal",
+		"ban":     ban,
+	})
+}
+
+// UnbanFromChannel levanta el bloqueo de un usuario
+func UnbanFromChannel(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := Services.Channels.Unban(c, actor(c), channelID, userID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bloqueo levantado exitosamente"})
+}
+
+// GetChannelBans obtiene los usuarios bloqueados en el canal
+func GetChannelBans(c *gin.Context) {
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	bans, err := Services.Channels.Bans(c, actor(c), channelID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bans": bans})
 }
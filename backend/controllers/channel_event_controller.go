@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/realtime"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive es cada cuánto se envía un comentario para que los proxies
+// no corten un stream sin eventos
+const streamKeepAlive = 15 * time.Second
+
+// streamRetry es cuánto espera EventSource para reconectarse, en milisegundos
+const streamRetry = 3000
+
+// StreamChannelEvents envía por Server-Sent Events los posts, comentarios,
+// likes y borrados de los canales del usuario. channel_id (repetido o
+// separado por comas) limita los canales; sin él se siguen todos. Al
+// reconectar, EventSource envía Last-Event-ID y se reenvían los eventos
+// perdidos; si no se pueden recuperar todos se envía un evento resync para
+// que el cliente recargue los canales.
+func StreamChannelEvents(c *gin.Context) {
+	channelIDs, ok := queryIDList(c, "channel_id")
+	if !ok {
+		return
+	}
+	afterID, ok := lastEventID(c)
+	if !ok {
+		return
+	}
+
+	stream, err := Services.Channels.Stream(c, actor(c), channelIDs, afterID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	defer stream.Subscription.Close()
+
+	// HTTP_WRITE_TIMEOUT cortaría el stream; dura lo que dure la conexión
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry)
+	writeStreamMessage(c, "", "ready", gin.H{"channels": stream.Channels})
+	if stream.Resync {
+		writeStreamMessage(c, "", "resync", gin.H{})
+	}
+	replayed := make(map[uint64]bool, len(stream.Backlog))
+	for _, event := range stream.Backlog {
+		replayed[event.ID] = true
+		writeStreamEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	refresh := time.NewTicker(services.ChannelStreamRefresh)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, open := <-stream.Subscription.Events:
+			// Cerrada por el apagado o por no consumir a tiempo: el cliente
+			// se reconecta y recupera lo perdido con Last-Event-ID
+			if !open {
+				return
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if err := writeStreamEvent(c, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-refresh.C:
+			if err := Services.Channels.RefreshStream(c, actor(c), stream); err != nil {
+				logging.FromContext(c).Error("Error actualizando los canales del stream", "error", err)
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeStreamEvent escribe un evento de canal con su ID para poder reanudar
+func writeStreamEvent(c *gin.Context, event realtime.Event) error {
+	return writeStreamMessage(c, strconv.FormatUint(event.ID, 10), event.Type, event)
+}
+
+// writeStreamMessage escribe un mensaje de Server-Sent Events con data en JSON
+func writeStreamMessage(c *gin.Context, id, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var msg strings.Builder
+	if id != "" {
+		fmt.Fprintf(&msg, "id: %s\n", id)
+	}
+	fmt.Fprintf(&msg, "event: %s\ndata: %s\n\n", eventType, payload)
+	_, err = c.Writer.WriteString(msg.String())
+	return err
+}
+
+// queryIDList lee un parámetro de query con ID numéricos, repetido o
+// separado por comas. Si alguno no es válido corta el pedido igual que
+// queryID.
+func queryIDList(c *gin.Context, name string) ([]uint, bool) {
+	var ids []uint
+	for _, value := range c.QueryArray(name) {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				apperror.Abort(c, apperror.InvalidField(name, "invalid_id"))
+				return nil, false
+			}
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, true
+}
+
+// lastEventID lee el último evento que recibió el cliente del encabezado
+// Last-Event-ID, que envía EventSource al reconectar, o del parámetro
+// last_event_id. Devuelve nil si no está.
+func lastEventID(c *gin.Context) (*uint64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField("last_event_id", "invalid_id"))
+		return nil, false
+	}
+	return &id, true
+}
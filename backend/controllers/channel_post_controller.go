@@ -1,211 +1,297 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
-	"github.com/LautaroRomano/repositorio-tecnologico/database"
-	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
 	"github.com/gin-gonic/gin"
 )
 
-// CreateChannelPost crea un nuevo post en un canal
+// CreateChannelPost crea un nuevo post en un canal. Acepta JSON o, para
+// adjuntar archivos, multipart con content, tag_ids (un array JSON),
+// announcement y files[]. Un anuncio se avisa por email a todos los miembros.
 func CreateChannelPost(c *gin.Context) {
-	channelID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
-
-	// Verificar si el usuario es miembro del canal
-	var member models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ?", channelID, userID).First(&member).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes acceso a este canal"})
+	channelID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	var input struct {
-		Content string   `json:"content" binding:"required"`
-		Tags    []string `json:"tags"`
-	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
-		return
-	}
+	var input services.NewChannelPost
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		input.Content = c.PostForm("content")
+		if tagIDs := c.PostForm("tag_ids"); tagIDs != "" {
+			if err := json.Unmarshal([]byte(tagIDs), &input.TagIDs); err != nil {
+				apperror.Abort(c, apperror.InvalidField("tag_ids", "invalid_format"))
+				return
+			}
+		}
+		if announcement := c.PostForm("announcement"); announcement != "" {
+			value, err := strconv.ParseBool(announcement)
+			if err != nil {
+				apperror.Abort(c, apperror.InvalidField("announcement", "invalid_format"))
+				return
+			}
+			input.Announcement = value
+		}
 
-	post := models.ChannelPost{
-		ChannelID: uint(parseUint(channelID)),
-		UserID:    userID,
-		Content:   input.Content,
-		Tags:      input.Tags,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		form, err := c.MultipartForm()
+		if err != nil {
+			apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+			return
+		}
+		for _, file := range form.File["files[]"] {
+			openedFile, err := file.Open()
+			if err != nil {
+				apperror.Abort(c, apperror.Internal(fmt.Errorf("abriendo el archivo %s: %w", file.Filename, err)))
+				return
+			}
+			defer openedFile.Close()
+
+			input.Files = append(input.Files, services.File{
+				Name:    file.Filename,
+				Size:    file.Size,
+				Content: openedFile,
+			})
+		}
+	} else {
+		var body struct {
+			Content      string `json:"content"`
+			TagIDs       []uint `json:"tag_ids"`
+			Announcement bool   `json:"announcement"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+			return
+		}
+		input.Content = body.Content
+		input.TagIDs = body.TagIDs
+		input.Announcement = body.Announcement
 	}
 
-	if err := database.DB.Create(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear el post"})
+	post, err := Services.Channels.CreatePost(c, actor(c), channelID, input)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Cargar la información del usuario que creó el post
-	database.DB.Preload("User").First(&post, post.PostID)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Post creado exitosamente",
 		"post":    post,
 	})
 }
 
-// GetChannelPosts obtiene los posts de un canal
+// GetChannelPosts obtiene los posts de un canal. first_unread_post_id marca
+// dónde empieza lo nuevo desde la última visita; la marca se mueve con
+// POST /channels/:id/read. Se puede filtrar por texto con q y por tags con
+// tag_id, repetido o separado por comas; trae los posts con alguno de los
+// tags.
 func GetChannelPosts(c *gin.Context) {
-	channelID := c.Param("id")
-	userID := c.MustGet("userID").(uint)
-
-	// Verificar si el usuario es miembro del canal
-	var member models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ?", channelID, userID).First(&member).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes acceso a este canal"})
+	channelID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	filter := repository.ChannelPostFilter{Query: strings.TrimSpace(c.Query("q"))}
+	if filter.TagIDs, ok = queryIDList(c, "tag_id"); !ok {
 		return
 	}
 
-	var posts []models.ChannelPost
-	if err := database.DB.
-		Where("channel_id = ?", channelID).
-		Preload("User").
-		Preload("Files").
-		Preload("Comments.User").
-		Preload("Likes").
-		Order("created_at DESC").
-		Find(&posts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener los posts"})
+	list, err := Services.Channels.Posts(c, actor(c), channelID, filter)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	c.JSON(http.StatusOK, gin.H{
+		"posts":                list.Posts,
+		"last_seen_at":         list.LastSeenAt,
+		"first_unread_post_id": list.FirstUnreadPostID,
+		"unread_posts":         list.UnreadPosts,
+	})
 }
 
-// AddChannelPostComment agrega un comentario a un post del canal
-func AddChannelPostComment(c *gin.Context) {
-	postID := c.Param("postId")
-	userID := c.MustGet("userID").(uint)
+// UpdateChannelPost cambia el contenido o los tags de un post; solo lo puede
+// hacer el autor. La versión anterior queda en el historial.
+func UpdateChannelPost(c *gin.Context) {
+	postID, ok := paramID(c, "postId")
+	if !ok {
+		return
+	}
 
 	var input struct {
-		Content string `json:"content" binding:"required"`
+		Content *string `json:"content"`
+		TagIDs  *[]uint `json:"tag_ids"`
 	}
-
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 		return
 	}
 
-	// Verificar si el post existe y obtener el channel_id
-	var post models.ChannelPost
-	if err := database.DB.First(&post, postID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post no encontrado"})
+	post, err := Services.Channels.UpdatePost(c, actor(c), postID, services.ChannelPostUpdate{
+		Content: input.Content,
+		TagIDs:  input.TagIDs,
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Verificar si el usuario es miembro del canal
-	var member models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ?", post.ChannelID, userID).First(&member).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes acceso a este canal"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post actualizado exitosamente",
+		"post":    post,
+	})
+}
+
+// GetChannelPostEdits devuelve el historial de ediciones de un post
+func GetChannelPostEdits(c *gin.Context) {
+	postID, ok := paramID(c, "postId")
+	if !ok {
 		return
 	}
 
-	comment := models.ChannelPostComment{
-		PostID:    uint(parseUint(postID)),
-		UserID:    userID,
-		Content:   input.Content,
-		CreatedAt: time.Now(),
+	edits, err := Services.Channels.PostEdits(c, actor(c), postID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
 	}
 
-	if err := database.DB.Create(&comment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear el comentario"})
+	c.JSON(http.StatusOK, gin.H{"edits": edits})
+}
+
+// PinChannelPost fija un post para que se muestre primero en el canal
+func PinChannelPost(c *gin.Context) {
+	setChannelPostPinned(c, true, "Post fijado exitosamente")
+}
+
+// UnpinChannelPost desfija un post
+func UnpinChannelPost(c *gin.Context) {
+	setChannelPostPinned(c, false, "Post desfijado exitosamente")
+}
+
+func setChannelPostPinned(c *gin.Context, pinned bool, message string) {
+	postID, ok := paramID(c, "postId")
+	if !ok {
 		return
 	}
 
-	// Cargar la información del usuario que creó el comentario
-	database.DB.Preload("User").First(&comment, comment.CommentID)
+	post, err := Services.Channels.SetPinned(c, actor(c), postID, pinned)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Comentario agregado exitosamente",
-		"comment": comment,
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"post":    post,
 	})
 }
 
-// LikeChannelPost agrega o quita un like a un post del canal
-func LikeChannelPost(c *gin.Context) {
-	postID := c.Param("postId")
-	userID := c.MustGet("userID").(uint)
+// GetChannelFileLink devuelve un enlace de descarga nuevo para un archivo de
+// un canal, para cuando venció el que vino con los posts
+func GetChannelFileLink(c *gin.Context) {
+	fileID, ok := paramID(c, "fileId")
+	if !ok {
+		return
+	}
 
-	// Verificar si el post existe y obtener el channel_id
-	var post models.ChannelPost
-	if err := database.DB.First(&post, postID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post no encontrado"})
+	link, err := Services.Channels.FileLink(c, actor(c), fileID)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Verificar si el usuario es miembro del canal
-	var member models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ?", post.ChannelID, userID).First(&member).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes acceso a este canal"})
+	c.JSON(http.StatusOK, gin.H{
+		"url":        link.URL,
+		"expires_at": link.ExpiresAt,
+	})
+}
+
+// DownloadChannelFile redirige a una URL del almacenamiento que vence en un
+// minuto. No usa el token de sesión sino el de token, firmado por
+// GetChannelFileLink o GetChannelPosts, para poder abrirse directamente
+// desde el navegador.
+func DownloadChannelFile(c *gin.Context) {
+	fileID, ok := paramID(c, "fileId")
+	if !ok {
 		return
 	}
 
-	// Verificar si ya existe un like
-	var existingLike models.ChannelPostLike
-	if err := database.DB.Where("post_id = ? AND user_id = ?", postID, userID).First(&existingLike).Error; err == nil {
-		// Si existe, lo eliminamos (toggle)
-		if err := database.DB.Delete(&existingLike).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al quitar el like"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"message": "Like quitado exitosamente"})
+	url, err := Services.Channels.DownloadFile(c, fileID, c.Query("token"))
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Si no existe, creamos uno nuevo
-	like := models.ChannelPostLike{
-		PostID: uint(parseUint(postID)),
-		UserID: userID,
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, url)
+}
+
+// AddChannelPostComment agrega un comentario a un post del canal
+func AddChannelPostComment(c *gin.Context) {
+	postID, ok := paramID(c, "postId")
+	if !ok {
+		return
 	}
 
-	if err := database.DB.Create(&like).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al agregar el like"})
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	comment, err := Services.Channels.AddComment(c, actor(c), postID, input.Content)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Like agregado exitosamente",
-		"like":    like,
+		"message": "Comentario agregado exitosamente",
+		"comment": comment,
 	})
 }
 
-// DeleteChannelPost elimina un post del canal
-func DeleteChannelPost(c *gin.Context) {
-	postID := c.Param("postId")
-	userID := c.MustGet("userID").(uint)
+// LikeChannelPost agrega o quita un like a un post del canal
+func LikeChannelPost(c *gin.Context) {
+	postID, ok := paramID(c, "postId")
+	if !ok {
+		return
+	}
 
-	// Verificar si el post existe y obtener el channel_id
-	var post models.ChannelPost
-	if err := database.DB.First(&post, postID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post no encontrado"})
+	like, err := Services.Channels.ToggleLike(c, actor(c), postID)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Verificar si el usuario es el creador del post o un administrador del canal
-	var member models.ChannelMember
-	if err := database.DB.Where("channel_id = ? AND user_id = ?", post.ChannelID, userID).First(&member).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes acceso a este canal"})
+	if like == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Like quitado exitosamente"})
 		return
 	}
 
-	if post.UserID != userID && !member.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes permiso para eliminar este post"})
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Like agregado exitosamente",
+		"like":    like,
+	})
+}
+
+// DeleteChannelPost elimina un post del canal
+func DeleteChannelPost(c *gin.Context) {
+	postID, ok := paramID(c, "postId")
+	if !ok {
 		return
 	}
 
-	// Eliminar el post y sus relaciones (cascade)
-	if err := database.DB.Delete(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar el post"})
+	if err := Services.Channels.DeletePost(c, actor(c), postID); err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
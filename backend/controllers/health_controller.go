@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/health"
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz indica que el proceso está vivo. No verifica dependencias: si la
+// base de datos se cae no tiene sentido reiniciar el servidor.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readyz indica si el servidor puede recibir tráfico, con el estado de cada
+// dependencia. Responde 503 si falla una dependencia crítica o si el servidor
+// se está apagando.
+func Readyz(c *gin.Context) {
+	report := health.Readiness(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
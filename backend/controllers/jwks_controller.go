@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publica las claves públicas vigentes para que otros servicios
+// (bot de notas, puente con Discord, etc.) puedan verificar los tokens
+func GetJWKS(c *gin.Context) {
+	if utils.Keys == nil {
+		apperror.Abort(c, apperror.New(apperror.CodeSigningKeys))
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(utils.JWKSMaxAge.Seconds())))
+	c.JSON(http.StatusOK, utils.Keys.JWKS())
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"crypto/subtle"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsToken protege /metrics cuando no está detrás de una red interna. Vacío
+// deja el endpoint abierto.
+var MetricsToken string
+
+var metricsHandler = promhttp.Handler()
+
+// Metrics expone las métricas en el formato de texto de Prometheus
+func Metrics(c *gin.Context) {
+	if MetricsToken != "" {
+		expected := "Bearer " + MetricsToken
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			apperror.Abort(c, apperror.New(apperror.CodeInvalidToken))
+			return
+		}
+	}
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
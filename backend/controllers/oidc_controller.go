@@ -0,0 +1,419 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/sso"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errUnverifiedEmail = errors.New("ya existe una cuenta con ese email, pero el proveedor no lo verificó")
+
+// linkRequiredError indica que la identidad coincide por email con una cuenta
+// local cuyo email nunca se verificó. No se vincula sola porque cualquiera
+// pudo registrar ese email: el usuario tiene que confirmar la contraseña.
+type linkRequiredError struct {
+	user  models.User
+	email string
+}
+
+func (e *linkRequiredError) Error() string {
+	return fmt.Sprintf("la cuenta %d tiene el mismo email pero sin verificar", e.user.UserID)
+}
+
+// oidcStateCookie guarda firmado el login OIDC en curso en el navegador que
+// lo inició
+const oidcStateCookie = "oidc_state"
+
+// OIDCLinkTTL es lo que dura el token para vincular una identidad confirmando
+// la contraseña
+const OIDCLinkTTL = 10 * time.Minute
+
+// GetOIDCProviders lista los proveedores de login externos disponibles
+func GetOIDCProviders(c *gin.Context) {
+	response := []gin.H{}
+	for _, p := range sso.List() {
+		response = append(response, gin.H{
+			"name":         p.Config.Name,
+			"display_name": p.Config.DisplayName,
+			"login_url":    "/auth/oidc/" + p.Config.Name + "/login",
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"providers": response})
+}
+
+// OIDCLogin redirige al usuario al proveedor para iniciar sesión
+func OIDCLogin(c *gin.Context) {
+	provider, err := sso.Get(c.Param("provider"))
+	if err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeProviderNotFound))
+		return
+	}
+
+	authURL, auth, err := provider.AuthCodeURL(c.Request.Context(), frontendPath(c.Query("redirect")))
+	if err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeProviderDown).Wrap(err))
+		return
+	}
+
+	state, err := utils.GenerateOIDCStateToken(utils.OIDCStateClaims{
+		Provider:   auth.Provider,
+		State:      auth.State,
+		Verifier:   auth.Verifier,
+		Nonce:      auth.Nonce,
+		RedirectTo: auth.RedirectTo,
+	}, time.Now().Add(sso.StateTTL))
+	if err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+	setOIDCStateCookie(c, provider, state, int(sso.StateTTL.Seconds()))
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// frontendPath devuelve redirect si es una ruta relativa del frontend, o "/"
+// si no, para evitar redirecciones abiertas. Se controla tanto el valor como
+// la ruta decodificada, y sin barras invertidas porque los navegadores leen
+// "/\" igual que "//".
+func frontendPath(redirect string) string {
+	u, err := url.Parse(redirect)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "/"
+	}
+	for _, path := range []string{redirect, u.Path} {
+		if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, `\`) {
+			return "/"
+		}
+	}
+	return redirect
+}
+
+// setOIDCStateCookie guarda el login en curso en una cookie que solo se envía
+// al callback del proveedor. Con maxAge negativo la borra.
+func setOIDCStateCookie(c *gin.Context, provider *sso.Provider, value string, maxAge int) {
+	path := "/"
+	if u, err := url.Parse(provider.Config.RedirectURL); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(provider.Config.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// pendingOIDCAuth lee el login en curso de la cookie y la borra: cada login se
+// completa una sola vez
+func pendingOIDCAuth(c *gin.Context, provider *sso.Provider) (sso.PendingAuth, bool) {
+	value, err := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, provider, "", -1)
+	if err != nil {
+		return sso.PendingAuth{}, false
+	}
+	claims, err := utils.ParseOIDCStateToken(value)
+	if err != nil {
+		return sso.PendingAuth{}, false
+	}
+	return sso.PendingAuth{
+		Provider:   claims.Provider,
+		State:      claims.State,
+		Verifier:   claims.Verifier,
+		Nonce:      claims.Nonce,
+		RedirectTo: claims.RedirectTo,
+	}, true
+}
+
+// OIDCCallback recibe la respuesta del proveedor, vincula o crea el usuario
+// y redirige al frontend con el token de sesión
+func OIDCCallback(c *gin.Context) {
+	provider, err := sso.Get(c.Param("provider"))
+	if err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeProviderNotFound))
+		return
+	}
+
+	// Sin la cookie del login el callback no viene de este navegador: puede
+	// ser alguien que intenta iniciar la sesión de la víctima en su cuenta
+	auth, ok := pendingOIDCAuth(c, provider)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		redirectToFrontend(c, "/login", url.Values{"error": {"El proveedor rechazó el inicio de sesión"}}, "")
+		return
+	}
+	if !ok {
+		redirectToFrontend(c, "/login", url.Values{"error": {"El inicio de sesión venció o se inició en otro navegador. Volvé a intentarlo."}}, "")
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), auth, c.Query("state"), c.Query("code"))
+	if err != nil {
+		redirectToFrontend(c, "/login", url.Values{"error": {"No se pudo iniciar sesión con " + provider.Config.DisplayName}}, "")
+		return
+	}
+
+	user, err := findOrCreateOIDCUser(c, provider.Config.Name, claims)
+	var linkRequired *linkRequiredError
+	if errors.As(err, &linkRequired) {
+		// El token viaja en el fragmento, igual que el de sesión
+		linkToken, err := utils.GenerateOIDCLinkToken(utils.OIDCLinkClaims{
+			UserID:   linkRequired.user.UserID,
+			Provider: provider.Config.Name,
+			Subject:  claims.Subject,
+			Email:    linkRequired.email,
+		}, time.Now().Add(OIDCLinkTTL))
+		if err == nil {
+			redirectToFrontend(c, "/login", url.Values{
+				"error":    {"Ya existe una cuenta con ese email. Ingresá tu contraseña para vincularla con " + provider.Config.DisplayName + "."},
+				"redirect": {auth.RedirectTo},
+			}, "link_token="+url.QueryEscape(linkToken))
+			return
+		}
+	}
+	if err != nil {
+		message := "No se pudo iniciar sesión con " + provider.Config.DisplayName
+		if errors.Is(err, errUnverifiedEmail) {
+			message = "Ya existe una cuenta con ese email. Iniciá sesión con tu contraseña."
+		}
+		redirectToFrontend(c, "/login", url.Values{"error": {message}}, "")
+		return
+	}
+
+	token, err := utils.GenerateJWT(user.UserID)
+	if err != nil {
+		redirectToFrontend(c, "/login", url.Values{"error": {"No se pudo generar el token"}}, "")
+		return
+	}
+
+	// El token viaja en el fragmento para que no quede en logs ni en el header Referer
+	redirectToFrontend(c, "/oauth/callback", url.Values{"redirect": {auth.RedirectTo}}, "token="+url.QueryEscape(token))
+}
+
+// LinkOIDCIdentity vincula una identidad OIDC a la cuenta local con el mismo
+// email después de confirmar la contraseña. El link_token lo entrega el
+// callback cuando la cuenta no tiene el email verificado.
+func LinkOIDCIdentity(c *gin.Context) {
+	var req struct {
+		LinkToken string `json:"link_token" binding:"required"`
+		Password  string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	claims, err := utils.ParseOIDCLinkToken(req.LinkToken)
+	if err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidToken))
+		return
+	}
+	var user models.User
+	if err := database.DB.WithContext(c).First(&user, claims.UserID).Error; err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidToken))
+		return
+	}
+	if !verifyPassword(c, &user, strings.ToLower(user.Email), req.Password) {
+		return
+	}
+
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var linked int64
+		err := tx.Model(&models.UserIdentity{}).
+			Where("provider = ? AND subject = ?", claims.Provider, claims.Subject).
+			Count(&linked).Error
+		if err != nil {
+			return err
+		}
+		if linked > 0 {
+			return apperror.New(apperror.CodeIdentityLinked)
+		}
+		if err := tx.Create(&models.UserIdentity{
+			UserID:   user.UserID,
+			Provider: claims.Provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error; err != nil {
+			return err
+		}
+
+		// El proveedor verificó el email y quien lo controla conoce la
+		// contraseña: la cuenta queda verificada
+		if !strings.EqualFold(user.Email, claims.Email) {
+			return nil
+		}
+		return tx.Model(&user).Update("email_verified_at", gorm.Expr("COALESCE(email_verified_at, ?)", time.Now())).Error
+	})
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		apperror.Abort(c, appErr)
+		return
+	case err != nil:
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+
+	token, err := utils.GenerateJWT(user.UserID)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token, "me": user})
+}
+
+// findOrCreateOIDCUser resuelve el usuario de una identidad externa: primero
+// por la identidad ya vinculada, después por email verificado en ambos lados
+// y si no existe lo crea. Si existe una cuenta con el email sin verificar
+// devuelve *linkRequiredError.
+func findOrCreateOIDCUser(ctx context.Context, provider string, claims *sso.Claims) (*models.User, error) {
+	var user models.User
+
+	var identity models.UserIdentity
+	err := database.DB.WithContext(ctx).Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+	if err == nil {
+		if err := database.DB.WithContext(ctx).First(&user, identity.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, fmt.Errorf("el proveedor %s no compartió el email", provider)
+	}
+	email, canonicalEmail, fe := validation.NormalizeEmail(claims.Email)
+	if fe != nil {
+		return nil, fmt.Errorf("el proveedor %s devolvió un email inválido", provider)
+	}
+
+	created := false
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("LOWER(email) = ? OR email_canonical = ?", email, canonicalEmail).First(&user).Error
+		switch {
+		case err == nil:
+			// Solo se vincula una cuenta existente si el proveedor verificó el
+			// email y la cuenta también lo tiene verificado
+			if !claims.EmailVerified {
+				return errUnverifiedEmail
+			}
+			if user.EmailVerifiedAt == nil {
+				return &linkRequiredError{user: user, email: email}
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			username, err := availableUsername(tx, claims)
+			if err != nil {
+				return err
+			}
+			user = models.User{
+				Username:       username,
+				Email:          email,
+				EmailCanonical: &canonicalEmail,
+				AccountName:    claims.Name,
+				Img:            claims.Picture,
+			}
+			if claims.EmailVerified {
+				now := time.Now()
+				user.EmailVerifiedAt = &now
+			}
+			if user.AccountName == "" {
+				user.AccountName = username
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.UserID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		metrics.Registrations.WithLabelValues("oidc").Inc()
+	}
+
+	return &user, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_.]+`)
+
+// availableUsername deriva un nombre de usuario libre a partir de los claims
+func availableUsername(tx *gorm.DB, claims *sso.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameInvalidChars.ReplaceAllString(validation.NormalizeUsername(base), "")
+	base = strings.Trim(base, "._")
+	if len(base) > validation.UsernameMaxLength-4 {
+		base = base[:validation.UsernameMaxLength-4]
+	}
+	if validation.Username(base) != nil {
+		base = "usuario"
+	}
+
+	candidate := base
+	for i := 1; i <= 100; i++ {
+		if validation.Username(candidate) != nil {
+			candidate = fmt.Sprintf("%s%d", base, i)
+			continue
+		}
+
+		var count int64
+		if err := tx.Model(&models.User{}).Where("LOWER(username) = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+
+	return "", errors.New("no se encontró un nombre de usuario disponible")
+}
+
+// FrontendURL es la URL pública del frontend, adonde vuelve el login OIDC
+var FrontendURL = "http://localhost:3000"
+
+// redirectToFrontend redirige a una ruta del frontend
+func redirectToFrontend(c *gin.Context, path string, query url.Values, fragment string) {
+	target := FrontendURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	if fragment != "" {
+		target += "#" + fragment
+	}
+
+	c.Redirect(http.StatusFound, target)
+}
@@ -1,19 +1,14 @@
 package controllers
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"math"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"time"
 
-	"github.com/LautaroRomano/repositorio-tecnologico/config"
-	"github.com/LautaroRomano/repositorio-tecnologico/database"
-	"github.com/LautaroRomano/repositorio-tecnologico/models"
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,153 +20,21 @@ func GetPosts(c *gin.Context) {
 	// Obtener número de página desde query params
 	pageParam := c.DefaultQuery("page", "1")
 	pageNum, err := strconv.Atoi(pageParam)
-	if err != nil || pageNum < 1 {
-		page = 1
-	} else {
+	if err == nil && pageNum > 0 {
 		page = pageNum
 	}
 
-	// Calcular offset para paginación
-	offset := (page - 1) * pageSize
-
-	// Obtener posts con relaciones necesarias
-	var posts []models.Post
-	result := database.DB.Model(&models.Post{}).
-		Preload("Comments").
-		Preload("Comments.User").
-		Preload("Likes").
-		Preload("Likes.User").
-		Preload("Tags").
-		Order("created_at DESC").
-		Limit(pageSize).
-		Offset(offset).
-		Find(&posts)
-
-	if result.Error != nil {
-		c.JSON(500, gin.H{"error": "Error al obtener los posts"})
+	posts, totalPosts, err := Services.Posts.List(c, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Construir respuesta
-	var response []gin.H
-
-	for _, post := range posts {
-		// Buscar información de Universidad
-		var universityName string
-		database.DB.Model(&models.University{}).
-			Select("name").
-			Where("university_id = ?", post.UniversityID).
-			Pluck("name", &universityName)
-
-		// Buscar información de Carrera
-		var careerName string
-		database.DB.Model(&models.Career{}).
-			Select("name").
-			Where("career_id = ?", post.CareerID).
-			Pluck("name", &careerName)
-
-		// Buscar archivos asociados al post
-		var files []models.PostFile
-		database.DB.Where("post_id = ?", post.PostID).Find(&files)
-
-		// Construir estructura de usuario
-		// Obtener información del usuario para cada post
-		var user models.User
-		database.DB.Where("user_id = ?", post.UserID).First(&user)
-
-		// Construir estructura de usuario
-		userResponse := gin.H{
-			"UserID":   user.UserID,
-			"Username": user.Username,
-			"Avatar":   user.Img,
-		}
-		// Construir estructura de comentarios
-		commentsResponse := []gin.H{}
-		for _, comment := range post.Comments {
-			commentUser := gin.H{
-				"UserID":   comment.User.UserID,
-				"Username": comment.User.Username,
-				"Avatar":   comment.User.Img,
-			}
-
-			commentsResponse = append(commentsResponse, gin.H{
-				"CommentID": comment.CommentID,
-				"PostID":    comment.PostID,
-				"UserID":    comment.UserID,
-				"Content":   comment.Content,
-				"CreatedAt": comment.CreatedAt,
-				"User":      commentUser,
-			})
-		}
-
-		// Construir estructura de likes
-		likesResponse := []gin.H{}
-		for _, like := range post.Likes {
-			likeUser := gin.H{
-				"UserID":   like.User.UserID,
-				"Username": like.User.Username,
-				"Avatar":   like.User.Img,
-			}
-
-			likesResponse = append(likesResponse, gin.H{
-				"LikeID":  like.LikeID,
-				"PostID":  like.PostID,
-				"UserID":  like.UserID,
-				"LikedAt": like.LikedAt,
-				"User":    likeUser,
-			})
-		}
-
-		// Construir estructura de archivos
-		filesResponse := []gin.H{}
-		for _, file := range files {
-			filesResponse = append(filesResponse, gin.H{
-				"FileID":   file.FileID,
-				"FileURL":  file.FileURL,
-				"FileType": file.FileType,
-				"PostID":   file.PostID,
-				"FileName": file.FileName,
-			})
-		}
-
-		// Construir estructura de tags
-		tagsResponse := []gin.H{}
-		for _, tag := range post.Tags {
-			tagsResponse = append(tagsResponse, gin.H{
-				"TagID": tag.TagID,
-				"Name":  tag.Name,
-			})
-		}
-
-		// Armar respuesta completa de cada post
-		postResponse := gin.H{
-			"PostID":       post.PostID,
-			"UserID":       post.UserID,
-			"Content":      post.Content,
-			"CreatedAt":    post.CreatedAt,
-			"Tags":         tagsResponse,
-			"UniversityID": post.UniversityID,
-			"CareerID":     post.CareerID,
-			"University":   gin.H{"Name": universityName},
-			"Career":       gin.H{"Name": careerName},
-			"User":         userResponse,
-			"Comments":     commentsResponse,
-			"Likes":        likesResponse,
-			"Files":        filesResponse,
-		}
-
-		response = append(response, postResponse)
-	}
-
-	// Obtener total de posts para metadatos de paginación
-	var totalPosts int64
-	database.DB.Model(&models.Post{}).Count(&totalPosts)
-
 	// Calcular total de páginas
 	totalPages := int(math.Ceil(float64(totalPosts) / float64(pageSize)))
 
 	c.JSON(200, gin.H{
-		"posts": response,
+		"posts": postsResponse(posts),
 		"pagination": gin.H{
 			"current_page": page,
 			"total_pages":  totalPages,
@@ -182,396 +45,257 @@ func GetPosts(c *gin.Context) {
 }
 
 func GetPostByID(c *gin.Context) {
-	// Implementar la lógica para obtener un post por ID
-}
-
-func CreatePost(c *gin.Context) {
-	// Implementar la lógica para crear un nuevo post
-	content := c.PostForm("content")
-	careerID := c.PostForm("career_id")
-	universityID := c.PostForm("university_id")
-	tagIDsStr := c.PostForm("tag_ids")
-
-	fmt.Println("Contenido del post:", content)
-	fmt.Println("ID de carrera:", careerID)
-	fmt.Println("ID de universidad:", universityID)
-	fmt.Println("IDs de tags:", tagIDsStr)
-
-	// Convertir IDs a uint
-	careerIDUint, err := strconv.ParseUint(careerID, 10, 32)
-	if err != nil {
-		c.JSON(400, gin.H{"error": "ID de carrera inválido"})
+	postID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	universityIDUint, err := strconv.ParseUint(universityID, 10, 32)
+	post, err := Services.Posts.Get(c, postID)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "ID de universidad inválido"})
+		apperror.Abort(c, err)
 		return
 	}
 
-	post := models.Post{
-		Content:      content,
-		CareerID:     uint(careerIDUint),
-		UniversityID: uint(universityIDUint),
-		UserID:       c.MustGet("userID").(uint), //agergar el ID del usuario autenticado
-	}
+	c.JSON(200, postResponse(post))
+}
 
-	// Guardar en base de datos
-	database.DB.Create(&post)
-	if post.PostID == 0 {
-		c.JSON(500, gin.H{"error": "Error al crear el post"})
-		return
+// postsResponse arma la representación de una lista de posts. Sin posts
+// devuelve nil (null en el JSON), como siempre respondió la API.
+func postsResponse(posts []services.PostDetails) []gin.H {
+	var response []gin.H
+	for _, post := range posts {
+		response = append(response, postResponse(post))
 	}
+	return response
+}
 
-	// Procesar tags si se proporcionaron
-	if tagIDsStr != "" {
-		var tagIDs []uint
-		err := json.Unmarshal([]byte(tagIDsStr), &tagIDs)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Formato de tag_ids inválido"})
-			return
-		}
-
-		// Verificar que los tags existen y asociarlos al post
-		for _, tagID := range tagIDs {
-			var tag models.Tag
-			if err := database.DB.First(&tag, tagID).Error; err != nil {
-				c.JSON(400, gin.H{"error": fmt.Sprintf("Tag con ID %d no encontrado", tagID)})
-				return
-			}
+// postResponse arma la representación de un post con su autor, universidad,
+// carrera, comentarios, likes, archivos y tags
+func postResponse(details services.PostDetails) gin.H {
+	post := details.Post
 
-			// Asociar tag al post usando la relación many-to-many
-			database.DB.Model(&post).Association("Tags").Append(&tag)
-		}
+	// Construir estructura de usuario
+	userResponse := gin.H{
+		"UserID":   post.User.UserID,
+		"Username": post.User.Username,
+		"Avatar":   post.User.Img,
 	}
 
-	// Procesar múltiples archivos
-	form, err := c.MultipartForm()
-	if err == nil {
-		files := form.File["files[]"]
-
-		// Verificar si hay archivos
-		if len(files) > 0 {
-			for _, file := range files {
-				// Abrir el archivo
-				openedFile, err := file.Open()
-				if err != nil {
-					c.JSON(500, gin.H{"error": fmt.Sprintf("No se pudo abrir el archivo %s: %v", file.Filename, err)})
-					return
-				}
-				defer openedFile.Close()
-
-				// Determinar el tipo de archivo basado en la extensión
-				fileType := determineFileType(file.Filename)
-
-				// Subir a Cloudinary
-				ctx := context.Background()
-				uploadParams := uploader.UploadParams{
-					Folder:       "post_files",
-					ResourceType: "auto",
-				}
-
-				result, err := config.Cld.Upload.Upload(ctx, openedFile, uploadParams)
-				if err != nil {
-					c.JSON(500, gin.H{"error": fmt.Sprintf("Error al subir archivo %s: %v", file.Filename, err)})
-					return
-				}
-
-				// Guardar referencia del archivo en la base de datos
-				postFile := models.PostFile{
-					FileURL:  result.SecureURL,
-					PostID:   post.PostID,
-					FileType: fileType,
-					FileName: file.Filename,
-				}
-
-				database.DB.Create(&postFile)
-				if postFile.FileID == 0 {
-					c.JSON(500, gin.H{"error": fmt.Sprintf("Error al guardar el archivo %s en la base de datos", file.Filename)})
-					return
-				}
-			}
+	// Construir estructura de comentarios
+	commentsResponse := []gin.H{}
+	for _, comment := range post.Comments {
+		commentUser := gin.H{
+			"UserID":   comment.User.UserID,
+			"Username": comment.User.Username,
+			"Avatar":   comment.User.Img,
 		}
-	}
-
-	c.JSON(201, gin.H{
-		"message": "Post creado exitosamente",
-		"post_id": post.PostID,
-	})
-}
 
-func determineFileType(filename string) string {
-	// Extract file extension - convert to lowercase for consistent comparison
-	extension := strings.ToLower(filepath.Ext(filename))
-
-	// Check file type based on extension
-	switch extension {
-	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp":
-		return "image/jpeg"
-	case ".pdf":
-		return "application/pdf"
-	case ".doc", ".docx":
-		return "application/msword"
-	case ".xls", ".xlsx":
-		return "application/vnd.ms-excel"
-	case ".ppt", ".pptx":
-		return "application/vnd.ms-powerpoint"
-	case ".mp4", ".avi", ".mov", ".wmv", ".flv", ".mkv":
-		return "video/mp4"
-	case ".mp3", ".wav", ".ogg", ".flac", ".aac":
-		return "audio/mpeg"
-	case ".zip", ".rar", ".7z", ".tar", ".gz":
-		return "application/zip"
-	default:
-		return "application/octet-stream"
+		commentsResponse = append(commentsResponse, gin.H{
+			"CommentID": comment.CommentID,
+			"PostID":    comment.PostID,
+			"UserID":    comment.UserID,
+			"Content":   comment.Content,
+			"CreatedAt": comment.CreatedAt,
+			"User":      commentUser,
+		})
 	}
-}
 
-func UpdatePost(c *gin.Context) {
-	// Implementar la lógica para actualizar un post existente
-}
+	// Construir estructura de likes
+	likesResponse := []gin.H{}
+	for _, like := range post.Likes {
+		likeUser := gin.H{
+			"UserID":   like.User.UserID,
+			"Username": like.User.Username,
+			"Avatar":   like.User.Img,
+		}
 
-func DeletePost(c *gin.Context) {
-	// Implementar la lógica para eliminar un post
-}
+		likesResponse = append(likesResponse, gin.H{
+			"LikeID":  like.LikeID,
+			"PostID":  like.PostID,
+			"UserID":  like.UserID,
+			"LikedAt": like.LikedAt,
+			"User":    likeUser,
+		})
+	}
 
-func SearchPosts(c *gin.Context) {
-	// Obtener parámetros de búsqueda
-	query := c.Query("q")
-	universityID := c.Query("university")
-	careerID := c.Query("career")
-	tagIDs := c.Query("tag_ids")
-
-	// Construir la consulta base
-	db := database.DB.Model(&models.Post{}).
-		Preload("Comments").
-		Preload("Comments.User").
-		Preload("Likes").
-		Preload("Likes.User").
-		Preload("User").
-		Preload("Tags")
-
-	// Aplicar filtros
-	if query != "" {
-		db = db.Where("content ILIKE ?", "%"+query+"%")
+	// Construir estructura de archivos
+	filesResponse := []gin.H{}
+	for _, file := range details.Files {
+		filesResponse = append(filesResponse, gin.H{
+			"FileID":   file.FileID,
+			"FileURL":  file.FileURL,
+			"FileType": file.FileType,
+			"PostID":   file.PostID,
+			"FileName": file.FileName,
+		})
 	}
 
-	if universityID != "" {
-		db = db.Where("university_id = ?", universityID)
+	// Construir estructura de tags
+	tagsResponse := []gin.H{}
+	for _, tag := range post.Tags {
+		tagsResponse = append(tagsResponse, gin.H{
+			"TagID": tag.TagID,
+			"Name":  tag.Name,
+		})
 	}
 
-	if careerID != "" {
-		db = db.Where("career_id = ?", careerID)
+	// Armar respuesta completa del post
+	return gin.H{
+		"PostID":       post.PostID,
+		"UserID":       post.UserID,
+		"Content":      post.Content,
+		"CreatedAt":    post.CreatedAt,
+		"Tags":         tagsResponse,
+		"UniversityID": post.UniversityID,
+		"CareerID":     post.CareerID,
+		"University":   gin.H{"Name": details.University},
+		"Career":       gin.H{"Name": details.Career},
+		"User":         userResponse,
+		"Comments":     commentsResponse,
+		"Likes":        likesResponse,
+		"Files":        filesResponse,
 	}
+}
 
-	// Filtrar por tags si se proporcionan
-	if tagIDs != "" {
-		var tagIDList []uint
-		err := json.Unmarshal([]byte(tagIDs), &tagIDList)
-		if err == nil && len(tagIDList) > 0 {
-			// Buscar posts que tengan al menos uno de los tags especificados
-			db = db.Joins("JOIN post_tags ON posts.post_id = post_tags.post_id").
-				Where("post_tags.tag_id IN ?", tagIDList).
-				Group("posts.post_id")
-		}
+func CreatePost(c *gin.Context) {
+	// Convertir IDs a uint
+	careerID, err := strconv.ParseUint(c.PostForm("career_id"), 10, 32)
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField("career_id", "invalid_id"))
+		return
 	}
 
-	// Ejecutar la consulta
-	var posts []models.Post
-	if err := db.Order("created_at DESC").Find(&posts).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Error al buscar posts"})
+	universityID, err := strconv.ParseUint(c.PostForm("university_id"), 10, 32)
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField("university_id", "invalid_id"))
 		return
 	}
 
-	// Construir la respuesta
-	var response []gin.H
-	for _, post := range posts {
-		// Buscar información de Universidad
-		var universityName string
-		database.DB.Model(&models.University{}).
-			Select("name").
-			Where("university_id = ?", post.UniversityID).
-			Pluck("name", &universityName)
-
-		// Buscar información de Carrera
-		var careerName string
-		database.DB.Model(&models.Career{}).
-			Select("name").
-			Where("career_id = ?", post.CareerID).
-			Pluck("name", &careerName)
-
-		// Buscar archivos asociados al post
-		var files []models.PostFile
-		database.DB.Where("post_id = ?", post.PostID).Find(&files)
-
-		// Construir estructura de usuario
-		userResponse := gin.H{
-			"UserID":   post.User.UserID,
-			"Username": post.User.Username,
-			"Avatar":   post.User.Img,
+	input := services.NewPost{
+		Content:      c.PostForm("content"),
+		CareerID:     uint(careerID),
+		UniversityID: uint(universityID),
+	}
+
+	// Procesar tags si se proporcionaron
+	if tagIDs := c.PostForm("tag_ids"); tagIDs != "" {
+		if err := json.Unmarshal([]byte(tagIDs), &input.TagIDs); err != nil {
+			apperror.Abort(c, apperror.InvalidField("tag_ids", "invalid_format"))
+			return
 		}
+	}
 
-		// Construir estructura de comentarios
-		commentsResponse := []gin.H{}
-		for _, comment := range post.Comments {
-			commentUser := gin.H{
-				"UserID":   comment.User.UserID,
-				"Username": comment.User.Username,
-				"Avatar":   comment.User.Img,
+	// Procesar múltiples archivos
+	if form, err := c.MultipartForm(); err == nil {
+		for _, file := range form.File["files[]"] {
+			openedFile, err := file.Open()
+			if err != nil {
+				apperror.Abort(c, apperror.Internal(fmt.Errorf("abriendo el archivo %s: %w", file.Filename, err)))
+				return
 			}
+			defer openedFile.Close()
 
-			commentsResponse = append(commentsResponse, gin.H{
-				"CommentID": comment.CommentID,
-				"PostID":    comment.PostID,
-				"UserID":    comment.UserID,
-				"Content":   comment.Content,
-				"CreatedAt": comment.CreatedAt,
-				"User":      commentUser,
+			input.Files = append(input.Files, services.File{
+				Name:    file.Filename,
+				Size:    file.Size,
+				Content: openedFile,
 			})
 		}
+	}
 
-		// Construir estructura de likes
-		likesResponse := []gin.H{}
-		for _, like := range post.Likes {
-			likeUser := gin.H{
-				"UserID":   like.User.UserID,
-				"Username": like.User.Username,
-				"Avatar":   like.User.Img,
-			}
+	post, err := Services.Posts.Create(c, actor(c), input)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
 
-			likesResponse = append(likesResponse, gin.H{
-				"LikeID":  like.LikeID,
-				"PostID":  like.PostID,
-				"UserID":  like.UserID,
-				"LikedAt": like.LikedAt,
-				"User":    likeUser,
-			})
-		}
+	c.JSON(201, gin.H{
+		"message": "Post creado exitosamente",
+		"post_id": post.PostID,
+	})
+}
 
-		// Construir estructura de archivos
-		filesResponse := []gin.H{}
-		for _, file := range files {
-			filesResponse = append(filesResponse, gin.H{
-				"FileID":   file.FileID,
-				"FileURL":  file.FileURL,
-				"FileType": file.FileType,
-				"PostID":   file.PostID,
-				"FileName": file.FileName,
-			})
-		}
+func SearchPosts(c *gin.Context) {
+	// Obtener parámetros de búsqueda
+	filter := repository.PostFilter{Query: c.Query("q")}
 
-		// Construir estructura de tags
-		tagsResponse := []gin.H{}
-		for _, tag := range post.Tags {
-			tagsResponse = append(tagsResponse, gin.H{
-				"TagID": tag.TagID,
-				"Name":  tag.Name,
-			})
-		}
+	var ok bool
+	if filter.UniversityID, ok = queryID(c, "university"); !ok {
+		return
+	}
+	if filter.CareerID, ok = queryID(c, "career"); !ok {
+		return
+	}
 
-		// Armar respuesta completa de cada post
-		postResponse := gin.H{
-			"PostID":       post.PostID,
-			"UserID":       post.UserID,
-			"Content":      post.Content,
-			"CreatedAt":    post.CreatedAt,
-			"Tags":         tagsResponse,
-			"UniversityID": post.UniversityID,
-			"CareerID":     post.CareerID,
-			"University":   gin.H{"Name": universityName},
-			"Career":       gin.H{"Name": careerName},
-			"User":         userResponse,
-			"Comments":     commentsResponse,
-			"Likes":        likesResponse,
-			"Files":        filesResponse,
-		}
+	// Filtrar por tags si se proporcionan; un formato inválido se ignora
+	if tagIDs := c.Query("tag_ids"); tagIDs != "" {
+		_ = json.Unmarshal([]byte(tagIDs), &filter.TagIDs)
+	}
 
-		response = append(response, postResponse)
+	posts, err := Services.Posts.Search(c, filter)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
 	}
 
 	c.JSON(200, gin.H{
-		"posts": response,
+		"posts": postsResponse(posts),
 	})
 }
 
 // LikePost maneja la acción de dar like a un post
 func LikePost(c *gin.Context) {
-	postID := c.Param("id")
-	userID := c.GetInt("userID") // Asumiendo que tienes middleware de autenticación
-
-	// Verificar si el like ya existe
-	var existingLike models.PostLike
-	result := database.DB.Where("post_id = ? AND user_id = ?", postID, userID).First(&existingLike)
-
-	if result.Error == nil {
-		// Si el like existe, lo eliminamos (toggle)
-		if err := database.DB.Delete(&existingLike).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Error al quitar el like"})
-			return
-		}
-		c.JSON(200, gin.H{"message": "Like eliminado"})
+	postID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	// Si el like no existe, lo creamos
-	postIDUint, _ := strconv.ParseUint(postID, 10, 32)
-	newLike := models.PostLike{
-		PostID:  uint(postIDUint),
-		UserID:  uint(userID),
-		LikedAt: time.Now(),
+	liked, err := Services.Posts.ToggleLike(c, actor(c), postID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
 	}
 
-	if err := database.DB.Create(&newLike).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Error al dar like"})
+	if !liked {
+		c.JSON(200, gin.H{"message": "Like eliminado"})
 		return
 	}
-
 	c.JSON(200, gin.H{"message": "Like agregado"})
 }
 
 // AddComment maneja la acción de agregar un comentario
 func AddComment(c *gin.Context) {
-	postID := c.Param("id")
-	userID := c.GetInt("userID") // Asumiendo que tienes middleware de autenticación
+	postID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
 	var commentData struct {
 		Content string `json:"content" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&commentData); err != nil {
-		c.JSON(400, gin.H{"error": "Contenido del comentario requerido"})
+		apperror.Abort(c, apperror.InvalidField("content", "required"))
 		return
 	}
 
-	postIDUint, _ := strconv.ParseUint(postID, 10, 32)
-	newComment := models.Comment{
-		PostID:    uint(postIDUint),
-		UserID:    uint(userID),
-		Content:   commentData.Content,
-		CreatedAt: time.Now(),
-	}
-
-	if err := database.DB.Create(&newComment).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Error al crear el comentario"})
+	comment, err := Services.Posts.AddComment(c, actor(c), postID, commentData.Content)
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Cargar la información del usuario para la respuesta
-	var user models.User
-	database.DB.First(&user, userID)
-
 	c.JSON(200, gin.H{
 		"comment": gin.H{
-			"CommentID": newComment.CommentID,
-			"PostID":    newComment.PostID,
-			"UserID":    newComment.UserID,
-			"Content":   newComment.Content,
-			"CreatedAt": newComment.CreatedAt,
+			"CommentID": comment.CommentID,
+			"PostID":    comment.PostID,
+			"UserID":    comment.UserID,
+			"Content":   comment.Content,
+			"CreatedAt": comment.CreatedAt,
 			"User": gin.H{
-				"UserID":   user.UserID,
-				"Username": user.Username,
-				"Avatar":   user.Img,
+				"UserID":   comment.User.UserID,
+				"Username": comment.User.Username,
+				"Avatar":   comment.User.Img,
 			},
 		},
 	})
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdatePost actualiza el contenido y los tags de un post. Puede hacerlo el
+// autor o un moderador de la universidad del post.
+func UpdatePost(c *gin.Context) {
+	postID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Content *string `json:"content"`
+		TagIDs  *[]uint `json:"tag_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
+		return
+	}
+
+	err := Services.Posts.Update(c, actor(c), postID, services.PostUpdate{
+		Content: input.Content,
+		TagIDs:  input.TagIDs,
+	})
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Post actualizado exitosamente"})
+}
+
+// DeletePost elimina un post junto con sus comentarios, likes, archivos y tags.
+// Puede hacerlo el autor o un moderador de la universidad del post.
+func DeletePost(c *gin.Context) {
+	postID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := Services.Posts.Delete(c, actor(c), postID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Post eliminado exitosamente"})
+}
+
+// DeleteComment elimina un comentario. Puede hacerlo su autor o un moderador
+// de la universidad del post.
+func DeleteComment(c *gin.Context) {
+	postID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	commentID, ok := paramID(c, "commentId")
+	if !ok {
+		return
+	}
+
+	if err := Services.Posts.DeleteComment(c, actor(c), postID, commentID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Comentario eliminado exitosamente"})
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Services son los servicios de dominio que usan los controladores de posts,
+// canales y usuarios. Se inicializan al levantar el servidor.
+var Services *services.Services
+
+// actor arma el services.Actor del usuario autenticado
+func actor(c *gin.Context) services.Actor {
+	return services.Actor{UserID: c.MustGet("userID").(uint), IP: c.ClientIP()}
+}
+
+// paramID lee un parámetro de ruta numérico. Si no es válido corta el pedido
+// con un error de validación sobre ese parámetro y devuelve false.
+func paramID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField(param, "invalid_id"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// queryID lee un parámetro de query numérico opcional; devuelve 0 si no está.
+// Si no es válido corta el pedido igual que paramID.
+func queryID(c *gin.Context, name string) (uint, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField(name, "invalid_id"))
+		return 0, false
+	}
+	return uint(id), true
+}
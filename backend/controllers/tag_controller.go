@@ -3,69 +3,47 @@ package controllers
 import (
 	"net/http"
 
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
 	"github.com/LautaroRomano/repositorio-tecnologico/database"
 	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/seed"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 func GetTags(c *gin.Context) {
-	// Inicializar tags de ejemplo si no existen
-	initializeDefaultTags(database.DB)
-
 	var tags []models.Tag
-	database.DB.Find(&tags)
+	database.DB.WithContext(c).Find(&tags)
 
 	c.JSON(http.StatusOK, tags)
 }
 
-func initializeDefaultTags(db *gorm.DB) {
-	// Lista de tags por defecto
-	defaultTags := []string{
-		"Apuntes",
-		"Parciales",
-		"Finales",
-		"Clases",
-		"Ejercicios",
-		"Resúmenes",
-		"Material de estudio",
-		"Proyectos",
-		"Trabajos prácticos",
-		"Exámenes",
-		"Guías",
-		"Presentaciones",
-		"Videos",
-		"Libros",
-		"Artículos",
-	}
-
-	// Verificar si ya existen tags
-	var count int64
-	db.Model(&models.Tag{}).Count(&count)
+// RecreateTagTables vacía las tablas de tags y vuelve a cargar los tags por
+// defecto. El esquema lo manejan las migraciones, acá solo se tocan los datos.
+func RecreateTagTables(c *gin.Context) {
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM post_tags").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM channel_post_tags").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM tags").Error; err != nil {
+			return err
+		}
 
-	// Si no hay tags, crear los por defecto
-	if count == 0 {
-		for _, tagName := range defaultTags {
-			tag := models.Tag{
-				Name: tagName,
-			}
-			db.Create(&tag)
+		// Cargar los tags por defecto
+		dataset, err := seed.Default()
+		if err != nil {
+			return err
 		}
+		_, err = seed.ApplyTags(tx, dataset.Tags)
+		return err
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
 	}
-}
-
-// Función para recrear las tablas de tags si es necesario
-func RecreateTagTables(c *gin.Context) {
-
-	// Eliminar tablas existentes si existen
-	database.DB.Migrator().DropTable(&models.PostTag{})
-	database.DB.Migrator().DropTable(&models.Tag{})
-
-	// Recrear las tablas
-	database.DB.AutoMigrate(&models.Tag{}, &models.PostTag{})
-
-	// Inicializar tags por defecto
-	initializeDefaultTags(database.DB)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Tablas de tags recreadas exitosamente"})
 }
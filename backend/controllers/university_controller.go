@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
 	"github.com/LautaroRomano/repositorio-tecnologico/database"
 	"github.com/LautaroRomano/repositorio-tecnologico/models"
 	"github.com/gin-gonic/gin"
@@ -9,13 +10,13 @@ import (
 func GetUniversities(c *gin.Context) {
 
 	var universities []models.University
-	result := database.DB.Model(&models.University{}).
+	result := database.DB.WithContext(c).Model(&models.University{}).
 		Preload("Careers").
 		Order("name ASC").
 		Find(&universities)
 
 	if result.Error != nil {
-		c.JSON(500, gin.H{"error": "Error al obtener las universidades"})
+		apperror.Abort(c, apperror.Internal(result.Error))
 		return
 	}
 
@@ -39,12 +40,12 @@ func GetCareersByUniversityID(c *gin.Context) {
 func GetCareers(c *gin.Context) {
 
 	var careers []models.Career
-	result := database.DB.Model(&models.Career{}).
+	result := database.DB.WithContext(c).Model(&models.Career{}).
 		Order("name ASC").
 		Find(&careers)
 
 	if result.Error != nil {
-		c.JSON(500, gin.H{"error": "Error al obtener las carreras"})
+		apperror.Abort(c, apperror.Internal(result.Error))
 		return
 	}
 
@@ -1,83 +1,58 @@
 package controllers
 
 import (
-	"context"
-	"errors"
-	"fmt"
 	"net/http"
 	"strconv"
 
-	"github.com/LautaroRomano/repositorio-tecnologico/config"
-	"github.com/LautaroRomano/repositorio-tecnologico/database"
-	"github.com/LautaroRomano/repositorio-tecnologico/models"
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // GetUserProfile obtiene información detallada del perfil de un usuario
 func GetUserProfile(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+	userID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
-		return
-	}
+	profileResponse(c, userID)
+}
 
-	// Obtener información de universidad si existe
-	var universityName string
-	if user.UniversityID > 0 {
-		database.DB.Model(&models.University{}).
-			Select("name").
-			Where("university_id = ?", user.UniversityID).
-			Pluck("name", &universityName)
-	}
+// GetCurrentUser obtiene la información del usuario autenticado
+func GetCurrentUser(c *gin.Context) {
+	profileResponse(c, c.MustGet("userID").(uint))
+}
 
-	// Obtener información de carrera si existe
-	var careerName string
-	if user.CareerID > 0 {
-		database.DB.Model(&models.Career{}).
-			Select("name").
-			Where("career_id = ?", user.CareerID).
-			Pluck("name", &careerName)
+func profileResponse(c *gin.Context, userID uint) {
+	profile, err := Services.Users.Profile(c, userID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
 	}
 
-	// Contar número de publicaciones del usuario
-	var postsCount int64
-	database.DB.Model(&models.Post{}).Where("user_id = ?", userID).Count(&postsCount)
-
-	// Contar likes recibidos en todas sus publicaciones
-	var likesReceived int64
-	database.DB.Model(&models.PostLike{}).
-		Joins("JOIN posts ON post_likes.post_id = posts.post_id").
-		Where("posts.user_id = ?", userID).
-		Count(&likesReceived)
-
 	// Construir respuesta con información completa
+	user := profile.User
 	userResponse := gin.H{
 		"UserID":        user.UserID,
 		"Username":      user.Username,
 		"Avatar":        user.Img,
 		"JoinDate":      user.CreatedAt,
-		"PostsCount":    postsCount,
-		"LikesReceived": likesReceived,
+		"PostsCount":    profile.PostsCount,
+		"LikesReceived": profile.LikesReceived,
 		"UniversityID":  user.UniversityID,
 		"CareerID":      user.CareerID,
 	}
 
 	// Añadir información de universidad si existe
-	if universityName != "" {
-		userResponse["University"] = gin.H{"Name": universityName}
+	if profile.University != "" {
+		userResponse["University"] = gin.H{"Name": profile.University}
 	}
 
 	// Añadir información de carrera si existe
-	if careerName != "" {
-		userResponse["Career"] = gin.H{"Name": careerName}
+	if profile.Career != "" {
+		userResponse["Career"] = gin.H{"Name": profile.Career}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -87,9 +62,8 @@ func GetUserProfile(c *gin.Context) {
 
 // GetUserPosts obtiene las publicaciones de un usuario específico
 func GetUserPosts(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+	userID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
@@ -97,139 +71,19 @@ func GetUserPosts(c *gin.Context) {
 	page := 1
 	pageSize := 10
 
-	pageParam := c.DefaultQuery("page", "1")
-	pageNum, err := strconv.Atoi(pageParam)
+	pageNum, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if err == nil && pageNum > 0 {
 		page = pageNum
 	}
 
-	offset := (page - 1) * pageSize
-
-	// Obtener publicaciones del usuario con sus relaciones
-	var posts []models.Post
-	result := database.DB.Model(&models.Post{}).
-		Where("user_id = ?", userID).
-		Preload("User").
-		Preload("Comments").
-		Preload("Comments.User").
-		Preload("Likes").
-		Preload("Likes.User").
-		Order("created_at DESC").
-		Limit(pageSize).
-		Offset(offset).
-		Find(&posts)
-
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las publicaciones"})
+	posts, totalPosts, err := Services.Posts.ListByUser(c, userID, repository.Page{Number: page, Size: pageSize})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Construir respuesta con el mismo formato que GetPosts
-	var response []gin.H
-
-	for _, post := range posts {
-		var postUser models.User
-		database.DB.First(&postUser, post.UserID)
-
-		// Buscar información de Universidad
-		var universityName string
-		database.DB.Model(&models.University{}).
-			Select("name").
-			Where("university_id = ?", post.UniversityID).
-			Pluck("name", &universityName)
-
-		// Buscar información de Carrera
-		var careerName string
-		database.DB.Model(&models.Career{}).
-			Select("name").
-			Where("career_id = ?", post.CareerID).
-			Pluck("name", &careerName)
-
-		// Buscar archivos asociados al post
-		var files []models.PostFile
-		database.DB.Where("post_id = ?", post.PostID).Find(&files)
-
-		// Construir estructura de usuario usando datos recuperados explícitamente
-		userResponse := gin.H{
-			"UserID":   postUser.UserID,
-			"Username": postUser.Username,
-			"Avatar":   postUser.Img,
-		}
-
-		// Construir estructura de comentarios
-		commentsResponse := []gin.H{}
-		for _, comment := range post.Comments {
-			commentUser := gin.H{
-				"UserID":   comment.User.UserID,
-				"Username": comment.User.Username,
-				"Avatar":   comment.User.Img,
-			}
-
-			commentsResponse = append(commentsResponse, gin.H{
-				"CommentID": comment.CommentID,
-				"PostID":    comment.PostID,
-				"UserID":    comment.UserID,
-				"Content":   comment.Content,
-				"CreatedAt": comment.CreatedAt,
-				"User":      commentUser,
-			})
-		}
-
-		// Construir estructura de likes
-		likesResponse := []gin.H{}
-		for _, like := range post.Likes {
-			likeUser := gin.H{
-				"UserID":   like.User.UserID,
-				"Username": like.User.Username,
-				"Avatar":   like.User.Img,
-			}
-
-			likesResponse = append(likesResponse, gin.H{
-				"LikeID":  like.LikeID,
-				"PostID":  like.PostID,
-				"UserID":  like.UserID,
-				"LikedAt": like.LikedAt,
-				"User":    likeUser,
-			})
-		}
-
-		// Construir estructura de archivos
-		filesResponse := []gin.H{}
-		for _, file := range files {
-			filesResponse = append(filesResponse, gin.H{
-				"FileID":   file.FileID,
-				"FileURL":  file.FileURL,
-				"FileType": file.FileType,
-				"PostID":   file.PostID,
-			})
-		}
-
-		// Armar respuesta completa de cada post
-		postResponse := gin.H{
-			"PostID":       post.PostID,
-			"UserID":       post.UserID,
-			"Content":      post.Content,
-			"CreatedAt":    post.CreatedAt,
-			"Tags":         post.Tags,
-			"UniversityID": post.UniversityID,
-			"CareerID":     post.CareerID,
-			"University":   gin.H{"Name": universityName},
-			"Career":       gin.H{"Name": careerName},
-			"User":         userResponse,
-			"Comments":     commentsResponse,
-			"Likes":        likesResponse,
-			"Files":        filesResponse,
-		}
-
-		response = append(response, postResponse)
-	}
-
-	// Contar total de posts para paginación
-	var totalPosts int64
-	database.DB.Model(&models.Post{}).Where("user_id = ?", userID).Count(&totalPosts)
-
 	c.JSON(http.StatusOK, gin.H{
-		"posts": response,
+		"posts": postsResponse(posts),
 		"pagination": gin.H{
 			"current_page": page,
 			"total_pages":  (int(totalPosts) + pageSize - 1) / pageSize,
@@ -239,138 +93,58 @@ func GetUserPosts(c *gin.Context) {
 	})
 }
 
-// GetCurrentUser obtiene la información del usuario autenticado
-func GetCurrentUser(c *gin.Context) {
-	// Obtener ID del usuario desde el token (implementado en middleware de autenticación)
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
-		return
-	}
-
-	// Convertir userID a string independientemente de si es uint o int
-	var userIDStr string
-	switch v := userID.(type) {
-	case int:
-		userIDStr = strconv.Itoa(v)
-	case uint:
-		userIDStr = strconv.FormatUint(uint64(v), 10)
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Tipo de ID de usuario no válido"})
+func GetFollowers(c *gin.Context) {
+	followers, err := Services.Users.Followers(c, c.Param("username"))
+	if err != nil {
+		apperror.Abort(c, err)
 		return
 	}
 
-	// Reutilizar la lógica existente pasando el ID como parámetro
-	c.Params = append(c.Params, gin.Param{Key: "id", Value: userIDStr})
-	GetUserProfile(c)
+	c.JSON(http.StatusOK, followers)
 }
 
-func GetFollowers(c *gin.Context) {
-	username := c.Param("username")
-	var user models.User
-	if err := database.DB.Where("username = ?", username).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+func UpdateUserProfile(c *gin.Context) {
+	form, err := c.MultipartForm()
+	if err != nil ||
+		len(form.File["avatar"]) == 0 ||
+		len(form.Value["university_id"]) == 0 ||
+		len(form.Value["career_id"]) == 0 {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 		return
 	}
 
-	var followers []models.User
-	if err := database.DB.Model(&user).Association("Followers").Find(&followers); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener seguidores"})
+	universityID, err := strconv.ParseUint(form.Value["university_id"][0], 10, 32)
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField("university_id", "invalid_id"))
 		return
 	}
 
-	c.JSON(http.StatusOK, followers)
-}
-
-func UpdateUserProfile(c *gin.Context) {
-	// Obtener ID del usuario desde el token (implementado en middleware de autenticación)
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+	careerID, err := strconv.ParseUint(form.Value["career_id"][0], 10, 32)
+	if err != nil {
+		apperror.Abort(c, apperror.InvalidField("career_id", "invalid_id"))
 		return
 	}
 
-	// Procesar múltiples archivos
-	form, err := c.MultipartForm()
-	if err == nil &&
-		form.File != nil &&
-		form.File["avatar"] != nil && len(form.File["avatar"]) > 0 &&
-		form.Value != nil &&
-		form.Value["university_id"] != nil && len(form.Value["university_id"]) > 0 &&
-		form.Value["career_id"] != nil && len(form.Value["career_id"]) > 0 {
-
-		file := form.File["avatar"][0]
-		university_id := form.Value["university_id"][0]
-		career_id := form.Value["career_id"][0]
-
-		userIDStr := strconv.FormatUint(uint64(userID.(uint)), 10)
-		userID, err := strconv.Atoi(userIDStr)
-
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
-			return
-		}
-
-		// Abrir el archivo
-		openedFile, err := file.Open()
-		if err != nil {
-			c.JSON(500, gin.H{"error": fmt.Sprintf("No se pudo abrir el archivo %s: %v", file.Filename, err)})
-			return
-		}
-		defer openedFile.Close()
-
-		// Determinar el tipo de archivo basado en la extensión
-		fileType := determineFileType(file.Filename)
-
-		if fileType != "image/jpeg" {
-			c.JSON(400, gin.H{"error": "El archivo debe ser una imagen"})
-			return
-		}
-
-		// Subir a Cloudinary
-		ctx := context.Background()
-		uploadParams := uploader.UploadParams{
-			Folder: "avatars",
-		}
-
-		result, err := config.Cld.Upload.Upload(ctx, openedFile, uploadParams)
-		if err != nil {
-			c.JSON(500, gin.H{"error": fmt.Sprintf("Error al subir archivo %s: %v", file.Filename, err)})
-			return
-		}
-
-		// Guardar URL del avatar en la base de datos
-		var user models.User
-		if err := database.DB.First(&user, userID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
-			return
-		}
-		user.Img = result.SecureURL
-
-		// Convertir university_id de string a uint
-		universityID, err := strconv.Atoi(university_id)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de universidad inválido"})
-			return
-		}
-		user.UniversityID = uint(universityID)
+	// Abrir el archivo
+	file := form.File["avatar"][0]
+	openedFile, err := file.Open()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal(err))
+		return
+	}
+	defer openedFile.Close()
 
-		// Convertir career_id de string a uint
-		careerID, err := strconv.Atoi(career_id)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de carrera inválido"})
-			return
-		}
-		user.CareerID = uint(careerID)
-		if err := database.DB.Save(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar el avatar"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"message": "Usuario actualizado con éxito", "user": user})
+	user, err := Services.Users.UpdateProfile(c, actor(c), services.ProfileUpdate{
+		Avatar:       services.File{Name: file.Filename, Size: file.Size, Content: openedFile},
+		UniversityID: uint(universityID),
+		CareerID:     uint(careerID),
+	})
+	if err != nil {
+		apperror.Abort(c, err)
 		return
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos"})
 	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Usuario actualizado con éxito", "user": user})
 }
 
 // ChangePassword handles changing user password
@@ -381,52 +155,15 @@ func ChangePassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&passwordChange); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid input format"})
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidRequest))
 		return
 	}
 
-	// Get the user ID from the token/session
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(401, gin.H{"error": "Authentication required"})
-		return
-	}
-
-	// Verify current password and update to new password
-	// This is where you would implement your password change logic
-	err := verifyAndUpdatePassword(userID.(uint), passwordChange.CurrentPassword, passwordChange.NewPassword)
+	err := Services.Users.ChangePassword(c, actor(c), passwordChange.CurrentPassword, passwordChange.NewPassword)
 	if err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		apperror.Abort(c, err)
 		return
 	}
 
 	c.JSON(200, gin.H{"message": "Password changed successfully"})
 }
-
-// Helper function to verify current password and update to new password
-func verifyAndUpdatePassword(userID uint, currentPassword, newPassword string) error {
-	// Get the user from the database
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		return err
-	}
-
-	// Verify the current password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
-		return errors.New("current password is incorrect")
-	}
-
-	// Hash the new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	if err != nil {
-		return errors.New("error processing new password")
-	}
-
-	// Update the password in the database
-	result := database.DB.Model(&user).Update("password_hash", string(hashedPassword))
-	if result.Error != nil {
-		return result.Error
-	}
-
-	return nil
-}
@@ -1,129 +1,89 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
-	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/config"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/tracing"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func Connect() {
-	// Esta función ahora se llama desde vercel.go con la configuración ya validada
-	// Obtener configuración de la base de datos
-	dbHost := os.Getenv("DB_HOST")
-		dbUser := os.Getenv("DB_USER")
-		dbPassword := os.Getenv("DB_PASSWORD")
-		dbName := os.Getenv("DB_NAME")
-		dbPort := os.Getenv("DB_PORT")
-		dbSSLMode := os.Getenv("DB_SSLMODE")
-		
-		if dbPort == "" {
-			dbPort = "5432"
-		}
-		if dbSSLMode == "" {
-			dbSSLMode = "require"
-		}
-		
-		if dbHost != "" && dbUser != "" && dbName != "" && dbSSLMode != "" {
-			log.Println("Variables de entorno encontradas, usando configuración de producción")
-		} else {
-			log.Println("Variables de entorno incompletas, usando valores por defecto")
-		}
-	
-	log.Printf("Conectando a la base de datos: %s:%s/%s", dbHost, dbPort, dbName)
-
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		dbHost, dbUser, dbPassword, dbName, dbPort, dbSSLMode,
-	)
+// Connect abre la conexión con PostgreSQL. Los errores de las consultas se
+// registran con el logger estructurado.
+func Connect(cfg config.DatabaseConfig) error {
+	slog.Info("Conectando a la base de datos", "host", cfg.Host, "port", cfg.Port, "database", cfg.Name)
 
 	var err error
-	// Disable foreign key constraints during migration
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true,
+	DB, err = gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
+		Logger: logging.NewGormLogger(),
 	})
 	if err != nil {
-		log.Fatalf("Error conectando a la base de datos: %v", err)
+		return fmt.Errorf("error conectando a la base de datos: %w", err)
 	}
-	log.Println("Conexión con PostgreSQL establecida correctamente.")
-}
-
-// ConnectWithConfig permite conectar con configuración específica
-func ConnectWithConfig(host, user, password, dbname, port, sslmode string) {
-	log.Printf("Conectando a la base de datos: %s:%s/%s", host, port, dbname)
-
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s timezone=Argentina/Buenos_Aires",
-		host, user, password, dbname, port, sslmode,
-	)
-
-	var err error
-	// Disable foreign key constraints during migration
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true,
-	})
+	if err := DB.Use(metrics.GormPlugin{}); err != nil {
+		return fmt.Errorf("error registrando las métricas de GORM: %w", err)
+	}
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return fmt.Errorf("error registrando las trazas de GORM: %w", err)
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatalf("Error conectando a la base de datos: %v", err)
+		return err
+	}
+	if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		return fmt.Errorf("error registrando las métricas del pool: %w", err)
 	}
-	log.Println("Conexión con PostgreSQL establecida correctamente.")
+	slog.Info("Conexión con PostgreSQL establecida correctamente")
+	return nil
 }
 
-func Migrate() {
-	// Migrate all models at once with foreign key constraints disabled
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.Post{},
-		&models.Comment{},
-		&models.PostLike{},
-		&models.PostFile{},
-		&models.PostTag{},
-		&models.Tag{},
-		&models.Follow{},
-		&models.University{},
-		&models.Career{},
-		&models.Channel{},
-		&models.ChannelMember{},
-		&models.ChannelInvitation{},
-		&models.ChannelPost{},
-		&models.ChannelPostComment{},
-		&models.ChannelPostLike{},
-		&models.ChannelPostFile{},
-	)
+// Migrate aplica las migraciones pendientes al iniciar el servidor
+func Migrate() error {
+	applied, err := MigrateUp(context.Background(), 0)
 	if err != nil {
-		log.Fatalf("Error en la migración: %v", err)
+		return fmt.Errorf("error en la migración: %w", err)
 	}
 
-	log.Println("Migración completada exitosamente.")
+	slog.Info("Migración completada exitosamente", "applied", applied)
+	return nil
 }
 
-func InitDB() {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "root:@tcp(127.0.0.1:3306)/repositorio_tecnologico?charset=utf8mb4&parseTime=True&loc=Local"
+// Ping verifica la conexión con la base de datos y devuelve el estado del pool
+func Ping(ctx context.Context) (map[string]interface{}, error) {
+	if DB == nil {
+		return nil, errors.New("la base de datos no está conectada")
 	}
-
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return nil, err
 	}
 
-	// Migrate the schema
-	err = DB.AutoMigrate(
-		&models.User{},
-		&models.University{},
-		&models.Career{},
-		&models.Post{},
-		&models.PostTag{},
-		&models.Tag{},
-		&models.Follow{},
-	)
+	stats := sqlDB.Stats()
+	details := map[string]interface{}{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"wait_count":       stats.WaitCount,
+	}
+	return details, sqlDB.PingContext(ctx)
+}
+
+// Close cierra el pool de conexiones
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return err
 	}
+	return sqlDB.Close()
 }
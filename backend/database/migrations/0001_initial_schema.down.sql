@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS channel_post_files;
+DROP TABLE IF EXISTS channel_post_likes;
+DROP TABLE IF EXISTS channel_post_comments;
+DROP TABLE IF EXISTS channel_posts;
+DROP TABLE IF EXISTS channel_invitations;
+DROP TABLE IF EXISTS channel_members;
+DROP TABLE IF EXISTS channels;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS post_files;
+DROP TABLE IF EXISTS post_likes;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS careers;
+DROP TABLE IF EXISTS universities;
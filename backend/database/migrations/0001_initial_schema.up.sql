@@ -0,0 +1,147 @@
+-- Esquema inicial, equivalente al que generaba AutoMigrate. Se usa
+-- IF NOT EXISTS para que las bases creadas con AutoMigrate puedan adoptar
+-- las migraciones sin perder datos.
+
+CREATE TABLE IF NOT EXISTS universities (
+	university_id bigserial PRIMARY KEY,
+	name text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS careers (
+	career_id bigserial PRIMARY KEY,
+	name text NOT NULL,
+	university_id bigint NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id bigserial PRIMARY KEY,
+	username text,
+	email text,
+	password_hash text,
+	account_name text,
+	img text,
+	reset_password_token text,
+	reset_password_expires timestamptz,
+	created_at timestamptz,
+	updated_at timestamptz,
+	university_id bigint,
+	career_id bigint,
+	CONSTRAINT uni_users_username UNIQUE (username),
+	CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS follows (
+	follow_id bigserial PRIMARY KEY,
+	follower_id bigint NOT NULL,
+	followed_id bigint NOT NULL,
+	created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+	post_id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	content text NOT NULL,
+	created_at timestamptz,
+	tsv tsvector,
+	university_id bigint NOT NULL,
+	career_id bigint NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+	comment_id bigserial PRIMARY KEY,
+	post_id bigint,
+	user_id bigint,
+	content text,
+	created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS post_likes (
+	like_id bigserial PRIMARY KEY,
+	post_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	liked_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS post_files (
+	file_id bigserial PRIMARY KEY,
+	file_url text NOT NULL,
+	file_type text NOT NULL,
+	file_name text NOT NULL,
+	post_id bigint NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+	tag_id bigserial PRIMARY KEY,
+	name text NOT NULL,
+	created_at timestamptz,
+	CONSTRAINT uni_tags_name UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id bigint NOT NULL,
+	tag_id bigint NOT NULL,
+	PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS channels (
+	channel_id bigserial PRIMARY KEY,
+	name text NOT NULL,
+	description text,
+	created_at timestamptz,
+	created_by bigint NOT NULL,
+	is_private boolean DEFAULT false,
+	university_id bigint NOT NULL,
+	career_id bigint NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS channel_members (
+	member_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	is_admin boolean DEFAULT false,
+	joined_at timestamptz,
+	last_seen_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS channel_invitations (
+	invitation_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL,
+	invited_by bigint NOT NULL,
+	invited_user bigint NOT NULL,
+	status varchar(20) DEFAULT 'pending',
+	created_at timestamptz,
+	updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS channel_posts (
+	post_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	content text NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz,
+	tags text[]
+);
+
+CREATE TABLE IF NOT EXISTS channel_post_comments (
+	comment_id bigserial PRIMARY KEY,
+	post_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	content text NOT NULL,
+	created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS channel_post_likes (
+	like_id bigserial PRIMARY KEY,
+	post_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	liked_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS channel_post_files (
+	file_id bigserial PRIMARY KEY,
+	post_id bigint NOT NULL,
+	file_url text NOT NULL,
+	file_type text NOT NULL,
+	file_name text NOT NULL
+);
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS signing_keys;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS auth_throttles;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS user_identities;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reset_password_token text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reset_password_expires timestamptz;
+
+DROP INDEX IF EXISTS idx_users_email_canonical;
+ALTER TABLE users DROP COLUMN IF EXISTS email_canonical;
@@ -0,0 +1,83 @@
+-- Tablas de autenticación y autorización: identidades OIDC, tokens de
+-- recuperación hasheados, contadores de intentos, auditoría, claves JWT y roles.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_canonical text;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_canonical ON users (email_canonical);
+
+-- Completar el email canónico de las cuentas existentes cuando no genera conflicto
+UPDATE users u
+SET email_canonical = c.canonical
+FROM (
+	SELECT user_id,
+		lower(regexp_replace(email, '\+[^@]*@', '@')) AS canonical,
+		count(*) OVER (PARTITION BY lower(regexp_replace(email, '\+[^@]*@', '@'))) AS total
+	FROM users
+	WHERE email IS NOT NULL AND email <> ''
+) c
+WHERE u.user_id = c.user_id AND c.total = 1 AND u.email_canonical IS NULL;
+
+-- Los tokens de recuperación en texto plano se reemplazan por password_reset_tokens
+ALTER TABLE users DROP COLUMN IF EXISTS reset_password_token;
+ALTER TABLE users DROP COLUMN IF EXISTS reset_password_expires;
+
+CREATE TABLE IF NOT EXISTS user_identities (
+	identity_id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	provider text NOT NULL,
+	subject text NOT NULL,
+	email text,
+	created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_identity_provider_subject ON user_identities (provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+	token_id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	token_hash text NOT NULL,
+	expires_at timestamptz,
+	used_at timestamptz,
+	created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS auth_throttles (
+	key varchar(255) PRIMARY KEY,
+	failures bigint NOT NULL DEFAULT 0,
+	last_failure_at timestamptz,
+	locked_until timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+	audit_id bigserial PRIMARY KEY,
+	user_id bigint,
+	actor_id bigint,
+	action varchar(64) NOT NULL,
+	ip varchar(64),
+	details text,
+	created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+	key_id varchar(64) PRIMARY KEY,
+	algorithm varchar(16) NOT NULL,
+	private_key text NOT NULL,
+	public_key text NOT NULL,
+	created_at timestamptz,
+	sign_until timestamptz NOT NULL,
+	verify_until timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_signing_keys_verify_until ON signing_keys (verify_until);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+	user_role_id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	role varchar(32) NOT NULL,
+	university_id bigint,
+	granted_by bigint,
+	created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_user_roles_user_id ON user_roles (user_id);
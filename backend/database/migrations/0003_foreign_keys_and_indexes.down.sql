@@ -0,0 +1,57 @@
+-- Las filas borradas por la migración (huérfanas y duplicadas) no se restauran.
+
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
+DROP INDEX IF EXISTS idx_channel_post_files_post_id;
+DROP INDEX IF EXISTS idx_channel_post_likes_user_id;
+DROP INDEX IF EXISTS idx_channel_post_comments_post_id;
+DROP INDEX IF EXISTS idx_channel_posts_user_id;
+DROP INDEX IF EXISTS idx_channel_posts_channel_created;
+DROP INDEX IF EXISTS idx_channel_invitations_invited_user;
+DROP INDEX IF EXISTS idx_channel_members_user_id;
+DROP INDEX IF EXISTS idx_channels_created_by;
+DROP INDEX IF EXISTS idx_channels_university_career;
+DROP INDEX IF EXISTS idx_post_tags_tag_id;
+DROP INDEX IF EXISTS idx_post_files_post_id;
+DROP INDEX IF EXISTS idx_post_likes_user_id;
+DROP INDEX IF EXISTS idx_comments_post_id;
+DROP INDEX IF EXISTS idx_posts_tsv;
+DROP INDEX IF EXISTS idx_posts_university_career;
+DROP INDEX IF EXISTS idx_posts_created_at;
+DROP INDEX IF EXISTS idx_posts_user_id;
+DROP INDEX IF EXISTS idx_follows_followed_id;
+DROP INDEX IF EXISTS idx_careers_university_id;
+DROP INDEX IF EXISTS idx_user_roles_scope;
+DROP INDEX IF EXISTS idx_channel_invitations_pending;
+
+ALTER TABLE user_roles DROP CONSTRAINT IF EXISTS fk_user_roles_university;
+ALTER TABLE user_roles DROP CONSTRAINT IF EXISTS fk_user_roles_user;
+ALTER TABLE password_reset_tokens DROP CONSTRAINT IF EXISTS fk_password_reset_tokens_user;
+ALTER TABLE user_identities DROP CONSTRAINT IF EXISTS fk_user_identities_user;
+ALTER TABLE channel_post_files DROP CONSTRAINT IF EXISTS fk_channel_post_files_post;
+ALTER TABLE channel_post_likes DROP CONSTRAINT IF EXISTS fk_channel_post_likes_user;
+ALTER TABLE channel_post_likes DROP CONSTRAINT IF EXISTS fk_channel_post_likes_post;
+ALTER TABLE channel_post_comments DROP CONSTRAINT IF EXISTS fk_channel_post_comments_user;
+ALTER TABLE channel_post_comments DROP CONSTRAINT IF EXISTS fk_channel_post_comments_post;
+ALTER TABLE channel_posts DROP CONSTRAINT IF EXISTS fk_channel_posts_user;
+ALTER TABLE channel_posts DROP CONSTRAINT IF EXISTS fk_channel_posts_channel;
+ALTER TABLE channel_invitations DROP CONSTRAINT IF EXISTS fk_channel_invitations_invitee;
+ALTER TABLE channel_invitations DROP CONSTRAINT IF EXISTS fk_channel_invitations_inviter;
+ALTER TABLE channel_invitations DROP CONSTRAINT IF EXISTS fk_channel_invitations_channel;
+ALTER TABLE channel_members DROP CONSTRAINT IF EXISTS fk_channel_members_user;
+ALTER TABLE channel_members DROP CONSTRAINT IF EXISTS fk_channel_members_channel;
+ALTER TABLE channels DROP CONSTRAINT IF EXISTS fk_channels_creator;
+ALTER TABLE post_tags DROP CONSTRAINT IF EXISTS fk_post_tags_tag;
+ALTER TABLE post_tags DROP CONSTRAINT IF EXISTS fk_post_tags_post;
+ALTER TABLE post_files DROP CONSTRAINT IF EXISTS fk_post_files_post;
+ALTER TABLE post_likes DROP CONSTRAINT IF EXISTS fk_post_likes_user;
+ALTER TABLE post_likes DROP CONSTRAINT IF EXISTS fk_post_likes_post;
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS fk_comments_user;
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS fk_comments_post;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS fk_posts_user;
+ALTER TABLE follows DROP CONSTRAINT IF EXISTS fk_follows_followed;
+ALTER TABLE follows DROP CONSTRAINT IF EXISTS fk_follows_follower;
+ALTER TABLE careers DROP CONSTRAINT IF EXISTS fk_careers_university;
+ALTER TABLE follows DROP CONSTRAINT IF EXISTS uni_follows_follower_followed;
+ALTER TABLE channel_members DROP CONSTRAINT IF EXISTS uni_channel_members_channel_user;
+ALTER TABLE channel_post_likes DROP CONSTRAINT IF EXISTS uni_channel_post_likes_post_user;
+ALTER TABLE post_likes DROP CONSTRAINT IF EXISTS uni_post_likes_post_user;
@@ -0,0 +1,134 @@
+-- Claves foráneas, restricciones únicas e índices que AutoMigrate nunca creó
+-- (corría con DisableForeignKeyConstraintWhenMigrating). Antes de crearlas se
+-- limpian los datos que las violarían: filas huérfanas y duplicados.
+
+-- Filas huérfanas
+DELETE FROM post_tags WHERE post_id NOT IN (SELECT post_id FROM posts) OR tag_id NOT IN (SELECT tag_id FROM tags);
+DELETE FROM post_likes WHERE post_id NOT IN (SELECT post_id FROM posts) OR user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM post_files WHERE post_id NOT IN (SELECT post_id FROM posts);
+DELETE FROM comments WHERE post_id IS NULL OR post_id NOT IN (SELECT post_id FROM posts) OR user_id IS NULL OR user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM posts WHERE user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM follows WHERE follower_id NOT IN (SELECT user_id FROM users) OR followed_id NOT IN (SELECT user_id FROM users);
+DELETE FROM channel_post_files WHERE post_id NOT IN (SELECT post_id FROM channel_posts);
+DELETE FROM channel_post_likes WHERE post_id NOT IN (SELECT post_id FROM channel_posts) OR user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM channel_post_comments WHERE post_id NOT IN (SELECT post_id FROM channel_posts) OR user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM channel_posts WHERE channel_id NOT IN (SELECT channel_id FROM channels) OR user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM channel_members WHERE channel_id NOT IN (SELECT channel_id FROM channels) OR user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM channel_invitations WHERE channel_id NOT IN (SELECT channel_id FROM channels)
+	OR invited_by NOT IN (SELECT user_id FROM users) OR invited_user NOT IN (SELECT user_id FROM users);
+DELETE FROM careers WHERE university_id NOT IN (SELECT university_id FROM universities);
+DELETE FROM user_identities WHERE user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM password_reset_tokens WHERE user_id NOT IN (SELECT user_id FROM users);
+DELETE FROM user_roles WHERE user_id NOT IN (SELECT user_id FROM users);
+UPDATE user_roles SET university_id = NULL WHERE university_id NOT IN (SELECT university_id FROM universities);
+
+-- Duplicados: se conserva la fila más antigua
+DELETE FROM post_likes a USING post_likes b
+	WHERE a.post_id = b.post_id AND a.user_id = b.user_id AND a.like_id > b.like_id;
+DELETE FROM channel_post_likes a USING channel_post_likes b
+	WHERE a.post_id = b.post_id AND a.user_id = b.user_id AND a.like_id > b.like_id;
+DELETE FROM follows a USING follows b
+	WHERE a.follower_id = b.follower_id AND a.followed_id = b.followed_id AND a.follow_id > b.follow_id;
+-- En membresías duplicadas se conserva la de administrador si la hay
+DELETE FROM channel_members a USING channel_members b
+	WHERE a.channel_id = b.channel_id AND a.user_id = b.user_id
+	AND (b.is_admin, -b.member_id) > (a.is_admin, -a.member_id);
+DELETE FROM channel_invitations a USING channel_invitations b
+	WHERE a.channel_id = b.channel_id AND a.invited_user = b.invited_user
+	AND a.status = 'pending' AND b.status = 'pending' AND a.invitation_id > b.invitation_id;
+DELETE FROM user_roles a USING user_roles b
+	WHERE a.user_id = b.user_id AND a.role = b.role
+	AND COALESCE(a.university_id, 0) = COALESCE(b.university_id, 0) AND a.user_role_id > b.user_role_id;
+
+-- Restricciones únicas
+ALTER TABLE post_likes ADD CONSTRAINT uni_post_likes_post_user UNIQUE (post_id, user_id);
+ALTER TABLE channel_post_likes ADD CONSTRAINT uni_channel_post_likes_post_user UNIQUE (post_id, user_id);
+ALTER TABLE channel_members ADD CONSTRAINT uni_channel_members_channel_user UNIQUE (channel_id, user_id);
+ALTER TABLE follows ADD CONSTRAINT uni_follows_follower_followed UNIQUE (follower_id, followed_id);
+CREATE UNIQUE INDEX idx_channel_invitations_pending ON channel_invitations (channel_id, invited_user) WHERE status = 'pending';
+CREATE UNIQUE INDEX idx_user_roles_scope ON user_roles (user_id, role, COALESCE(university_id, 0));
+
+-- Claves foráneas. Los usuarios, posts y canales guardan universidad y carrera
+-- con 0 cuando no se eligieron, por eso esas columnas no llevan FK.
+ALTER TABLE careers ADD CONSTRAINT fk_careers_university
+	FOREIGN KEY (university_id) REFERENCES universities (university_id) ON DELETE CASCADE;
+
+ALTER TABLE follows ADD CONSTRAINT fk_follows_follower
+	FOREIGN KEY (follower_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE follows ADD CONSTRAINT fk_follows_followed
+	FOREIGN KEY (followed_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE posts ADD CONSTRAINT fk_posts_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE comments ADD CONSTRAINT fk_comments_post
+	FOREIGN KEY (post_id) REFERENCES posts (post_id) ON DELETE CASCADE;
+ALTER TABLE comments ADD CONSTRAINT fk_comments_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE post_likes ADD CONSTRAINT fk_post_likes_post
+	FOREIGN KEY (post_id) REFERENCES posts (post_id) ON DELETE CASCADE;
+ALTER TABLE post_likes ADD CONSTRAINT fk_post_likes_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE post_files ADD CONSTRAINT fk_post_files_post
+	FOREIGN KEY (post_id) REFERENCES posts (post_id) ON DELETE CASCADE;
+ALTER TABLE post_tags ADD CONSTRAINT fk_post_tags_post
+	FOREIGN KEY (post_id) REFERENCES posts (post_id) ON DELETE CASCADE;
+ALTER TABLE post_tags ADD CONSTRAINT fk_post_tags_tag
+	FOREIGN KEY (tag_id) REFERENCES tags (tag_id) ON DELETE CASCADE;
+
+ALTER TABLE channels ADD CONSTRAINT fk_channels_creator
+	FOREIGN KEY (created_by) REFERENCES users (user_id) ON DELETE RESTRICT;
+ALTER TABLE channel_members ADD CONSTRAINT fk_channel_members_channel
+	FOREIGN KEY (channel_id) REFERENCES channels (channel_id) ON DELETE CASCADE;
+ALTER TABLE channel_members ADD CONSTRAINT fk_channel_members_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE channel_invitations ADD CONSTRAINT fk_channel_invitations_channel
+	FOREIGN KEY (channel_id) REFERENCES channels (channel_id) ON DELETE CASCADE;
+ALTER TABLE channel_invitations ADD CONSTRAINT fk_channel_invitations_inviter
+	FOREIGN KEY (invited_by) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE channel_invitations ADD CONSTRAINT fk_channel_invitations_invitee
+	FOREIGN KEY (invited_user) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE channel_posts ADD CONSTRAINT fk_channel_posts_channel
+	FOREIGN KEY (channel_id) REFERENCES channels (channel_id) ON DELETE CASCADE;
+ALTER TABLE channel_posts ADD CONSTRAINT fk_channel_posts_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE channel_post_comments ADD CONSTRAINT fk_channel_post_comments_post
+	FOREIGN KEY (post_id) REFERENCES channel_posts (post_id) ON DELETE CASCADE;
+ALTER TABLE channel_post_comments ADD CONSTRAINT fk_channel_post_comments_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE channel_post_likes ADD CONSTRAINT fk_channel_post_likes_post
+	FOREIGN KEY (post_id) REFERENCES channel_posts (post_id) ON DELETE CASCADE;
+ALTER TABLE channel_post_likes ADD CONSTRAINT fk_channel_post_likes_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE channel_post_files ADD CONSTRAINT fk_channel_post_files_post
+	FOREIGN KEY (post_id) REFERENCES channel_posts (post_id) ON DELETE CASCADE;
+
+ALTER TABLE user_identities ADD CONSTRAINT fk_user_identities_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE password_reset_tokens ADD CONSTRAINT fk_password_reset_tokens_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD CONSTRAINT fk_user_roles_user
+	FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD CONSTRAINT fk_user_roles_university
+	FOREIGN KEY (university_id) REFERENCES universities (university_id) ON DELETE CASCADE;
+
+-- Índices para las consultas más frecuentes
+CREATE INDEX idx_careers_university_id ON careers (university_id);
+CREATE INDEX idx_follows_followed_id ON follows (followed_id);
+CREATE INDEX idx_posts_user_id ON posts (user_id);
+CREATE INDEX idx_posts_created_at ON posts (created_at DESC);
+CREATE INDEX idx_posts_university_career ON posts (university_id, career_id);
+CREATE INDEX idx_posts_tsv ON posts USING GIN (tsv);
+CREATE INDEX idx_comments_post_id ON comments (post_id);
+CREATE INDEX idx_post_likes_user_id ON post_likes (user_id);
+CREATE INDEX idx_post_files_post_id ON post_files (post_id);
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
+CREATE INDEX idx_channels_university_career ON channels (university_id, career_id);
+CREATE INDEX idx_channels_created_by ON channels (created_by);
+CREATE INDEX idx_channel_members_user_id ON channel_members (user_id);
+CREATE INDEX idx_channel_invitations_invited_user ON channel_invitations (invited_user, status);
+CREATE INDEX idx_channel_posts_channel_created ON channel_posts (channel_id, created_at DESC);
+CREATE INDEX idx_channel_posts_user_id ON channel_posts (user_id);
+CREATE INDEX idx_channel_post_comments_post_id ON channel_post_comments (post_id);
+CREATE INDEX idx_channel_post_likes_user_id ON channel_post_likes (user_id);
+CREATE INDEX idx_channel_post_files_post_id ON channel_post_files (post_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at DESC);
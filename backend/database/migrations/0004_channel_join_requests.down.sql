@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_channels_public;
+DROP TABLE IF EXISTS channel_join_requests;
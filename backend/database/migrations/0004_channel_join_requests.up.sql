@@ -0,0 +1,18 @@
+-- Solicitudes para entrar a canales privados. Los canales públicos no las
+-- necesitan: cualquier usuario se une directamente.
+
+CREATE TABLE IF NOT EXISTS channel_join_requests (
+	request_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL REFERENCES channels (channel_id) ON DELETE CASCADE,
+	user_id bigint NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+	message text,
+	status varchar(20) DEFAULT 'pending',
+	reviewed_by bigint REFERENCES users (user_id) ON DELETE SET NULL,
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_channel_join_requests_pending ON channel_join_requests (channel_id, user_id) WHERE status = 'pending';
+CREATE INDEX idx_channel_join_requests_user_id ON channel_join_requests (user_id);
+
+-- Índice para el listado de canales públicos
+CREATE INDEX idx_channels_public ON channels (university_id, career_id) WHERE NOT is_private;
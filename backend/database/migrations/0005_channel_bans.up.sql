@@ -0,0 +1,13 @@
+-- Usuarios bloqueados en un canal: no pueden volver a unirse, pedir entrar ni
+-- ser invitados hasta que un administrador levante el bloqueo.
+
+CREATE TABLE IF NOT EXISTS channel_bans (
+	ban_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL REFERENCES channels (channel_id) ON DELETE CASCADE,
+	user_id bigint NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+	banned_by bigint REFERENCES users (user_id) ON DELETE SET NULL,
+	reason text,
+	created_at timestamptz,
+	CONSTRAINT uni_channel_bans_channel_user UNIQUE (channel_id, user_id)
+);
+CREATE INDEX idx_channel_bans_user_id ON channel_bans (user_id);
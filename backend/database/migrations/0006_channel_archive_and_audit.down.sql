@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS channel_audit_logs;
+
+DROP INDEX IF EXISTS idx_channels_public;
+CREATE INDEX idx_channels_public ON channels (university_id, career_id) WHERE NOT is_private;
+
+ALTER TABLE channels DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,18 @@
+-- Canales archivados (solo lectura y fuera del listado público) y el registro
+-- de cambios de cada canal. El registro no tiene clave foránea al canal para
+-- que sobreviva a su borrado.
+
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS archived_at timestamptz;
+
+DROP INDEX IF EXISTS idx_channels_public;
+CREATE INDEX idx_channels_public ON channels (university_id, career_id) WHERE NOT is_private AND archived_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS channel_audit_logs (
+	log_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL,
+	actor_id bigint REFERENCES users (user_id) ON DELETE SET NULL,
+	action varchar(64) NOT NULL,
+	details text,
+	created_at timestamptz
+);
+CREATE INDEX idx_channel_audit_logs_channel_created ON channel_audit_logs (channel_id, created_at DESC);
@@ -0,0 +1,12 @@
+ALTER TABLE channels
+	DROP COLUMN IF EXISTS perm_post,
+	DROP COLUMN IF EXISTS perm_comment,
+	DROP COLUMN IF EXISTS perm_invite,
+	DROP COLUMN IF EXISTS perm_pin,
+	DROP COLUMN IF EXISTS perm_delete_posts;
+
+DROP INDEX IF EXISTS idx_channel_members_owner;
+ALTER TABLE channel_members DROP CONSTRAINT IF EXISTS chk_channel_members_role;
+ALTER TABLE channel_members ADD COLUMN IF NOT EXISTS is_admin boolean DEFAULT false;
+UPDATE channel_members SET is_admin = role IN ('owner', 'moderator');
+ALTER TABLE channel_members DROP COLUMN IF EXISTS role;
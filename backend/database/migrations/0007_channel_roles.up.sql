@@ -0,0 +1,20 @@
+-- Roles por canal en lugar de is_admin: el creador queda como owner y los
+-- administradores como moderadores. Cada canal guarda además el rol mínimo
+-- para publicar, comentar, invitar, fijar posts y borrar posts ajenos.
+
+ALTER TABLE channel_members ADD COLUMN IF NOT EXISTS role varchar(20) NOT NULL DEFAULT 'member';
+UPDATE channel_members SET role = 'moderator' WHERE is_admin;
+UPDATE channel_members m SET role = 'owner'
+	FROM channels c
+	WHERE c.channel_id = m.channel_id AND c.created_by = m.user_id;
+ALTER TABLE channel_members DROP COLUMN IF EXISTS is_admin;
+ALTER TABLE channel_members ADD CONSTRAINT chk_channel_members_role
+	CHECK (role IN ('owner', 'moderator', 'member', 'readonly'));
+CREATE UNIQUE INDEX idx_channel_members_owner ON channel_members (channel_id) WHERE role = 'owner';
+
+ALTER TABLE channels
+	ADD COLUMN IF NOT EXISTS perm_post varchar(20) NOT NULL DEFAULT 'member',
+	ADD COLUMN IF NOT EXISTS perm_comment varchar(20) NOT NULL DEFAULT 'member',
+	ADD COLUMN IF NOT EXISTS perm_invite varchar(20) NOT NULL DEFAULT 'moderator',
+	ADD COLUMN IF NOT EXISTS perm_pin varchar(20) NOT NULL DEFAULT 'moderator',
+	ADD COLUMN IF NOT EXISTS perm_delete_posts varchar(20) NOT NULL DEFAULT 'moderator';
@@ -0,0 +1,22 @@
+-- Enlaces de invitación a canales. Cualquiera que tenga el código puede
+-- entrar con el rol del enlace mientras no venza, no se revoque y no agote
+-- sus usos. Los enlaces con email son las invitaciones enviadas a personas
+-- sin cuenta: tienen un solo uso y solo los canjea esa dirección.
+
+CREATE TABLE IF NOT EXISTS channel_invite_links (
+	link_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL REFERENCES channels (channel_id) ON DELETE CASCADE,
+	code varchar(32) NOT NULL,
+	role varchar(20) NOT NULL DEFAULT 'member',
+	max_uses integer,
+	uses integer NOT NULL DEFAULT 0,
+	expires_at timestamptz,
+	email text,
+	created_by bigint REFERENCES users (user_id) ON DELETE SET NULL,
+	revoked_at timestamptz,
+	created_at timestamptz,
+	CONSTRAINT uni_channel_invite_links_code UNIQUE (code),
+	CONSTRAINT chk_channel_invite_links_role CHECK (role IN ('moderator', 'member', 'readonly')),
+	CONSTRAINT chk_channel_invite_links_uses CHECK (max_uses IS NULL OR uses <= max_uses)
+);
+CREATE INDEX idx_channel_invite_links_channel_id ON channel_invite_links (channel_id, created_at DESC);
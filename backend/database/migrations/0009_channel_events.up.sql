@@ -0,0 +1,15 @@
+-- Eventos de los canales que se envían en tiempo real. Se guardan para que
+-- un cliente que se reconecta reciba los que se perdió, y para que todas las
+-- réplicas los lean al recibir el NOTIFY. Los más viejos que la retención
+-- se borran periódicamente; channel_id no tiene clave foránea para que
+-- borrar un canal no bloquee ni dependa de esta tabla.
+
+CREATE TABLE IF NOT EXISTS channel_events (
+	event_id bigserial PRIMARY KEY,
+	channel_id bigint NOT NULL,
+	type varchar(64) NOT NULL,
+	data jsonb NOT NULL DEFAULT '{}',
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_channel_events_channel_id ON channel_events (channel_id, event_id);
+CREATE INDEX idx_channel_events_created_at ON channel_events (created_at);
@@ -0,0 +1,5 @@
+CREATE INDEX idx_channel_post_comments_post_id ON channel_post_comments (post_id);
+DROP INDEX IF EXISTS idx_channel_post_comments_post_created;
+
+ALTER TABLE channel_members ALTER COLUMN last_seen_at DROP NOT NULL;
+ALTER TABLE channel_members ALTER COLUMN last_seen_at DROP DEFAULT;
@@ -0,0 +1,11 @@
+-- last_seen_at pasa a ser la marca de lectura de cada miembro: lo publicado
+-- por otros después de esa fecha cuenta como no leído. Los miembros sin
+-- marca toman la fecha en que entraron, o ahora si tampoco la tienen, para
+-- no mostrar todo el historial como nuevo.
+
+UPDATE channel_members SET last_seen_at = COALESCE(joined_at, now()) WHERE last_seen_at IS NULL;
+ALTER TABLE channel_members ALTER COLUMN last_seen_at SET DEFAULT now();
+ALTER TABLE channel_members ALTER COLUMN last_seen_at SET NOT NULL;
+
+CREATE INDEX idx_channel_post_comments_post_created ON channel_post_comments (post_id, created_at);
+DROP INDEX IF EXISTS idx_channel_post_comments_post_id;
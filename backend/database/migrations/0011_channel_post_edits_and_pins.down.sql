@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS channel_post_edits;
+
+DROP INDEX IF EXISTS idx_channel_posts_pinned;
+ALTER TABLE channel_posts DROP COLUMN IF EXISTS is_announcement;
+ALTER TABLE channel_posts DROP COLUMN IF EXISTS pinned_by;
+ALTER TABLE channel_posts DROP COLUMN IF EXISTS pinned_at;
+ALTER TABLE channel_posts DROP COLUMN IF EXISTS edited_at;
@@ -0,0 +1,20 @@
+-- Edición de posts de canales con historial, posts fijados y anuncios. Cada
+-- edición guarda el contenido y los tags anteriores; pinned_at ordena los
+-- fijados, que se muestran primero.
+
+ALTER TABLE channel_posts ADD COLUMN IF NOT EXISTS edited_at timestamptz;
+ALTER TABLE channel_posts ADD COLUMN IF NOT EXISTS pinned_at timestamptz;
+ALTER TABLE channel_posts ADD COLUMN IF NOT EXISTS pinned_by bigint REFERENCES users (user_id) ON DELETE SET NULL;
+ALTER TABLE channel_posts ADD COLUMN IF NOT EXISTS is_announcement boolean NOT NULL DEFAULT false;
+
+CREATE INDEX idx_channel_posts_pinned ON channel_posts (channel_id, pinned_at DESC) WHERE pinned_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS channel_post_edits (
+	edit_id bigserial PRIMARY KEY,
+	post_id bigint NOT NULL REFERENCES channel_posts (post_id) ON DELETE CASCADE,
+	editor_id bigint REFERENCES users (user_id) ON DELETE SET NULL,
+	content text NOT NULL,
+	tags text[],
+	created_at timestamptz
+);
+CREATE INDEX idx_channel_post_edits_post_created ON channel_post_edits (post_id, created_at DESC);
@@ -0,0 +1,13 @@
+ALTER TABLE channel_posts ADD COLUMN IF NOT EXISTS tags text[];
+
+UPDATE channel_posts p
+SET tags = linked.names
+FROM (
+	SELECT pt.post_id, array_agg(t.name ORDER BY t.name) AS names
+	FROM channel_post_tags pt
+	JOIN tags t ON t.tag_id = pt.tag_id
+	GROUP BY pt.post_id
+) AS linked
+WHERE linked.post_id = p.post_id;
+
+DROP TABLE IF EXISTS channel_post_tags;
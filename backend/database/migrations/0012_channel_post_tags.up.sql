@@ -0,0 +1,31 @@
+-- Los posts de canales usan los mismos tags que los posts públicos, por
+-- medio de channel_post_tags. Los tags que estaban guardados como texto se
+-- asocian al tag con el mismo nombre, sin distinguir mayúsculas, y se crean
+-- los que no existían.
+
+CREATE TABLE IF NOT EXISTS channel_post_tags (
+	post_id bigint NOT NULL REFERENCES channel_posts (post_id) ON DELETE CASCADE,
+	tag_id bigint NOT NULL REFERENCES tags (tag_id) ON DELETE CASCADE,
+	PRIMARY KEY (post_id, tag_id)
+);
+CREATE INDEX idx_channel_post_tags_tag_id ON channel_post_tags (tag_id);
+
+INSERT INTO tags (name, created_at)
+SELECT DISTINCT ON (lower(name)) name, now()
+FROM (
+	SELECT btrim(tag) AS name, post_id
+	FROM channel_posts, unnest(tags) AS tag
+) AS legacy
+WHERE name <> ''
+	AND NOT EXISTS (SELECT 1 FROM tags t WHERE lower(t.name) = lower(legacy.name))
+ORDER BY lower(name), post_id;
+
+INSERT INTO channel_post_tags (post_id, tag_id)
+SELECT DISTINCT ON (p.post_id, lower(t.name)) p.post_id, t.tag_id
+FROM channel_posts p
+CROSS JOIN unnest(p.tags) AS tag
+JOIN tags t ON lower(t.name) = lower(btrim(tag))
+ORDER BY p.post_id, lower(t.name), t.tag_id
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE channel_posts DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,6 @@
+-- Marca cuándo se comprobó que el usuario controla su email: al entrar con un
+-- proveedor OIDC que lo verificó o al recuperar la contraseña con el enlace
+-- enviado por email. Solo las cuentas verificadas se vinculan solas a una
+-- identidad OIDC con el mismo email.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
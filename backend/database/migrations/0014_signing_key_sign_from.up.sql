@@ -0,0 +1,7 @@
+-- Una clave nueva se publica en el JWKS antes de firmar con ella, para que
+-- quienes tienen el JWKS en caché ya la conozcan cuando vean el primer token.
+-- Las claves existentes firman desde que se crearon.
+
+ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS sign_from timestamptz;
+UPDATE signing_keys SET sign_from = COALESCE(created_at, now()) WHERE sign_from IS NULL;
+ALTER TABLE signing_keys ALTER COLUMN sign_from SET NOT NULL;
@@ -0,0 +1,144 @@
+// Package health mantiene las verificaciones de las dependencias del servidor
+// que usa el endpoint de readiness.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+)
+
+// Estados de una dependencia y del servidor
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusError       = "error"
+	StatusUnavailable = "unavailable"
+)
+
+// checkTimeout limita cuánto puede tardar cada verificación
+const checkTimeout = 3 * time.Second
+
+// Check verifica una dependencia. Devuelve un error si no funciona y detalles
+// opcionales (por ejemplo estadísticas del pool) que se registran con él.
+type Check func(ctx context.Context) (map[string]interface{}, error)
+
+// Dependency es una dependencia registrada
+type Dependency struct {
+	Name string
+	// Critical indica si el servidor deja de estar listo cuando falla. Las no
+	// críticas solo marcan el estado como degradado.
+	Critical bool
+	// CacheFor evita consultar servicios externos (con límites de uso) en cada
+	// pedido de readiness; 0 verifica siempre
+	CacheFor time.Duration
+	Check    Check
+
+	mu        sync.Mutex
+	last      DependencyStatus
+	checkedAt time.Time
+}
+
+// DependencyStatus es el resultado de verificar una dependencia. El endpoint
+// es público, así que no incluye el error: se registra en el log.
+type DependencyStatus struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+}
+
+// Report es el estado de readiness completo
+type Report struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+var (
+	mu           sync.RWMutex
+	dependencies []*Dependency
+	shuttingDown atomic.Bool
+)
+
+// Register agrega una dependencia a la verificación de readiness
+func Register(dep *Dependency) {
+	mu.Lock()
+	defer mu.Unlock()
+	dependencies = append(dependencies, dep)
+}
+
+// SetShuttingDown marca el servidor como no listo para que el balanceador deje
+// de enviarle tráfico mientras termina los pedidos en curso
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// ShuttingDown indica si el servidor se está apagando
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Readiness verifica todas las dependencias en paralelo
+func Readiness(ctx context.Context) Report {
+	mu.RLock()
+	deps := append([]*Dependency(nil), dependencies...)
+	mu.RUnlock()
+
+	report := Report{Status: StatusOK, Dependencies: map[string]DependencyStatus{}}
+	results := make([]DependencyStatus, len(deps))
+
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep *Dependency) {
+			defer wg.Done()
+			results[i] = dep.status(ctx)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	for i, dep := range deps {
+		result := results[i]
+		report.Dependencies[dep.Name] = result
+		if result.Status == StatusOK {
+			continue
+		}
+		if dep.Critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	if ShuttingDown() {
+		report.Status = StatusUnavailable
+	}
+	return report
+}
+
+func (d *Dependency) status(ctx context.Context) DependencyStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.CacheFor > 0 && !d.checkedAt.IsZero() && time.Since(d.checkedAt) < d.CacheFor {
+		return d.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := d.Check(ctx)
+	result := DependencyStatus{Status: StatusOK, Critical: d.Critical}
+	if err != nil {
+		result.Status = StatusError
+		logging.FromContext(ctx).Error("Falló la verificación de una dependencia",
+			"dependency", d.Name, "critical", d.Critical, "duration_ms", time.Since(start).Milliseconds(),
+			"details", details, "error", err)
+	}
+
+	d.last = result
+	d.checkedAt = start
+	return result
+}
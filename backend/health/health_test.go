@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadinessHidesDependencyErrors(t *testing.T) {
+	previous := dependencies
+	t.Cleanup(func() { dependencies = previous })
+	dependencies = nil
+
+	Register(&Dependency{Name: "database", Critical: true, Check: func(ctx context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"idle": 0}, errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	}})
+	Register(&Dependency{Name: "email", Check: func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, nil
+	}})
+
+	report := Readiness(context.Background())
+	if report.Status != StatusUnavailable || report.Dependencies["database"].Status != StatusError || report.Dependencies["email"].Status != StatusOK {
+		t.Fatalf("estado inesperado: %+v", report)
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "10.0.0.5") || strings.Contains(string(body), "idle") {
+		t.Fatalf("la respuesta expone el error de la dependencia: %s", body)
+	}
+}
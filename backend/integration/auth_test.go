@@ -0,0 +1,231 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterLoginAndMe(t *testing.T) {
+	h := newHarness(t)
+	anon := h.anonymous()
+
+	anon.post(t, "/auth/register", gin.H{
+		"username":     "ana.perez",
+		"email":        "Ana@Repositorio.test",
+		"password":     testPassword,
+		"account_name": "Ana Pérez",
+	}).expect(t, http.StatusOK)
+
+	res := anon.post(t, "/auth/login", gin.H{"email": "ana@repositorio.test", "password": testPassword})
+	res.expect(t, http.StatusOK)
+	var login struct {
+		Token string `json:"token"`
+	}
+	res.decode(t, &login)
+	if login.Token == "" {
+		t.Fatal("el login no devolvió token")
+	}
+
+	me := (&client{h: h, token: login.Token}).get(t, "/users/me")
+	me.expect(t, http.StatusOK)
+	var body struct {
+		User struct {
+			Username string
+		} `json:"user"`
+	}
+	me.decode(t, &body)
+	if body.User.Username != "ana.perez" {
+		t.Fatalf("usuario %q, se esperaba ana.perez", body.User.Username)
+	}
+}
+
+func TestRegisterRejectsTakenUsernameAndEmail(t *testing.T) {
+	h := newHarness(t)
+	h.createUser(t, "bruno")
+
+	register := func(username, email string) map[string]string {
+		res := h.anonymous().post(t, "/auth/register", gin.H{
+			"username":     username,
+			"email":        email,
+			"password":     testPassword,
+			"account_name": "Bruno",
+		})
+		res.expectError(t, http.StatusConflict, "VALIDATION_FAILED")
+
+		var body struct {
+			Fields []struct {
+				Field string `json:"field"`
+				Code  string `json:"code"`
+			} `json:"fields"`
+		}
+		res.decode(t, &body)
+		codes := map[string]string{}
+		for _, f := range body.Fields {
+			codes[f.Field] = f.Code
+		}
+		return codes
+	}
+
+	if codes := register("Bruno", "otro@repositorio.test"); codes["username"] != "username_taken" {
+		t.Fatalf("errores de campo inesperados: %v", codes)
+	}
+	// El sufijo +etiqueta no permite registrar otra cuenta con la misma casilla
+	if codes := register("bruno2", "bruno+otro@repositorio.test"); codes["email"] != "email_taken" {
+		t.Fatalf("errores de campo inesperados: %v", codes)
+	}
+}
+
+func TestLoginWithWrongPassword(t *testing.T) {
+	h := newHarness(t)
+	h.createUser(t, "carla")
+
+	h.anonymous().
+		post(t, "/auth/login", gin.H{"username": "carla", "password": "otra-clave-1"}).
+		expectError(t, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	h.anonymous().
+		post(t, "/auth/login", gin.H{"username": "nadie", "password": testPassword}).
+		expectError(t, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	h := newHarness(t)
+
+	h.anonymous().get(t, "/users/me").expectError(t, http.StatusUnauthorized, "UNAUTHENTICATED")
+	(&client{h: h, token: "no-es-un-jwt"}).get(t, "/users/me").expectError(t, http.StatusUnauthorized, "INVALID_TOKEN")
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	h := newHarness(t)
+	user := h.createUser(t, "diego")
+
+	h.anonymous().post(t, "/auth/forgot-password", gin.H{"email": user.Email}).expect(t, http.StatusOK)
+
+	sent := h.mail.to(user.Email)
+	if len(sent) != 1 {
+		t.Fatalf("se enviaron %d emails, se esperaba 1", len(sent))
+	}
+	match := regexp.MustCompile(`token=([0-9a-f]+)`).FindStringSubmatch(sent[0].Html)
+	if match == nil {
+		t.Fatalf("el email no tiene el enlace de recuperación: %s", sent[0].Html)
+	}
+
+	const newPassword = "Resumenes#2025"
+	h.anonymous().post(t, "/auth/reset-password", gin.H{"token": match[1], "password": newPassword}).expect(t, http.StatusOK)
+
+	// El token es de un solo uso
+	h.anonymous().
+		post(t, "/auth/reset-password", gin.H{"token": match[1], "password": newPassword}).
+		expectError(t, http.StatusBadRequest, "INVALID_RESET_TOKEN")
+
+	h.anonymous().post(t, "/auth/login", gin.H{"username": "diego", "password": newPassword}).expect(t, http.StatusOK)
+	h.anonymous().
+		post(t, "/auth/login", gin.H{"username": "diego", "password": testPassword}).
+		expectError(t, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+}
+
+func TestConcurrentLoginsCannotSkipTheThrottle(t *testing.T) {
+	h := newHarness(t)
+	user := h.createUser(t, "dolores")
+
+	// Todos los pedidos llegan a la vez: sin reservar el intento antes de
+	// controlar la contraseña pasarían todos
+	const attempts = 20
+	codes := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"dolores","password":"otra-clave-1"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			h.router.ServeHTTP(rec, req)
+			codes <- rec.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	// Pasan las fallas toleradas y el intento siguiente, que recién ahí
+	// empieza a exigir demora
+	allowed := security.LoginAccountPolicy.FreeAttempts + 1
+	if counts[http.StatusUnauthorized] != allowed || counts[http.StatusTooManyRequests] != attempts-allowed {
+		t.Fatalf("respuestas inesperadas: %v", counts)
+	}
+
+	var throttle models.AuthThrottle
+	if err := h.db.First(&throttle, "key = ?", fmt.Sprintf("login:user:%d", user.UserID)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if throttle.Failures != allowed {
+		t.Fatalf("se contaron %d fallas, se esperaban %d", throttle.Failures, allowed)
+	}
+}
+
+func TestSuccessfulLoginReturnsTheReservedAttempt(t *testing.T) {
+	h := newHarness(t)
+	user := h.createUser(t, "esteban")
+
+	h.anonymous().post(t, "/auth/login", gin.H{"username": "esteban", "password": "otra-clave-1"}).
+		expectError(t, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	h.anonymous().post(t, "/auth/login", gin.H{"username": "esteban", "password": testPassword}).
+		expect(t, http.StatusOK)
+
+	// El intento correcto no cuenta para la IP y la cuenta vuelve a cero
+	var ip models.AuthThrottle
+	if err := h.db.First(&ip, "key LIKE ?", "login:ip:%").Error; err != nil {
+		t.Fatal(err)
+	}
+	if ip.Failures != 1 {
+		t.Fatalf("la IP tiene %d fallas, se esperaba 1", ip.Failures)
+	}
+	var account int64
+	h.db.Model(&models.AuthThrottle{}).Where("key = ?", fmt.Sprintf("login:user:%d", user.UserID)).Count(&account)
+	if account != 0 {
+		t.Fatal("el contador de la cuenta no se reinició")
+	}
+}
+
+func TestConcurrentResetTokensCannotSkipTheThrottle(t *testing.T) {
+	h := newHarness(t)
+
+	const attempts = 20
+	codes := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"token":"token-falso-%d","password":"Nueva#Clave2024"}`, i)
+			req := httptest.NewRequest(http.MethodPost, "/auth/reset-password", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			h.router.ServeHTTP(rec, req)
+			codes <- rec.Code
+		}(i)
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	allowed := security.ResetTokenIPPolicy.FreeAttempts + 1
+	if counts[http.StatusBadRequest] != allowed || counts[http.StatusTooManyRequests] != attempts-allowed {
+		t.Fatalf("respuestas inesperadas: %v", counts)
+	}
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+)
+
+func TestChannelPostFiles(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "xavier")
+	member := h.createUser(t, "yamila")
+	outsider := h.createUser(t, "zoe")
+	channelID := h.createChannel(t, owner.UserID, "Bases de Datos")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	postsPath := fmt.Sprintf("/channels/%d/posts", channelID)
+	tag := h.createTag(t, "der")
+
+	tooMany := map[string]string{}
+	for i := 0; i <= services.MaxChannelPostFiles; i++ {
+		tooMany[fmt.Sprintf("parte%d.pdf", i)] = "%PDF"
+	}
+	h.asUser(t, owner.UserID).multipart(t, postsPath, map[string]string{"content": "Todo junto"}, tooMany).
+		expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, owner.UserID).multipart(t, postsPath, map[string]string{
+		"content": "Modelo entidad-relación",
+		"tag_ids": fmt.Sprintf("[%d]", tag.TagID),
+	}, map[string]string{"der.pdf": "%PDF-1.7"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+	if len(created.Post.Files) != 1 || created.Post.Files[0].FileName != "der.pdf" || len(created.Post.Tags) != 1 {
+		t.Fatalf("post inesperado: %+v", created.Post)
+	}
+	fileID := created.Post.Files[0].FileID
+
+	var stored models.ChannelPostFile
+	if err := h.db.First(&stored, fileID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := h.storage.file(stored.FileURL); !ok || string(data) != "%PDF-1.7" || !strings.Contains(stored.FileURL, "/private/") {
+		t.Fatalf("el archivo no se guardó como privado: %s", stored.FileURL)
+	}
+
+	// Los posts traen el enlace firmado para quien los pide, nunca la URL
+	// del almacenamiento
+	res := h.asUser(t, member.UserID).get(t, postsPath).expect(t, http.StatusOK)
+	if strings.Contains(res.Body.String(), stored.FileURL) {
+		t.Fatalf("la respuesta expone la URL del almacenamiento: %s", res.Body.String())
+	}
+	var list channelPostList
+	res.decode(t, &list)
+	link := list.Posts[0].Files[0].DownloadURL
+	if !strings.HasPrefix(link, fmt.Sprintf("/channels/files/%d/download?token=", fileID)) {
+		t.Fatalf("enlace inesperado: %q", link)
+	}
+
+	download := h.anonymous().get(t, link).expect(t, http.StatusFound)
+	if location := download.Header().Get("Location"); !strings.HasPrefix(location, stored.FileURL+"?expires_at=") {
+		t.Fatalf("redirige a %q", location)
+	}
+
+	filePath := fmt.Sprintf("/channels/files/%d", fileID)
+	h.asUser(t, outsider.UserID).get(t, filePath).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	var fresh struct {
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	h.asUser(t, member.UserID).get(t, filePath).expect(t, http.StatusOK).decode(t, &fresh)
+	if !strings.HasPrefix(fresh.URL, "/channels/files/") || time.Until(fresh.ExpiresAt) > services.ChannelFileLinkTTL {
+		t.Fatalf("enlace inesperado: %+v", fresh)
+	}
+
+	downloadPath := fmt.Sprintf("/channels/files/%d/download?token=", fileID)
+	fileToken := strings.TrimPrefix(fresh.URL, downloadPath)
+	sessionToken, err := utils.GenerateJWT(member.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := utils.GenerateFileToken(fileID, member.UserID, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsiderToken, err := utils.GenerateFileToken(fileID, outsider.UserID, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.anonymous().get(t, downloadPath).expectError(t, http.StatusForbidden, "FILE_LINK_INVALID")
+	h.anonymous().get(t, downloadPath+sessionToken).expectError(t, http.StatusForbidden, "FILE_LINK_INVALID")
+	h.anonymous().get(t, downloadPath+expired).expectError(t, http.StatusGone, "FILE_LINK_EXPIRED")
+	h.anonymous().get(t, downloadPath+outsiderToken).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	h.anonymous().get(t, fmt.Sprintf("/channels/files/%d/download?token=%s", fileID+1, fileToken)).
+		expectError(t, http.StatusForbidden, "FILE_LINK_INVALID")
+
+	// Un token de descarga no sirve como sesión
+	h.anonymous().get(t, "/channels/events?access_token="+fileToken).expectError(t, http.StatusUnauthorized, "UNAUTHENTICATED")
+
+	// Quien deja el canal pierde el acceso aunque tenga un enlace vigente
+	h.asUser(t, member.UserID).post(t, fmt.Sprintf("/channels/%d/leave", channelID), nil).expect(t, http.StatusOK)
+	h.anonymous().get(t, fresh.URL).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+
+	h.asUser(t, owner.UserID).delete(t, fmt.Sprintf("/channels/posts/%d", created.Post.PostID)).expect(t, http.StatusOK)
+	if _, ok := h.storage.file(stored.FileURL); ok {
+		t.Fatal("el archivo no se borró del almacenamiento")
+	}
+	h.asUser(t, owner.UserID).get(t, filePath).expectError(t, http.StatusNotFound, "FILE_NOT_FOUND")
+}
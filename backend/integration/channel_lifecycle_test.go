@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/gin-gonic/gin"
+)
+
+// auditActions devuelve las acciones del registro del canal, la más reciente primero
+func auditActions(t *testing.T, h *harness, channelID, adminID uint) []string {
+	t.Helper()
+	var body struct {
+		Entries []models.ChannelAuditLog `json:"entries"`
+	}
+	h.asUser(t, adminID).get(t, fmt.Sprintf("/channels/%d/audit-log", channelID)).expect(t, http.StatusOK).decode(t, &body)
+	actions := make([]string, len(body.Entries))
+	for i, entry := range body.Entries {
+		actions[i] = entry.Action
+	}
+	return actions
+}
+
+func TestUpdateChannel(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "wanda")
+	member := h.createUser(t, "xavier")
+	channelID := h.createChannel(t, owner.UserID, "Física")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	path := fmt.Sprintf("/channels/%d", channelID)
+
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"name": "Otro"}).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).patch(t, path, gin.H{"name": "  "}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	h.asUser(t, owner.UserID).patch(t, path, gin.H{"career_id": h.careerID + 100}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	var body struct {
+		Channel models.Channel `json:"channel"`
+	}
+	h.asUser(t, owner.UserID).patch(t, path, gin.H{"name": "Física I", "description": "Primer año", "is_private": true}).
+		expect(t, http.StatusOK).
+		decode(t, &body)
+	if body.Channel.Name != "Física I" || body.Channel.Description != "Primer año" || !body.Channel.IsPrivate {
+		t.Fatalf("canal inesperado: %+v", body.Channel)
+	}
+
+	h.asUser(t, owner.UserID).get(t, path).expect(t, http.StatusOK).decode(t, &body)
+	if body.Channel.Name != "Física I" || !body.Channel.IsPrivate {
+		t.Fatalf("los cambios no se guardaron: %+v", body.Channel)
+	}
+	if actions := auditActions(t, h, channelID, owner.UserID); len(actions) == 0 || actions[0] != services.ChannelAuditUpdated {
+		t.Fatalf("registro inesperado: %v", actions)
+	}
+}
+
+func TestArchivedChannelIsReadOnly(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "yago")
+	member := h.createUser(t, "zaira")
+	outsider := h.createUser(t, "abril")
+	channelID := h.createChannel(t, owner.UserID, "Historia")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, member.UserID).post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Antes de archivar"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+
+	archive := fmt.Sprintf("/channels/%d/archive", channelID)
+	h.asUser(t, member.UserID).post(t, archive, nil).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).post(t, archive, nil).expect(t, http.StatusOK)
+
+	as := h.asUser(t, member.UserID)
+	as.get(t, fmt.Sprintf("/channels/%d/posts", channelID)).expect(t, http.StatusOK)
+	as.post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Nuevo"}).expectError(t, http.StatusConflict, "CHANNEL_ARCHIVED")
+	as.post(t, fmt.Sprintf("/channels/posts/%d/comments", created.Post.PostID), gin.H{"content": "Hola"}).
+		expectError(t, http.StatusConflict, "CHANNEL_ARCHIVED")
+	as.post(t, fmt.Sprintf("/channels/posts/%d/like", created.Post.PostID), nil).expectError(t, http.StatusConflict, "CHANNEL_ARCHIVED")
+	h.asUser(t, owner.UserID).patch(t, fmt.Sprintf("/channels/%d", channelID), gin.H{"name": "Otro"}).
+		expectError(t, http.StatusConflict, "CHANNEL_ARCHIVED")
+	h.asUser(t, outsider.UserID).post(t, fmt.Sprintf("/channels/%d/join", channelID), nil).expectError(t, http.StatusConflict, "CHANNEL_ARCHIVED")
+	// Ni el autor ni los administradores del canal borran posts; un moderador
+	// de rbac sí puede sacar contenido
+	postPath := fmt.Sprintf("/channels/posts/%d", created.Post.PostID)
+	as.delete(t, postPath).expectError(t, http.StatusConflict, "CHANNEL_ARCHIVED")
+	h.asUser(t, owner.UserID).delete(t, postPath).expectError(t, http.StatusConflict, "CHANNEL_ARCHIVED")
+	if _, err := rbac.Grant(context.Background(), outsider.UserID, rbac.RoleModerator, &h.universityID, nil); err != nil {
+		t.Fatal(err)
+	}
+	h.asUser(t, outsider.UserID).delete(t, postPath).expect(t, http.StatusOK)
+
+	var discover struct {
+		Channels []struct{} `json:"channels"`
+	}
+	h.asUser(t, outsider.UserID).get(t, "/channels/discover").expect(t, http.StatusOK).decode(t, &discover)
+	if len(discover.Channels) != 0 {
+		t.Fatalf("el canal archivado aparece en el listado público")
+	}
+
+	h.asUser(t, owner.UserID).delete(t, archive).expect(t, http.StatusOK)
+	as.post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Reactivado"}).expect(t, http.StatusCreated)
+
+	actions := auditActions(t, h, channelID, owner.UserID)
+	if len(actions) < 2 || actions[0] != services.ChannelAuditUnarchived || actions[1] != services.ChannelAuditArchived {
+		t.Fatalf("registro inesperado: %v", actions)
+	}
+}
+
+func TestDeleteChannelCascades(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "bruno")
+	admin := h.createUser(t, "celia")
+	channelID := h.createChannel(t, owner.UserID, "Electrónica")
+	h.addMember(t, channelID, owner.UserID, admin.UserID)
+	h.asUser(t, owner.UserID).put(t, fmt.Sprintf("/channels/%d/members/%d/role", channelID, admin.UserID), gin.H{"role": "moderator"}).
+		expect(t, http.StatusOK)
+
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, admin.UserID).post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Circuitos"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/posts/%d/comments", created.Post.PostID), gin.H{"content": "Gracias"}).
+		expect(t, http.StatusCreated)
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/posts/%d/like", created.Post.PostID), nil).expect(t, http.StatusCreated)
+
+	url, err := h.storage.Upload(context.Background(), "channel_files", services.File{Name: "esquema.pdf", Content: strings.NewReader("%PDF")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := models.ChannelPostFile{PostID: created.Post.PostID, FileURL: url, FileType: "application/pdf", FileName: "esquema.pdf"}
+	if err := h.db.Create(&file).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/channels/%d", channelID)
+	h.asUser(t, admin.UserID).delete(t, path).expectError(t, http.StatusForbidden, "CHANNEL_OWNER_REQUIRED")
+	h.asUser(t, owner.UserID).delete(t, path).expect(t, http.StatusOK)
+	h.asUser(t, owner.UserID).get(t, path).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+
+	for _, table := range []string{"channels", "channel_members", "channel_invitations", "channel_posts", "channel_post_comments", "channel_post_likes", "channel_post_files"} {
+		var count int64
+		h.db.Table(table).Count(&count)
+		if count != 0 {
+			t.Fatalf("quedaron %d filas en %s", count, table)
+		}
+	}
+	if _, ok := h.storage.file(url); ok {
+		t.Fatal("el archivo no se borró del almacenamiento")
+	}
+
+	// El registro del canal sobrevive al borrado
+	var entry models.ChannelAuditLog
+	if err := h.db.Where("channel_id = ?", channelID).Order("log_id DESC").First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if entry.Action != services.ChannelAuditDeleted || entry.ActorID == nil || *entry.ActorID != owner.UserID {
+		t.Fatalf("entrada inesperada: %+v", entry)
+	}
+}
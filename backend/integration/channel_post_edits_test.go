@@ -0,0 +1,157 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestEditChannelPost(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "abril")
+	member := h.createUser(t, "bruno")
+	channelID := h.createChannel(t, owner.UserID, "Física I")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, member.UserID).post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Resumen de cinemática"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+	path := fmt.Sprintf("/channels/posts/%d", created.Post.PostID)
+
+	// Solo el autor edita, aunque otro pueda moderar el canal
+	h.asUser(t, owner.UserID).patch(t, path, gin.H{"content": "Otro texto"}).expectError(t, http.StatusForbidden, "FORBIDDEN")
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"content": "  "}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	var updated struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"content": "Resumen de cinemática y dinámica"}).
+		expect(t, http.StatusOK).
+		decode(t, &updated)
+	if updated.Post.Content != "Resumen de cinemática y dinámica" || updated.Post.EditedAt == nil {
+		t.Fatalf("post inesperado: %+v", updated.Post)
+	}
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"content": "Resumen completo"}).expect(t, http.StatusOK)
+
+	var history struct {
+		Edits []models.ChannelPostEdit `json:"edits"`
+	}
+	h.asUser(t, owner.UserID).get(t, path+"/edits").expect(t, http.StatusOK).decode(t, &history)
+	if len(history.Edits) != 2 || history.Edits[0].Content != "Resumen de cinemática y dinámica" || history.Edits[1].Content != "Resumen de cinemática" {
+		t.Fatalf("historial inesperado: %+v", history.Edits)
+	}
+	if history.Edits[0].Editor == nil || history.Edits[0].Editor.UserID != member.UserID {
+		t.Fatalf("editor inesperado: %+v", history.Edits[0])
+	}
+
+	// Sin cambios no se guarda otra versión
+	var unchanged struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, member.UserID).patch(t, path, gin.H{}).expect(t, http.StatusOK)
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"content": "Resumen completo", "tag_ids": []uint{}}).
+		expect(t, http.StatusOK).
+		decode(t, &unchanged)
+	if unchanged.Post.Content != "Resumen completo" {
+		t.Fatalf("post inesperado: %+v", unchanged.Post)
+	}
+
+	// Dos ediciones simultáneas guardan cada una la versión que reemplazan
+	var wg sync.WaitGroup
+	for _, content := range []string{"Versión A", "Versión B"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.asUser(t, member.UserID).patch(t, path, gin.H{"content": content})
+		}()
+	}
+	wg.Wait()
+	h.asUser(t, owner.UserID).get(t, path+"/edits").expect(t, http.StatusOK).decode(t, &history)
+	if len(history.Edits) != 4 || history.Edits[1].Content != "Resumen completo" || history.Edits[0].Content == history.Edits[1].Content {
+		t.Fatalf("historial inesperado: %+v", history.Edits)
+	}
+
+	// Quien pasa a solo lectura ya no puede editar
+	h.asUser(t, owner.UserID).put(t, fmt.Sprintf("/channels/%d/members/%d/role", channelID, member.UserID), gin.H{"role": models.ChannelRoleReadOnly}).
+		expect(t, http.StatusOK)
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"content": "Sin permiso"}).expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+}
+
+func TestPinChannelPostsAndAnnouncements(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "camila")
+	member := h.createUser(t, "dario")
+	other := h.createUser(t, "elena")
+	channelID := h.createChannel(t, owner.UserID, "Química")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	h.addMember(t, channelID, owner.UserID, other.UserID)
+	postsPath := fmt.Sprintf("/channels/%d/posts", channelID)
+
+	publish := func(userID uint, content string) uint {
+		var created struct {
+			Post models.ChannelPost `json:"post"`
+		}
+		h.asUser(t, userID).post(t, postsPath, gin.H{"content": content}).expect(t, http.StatusCreated).decode(t, &created)
+		return created.Post.PostID
+	}
+	ids := make([]uint, services.MaxPinnedChannelPosts+1)
+	for i := range ids {
+		ids[i] = publish(member.UserID, fmt.Sprintf("Ejercicio %d", i+1))
+	}
+	pinPath := func(postID uint) string { return fmt.Sprintf("/channels/posts/%d/pin", postID) }
+
+	h.asUser(t, member.UserID).post(t, pinPath(ids[0]), nil).expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	for _, id := range ids[:services.MaxPinnedChannelPosts] {
+		h.asUser(t, owner.UserID).post(t, pinPath(id), nil).expect(t, http.StatusOK)
+	}
+	h.asUser(t, owner.UserID).post(t, pinPath(ids[services.MaxPinnedChannelPosts]), nil).
+		expectError(t, http.StatusConflict, "CHANNEL_PIN_LIMIT_REACHED")
+
+	// Al desfijar uno queda lugar; los fijados van primero, el último fijado
+	// antes
+	h.asUser(t, owner.UserID).delete(t, pinPath(ids[0])).expect(t, http.StatusOK)
+	last := ids[services.MaxPinnedChannelPosts]
+	h.asUser(t, owner.UserID).post(t, pinPath(last), nil).expect(t, http.StatusOK)
+	var list channelPostList
+	h.asUser(t, other.UserID).get(t, postsPath).expect(t, http.StatusOK).decode(t, &list)
+	if list.Posts[0].PostID != last || list.Posts[0].PinnedAt == nil || list.Posts[len(list.Posts)-1].PostID != ids[0] {
+		t.Fatalf("orden inesperado: primero %d, último %d", list.Posts[0].PostID, list.Posts[len(list.Posts)-1].PostID)
+	}
+	// El límite de no leídos sigue siendo el post más viejo aunque haya fijados
+	if list.FirstUnreadPostID == nil || *list.FirstUnreadPostID != ids[0] || list.UnreadPosts != len(ids) {
+		t.Fatalf("no leídos inesperados: %+v", list)
+	}
+
+	// Publicar un anuncio requiere el permiso pin y avisa a los demás miembros
+	h.asUser(t, member.UserID).post(t, postsPath, gin.H{"content": "Aviso", "announcement": true}).
+		expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	h.mail.reset()
+	var announced struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, owner.UserID).post(t, postsPath, gin.H{"content": "El parcial se pasa al jueves", "announcement": true}).
+		expect(t, http.StatusCreated).
+		decode(t, &announced)
+	if !announced.Post.IsAnnouncement {
+		t.Fatalf("el post no quedó como anuncio: %+v", announced.Post)
+	}
+	for _, user := range []models.User{member, other} {
+		sent := h.mail.waitFor(t, user.Email, 1)
+		if len(sent) != 1 || !strings.Contains(sent[0].Html, "El parcial se pasa al jueves") ||
+			!strings.Contains(sent[0].Html, fmt.Sprintf("/channels/%d", channelID)) {
+			t.Fatalf("aviso inesperado a %s: %+v", user.Username, sent)
+		}
+	}
+	if sent := h.mail.to(owner.Email); len(sent) != 0 {
+		t.Fatalf("se avisó al autor: %+v", sent)
+	}
+}
@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+func TestChannelPostTagsAndFilters(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "fabian")
+	member := h.createUser(t, "gisela")
+	channelID := h.createChannel(t, owner.UserID, "Análisis II")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	postsPath := fmt.Sprintf("/channels/%d/posts", channelID)
+	parcial := h.createTag(t, "parcial")
+	integrales := h.createTag(t, "integrales")
+
+	// Los tags son los mismos que los de los posts públicos y tienen que existir
+	h.asUser(t, member.UserID).post(t, postsPath, gin.H{"content": "Dudas", "tag_ids": []uint{parcial.TagID, 999999}}).
+		expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	publish := func(content string, tagIDs ...uint) models.ChannelPost {
+		var created struct {
+			Post models.ChannelPost `json:"post"`
+		}
+		h.asUser(t, member.UserID).post(t, postsPath, gin.H{"content": content, "tag_ids": tagIDs}).
+			expect(t, http.StatusCreated).
+			decode(t, &created)
+		return created.Post
+	}
+	first := publish("Resolución del primer parcial", parcial.TagID)
+	second := publish("Guía de ejercicios", integrales.TagID)
+	third := publish("Consultas del jueves")
+	if len(first.Tags) != 1 || first.Tags[0].TagID != parcial.TagID {
+		t.Fatalf("tags inesperados: %+v", first.Tags)
+	}
+
+	ids := func(query string) []uint {
+		var list channelPostList
+		h.asUser(t, owner.UserID).get(t, postsPath+query).expect(t, http.StatusOK).decode(t, &list)
+		found := make([]uint, len(list.Posts))
+		for i, post := range list.Posts {
+			found[i] = post.PostID
+		}
+		return found
+	}
+	expectIDs := func(query string, want ...uint) {
+		t.Helper()
+		if got := ids(query); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s devolvió %v, se esperaba %v", query, got, want)
+		}
+	}
+	expectIDs("", third.PostID, second.PostID, first.PostID)
+	expectIDs(fmt.Sprintf("?tag_id=%d", parcial.TagID), first.PostID)
+	expectIDs(fmt.Sprintf("?tag_id=%d,%d", parcial.TagID, integrales.TagID), second.PostID, first.PostID)
+	expectIDs("?q=JUEVES", third.PostID)
+	// La búsqueda también mira los nombres de los tags
+	expectIDs("?q=integral", second.PostID)
+	expectIDs(fmt.Sprintf("?q=guía&tag_id=%d", parcial.TagID))
+	// Los comodines de LIKE se buscan como texto
+	expectIDs("?q=%25")
+	expectIDs("?q=_")
+	h.asUser(t, owner.UserID).get(t, postsPath+"?tag_id=uno").expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	// Editar los tags los reemplaza y el historial guarda los nombres
+	// anteriores
+	path := fmt.Sprintf("/channels/posts/%d", third.PostID)
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"tag_ids": []uint{0}}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	var updated struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"tag_ids": []uint{parcial.TagID}}).
+		expect(t, http.StatusOK).
+		decode(t, &updated)
+	if len(updated.Post.Tags) != 1 || updated.Post.Tags[0].Name != "parcial" {
+		t.Fatalf("tags inesperados: %+v", updated.Post.Tags)
+	}
+	h.asUser(t, member.UserID).patch(t, path, gin.H{"tag_ids": []uint{}}).expect(t, http.StatusOK)
+
+	var history struct {
+		Edits []models.ChannelPostEdit `json:"edits"`
+	}
+	h.asUser(t, owner.UserID).get(t, path+"/edits").expect(t, http.StatusOK).decode(t, &history)
+	if len(history.Edits) != 2 || len(history.Edits[0].Tags) != 1 || history.Edits[0].Tags[0] != "parcial" || len(history.Edits[1].Tags) != 0 {
+		t.Fatalf("historial inesperado: %+v", history.Edits)
+	}
+	expectIDs(fmt.Sprintf("?tag_id=%d", parcial.TagID), first.PostID)
+}
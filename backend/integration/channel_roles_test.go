@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+)
+
+func TestReadOnlyMember(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "dario")
+	member := h.createUser(t, "emma")
+	reader := h.createUser(t, "fabio")
+	channelID := h.createChannel(t, owner.UserID, "Probabilidad")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	h.addMember(t, channelID, owner.UserID, reader.UserID)
+	h.asUser(t, owner.UserID).put(t, fmt.Sprintf("/channels/%d/members/%d/role", channelID, reader.UserID), gin.H{"role": "readonly"}).
+		expect(t, http.StatusOK)
+
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, member.UserID).post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Ejercicios"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+
+	as := h.asUser(t, reader.UserID)
+	as.get(t, fmt.Sprintf("/channels/%d/posts", channelID)).expect(t, http.StatusOK)
+	as.post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Hola"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	as.post(t, fmt.Sprintf("/channels/posts/%d/comments", created.Post.PostID), gin.H{"content": "Gracias"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	as.post(t, fmt.Sprintf("/channels/posts/%d/like", created.Post.PostID), nil).
+		expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+
+	var summary struct {
+		Role        string          `json:"role"`
+		Permissions map[string]bool `json:"permissions"`
+	}
+	as.get(t, fmt.Sprintf("/channels/%d/permissions", channelID)).expect(t, http.StatusOK).decode(t, &summary)
+	if summary.Role != models.ChannelRoleReadOnly || !summary.Permissions[services.ChannelPermView] || summary.Permissions[services.ChannelPermPost] {
+		t.Fatalf("permisos inesperados: %+v", summary)
+	}
+}
+
+func TestCustomChannelPermissions(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "gala")
+	member := h.createUser(t, "hugo")
+	guest := h.createUser(t, "ines")
+	channelID := h.createChannel(t, owner.UserID, "Estadística")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	path := fmt.Sprintf("/channels/%d/permissions", channelID)
+
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Programa"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+	postPath := fmt.Sprintf("/channels/posts/%d", created.Post.PostID)
+	h.asUser(t, member.UserID).delete(t, postPath).expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+
+	h.asUser(t, member.UserID).put(t, path, gin.H{"invite": "member"}).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).put(t, path, gin.H{"pin": "jefe"}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	var body struct {
+		Settings models.ChannelPermissions `json:"settings"`
+	}
+	h.asUser(t, owner.UserID).put(t, path, gin.H{"invite": "member", "delete_posts": "member", "post": "moderator"}).
+		expect(t, http.StatusOK).
+		decode(t, &body)
+	if body.Settings.Invite != "member" || body.Settings.DeletePosts != "member" || body.Settings.Post != "moderator" || body.Settings.Comment != "member" {
+		t.Fatalf("configuración inesperada: %+v", body.Settings)
+	}
+
+	as := h.asUser(t, member.UserID)
+	as.post(t, fmt.Sprintf("/channels/%d/invite", channelID), gin.H{"invited_user_id": guest.UserID}).expect(t, http.StatusCreated)
+	as.post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Hola"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	as.delete(t, postPath).expect(t, http.StatusOK)
+
+	if actions := auditActions(t, h, channelID, owner.UserID); len(actions) == 0 || actions[0] != services.ChannelAuditPermissionsChanged {
+		t.Fatalf("registro inesperado: %v", actions)
+	}
+}
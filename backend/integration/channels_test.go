@@ -0,0 +1,169 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateChannelMakesCreatorAdmin(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "nico")
+	channelID := h.createChannel(t, owner.UserID, "Análisis I")
+
+	var body struct {
+		Channel models.Channel `json:"channel"`
+	}
+	h.asUser(t, owner.UserID).get(t, fmt.Sprintf("/channels/%d", channelID)).expect(t, http.StatusOK).decode(t, &body)
+	if body.Channel.Name != "Análisis I" || len(body.Channel.Members) != 1 {
+		t.Fatalf("canal inesperado: %+v", body.Channel)
+	}
+	if member := body.Channel.Members[0]; member.UserID != owner.UserID || member.Role != models.ChannelRoleOwner {
+		t.Fatalf("el creador no quedó como administrador: %+v", member)
+	}
+
+	var list struct {
+		Channels []models.Channel `json:"channels"`
+	}
+	h.asUser(t, owner.UserID).get(t, "/channels").expect(t, http.StatusOK).decode(t, &list)
+	if len(list.Channels) != 1 || list.Channels[0].ChannelID != channelID {
+		t.Fatalf("canales inesperados: %+v", list.Channels)
+	}
+}
+
+func TestNonMembersCannotSeeChannel(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "olga")
+	outsider := h.createUser(t, "pablo")
+	channelID := h.createChannel(t, owner.UserID, "Privado")
+	as := h.asUser(t, outsider.UserID)
+
+	as.get(t, fmt.Sprintf("/channels/%d", channelID)).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	as.get(t, fmt.Sprintf("/channels/%d/posts", channelID)).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	as.post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Hola"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+
+	var list struct {
+		Channels []models.Channel `json:"channels"`
+	}
+	as.get(t, "/channels").expect(t, http.StatusOK).decode(t, &list)
+	if len(list.Channels) != 0 {
+		t.Fatalf("un no miembro ve %d canales", len(list.Channels))
+	}
+}
+
+func TestChannelPostsCommentsAndLikes(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "quique")
+	member := h.createUser(t, "rocio")
+	channelID := h.createChannel(t, owner.UserID, "Física II")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	as := h.asUser(t, member.UserID)
+	tag := h.createTag(t, "tp")
+
+	// Un post de solo espacios se rechaza igual que al editarlo
+	postsPath := fmt.Sprintf("/channels/%d/posts", channelID)
+	as.post(t, postsPath, gin.H{"content": "   "}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	as.post(t, postsPath, gin.H{}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	as.multipart(t, postsPath, map[string]string{"content": " \n "}, nil).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	res := as.post(t, postsPath, gin.H{"content": "¿Alguien tiene el TP 3?", "tag_ids": []uint{tag.TagID}})
+	res.expect(t, http.StatusCreated)
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	res.decode(t, &created)
+	postID := created.Post.PostID
+
+	h.asUser(t, owner.UserID).
+		post(t, fmt.Sprintf("/channels/posts/%d/comments", postID), gin.H{"content": "Lo subo en un rato"}).
+		expect(t, http.StatusCreated)
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/posts/%d/like", postID), nil).expect(t, http.StatusCreated)
+
+	var list struct {
+		Posts []models.ChannelPost `json:"posts"`
+	}
+	as.get(t, fmt.Sprintf("/channels/%d/posts", channelID)).expect(t, http.StatusOK).decode(t, &list)
+	if len(list.Posts) != 1 {
+		t.Fatalf("el canal tiene %d posts, se esperaba 1", len(list.Posts))
+	}
+	post := list.Posts[0]
+	if post.User.Username != "rocio" || len(post.Tags) != 1 || post.Tags[0].Name != "tp" {
+		t.Fatalf("post inesperado: %+v", post)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].User.Username != "quique" || len(post.Likes) != 1 {
+		t.Fatalf("comentarios o likes inesperados: %+v %+v", post.Comments, post.Likes)
+	}
+
+	// El segundo like lo quita
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/posts/%d/like", postID), nil).expect(t, http.StatusOK)
+}
+
+func TestConcurrentChannelLikes(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "sabrina")
+	channelID := h.createChannel(t, owner.UserID, "Termodinámica")
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Guía 2"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+	path := fmt.Sprintf("/channels/posts/%d/like", created.Post.PostID)
+
+	// Un doble clic no puede terminar en un error del servidor
+	const likes = 5
+	statuses := make(chan int, likes)
+	var wg sync.WaitGroup
+	for i := 0; i < likes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- h.asUser(t, owner.UserID).post(t, path, nil).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		if status != http.StatusCreated && status != http.StatusOK {
+			t.Fatalf("respuesta inesperada: %d", status)
+		}
+	}
+}
+
+func TestDeleteChannelPostPermissions(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "sofi")
+	author := h.createUser(t, "tomas")
+	other := h.createUser(t, "ulises")
+	outsider := h.createUser(t, "vera")
+	channelID := h.createChannel(t, owner.UserID, "Química")
+	h.addMember(t, channelID, owner.UserID, author.UserID)
+	h.addMember(t, channelID, owner.UserID, other.UserID)
+
+	createPost := func() uint {
+		var created struct {
+			Post models.ChannelPost `json:"post"`
+		}
+		h.asUser(t, author.UserID).
+			post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Guía de ejercicios"}).
+			expect(t, http.StatusCreated).
+			decode(t, &created)
+		return created.Post.PostID
+	}
+
+	postID := createPost()
+	path := fmt.Sprintf("/channels/posts/%d", postID)
+	h.asUser(t, outsider.UserID).delete(t, path).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	h.asUser(t, other.UserID).delete(t, path).expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	h.asUser(t, author.UserID).delete(t, path).expect(t, http.StatusOK)
+	h.asUser(t, author.UserID).delete(t, path).expectError(t, http.StatusNotFound, "POST_NOT_FOUND")
+
+	// Los administradores del canal pueden borrar posts ajenos
+	postID = createPost()
+	h.asUser(t, owner.UserID).delete(t, fmt.Sprintf("/channels/posts/%d", postID)).expect(t, http.StatusOK)
+}
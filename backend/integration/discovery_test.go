@@ -0,0 +1,147 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+// createPrivateChannel crea un canal privado y devuelve su ID
+func createPrivateChannel(t *testing.T, h *harness, ownerID uint, name string) uint {
+	t.Helper()
+	var body struct {
+		Channel models.Channel `json:"channel"`
+	}
+	h.asUser(t, ownerID).post(t, "/channels", gin.H{
+		"name":          name,
+		"is_private":    true,
+		"university_id": h.universityID,
+		"career_id":     h.careerID,
+	}).expect(t, http.StatusCreated).decode(t, &body)
+	return body.Channel.ChannelID
+}
+
+func TestDiscoverPublicChannels(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "eva")
+	quiet := h.createChannel(t, owner.UserID, "Álgebra")
+	active := h.createChannel(t, owner.UserID, "Física I")
+	createPrivateChannel(t, h, owner.UserID, "Física privada")
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/%d/posts", active), gin.H{"content": "Hola"}).expect(t, http.StatusCreated)
+
+	var body struct {
+		Channels []struct {
+			Channel        models.Channel `json:"channel"`
+			MemberCount    int64          `json:"member_count"`
+			LastActivityAt *string        `json:"last_activity_at"`
+		} `json:"channels"`
+		Pagination struct {
+			TotalItems int64 `json:"total_items"`
+		} `json:"pagination"`
+	}
+	as := h.asUser(t, h.createUser(t, "fran").UserID)
+	as.get(t, "/channels/discover").expect(t, http.StatusOK).decode(t, &body)
+	if len(body.Channels) != 2 || body.Pagination.TotalItems != 2 {
+		t.Fatalf("se esperaban los 2 canales públicos: %+v", body.Channels)
+	}
+	// Primero el de actividad más reciente
+	if first := body.Channels[0]; first.Channel.ChannelID != active || first.MemberCount != 1 || first.LastActivityAt == nil {
+		t.Fatalf("primer canal inesperado: %+v", first)
+	}
+	if second := body.Channels[1]; second.Channel.ChannelID != quiet || second.LastActivityAt != nil {
+		t.Fatalf("segundo canal inesperado: %+v", second)
+	}
+
+	as.get(t, "/channels/discover?q=sica").expect(t, http.StatusOK).decode(t, &body)
+	if len(body.Channels) != 1 || body.Channels[0].Channel.ChannelID != active {
+		t.Fatalf("la búsqueda por texto devolvió %+v", body.Channels)
+	}
+	as.get(t, fmt.Sprintf("/channels/discover?university=%d", h.universityID+1)).expect(t, http.StatusOK).decode(t, &body)
+	if len(body.Channels) != 0 {
+		t.Fatalf("el filtro por universidad devolvió %d canales", len(body.Channels))
+	}
+	as.get(t, "/channels/discover?career=abc").expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+}
+
+func TestDiscoverMatchesWildcardsLiterally(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "ivo")
+	percent := h.createChannel(t, owner.UserID, "Álgebra 100% práctica")
+	underscore := h.createChannel(t, owner.UserID, "final_2024")
+	h.createChannel(t, owner.UserID, "Finales 2024")
+
+	as := h.asUser(t, h.createUser(t, "jazmin").UserID)
+	for query, want := range map[string]uint{"%25": percent, "_": underscore} {
+		var body struct {
+			Channels []struct {
+				Channel models.Channel `json:"channel"`
+			} `json:"channels"`
+		}
+		as.get(t, "/channels/discover?q="+query).expect(t, http.StatusOK).decode(t, &body)
+		if len(body.Channels) != 1 || body.Channels[0].Channel.ChannelID != want {
+			t.Fatalf("q=%s devolvió %+v", query, body.Channels)
+		}
+	}
+}
+
+func TestJoinPublicChannel(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "gala")
+	user := h.createUser(t, "hernan")
+	public := h.createChannel(t, owner.UserID, "Discreta")
+	private := createPrivateChannel(t, h, owner.UserID, "Discreta privada")
+	as := h.asUser(t, user.UserID)
+
+	as.post(t, fmt.Sprintf("/channels/%d/join", public), nil).expect(t, http.StatusCreated)
+	as.get(t, fmt.Sprintf("/channels/%d", public)).expect(t, http.StatusOK)
+	as.post(t, fmt.Sprintf("/channels/%d/join", public), nil).expectError(t, http.StatusConflict, "ALREADY_CHANNEL_MEMBER")
+
+	as.post(t, fmt.Sprintf("/channels/%d/join", private), nil).expectError(t, http.StatusForbidden, "CHANNEL_IS_PRIVATE")
+	as.post(t, fmt.Sprintf("/channels/%d/join-requests", public), nil).expectError(t, http.StatusConflict, "CHANNEL_IS_PUBLIC")
+	as.post(t, "/channels/999/join", nil).expectError(t, http.StatusNotFound, "CHANNEL_NOT_FOUND")
+}
+
+func TestJoinRequestFlow(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "ivo")
+	user := h.createUser(t, "jana")
+	other := h.createUser(t, "kevin")
+	channelID := createPrivateChannel(t, h, owner.UserID, "Tesis")
+	path := fmt.Sprintf("/channels/%d/join-requests", channelID)
+
+	var created struct {
+		Request models.ChannelJoinRequest `json:"request"`
+	}
+	h.asUser(t, user.UserID).post(t, path, gin.H{"message": "Curso la materia"}).expect(t, http.StatusCreated).decode(t, &created)
+	h.asUser(t, user.UserID).post(t, path, nil).expectError(t, http.StatusConflict, "JOIN_REQUEST_ALREADY_PENDING")
+	h.asUser(t, other.UserID).post(t, path, nil).expect(t, http.StatusCreated)
+
+	// Solo los administradores ven y resuelven las solicitudes
+	h.asUser(t, user.UserID).get(t, path).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	var pending struct {
+		Requests []models.ChannelJoinRequest `json:"requests"`
+	}
+	h.asUser(t, owner.UserID).get(t, path).expect(t, http.StatusOK).decode(t, &pending)
+	if len(pending.Requests) != 2 || pending.Requests[0].User.Username != "jana" || pending.Requests[0].Message != "Curso la materia" {
+		t.Fatalf("solicitudes inesperadas: %+v", pending.Requests)
+	}
+
+	respond := fmt.Sprintf("/channels/join-requests/%d", created.Request.RequestID)
+	h.asUser(t, user.UserID).post(t, respond, gin.H{"action": "approve"}).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).post(t, respond, gin.H{"action": "maybe"}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	h.asUser(t, owner.UserID).post(t, respond, gin.H{"action": "approve"}).expect(t, http.StatusOK)
+	h.asUser(t, owner.UserID).post(t, respond, gin.H{"action": "deny"}).expectError(t, http.StatusConflict, "JOIN_REQUEST_ALREADY_PROCESSED")
+	h.asUser(t, user.UserID).get(t, fmt.Sprintf("/channels/%d", channelID)).expect(t, http.StatusOK)
+
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/join-requests/%d", pending.Requests[1].RequestID), gin.H{"action": "deny"}).
+		expect(t, http.StatusOK)
+	h.asUser(t, other.UserID).get(t, fmt.Sprintf("/channels/%d", channelID)).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	h.asUser(t, owner.UserID).get(t, path).expect(t, http.StatusOK).decode(t, &pending)
+	if len(pending.Requests) != 0 {
+		t.Fatalf("quedaron %d solicitudes pendientes", len(pending.Requests))
+	}
+}
@@ -0,0 +1,552 @@
+// Package integration tiene los tests de punta a punta de la API. Levantan un
+// PostgreSQL descartable, aplican las migraciones y hacen pedidos HTTP al
+// router real, con almacenamiento de archivos y email en memoria.
+//
+// Si TEST_DATABASE_URL está definida se usa esa base, y su contenido se borra
+// entre tests. Si no, se levanta un PostgreSQL con embedded-postgres; cuando
+// tampoco es posible (sin binarios descargables, corriendo como root, etc.)
+// los tests se saltean indicando el motivo, salvo que CI o REQUIRE_DB estén
+// definidas: ahí la falta de base es un error para que no pase inadvertida.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/controllers"
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/realtime"
+	"github.com/LautaroRomano/repositorio-tecnologico/routes"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/gin-gonic/gin"
+	"github.com/resendlabs/resend-go"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testPassword es la contraseña de los usuarios que crea createUser
+const testPassword = "Apuntes#2024"
+
+// keptTables no se vacían entre tests: el registro de migraciones y las
+// claves del llavero JWT, que se cargan una sola vez
+var keptTables = map[string]bool{"migrations": true, "signing_keys": true}
+
+// testKeyEncryptionKey cifra las claves del llavero de la base de tests. Es
+// fija para poder reusar las claves de una corrida anterior.
+var testKeyEncryptionKey = []byte("clave-de-cifrado-de-32-bytes!!!!")
+
+var (
+	env        *harness
+	skipReason string
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	stop, err := setup()
+	if err != nil {
+		skipReason = "sin base de datos para los tests de integración: " + err.Error()
+		fmt.Fprintln(os.Stderr, skipReason)
+		if os.Getenv("CI") != "" || os.Getenv("REQUIRE_DB") != "" {
+			os.Exit(1)
+		}
+	}
+
+	code := m.Run()
+	if stop != nil {
+		stop()
+	}
+	os.Exit(code)
+}
+
+// harness es el entorno compartido por los tests: router, base y fakes
+type harness struct {
+	router  *gin.Engine
+	db      *gorm.DB
+	storage *fakeStorage
+	mail    *fakeMailer
+
+	universityID uint
+	careerID     uint
+}
+
+func setup() (stop func(), err error) {
+	dsn, stop, err := startDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			stop()
+			stop = nil
+		}
+	}()
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return stop, err
+	}
+	database.DB = db
+
+	if _, err := database.MigrateUp(context.Background(), 0); err != nil {
+		return stop, fmt.Errorf("aplicando migraciones: %w", err)
+	}
+
+	err = utils.InitKeyRing(db, utils.KeyRingConfig{
+		Algorithm:        utils.AlgorithmEdDSA,
+		RotationInterval: 24 * time.Hour,
+		TokenTTL:         utils.TokenTTL,
+		Issuer:           "repositorio-tecnologico-test",
+		EncryptionKey:    testKeyEncryptionKey,
+	})
+	if err != nil {
+		return stop, fmt.Errorf("inicializando el llavero JWT: %w", err)
+	}
+
+	storage := &fakeStorage{}
+	mail := &fakeMailer{}
+	controllers.Services = services.New(db, storage, realtime.NewMemoryBroker(100))
+	go controllers.Services.Announcements.Run()
+	utils.UseEmailTransport(utils.EmailConfig{
+		From:        "Repositorio <no-reply@repositorio.test>",
+		FrontendURL: "http://frontend.test",
+	}, mail)
+
+	env = &harness{
+		router:  routes.NewRouter("repositorio-tecnologico-test"),
+		db:      db,
+		storage: storage,
+		mail:    mail,
+	}
+	return stop, nil
+}
+
+// startDatabase devuelve la DSN de la base de tests y cómo detenerla
+func startDatabase() (string, func(), error) {
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		return url, func() {}, nil
+	}
+	if os.Geteuid() == 0 {
+		return "", nil, errors.New("PostgreSQL no se puede iniciar como root; definí TEST_DATABASE_URL")
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "repositorio-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V16).
+		Port(port).
+		Database("repositorio_test").
+		RuntimePath(filepath.Join(dir, "runtime")).
+		StartTimeout(time.Minute).
+		Logger(io.Discard))
+	if err := pg.Start(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("iniciando embedded-postgres: %w", err)
+	}
+
+	dsn := fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=repositorio_test sslmode=disable", port)
+	stop := func() {
+		if err := pg.Stop(); err != nil {
+			fmt.Fprintln(os.Stderr, "deteniendo embedded-postgres:", err)
+		}
+		os.RemoveAll(dir)
+	}
+	return dsn, stop, nil
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// newHarness devuelve el entorno con la base vacía y una universidad y una
+// carrera cargadas. Saltea el test si no hay base disponible.
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	if env == nil {
+		t.Skip(skipReason)
+	}
+	env.reset(t)
+	return env
+}
+
+func (h *harness) reset(t *testing.T) {
+	t.Helper()
+
+	var tables []string
+	err := h.db.Raw("SELECT tablename FROM pg_tables WHERE schemaname = 'public'").Scan(&tables).Error
+	if err != nil {
+		t.Fatalf("listando tablas: %v", err)
+	}
+	var truncate []string
+	for _, table := range tables {
+		if !keptTables[table] {
+			truncate = append(truncate, `"`+table+`"`)
+		}
+	}
+	if err := h.db.Exec("TRUNCATE " + strings.Join(truncate, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+		t.Fatalf("vaciando tablas: %v", err)
+	}
+
+	h.storage.reset()
+	h.mail.reset()
+
+	university := models.University{Name: "Universidad de Prueba"}
+	if err := h.db.Create(&university).Error; err != nil {
+		t.Fatalf("creando universidad: %v", err)
+	}
+	career := models.Career{Name: "Ingeniería en Sistemas", UniversityID: university.UniversityID}
+	if err := h.db.Create(&career).Error; err != nil {
+		t.Fatalf("creando carrera: %v", err)
+	}
+	h.universityID = university.UniversityID
+	h.careerID = career.CareerID
+}
+
+// createUser crea un usuario con testPassword directamente en la base
+func (h *harness) createUser(t *testing.T, username string) models.User {
+	t.Helper()
+	email := username + "@repositorio.test"
+	user := models.User{
+		Username:       username,
+		Email:          email,
+		EmailCanonical: &email,
+		AccountName:    strings.ToUpper(username[:1]) + username[1:],
+		UniversityID:   h.universityID,
+		CareerID:       h.careerID,
+	}
+	if err := user.SetPassword(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.db.Create(&user).Error; err != nil {
+		t.Fatalf("creando usuario %s: %v", username, err)
+	}
+	return user
+}
+
+// createTag crea un tag directamente en la base
+func (h *harness) createTag(t *testing.T, name string) models.Tag {
+	t.Helper()
+	tag := models.Tag{Name: name}
+	if err := h.db.Create(&tag).Error; err != nil {
+		t.Fatalf("creando tag %s: %v", name, err)
+	}
+	return tag
+}
+
+// anonymous devuelve un cliente sin autenticar
+func (h *harness) anonymous() *client {
+	return &client{h: h}
+}
+
+// asUser devuelve un cliente autenticado con un JWT del usuario
+func (h *harness) asUser(t *testing.T, userID uint) *client {
+	t.Helper()
+	token, err := utils.GenerateJWT(userID)
+	if err != nil {
+		t.Fatalf("generando token: %v", err)
+	}
+	return &client{h: h, token: token}
+}
+
+// createPost publica un post en el feed a nombre del usuario y devuelve su ID
+func (h *harness) createPost(t *testing.T, userID uint, content string) uint {
+	t.Helper()
+	res := h.asUser(t, userID).multipart(t, "/posts", map[string]string{
+		"content":       content,
+		"university_id": fmt.Sprint(h.universityID),
+		"career_id":     fmt.Sprint(h.careerID),
+	}, nil)
+	res.expect(t, http.StatusCreated)
+
+	var body struct {
+		PostID uint `json:"post_id"`
+	}
+	res.decode(t, &body)
+	return body.PostID
+}
+
+// createChannel crea un canal con el usuario como administrador y devuelve su ID
+func (h *harness) createChannel(t *testing.T, ownerID uint, name string) uint {
+	t.Helper()
+	res := h.asUser(t, ownerID).post(t, "/channels", gin.H{
+		"name":          name,
+		"description":   "Canal de " + name,
+		"university_id": h.universityID,
+		"career_id":     h.careerID,
+	})
+	res.expect(t, http.StatusCreated)
+
+	var body struct {
+		Channel models.Channel `json:"channel"`
+	}
+	res.decode(t, &body)
+	return body.Channel.ChannelID
+}
+
+// addMember agrega al usuario como miembro del canal invitándolo y aceptando
+func (h *harness) addMember(t *testing.T, channelID, adminID, userID uint) {
+	t.Helper()
+	res := h.asUser(t, adminID).post(t, fmt.Sprintf("/channels/%d/invite", channelID), gin.H{"invited_user_id": userID})
+	res.expect(t, http.StatusCreated)
+
+	var body struct {
+		Invitation models.ChannelInvitation `json:"invitation"`
+	}
+	res.decode(t, &body)
+
+	h.asUser(t, userID).
+		post(t, fmt.Sprintf("/channels/invitations/%d", body.Invitation.InvitationID), gin.H{"action": "accept"}).
+		expect(t, http.StatusOK)
+}
+
+// client hace pedidos al router, con o sin token
+type client struct {
+	h     *harness
+	token string
+}
+
+func (c *client) get(t *testing.T, path string) *response {
+	return c.do(t, http.MethodGet, path, nil)
+}
+
+func (c *client) post(t *testing.T, path string, body interface{}) *response {
+	return c.do(t, http.MethodPost, path, body)
+}
+
+func (c *client) put(t *testing.T, path string, body interface{}) *response {
+	return c.do(t, http.MethodPut, path, body)
+}
+
+func (c *client) patch(t *testing.T, path string, body interface{}) *response {
+	return c.do(t, http.MethodPatch, path, body)
+}
+
+func (c *client) delete(t *testing.T, path string) *response {
+	return c.do(t, http.MethodDelete, path, nil)
+}
+
+func (c *client) do(t *testing.T, method, path string, body interface{}) *response {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req)
+}
+
+// multipart envía un formulario multipart con campos y archivos (nombre → contenido)
+func (c *client) multipart(t *testing.T, path string, fields map[string]string, files map[string]string) *response {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		part, err := w.CreateFormFile("files[]", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return c.send(req)
+}
+
+func (c *client) send(req *http.Request) *response {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	rec := httptest.NewRecorder()
+	c.h.router.ServeHTTP(rec, req)
+	return &response{rec}
+}
+
+// response envuelve la respuesta grabada con helpers para verificarla
+type response struct {
+	*httptest.ResponseRecorder
+}
+
+// expect falla el test si el status no es el esperado
+func (r *response) expect(t *testing.T, status int) *response {
+	t.Helper()
+	if r.Code != status {
+		t.Fatalf("status %d, se esperaba %d: %s", r.Code, status, r.Body.String())
+	}
+	return r
+}
+
+// expectError verifica el status y el código de error de apperror
+func (r *response) expectError(t *testing.T, status int, code string) {
+	t.Helper()
+	r.expect(t, status)
+	var body struct {
+		Code string `json:"code"`
+	}
+	r.decode(t, &body)
+	if body.Code != code {
+		t.Fatalf("código %q, se esperaba %q: %s", body.Code, code, r.Body.String())
+	}
+}
+
+func (r *response) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		t.Fatalf("decodificando %s: %v", r.Body.String(), err)
+	}
+}
+
+// fakeStorage guarda en memoria los archivos subidos
+type fakeStorage struct {
+	mu      sync.Mutex
+	uploads map[string][]byte
+}
+
+func (s *fakeStorage) Upload(_ context.Context, folder string, file services.File) (string, error) {
+	data, err := io.ReadAll(file.Content)
+	if err != nil {
+		return "", err
+	}
+	url := "https://storage.test/" + folder + "/" + file.Name
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads[url] = data
+	return url, nil
+}
+
+func (s *fakeStorage) UploadPrivate(ctx context.Context, folder string, file services.File) (string, error) {
+	return s.Upload(ctx, "private/"+folder, file)
+}
+
+// SignedURL devuelve la URL del archivo con el vencimiento, para que los
+// tests verifiquen a dónde redirige una descarga
+func (s *fakeStorage) SignedURL(_ context.Context, url string, expiresAt time.Time) (string, error) {
+	return fmt.Sprintf("%s?expires_at=%d", url, expiresAt.Unix()), nil
+}
+
+func (s *fakeStorage) Delete(_ context.Context, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, url)
+	return nil
+}
+
+func (s *fakeStorage) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads = map[string][]byte{}
+}
+
+func (s *fakeStorage) file(url string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.uploads[url]
+	return data, ok
+}
+
+// fakeMailer guarda en memoria los emails enviados
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []*resend.SendEmailRequest
+}
+
+func (m *fakeMailer) Send(_ context.Context, params *resend.SendEmailRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, params)
+	return nil
+}
+
+func (m *fakeMailer) SendBatch(_ context.Context, params []*resend.SendEmailRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(params) > utils.EmailBatchSize {
+		return fmt.Errorf("lote de %d emails", len(params))
+	}
+	m.sent = append(m.sent, params...)
+	return nil
+}
+
+func (m *fakeMailer) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = nil
+}
+
+// waitFor espera a que se envíen count emails a la dirección, para los que se
+// envían en segundo plano
+func (m *fakeMailer) waitFor(t *testing.T, address string, count int) []*resend.SendEmailRequest {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sent := m.to(address)
+		if len(sent) >= count || time.Now().After(deadline) {
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// to devuelve los emails enviados a la dirección
+func (m *fakeMailer) to(address string) []*resend.SendEmailRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sent []*resend.SendEmailRequest
+	for _, email := range m.sent {
+		for _, to := range email.To {
+			if to == address {
+				sent = append(sent, email)
+			}
+		}
+	}
+	return sent
+}
@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+// invite invita al usuario al canal y devuelve la invitación creada
+func invite(t *testing.T, h *harness, channelID, adminID, userID uint) models.ChannelInvitation {
+	t.Helper()
+	var body struct {
+		Invitation models.ChannelInvitation `json:"invitation"`
+	}
+	h.asUser(t, adminID).
+		post(t, fmt.Sprintf("/channels/%d/invite", channelID), gin.H{"invited_user_id": userID}).
+		expect(t, http.StatusCreated).
+		decode(t, &body)
+	return body.Invitation
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "walter")
+	guest := h.createUser(t, "ximena")
+	channelID := h.createChannel(t, owner.UserID, "Programación")
+	invitation := invite(t, h, channelID, owner.UserID, guest.UserID)
+	as := h.asUser(t, guest.UserID)
+
+	var pending struct {
+		Invitations []models.ChannelInvitation `json:"invitations"`
+	}
+	as.get(t, "/channels/invitations").expect(t, http.StatusOK).decode(t, &pending)
+	if len(pending.Invitations) != 1 || pending.Invitations[0].Channel.Name != "Programación" || pending.Invitations[0].Inviter.Username != "walter" {
+		t.Fatalf("invitaciones pendientes inesperadas: %+v", pending.Invitations)
+	}
+
+	var body struct {
+		Invitation models.ChannelInvitation `json:"invitation"`
+	}
+	as.post(t, fmt.Sprintf("/channels/invitations/%d", invitation.InvitationID), gin.H{"action": "accept"}).
+		expect(t, http.StatusOK).
+		decode(t, &body)
+	if body.Invitation.Status != "accepted" {
+		t.Fatalf("estado %q, se esperaba accepted", body.Invitation.Status)
+	}
+
+	as.get(t, fmt.Sprintf("/channels/%d", channelID)).expect(t, http.StatusOK)
+	as.get(t, "/channels/invitations").expect(t, http.StatusOK).decode(t, &pending)
+	if len(pending.Invitations) != 0 {
+		t.Fatalf("quedaron %d invitaciones pendientes", len(pending.Invitations))
+	}
+}
+
+func TestRejectInvitation(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "yamila")
+	guest := h.createUser(t, "zoe")
+	other := h.createUser(t, "alan")
+	channelID := h.createChannel(t, owner.UserID, "Estadística")
+	invitation := invite(t, h, channelID, owner.UserID, guest.UserID)
+	path := fmt.Sprintf("/channels/invitations/%d", invitation.InvitationID)
+
+	// Solo el invitado puede responder
+	h.asUser(t, other.UserID).post(t, path, gin.H{"action": "accept"}).expectError(t, http.StatusForbidden, "FORBIDDEN")
+	h.asUser(t, guest.UserID).post(t, path, gin.H{"action": "maybe"}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	h.asUser(t, guest.UserID).post(t, path, gin.H{"action": "reject"}).expect(t, http.StatusOK)
+	h.asUser(t, guest.UserID).post(t, path, gin.H{"action": "accept"}).expectError(t, http.StatusConflict, "INVITATION_ALREADY_PROCESSED")
+	h.asUser(t, guest.UserID).get(t, fmt.Sprintf("/channels/%d", channelID)).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+}
+
+func TestInviteRules(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "bea")
+	member := h.createUser(t, "ciro")
+	guest := h.createUser(t, "dana")
+	channelID := h.createChannel(t, owner.UserID, "Sistemas Operativos")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	path := fmt.Sprintf("/channels/%d/invite", channelID)
+
+	h.asUser(t, member.UserID).post(t, path, gin.H{"invited_user_id": guest.UserID}).
+		expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"invited_user_id": member.UserID}).
+		expectError(t, http.StatusConflict, "ALREADY_CHANNEL_MEMBER")
+
+	invite(t, h, channelID, owner.UserID, guest.UserID)
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"invited_user_id": guest.UserID}).
+		expectError(t, http.StatusConflict, "INVITATION_ALREADY_PENDING")
+
+	h.asUser(t, guest.UserID).post(t, "/channels/invitations/999", gin.H{"action": "accept"}).
+		expectError(t, http.StatusNotFound, "INVITATION_NOT_FOUND")
+}
+
+// Una invitación pendiente se puede aceptar aunque el usuario haya entrado
+// al canal por otra vía mientras tanto
+func TestAcceptInvitationAfterJoining(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "bruno")
+	guest := h.createUser(t, "carla")
+	channelID := h.createChannel(t, owner.UserID, "Redes")
+	invitation := invite(t, h, channelID, owner.UserID, guest.UserID)
+	as := h.asUser(t, guest.UserID)
+
+	as.post(t, fmt.Sprintf("/channels/%d/join", channelID), nil).expect(t, http.StatusCreated)
+
+	var body struct {
+		Invitation models.ChannelInvitation `json:"invitation"`
+	}
+	as.post(t, fmt.Sprintf("/channels/invitations/%d", invitation.InvitationID), gin.H{"action": "accept"}).
+		expect(t, http.StatusOK).
+		decode(t, &body)
+	if body.Invitation.Status != "accepted" {
+		t.Fatalf("estado %q, se esperaba accepted", body.Invitation.Status)
+	}
+	as.get(t, fmt.Sprintf("/channels/%d", channelID)).expect(t, http.StatusOK)
+}
+
+// Entradas simultáneas al mismo canal: una sola crea la membresía y las
+// demás responden que el usuario ya es miembro
+func TestConcurrentJoinsReportAlreadyMember(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "delia")
+	guest := h.createUser(t, "emilio")
+	channelID := h.createChannel(t, owner.UserID, "Compiladores")
+	as := h.asUser(t, guest.UserID)
+	path := fmt.Sprintf("/channels/%d/join", channelID)
+
+	const attempts = 10
+	codes := make(chan string, attempts)
+	var wg sync.WaitGroup
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := as.post(t, path, nil)
+			if res.Code == http.StatusCreated {
+				codes <- "created"
+				return
+			}
+			var body struct {
+				Code string `json:"code"`
+			}
+			json.Unmarshal(res.Body.Bytes(), &body)
+			codes <- fmt.Sprintf("%d %s", res.Code, body.Code)
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		switch code {
+		case "created":
+			created++
+		case "409 ALREADY_CHANNEL_MEMBER":
+		default:
+			t.Fatalf("respuesta inesperada: %s", code)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("se crearon %d membresías", created)
+	}
+}
@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+// createInviteLink crea un enlace de invitación y lo devuelve
+func createInviteLink(t *testing.T, h *harness, channelID, adminID uint, body gin.H) models.ChannelInviteLink {
+	t.Helper()
+	var created struct {
+		Link models.ChannelInviteLink `json:"link"`
+	}
+	h.asUser(t, adminID).post(t, fmt.Sprintf("/channels/%d/invite-links", channelID), body).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+	return created.Link
+}
+
+func TestInviteLinks(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "julia")
+	member := h.createUser(t, "kiara")
+	first := h.createUser(t, "lucio")
+	second := h.createUser(t, "marta")
+	channelID := createPrivateChannel(t, h, owner.UserID, "Ayudantía")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	path := fmt.Sprintf("/channels/%d/invite-links", channelID)
+
+	h.asUser(t, member.UserID).post(t, path, nil).expectError(t, http.StatusForbidden, "CHANNEL_PERMISSION_DENIED")
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"role": "owner"}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"max_uses": 0}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"expires_at": time.Now().Add(-time.Hour)}).
+		expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	link := createInviteLink(t, h, channelID, owner.UserID, gin.H{"role": "readonly", "max_uses": 1, "expires_at": time.Now().Add(time.Hour)})
+	join := "/channels/join/" + link.Code
+
+	// Un enlace sirve para entrar a un canal privado sin solicitud
+	var joined struct {
+		Member models.ChannelMember `json:"member"`
+	}
+	h.asUser(t, first.UserID).post(t, join, nil).expect(t, http.StatusCreated).decode(t, &joined)
+	if joined.Member.Role != models.ChannelRoleReadOnly {
+		t.Fatalf("rol inesperado: %q", joined.Member.Role)
+	}
+	h.asUser(t, second.UserID).post(t, join, nil).expectError(t, http.StatusGone, "INVITE_LINK_EXHAUSTED")
+	h.asUser(t, second.UserID).post(t, "/channels/join/desconocido", nil).expectError(t, http.StatusNotFound, "INVITE_LINK_NOT_FOUND")
+
+	open := createInviteLink(t, h, channelID, owner.UserID, nil)
+	var list struct {
+		Links []models.ChannelInviteLink `json:"links"`
+	}
+	h.asUser(t, member.UserID).get(t, path).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).get(t, path).expect(t, http.StatusOK).decode(t, &list)
+	if len(list.Links) != 2 || list.Links[0].LinkID != open.LinkID || list.Links[1].Uses != 1 {
+		t.Fatalf("enlaces inesperados: %+v", list.Links)
+	}
+
+	revoke := fmt.Sprintf("/channels/invite-links/%d", open.LinkID)
+	h.asUser(t, member.UserID).delete(t, revoke).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).delete(t, revoke).expect(t, http.StatusOK)
+	h.asUser(t, second.UserID).post(t, "/channels/join/"+open.Code, nil).expectError(t, http.StatusGone, "INVITE_LINK_EXPIRED")
+	h.asUser(t, owner.UserID).get(t, path).expect(t, http.StatusOK).decode(t, &list)
+	if len(list.Links) != 1 {
+		t.Fatalf("el enlace revocado sigue en la lista: %+v", list.Links)
+	}
+}
+
+func TestInviteByEmail(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "nora")
+	existing := h.createUser(t, "omar")
+	other := h.createUser(t, "pilar")
+	channelID := h.createChannel(t, owner.UserID, "Álgebra")
+	path := fmt.Sprintf("/channels/%d/invite", channelID)
+
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"email": "no-es-un-email"}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	h.asUser(t, owner.UserID).post(t, path, gin.H{}).expectError(t, http.StatusBadRequest, "INVALID_REQUEST")
+
+	// Con cuenta, la invitación es la de siempre
+	var invited struct {
+		Invitation *models.ChannelInvitation `json:"invitation"`
+	}
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"email": strings.ToUpper(existing.Email)}).
+		expect(t, http.StatusCreated).
+		decode(t, &invited)
+	if invited.Invitation == nil || invited.Invitation.InvitedUser != existing.UserID {
+		t.Fatalf("invitación inesperada: %+v", invited.Invitation)
+	}
+
+	// Sin cuenta, se envía un enlace de un solo uso para esa dirección
+	const address = "quique@repositorio.test"
+	var emailed struct {
+		Link models.ChannelInviteLink `json:"link"`
+	}
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"email": address}).expect(t, http.StatusCreated).decode(t, &emailed)
+	sent := h.mail.to(address)
+	if len(sent) != 1 || !strings.Contains(sent[0].Html, "/channels/join/"+emailed.Link.Code) {
+		t.Fatalf("emails inesperados: %+v", sent)
+	}
+
+	join := "/channels/join/" + emailed.Link.Code
+	h.asUser(t, other.UserID).post(t, join, nil).expectError(t, http.StatusForbidden, "INVITE_LINK_EMAIL_MISMATCH")
+	newcomer := h.createUser(t, "quique")
+	h.asUser(t, newcomer.UserID).post(t, join, nil).expect(t, http.StatusCreated)
+	if _, ok := channelMembers(t, h, channelID, owner.UserID)[newcomer.UserID]; !ok {
+		t.Fatal("el invitado por email no quedó en el canal")
+	}
+}
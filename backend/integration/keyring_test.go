@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+)
+
+// signedKID firma un token de sesión con el llavero global y devuelve su kid
+func signedKID(t *testing.T) (string, string) {
+	t.Helper()
+	token, err := utils.GenerateJWT(1)
+	if err != nil {
+		t.Fatalf("firmando: %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return token, kid
+}
+
+// Dos réplicas con un llavero RS256 propio, para no tocar las claves EdDSA
+// que usan los demás tests
+func TestKeyRingRotation(t *testing.T) {
+	h := newHarness(t)
+	previous := utils.Keys
+	t.Cleanup(func() { utils.Keys = previous })
+	if err := h.db.Where("algorithm = ?", utils.AlgorithmRS256).Delete(&models.SigningKey{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	config := utils.KeyRingConfig{
+		Algorithm:        utils.AlgorithmRS256,
+		RotationInterval: 2 * time.Hour,
+		TokenTTL:         utils.TokenTTL,
+		Issuer:           "repositorio-tecnologico-test",
+		EncryptionKey:    testKeyEncryptionKey,
+	}
+
+	// Sin ninguna clave, la primera firma enseguida
+	if err := utils.InitKeyRing(h.db, config); err != nil {
+		t.Fatal(err)
+	}
+	replica := utils.Keys
+	_, first := signedKID(t)
+
+	var stored models.SigningKey
+	if err := h.db.First(&stored, "key_id = ?", first).Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored.PrivateKey, "PRIVATE KEY") {
+		t.Fatal("la clave privada quedó guardada en texto plano")
+	}
+
+	// Cerca del vencimiento otra réplica genera la siguiente, que se publica
+	// antes de firmar
+	if err := h.db.Model(&stored).Update("sign_until", time.Now().Add(30*time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.InitKeyRing(h.db, config); err != nil {
+		t.Fatal(err)
+	}
+	rotating := utils.Keys
+	var next models.SigningKey
+	if err := h.db.Where("algorithm = ? AND key_id <> ?", utils.AlgorithmRS256, first).First(&next).Error; err != nil {
+		t.Fatalf("no se generó la clave siguiente: %v", err)
+	}
+	if next.SignFrom.Before(time.Now().Add(utils.JWKSMaxAge - time.Minute)) {
+		t.Fatalf("la clave nueva firma desde %v, antes de publicarse", next.SignFrom)
+	}
+	if _, kid := signedKID(t); kid != first {
+		t.Fatalf("se firmó con %s antes de publicarla", kid)
+	}
+	published := false
+	for _, key := range rotating.JWKS().Keys {
+		published = published || key.KeyID == next.KeyID
+	}
+	if !published {
+		t.Fatal("el JWKS no publica la clave siguiente")
+	}
+
+	// Pasado JWKSMaxAge la clave nueva firma
+	if err := h.db.Model(&next).Update("sign_from", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := rotating.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	token, kid := signedKID(t)
+	if kid != next.KeyID {
+		t.Fatalf("se firmó con %s, se esperaba %s", kid, next.KeyID)
+	}
+
+	// La primera réplica no conoce la clave: la recarga al verificar, pasado
+	// el intervalo mínimo entre recargas (10s)
+	time.Sleep(10*time.Second + 100*time.Millisecond)
+	utils.Keys = replica
+	if claims, err := utils.ParseJWT(token); err != nil || claims.UserID != 1 {
+		t.Fatalf("la réplica no verificó el token con la clave nueva: %v", err)
+	}
+}
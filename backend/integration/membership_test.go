@@ -0,0 +1,149 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+// channelMembers devuelve los miembros del canal por ID de usuario
+func channelMembers(t *testing.T, h *harness, channelID, viewerID uint) map[uint]models.ChannelMember {
+	t.Helper()
+	var body struct {
+		Channel models.Channel `json:"channel"`
+	}
+	h.asUser(t, viewerID).get(t, fmt.Sprintf("/channels/%d", channelID)).expect(t, http.StatusOK).decode(t, &body)
+	members := map[uint]models.ChannelMember{}
+	for _, m := range body.Channel.Members {
+		members[m.UserID] = m
+	}
+	return members
+}
+
+func TestLeaveChannel(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "lara")
+	member := h.createUser(t, "mateo")
+	channelID := h.createChannel(t, owner.UserID, "Redes")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	path := fmt.Sprintf("/channels/%d/leave", channelID)
+
+	h.asUser(t, owner.UserID).post(t, path, nil).expectError(t, http.StatusConflict, "CHANNEL_OWNER_CANNOT_LEAVE")
+	h.asUser(t, member.UserID).post(t, path, nil).expect(t, http.StatusOK)
+	h.asUser(t, member.UserID).get(t, fmt.Sprintf("/channels/%d", channelID)).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	h.asUser(t, member.UserID).post(t, path, nil).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+}
+
+func TestPromoteDemoteAndKick(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "nadia")
+	admin := h.createUser(t, "oscar")
+	member := h.createUser(t, "paula")
+	channelID := h.createChannel(t, owner.UserID, "Bases de Datos")
+	h.addMember(t, channelID, owner.UserID, admin.UserID)
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	rolePath := func(userID uint) string { return fmt.Sprintf("/channels/%d/members/%d/role", channelID, userID) }
+	memberPath := func(userID uint) string { return fmt.Sprintf("/channels/%d/members/%d", channelID, userID) }
+
+	h.asUser(t, member.UserID).put(t, rolePath(member.UserID), gin.H{"role": "moderator"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).put(t, rolePath(admin.UserID), gin.H{"role": "moderator"}).expect(t, http.StatusOK)
+	if channelMembers(t, h, channelID, owner.UserID)[admin.UserID].Role != models.ChannelRoleModerator {
+		t.Fatal("el miembro no quedó como moderador")
+	}
+
+	// Un moderador no puede cambiarle el rol al creador ni a otro moderador,
+	// ni expulsarlos
+	h.asUser(t, admin.UserID).put(t, rolePath(owner.UserID), gin.H{"role": "member"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_OWNER_PROTECTED")
+	h.asUser(t, admin.UserID).delete(t, memberPath(owner.UserID)).expectError(t, http.StatusForbidden, "CHANNEL_OWNER_PROTECTED")
+	h.asUser(t, owner.UserID).put(t, rolePath(member.UserID), gin.H{"role": "moderator"}).expect(t, http.StatusOK)
+	h.asUser(t, admin.UserID).put(t, rolePath(member.UserID), gin.H{"role": "member"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_OWNER_REQUIRED")
+	h.asUser(t, admin.UserID).delete(t, memberPath(member.UserID)).expectError(t, http.StatusForbidden, "CHANNEL_OWNER_REQUIRED")
+
+	h.asUser(t, owner.UserID).put(t, rolePath(member.UserID), gin.H{"role": "member"}).expect(t, http.StatusOK)
+	h.asUser(t, admin.UserID).delete(t, memberPath(member.UserID)).expect(t, http.StatusOK)
+	if _, ok := channelMembers(t, h, channelID, owner.UserID)[member.UserID]; ok {
+		t.Fatal("el miembro expulsado sigue en el canal")
+	}
+	h.asUser(t, admin.UserID).delete(t, memberPath(member.UserID)).expectError(t, http.StatusNotFound, "CHANNEL_MEMBER_NOT_FOUND")
+	h.asUser(t, owner.UserID).put(t, rolePath(admin.UserID), gin.H{}).expectError(t, http.StatusBadRequest, "INVALID_REQUEST")
+	h.asUser(t, owner.UserID).put(t, rolePath(admin.UserID), gin.H{"role": "owner"}).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+}
+
+func TestTransferOwnership(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "quimey")
+	heir := h.createUser(t, "ramiro")
+	outsider := h.createUser(t, "sara")
+	channelID := h.createChannel(t, owner.UserID, "Compiladores")
+	h.addMember(t, channelID, owner.UserID, heir.UserID)
+	path := fmt.Sprintf("/channels/%d/transfer", channelID)
+
+	h.asUser(t, heir.UserID).post(t, path, gin.H{"user_id": heir.UserID}).expectError(t, http.StatusForbidden, "CHANNEL_OWNER_REQUIRED")
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"user_id": outsider.UserID}).expectError(t, http.StatusNotFound, "CHANNEL_MEMBER_NOT_FOUND")
+
+	var body struct {
+		Channel models.Channel `json:"channel"`
+	}
+	h.asUser(t, owner.UserID).post(t, path, gin.H{"user_id": heir.UserID}).expect(t, http.StatusOK).decode(t, &body)
+	if body.Channel.CreatedBy != heir.UserID {
+		t.Fatalf("el canal quedó a nombre de %d", body.Channel.CreatedBy)
+	}
+	members := channelMembers(t, h, channelID, heir.UserID)
+	if members[heir.UserID].Role != models.ChannelRoleOwner || members[owner.UserID].Role != models.ChannelRoleModerator {
+		t.Fatalf("roles inesperados después de transferir: %+v", members)
+	}
+
+	// El creador anterior ya puede salir; el nuevo no puede ser expulsado
+	h.asUser(t, owner.UserID).delete(t, fmt.Sprintf("/channels/%d/members/%d", channelID, heir.UserID)).
+		expectError(t, http.StatusForbidden, "CHANNEL_OWNER_PROTECTED")
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/%d/leave", channelID), nil).expect(t, http.StatusOK)
+
+	// El creador no puede cambiarse el rol
+	h.asUser(t, heir.UserID).put(t, fmt.Sprintf("/channels/%d/members/%d/role", channelID, heir.UserID), gin.H{"role": "member"}).
+		expectError(t, http.StatusForbidden, "CHANNEL_OWNER_PROTECTED")
+}
+
+func TestBanStopsRejoining(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "tamara")
+	member := h.createUser(t, "ulises")
+	invited := h.createUser(t, "valen")
+	channelID := h.createChannel(t, owner.UserID, "Paradigmas")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	invitation := invite(t, h, channelID, owner.UserID, invited.UserID)
+	bans := fmt.Sprintf("/channels/%d/bans", channelID)
+
+	h.asUser(t, member.UserID).post(t, bans, gin.H{"user_id": invited.UserID}).expectError(t, http.StatusForbidden, "CHANNEL_ADMIN_REQUIRED")
+	h.asUser(t, owner.UserID).post(t, bans, gin.H{"user_id": owner.UserID}).expectError(t, http.StatusForbidden, "FORBIDDEN")
+	h.asUser(t, owner.UserID).post(t, bans, gin.H{"user_id": member.UserID, "reason": "spam"}).expect(t, http.StatusCreated)
+	h.asUser(t, owner.UserID).post(t, bans, gin.H{"user_id": member.UserID}).expectError(t, http.StatusConflict, "USER_ALREADY_BANNED")
+	h.asUser(t, owner.UserID).post(t, bans, gin.H{"user_id": invited.UserID}).expect(t, http.StatusCreated)
+
+	// El bloqueo saca al miembro, cancela la invitación y no deja volver
+	h.asUser(t, member.UserID).get(t, fmt.Sprintf("/channels/%d", channelID)).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	h.asUser(t, member.UserID).post(t, fmt.Sprintf("/channels/%d/join", channelID), nil).expectError(t, http.StatusForbidden, "CHANNEL_USER_BANNED")
+	h.asUser(t, invited.UserID).post(t, fmt.Sprintf("/channels/invitations/%d", invitation.InvitationID), gin.H{"action": "accept"}).
+		expectError(t, http.StatusConflict, "INVITATION_ALREADY_PROCESSED")
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/%d/invite", channelID), gin.H{"invited_user_id": member.UserID}).
+		expectError(t, http.StatusForbidden, "CHANNEL_USER_BANNED")
+
+	var list struct {
+		Bans []models.ChannelBan `json:"bans"`
+	}
+	h.asUser(t, owner.UserID).get(t, bans).expect(t, http.StatusOK).decode(t, &list)
+	if len(list.Bans) != 2 {
+		t.Fatalf("se esperaban 2 bloqueos: %+v", list.Bans)
+	}
+
+	unban := fmt.Sprintf("/channels/%d/bans/%d", channelID, member.UserID)
+	h.asUser(t, owner.UserID).delete(t, unban).expect(t, http.StatusOK)
+	h.asUser(t, owner.UserID).delete(t, unban).expectError(t, http.StatusNotFound, "BAN_NOT_FOUND")
+	h.asUser(t, member.UserID).post(t, fmt.Sprintf("/channels/%d/join", channelID), nil).expect(t, http.StatusCreated)
+}
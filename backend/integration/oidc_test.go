@@ -0,0 +1,343 @@
+package integration
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/sso"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+)
+
+// mockOIDC es un proveedor OIDC local: discovery, JWKS y endpoint de token.
+// El paso de autorización lo simula authorize, que entrega un código para
+// la identidad pedida.
+type mockOIDC struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+// mockGrant es lo que el proveedor recuerda de un código entregado
+type mockGrant struct {
+	identity  mockIdentity
+	nonce     string
+	challenge string
+}
+
+type mockIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+const mockClientID = "repositorio-test"
+
+// newMockOIDC levanta el proveedor y lo registra en sso con el nombre "mock"
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDC{key: key, codes: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	sso.Register(&sso.Provider{
+		Config: sso.ProviderConfig{
+			Name:         "mock",
+			DisplayName:  "Mock",
+			Issuer:       m.server.URL,
+			ClientID:     mockClientID,
+			ClientSecret: "secreto",
+			RedirectURL:  "http://backend.test/auth/oidc/mock/callback",
+		},
+		HTTPClient: m.server.Client(),
+	})
+	return m
+}
+
+// authorize simula que el usuario inicia sesión en el proveedor con la URL
+// de autorización y devuelve el código para el callback
+func (m *mockOIDC) authorize(t *testing.T, authURL string, identity mockIdentity) (state, code string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != mockClientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("URL de autorización inesperada: %s", authURL)
+	}
+
+	code = "code-" + identity.Subject + "-" + query.Get("state")[:8]
+	m.mu.Lock()
+	m.codes[code] = mockGrant{identity: identity, nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+	m.mu.Unlock()
+	return query.Get("state"), code
+}
+
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	grant, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	// El verificador PKCE tiene que corresponder al challenge de la autorización
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            mockClientID,
+		"sub":            grant.identity.Subject,
+		"email":          grant.identity.Email,
+		"email_verified": grant.identity.EmailVerified,
+		"nonce":          grant.nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	})
+	idToken.Header["kid"] = "mock"
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": "access-" + grant.identity.Subject,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// oidcLogin inicia el login y devuelve la URL del proveedor y la cookie del
+// navegador
+func (h *harness) oidcLogin(t *testing.T, redirect string) (string, *http.Cookie) {
+	t.Helper()
+	res := h.anonymous().get(t, "/auth/oidc/mock/login?redirect="+url.QueryEscape(redirect)).expect(t, http.StatusFound)
+	for _, cookie := range res.Result().Cookies() {
+		if cookie.Name == "oidc_state" {
+			if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/auth/oidc/mock/callback" {
+				t.Fatalf("cookie inesperada: %+v", cookie)
+			}
+			return res.Header().Get("Location"), cookie
+		}
+	}
+	t.Fatal("el login no dejó la cookie de estado")
+	return "", nil
+}
+
+// oidcCallback vuelve del proveedor con la cookie indicada (o sin cookie) y
+// devuelve la URL del frontend a la que redirige
+func (h *harness) oidcCallback(t *testing.T, state, code string, cookie *http.Cookie) *url.URL {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/mock/callback?state="+url.QueryEscape(state)+"&code="+url.QueryEscape(code), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	res := h.anonymous().send(req).expect(t, http.StatusFound)
+	location, err := url.Parse(res.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+// oidcSession completa un login y devuelve el usuario de la sesión
+func (h *harness) oidcSession(t *testing.T, provider *mockOIDC, identity mockIdentity) uint {
+	t.Helper()
+	authURL, cookie := h.oidcLogin(t, "/canales")
+	state, code := provider.authorize(t, authURL, identity)
+	location := h.oidcCallback(t, state, code, cookie)
+	if location.Path != "/oauth/callback" || location.Query().Get("redirect") != "/canales" {
+		t.Fatalf("redirección inesperada: %s", location)
+	}
+	fragment, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := utils.ParseJWT(fragment.Get("token"))
+	if err != nil {
+		t.Fatalf("token de sesión inválido: %v", err)
+	}
+	return claims.UserID
+}
+
+func TestOIDCLoginCreatesAndReusesAccount(t *testing.T) {
+	h := newHarness(t)
+	provider := newMockOIDC(t)
+	identity := mockIdentity{Subject: "sub-1", Email: "Lucia.Perez@Universidad.test", EmailVerified: true}
+
+	userID := h.oidcSession(t, provider, identity)
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "lucia.perez@universidad.test" || user.EmailVerifiedAt == nil {
+		t.Fatalf("usuario inesperado: %+v", user)
+	}
+
+	// El segundo login usa la identidad vinculada
+	if again := h.oidcSession(t, provider, identity); again != userID {
+		t.Fatalf("el segundo login entró como %d, se esperaba %d", again, userID)
+	}
+}
+
+func TestOIDCCallbackRequiresTheLoginBrowser(t *testing.T) {
+	h := newHarness(t)
+	provider := newMockOIDC(t)
+	identity := mockIdentity{Subject: "sub-2", Email: "atacante@universidad.test", EmailVerified: true}
+
+	// El atacante inicia el login y le pasa el callback a la víctima, que no
+	// tiene la cookie
+	authURL, attackerCookie := h.oidcLogin(t, "/")
+	state, code := provider.authorize(t, authURL, identity)
+	if location := h.oidcCallback(t, state, code, nil); location.Path != "/login" || location.Fragment != "" {
+		t.Fatalf("se aceptó el callback sin cookie: %s", location)
+	}
+
+	// La cookie de otro login tampoco sirve
+	otherURL, victimCookie := h.oidcLogin(t, "/")
+	provider.authorize(t, otherURL, identity)
+	if location := h.oidcCallback(t, state, code, victimCookie); location.Path != "/login" || location.Fragment != "" {
+		t.Fatalf("se aceptó el callback con la cookie de otro login: %s", location)
+	}
+
+	// Una cookie alterada no pasa la firma
+	forged := *attackerCookie
+	forged.Value += "x"
+	if location := h.oidcCallback(t, state, code, &forged); location.Path != "/login" {
+		t.Fatalf("se aceptó una cookie alterada: %s", location)
+	}
+	var identities int64
+	h.db.Model(&models.UserIdentity{}).Count(&identities)
+	if identities != 0 {
+		t.Fatalf("se vincularon %d identidades", identities)
+	}
+}
+
+func TestOIDCRedirectOnlyToFrontendPaths(t *testing.T) {
+	h := newHarness(t)
+	provider := newMockOIDC(t)
+
+	for i, redirect := range []string{"//evil.test", `/\evil.test`, "/%2F%2Fevil.test", "/%5Cevil.test", "https://evil.test", "canales"} {
+		authURL, cookie := h.oidcLogin(t, redirect)
+		state, code := provider.authorize(t, authURL, mockIdentity{Subject: "sub-3", Email: "redir@universidad.test", EmailVerified: true})
+		location := h.oidcCallback(t, state, code, cookie)
+		if got := location.Query().Get("redirect"); got != "/" {
+			t.Fatalf("caso %d: %q redirige a %q", i, redirect, got)
+		}
+	}
+}
+
+func TestOIDCLinksOnlyVerifiedAccounts(t *testing.T) {
+	h := newHarness(t)
+	provider := newMockOIDC(t)
+
+	// Una cuenta local sin email verificado: quien la registró pudo no ser el
+	// dueño del email, así que no se vincula sola
+	local := h.createUser(t, "mariana")
+	authURL, cookie := h.oidcLogin(t, "/perfil")
+	state, code := provider.authorize(t, authURL, mockIdentity{Subject: "sub-4", Email: local.Email, EmailVerified: true})
+	location := h.oidcCallback(t, state, code, cookie)
+	fragment, _ := url.ParseQuery(location.Fragment)
+	linkToken := fragment.Get("link_token")
+	if location.Path != "/login" || linkToken == "" || fragment.Get("token") != "" {
+		t.Fatalf("redirección inesperada: %s", location)
+	}
+
+	h.anonymous().post(t, "/auth/oidc/link", gin.H{"link_token": linkToken, "password": "otra"}).
+		expectError(t, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	h.anonymous().post(t, "/auth/oidc/link", gin.H{"link_token": linkToken + "x", "password": testPassword}).
+		expectError(t, http.StatusUnauthorized, "INVALID_TOKEN")
+	var linked struct {
+		Token string      `json:"token"`
+		Me    models.User `json:"me"`
+	}
+	h.anonymous().post(t, "/auth/oidc/link", gin.H{"link_token": linkToken, "password": testPassword}).
+		expect(t, http.StatusOK).
+		decode(t, &linked)
+	if linked.Me.UserID != local.UserID || linked.Token == "" {
+		t.Fatalf("vinculación inesperada: %+v", linked)
+	}
+	h.anonymous().post(t, "/auth/oidc/link", gin.H{"link_token": linkToken, "password": testPassword}).
+		expectError(t, http.StatusConflict, "IDENTITY_ALREADY_LINKED")
+
+	// Ya vinculada, la identidad entra directo a la cuenta
+	if userID := h.oidcSession(t, provider, mockIdentity{Subject: "sub-4", Email: local.Email, EmailVerified: true}); userID != local.UserID {
+		t.Fatalf("entró como %d, se esperaba %d", userID, local.UserID)
+	}
+
+	// Una cuenta con el email verificado se vincula sola
+	verified := h.createUser(t, "nicolas")
+	if err := h.db.Model(&verified).Update("email_verified_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if userID := h.oidcSession(t, provider, mockIdentity{Subject: "sub-5", Email: strings.ToUpper(verified.Email), EmailVerified: true}); userID != verified.UserID {
+		t.Fatalf("entró como %d, se esperaba %d", userID, verified.UserID)
+	}
+
+	// Si el proveedor no verificó el email no se ofrece vincular
+	authURL, cookie = h.oidcLogin(t, "/")
+	state, code = provider.authorize(t, authURL, mockIdentity{Subject: "sub-6", Email: verified.Email})
+	location = h.oidcCallback(t, state, code, cookie)
+	if location.Path != "/login" || location.Fragment != "" {
+		t.Fatalf("redirección inesperada: %s", location)
+	}
+}
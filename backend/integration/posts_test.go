@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postBody es la parte de la respuesta de un post que verifican los tests
+type postBody struct {
+	PostID   uint
+	Content  string
+	User     struct{ Username string }
+	Tags     []struct{ Name string }
+	Files    []struct{ FileURL, FileName string }
+	Likes    []struct{ UserID uint }
+	Comments []struct{ Content string }
+}
+
+func getPost(t *testing.T, h *harness, postID uint) postBody {
+	t.Helper()
+	var post postBody
+	h.anonymous().get(t, fmt.Sprintf("/posts/%d", postID)).expect(t, http.StatusOK).decode(t, &post)
+	return post
+}
+
+func TestCreatePostWithTagsAndFiles(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "elena")
+	tag := h.createTag(t, "parciales")
+
+	res := h.asUser(t, author.UserID).multipart(t, "/posts", map[string]string{
+		"content":       "Resumen del primer parcial",
+		"university_id": fmt.Sprint(h.universityID),
+		"career_id":     fmt.Sprint(h.careerID),
+		"tag_ids":       fmt.Sprintf("[%d]", tag.TagID),
+	}, map[string]string{"resumen.pdf": "%PDF-1.4 contenido"})
+	res.expect(t, http.StatusCreated)
+	var created struct {
+		PostID uint `json:"post_id"`
+	}
+	res.decode(t, &created)
+
+	post := getPost(t, h, created.PostID)
+	if post.Content != "Resumen del primer parcial" || post.User.Username != "elena" {
+		t.Fatalf("post inesperado: %+v", post)
+	}
+	if len(post.Tags) != 1 || post.Tags[0].Name != "parciales" {
+		t.Fatalf("tags inesperados: %+v", post.Tags)
+	}
+	if len(post.Files) != 1 || post.Files[0].FileName != "resumen.pdf" {
+		t.Fatalf("archivos inesperados: %+v", post.Files)
+	}
+	if data, ok := h.storage.file(post.Files[0].FileURL); !ok || string(data) != "%PDF-1.4 contenido" {
+		t.Fatalf("el archivo no llegó al almacenamiento: %q", data)
+	}
+}
+
+func TestCreatePostRejectsUnknownTag(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "elena")
+
+	h.asUser(t, author.UserID).multipart(t, "/posts", map[string]string{
+		"content":       "Post con tag inexistente",
+		"university_id": fmt.Sprint(h.universityID),
+		"career_id":     fmt.Sprint(h.careerID),
+		"tag_ids":       "[999]",
+	}, nil).expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	var count int64
+	h.db.Table("posts").Count(&count)
+	if count != 0 {
+		t.Fatalf("quedaron %d posts creados", count)
+	}
+}
+
+func TestListPostsIsPaginated(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "fede")
+	for i := 1; i <= 6; i++ {
+		h.createPost(t, author.UserID, fmt.Sprintf("Post %d", i))
+	}
+
+	var page struct {
+		Posts      []postBody `json:"posts"`
+		Pagination struct {
+			TotalPages int   `json:"total_pages"`
+			TotalItems int64 `json:"total_items"`
+		} `json:"pagination"`
+	}
+	h.anonymous().get(t, "/posts?page=1").expect(t, http.StatusOK).decode(t, &page)
+	if len(page.Posts) != 5 || page.Pagination.TotalItems != 6 || page.Pagination.TotalPages != 2 {
+		t.Fatalf("paginación inesperada: %d posts, %+v", len(page.Posts), page.Pagination)
+	}
+	if page.Posts[0].Content != "Post 6" {
+		t.Fatalf("el primer post es %q, se esperaba el más nuevo", page.Posts[0].Content)
+	}
+
+	h.anonymous().get(t, "/posts?page=2").expect(t, http.StatusOK).decode(t, &page)
+	if len(page.Posts) != 1 {
+		t.Fatalf("la página 2 tiene %d posts, se esperaba 1", len(page.Posts))
+	}
+}
+
+func TestLikeAndCommentPost(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "gabi")
+	reader := h.createUser(t, "hugo")
+	postID := h.createPost(t, author.UserID, "Apuntes de álgebra")
+	as := h.asUser(t, reader.UserID)
+
+	as.post(t, fmt.Sprintf("/posts/%d/likes", postID), nil).expect(t, http.StatusOK)
+	as.post(t, fmt.Sprintf("/posts/%d/comments", postID), gin.H{"content": "¡Gracias!"}).expect(t, http.StatusOK)
+
+	post := getPost(t, h, postID)
+	if len(post.Likes) != 1 || post.Likes[0].UserID != reader.UserID {
+		t.Fatalf("likes inesperados: %+v", post.Likes)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].Content != "¡Gracias!" {
+		t.Fatalf("comentarios inesperados: %+v", post.Comments)
+	}
+
+	// El segundo like lo quita
+	as.post(t, fmt.Sprintf("/posts/%d/likes", postID), nil).expect(t, http.StatusOK)
+	if post := getPost(t, h, postID); len(post.Likes) != 0 {
+		t.Fatalf("el like no se quitó: %+v", post.Likes)
+	}
+
+	as.post(t, "/posts/999/likes", nil).expectError(t, http.StatusNotFound, "POST_NOT_FOUND")
+}
+
+func TestOnlyAuthorCanEditPost(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "ines")
+	other := h.createUser(t, "juan")
+	postID := h.createPost(t, author.UserID, "Versión original")
+	path := fmt.Sprintf("/posts/%d", postID)
+
+	h.asUser(t, other.UserID).put(t, path, gin.H{"content": "Editado por otro"}).expectError(t, http.StatusForbidden, "FORBIDDEN")
+	h.asUser(t, author.UserID).put(t, path, gin.H{"content": "Versión corregida"}).expect(t, http.StatusOK)
+
+	if post := getPost(t, h, postID); post.Content != "Versión corregida" {
+		t.Fatalf("contenido %q, se esperaba la versión corregida", post.Content)
+	}
+}
+
+func TestDeletePostRemovesRelations(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "karen")
+	reader := h.createUser(t, "lucas")
+	postID := h.createPost(t, author.UserID, "Post a borrar")
+	path := fmt.Sprintf("/posts/%d", postID)
+
+	h.asUser(t, reader.UserID).post(t, path+"/likes", nil).expect(t, http.StatusOK)
+	h.asUser(t, reader.UserID).post(t, path+"/comments", gin.H{"content": "Comentario"}).expect(t, http.StatusOK)
+
+	h.asUser(t, reader.UserID).delete(t, path).expectError(t, http.StatusForbidden, "FORBIDDEN")
+	h.asUser(t, author.UserID).delete(t, path).expect(t, http.StatusOK)
+
+	h.anonymous().get(t, path).expectError(t, http.StatusNotFound, "POST_NOT_FOUND")
+	for _, table := range []string{"comments", "post_likes"} {
+		var count int64
+		h.db.Table(table).Where("post_id = ?", postID).Count(&count)
+		if count != 0 {
+			t.Fatalf("quedaron %d filas en %s", count, table)
+		}
+	}
+}
+
+func TestDeletePostRemovesStoredFiles(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "mateo")
+
+	res := h.asUser(t, author.UserID).multipart(t, "/posts", map[string]string{
+		"content":       "Apunte con archivo",
+		"university_id": fmt.Sprint(h.universityID),
+		"career_id":     fmt.Sprint(h.careerID),
+	}, map[string]string{"apunte.pdf": "%PDF-1.4"})
+	res.expect(t, http.StatusCreated)
+	var created struct {
+		PostID uint `json:"post_id"`
+	}
+	res.decode(t, &created)
+	url := getPost(t, h, created.PostID).Files[0].FileURL
+
+	h.asUser(t, author.UserID).delete(t, fmt.Sprintf("/posts/%d", created.PostID)).expect(t, http.StatusOK)
+	if _, ok := h.storage.file(url); ok {
+		t.Fatalf("el archivo %s quedó en el almacenamiento", url)
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	h := newHarness(t)
+	author := h.createUser(t, "mora")
+	h.createPost(t, author.UserID, "Final de Física II")
+	h.createPost(t, author.UserID, "Parcial de Química")
+
+	var result struct {
+		Posts []postBody `json:"posts"`
+	}
+	h.anonymous().get(t, "/posts/search?q=final").expect(t, http.StatusOK).decode(t, &result)
+	if len(result.Posts) != 1 || result.Posts[0].Content != "Final de Física II" {
+		t.Fatalf("resultado inesperado: %+v", result.Posts)
+	}
+
+	h.anonymous().get(t, "/posts/search?university=abc").expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+}
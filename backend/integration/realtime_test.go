@@ -0,0 +1,400 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/realtime"
+	"github.com/LautaroRomano/repositorio-tecnologico/services"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+)
+
+// sseMessage es un mensaje recibido por un stream de eventos
+type sseMessage struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// eventStream lee los mensajes de un stream de Server-Sent Events
+type eventStream struct {
+	res      *http.Response
+	messages chan sseMessage
+}
+
+// openEventStream abre el stream de eventos del usuario contra un servidor
+// real, autenticado por query como lo hace EventSource
+func openEventStream(t *testing.T, server *httptest.Server, userID uint, query, lastEventID string) *eventStream {
+	t.Helper()
+	token, err := utils.GenerateJWT(userID)
+	if err != nil {
+		t.Fatalf("generando token: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/channels/events?access_token="+token+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("abriendo el stream: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status %d abriendo el stream", res.StatusCode)
+	}
+
+	stream := &eventStream{res: res, messages: make(chan sseMessage, 16)}
+	go func() {
+		defer close(stream.messages)
+		var msg sseMessage
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if msg.Event != "" {
+					stream.messages <- msg
+				}
+				msg = sseMessage{}
+			case strings.HasPrefix(line, "id: "):
+				msg.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				msg.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				msg.Data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return stream
+}
+
+// next espera el próximo mensaje del stream
+func (s *eventStream) next(t *testing.T) sseMessage {
+	t.Helper()
+	select {
+	case msg, ok := <-s.messages:
+		if !ok {
+			t.Fatal("el stream se cerró")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no llegó ningún evento")
+	}
+	return sseMessage{}
+}
+
+// expectEvent espera el próximo mensaje y verifica su tipo
+func (s *eventStream) expectEvent(t *testing.T, eventType string) realtime.Event {
+	t.Helper()
+	msg := s.next(t)
+	if msg.Event != eventType {
+		t.Fatalf("evento %q, se esperaba %q: %s", msg.Event, eventType, msg.Data)
+	}
+	var event realtime.Event
+	if err := json.Unmarshal([]byte(msg.Data), &event); err != nil {
+		t.Fatalf("decodificando %s: %v", msg.Data, err)
+	}
+	if msg.ID != fmt.Sprint(event.ID) {
+		t.Fatalf("id %q distinto del evento %d", msg.ID, event.ID)
+	}
+	return event
+}
+
+func TestChannelEventStream(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "raul")
+	member := h.createUser(t, "sara")
+	outsider := h.createUser(t, "tomas")
+	channelID := h.createChannel(t, owner.UserID, "Redes")
+	otherID := h.createChannel(t, outsider.UserID, "Compiladores")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+
+	h.asUser(t, outsider.UserID).get(t, fmt.Sprintf("/channels/events?channel_id=%d", channelID)).
+		expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	h.anonymous().get(t, "/channels/events").expectError(t, http.StatusUnauthorized, "UNAUTHENTICATED")
+
+	// Se cierra después que los streams, que se cierran en t.Cleanup
+	server := httptest.NewServer(h.router)
+	t.Cleanup(server.Close)
+
+	stream := openEventStream(t, server, member.UserID, "", "")
+	if ready := stream.next(t); ready.Event != "ready" || ready.Data != fmt.Sprintf(`{"channels":[%d]}`, channelID) {
+		t.Fatalf("mensaje inicial inesperado: %+v", ready)
+	}
+
+	// Los eventos de canales ajenos no llegan
+	h.asUser(t, outsider.UserID).post(t, fmt.Sprintf("/channels/%d/posts", otherID), gin.H{"content": "Ajeno"}).
+		expect(t, http.StatusCreated)
+
+	var created struct {
+		Post models.ChannelPost `json:"post"`
+	}
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/%d/posts", channelID), gin.H{"content": "Parcial el lunes"}).
+		expect(t, http.StatusCreated).
+		decode(t, &created)
+	first := stream.expectEvent(t, services.ChannelEventPostCreated)
+	var post models.ChannelPost
+	if err := json.Unmarshal(first.Data, &post); err != nil || post.PostID != created.Post.PostID || first.ChannelID != channelID {
+		t.Fatalf("evento inesperado: %+v", first)
+	}
+
+	postPath := fmt.Sprintf("/channels/posts/%d", created.Post.PostID)
+	h.asUser(t, owner.UserID).post(t, postPath+"/comments", gin.H{"content": "¿Qué temas?"}).expect(t, http.StatusCreated)
+	stream.expectEvent(t, services.ChannelEventCommentCreated)
+	h.asUser(t, owner.UserID).post(t, postPath+"/like", nil).expect(t, http.StatusOK)
+	stream.expectEvent(t, services.ChannelEventLikeAdded)
+	h.asUser(t, owner.UserID).delete(t, postPath).expect(t, http.StatusOK)
+	stream.expectEvent(t, services.ChannelEventPostDeleted)
+
+	// Al reconectar con el último evento recibido llegan los que se perdió
+	stream.res.Body.Close()
+	resumed := openEventStream(t, server, member.UserID, fmt.Sprintf("&channel_id=%d", channelID), fmt.Sprint(first.ID))
+	resumed.next(t)
+	for _, eventType := range []string{services.ChannelEventCommentCreated, services.ChannelEventLikeAdded, services.ChannelEventPostDeleted} {
+		resumed.expectEvent(t, eventType)
+	}
+
+	// Un ID que el broker no conoce pide recargar
+	stale := openEventStream(t, server, member.UserID, "", "999999")
+	stale.next(t)
+	if msg := stale.next(t); msg.Event != "resync" {
+		t.Fatalf("se esperaba resync: %+v", msg)
+	}
+}
+
+func TestPostgresBroker(t *testing.T) {
+	h := newHarness(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Dos réplicas sobre la misma base
+	publisher := realtime.NewPostgresBroker(h.db, time.Hour)
+	replica := realtime.NewPostgresBroker(h.db, time.Hour)
+	defer publisher.Close()
+	defer replica.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		replica.Run(ctx)
+	}()
+	defer func() { cancel(); <-done }()
+
+	sub := replica.Subscribe([]uint{1})
+	defer sub.Close()
+
+	// Los avisos llegan desde que la réplica escucha; se reintenta hasta que
+	// el primero se entrega
+	var first realtime.Event
+	deadline := time.After(5 * time.Second)
+	for first.ID == 0 {
+		published, err := publisher.Publish(ctx, 1, "post.created", gin.H{"post_id": 7})
+		if err != nil {
+			t.Fatalf("publicando: %v", err)
+		}
+		select {
+		case event := <-sub.Events:
+			first = event
+			if event.ID > published.ID {
+				t.Fatalf("evento inesperado: %+v", event)
+			}
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("la réplica no recibió el evento")
+		}
+	}
+
+	if _, err := publisher.Publish(ctx, 2, "post.created", gin.H{"post_id": 8}); err != nil {
+		t.Fatal(err)
+	}
+	last, err := publisher.Publish(ctx, 1, "like.added", gin.H{"post_id": 7, "user_id": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, complete, err := replica.Replay(ctx, []uint{1}, first.ID)
+	if err != nil || !complete {
+		t.Fatalf("replay: %v, completo %v", err, complete)
+	}
+	if len(events) == 0 || events[len(events)-1].ID != last.ID || string(events[len(events)-1].Data) != `{"post_id": 7, "user_id": 3}` {
+		t.Fatalf("eventos inesperados: %+v", events)
+	}
+	for _, event := range events {
+		if event.ChannelID != 1 {
+			t.Fatalf("evento de otro canal: %+v", event)
+		}
+	}
+	if _, complete, _ := replica.Replay(ctx, []uint{1}, last.ID+100); complete {
+		t.Fatal("un ID desconocido debería pedir recargar")
+	}
+}
+
+// Un evento confirmado después de LISTEN pero antes de catchUp llega por los
+// dos lados al volver a escuchar y se tiene que entregar una sola vez
+func TestPostgresBrokerDeliversOnceAfterReconnect(t *testing.T) {
+	h := newHarness(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisher := realtime.NewPostgresBroker(h.db, time.Hour)
+	replica := realtime.NewPostgresBroker(h.db, time.Hour)
+	defer publisher.Close()
+	defer replica.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		replica.Run(ctx)
+	}()
+	defer func() { cancel(); <-done }()
+
+	sub := replica.Subscribe([]uint{1})
+	defer sub.Close()
+	expect := func(id uint64) {
+		t.Helper()
+		select {
+		case event := <-sub.Events:
+			if event.ID != id {
+				t.Fatalf("evento %d, se esperaba %d", event.ID, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no llegó el evento %d", id)
+		}
+	}
+
+	// Se publica hasta que la réplica escucha
+	deadline := time.After(5 * time.Second)
+	for listening := false; !listening; {
+		if _, err := publisher.Publish(ctx, 1, "post.created", gin.H{"post_id": 1}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-sub.Events:
+			listening = true
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("la réplica no recibió ningún evento")
+		}
+	}
+	for drained := false; !drained; {
+		select {
+		case <-sub.Events:
+		case <-time.After(300 * time.Millisecond):
+			drained = true
+		}
+	}
+
+	// Con la tabla bloqueada, la réplica vuelve a escuchar y su catchUp espera
+	// al bloqueo. El evento se confirma con LISTEN ya activo, así que también
+	// llega su aviso.
+	tx := h.db.Begin()
+	defer tx.Rollback()
+	if err := tx.Exec("LOCK TABLE channel_events IN ACCESS EXCLUSIVE MODE").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := h.db.Exec("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query = 'LISTEN channel_events'").Error; err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.Now().Add(10 * time.Second)
+	for {
+		var waiting int64
+		err := h.db.Raw("SELECT count(*) FROM pg_locks WHERE NOT granted AND relation = 'channel_events'::regclass").Scan(&waiting).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		if waiting > 0 {
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatal("la réplica no volvió a escuchar")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	during, err := publisher.PublishTx(tx, 1, "post.created", json.RawMessage(`{"post_id": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := publisher.Publish(ctx, 1, "post.created", gin.H{"post_id": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(during.ID)
+	expect(after.ID)
+}
+
+// Una publicación que empieza después de otra todavía sin confirmar no puede
+// confirmarse antes: si lo hiciera, quien viera su ID pediría después
+// "event_id > ID" y perdería el evento anterior.
+func TestPostgresBrokerCommitsInOrder(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	publisher := realtime.NewPostgresBroker(h.db, time.Hour)
+	defer publisher.Close()
+
+	before, err := publisher.Publish(ctx, 1, "post.created", gin.H{"post_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// La primera publicación queda abierta dentro de su transacción
+	tx := h.db.Begin()
+	defer tx.Rollback()
+	slow, err := publisher.PublishTx(tx, 1, "post.created", json.RawMessage(`{"post_id": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	published := make(chan realtime.Event, 1)
+	go func() {
+		event, err := publisher.Publish(ctx, 1, "post.created", gin.H{"post_id": 3})
+		if err != nil {
+			t.Errorf("publicando: %v", err)
+		}
+		published <- event
+	}()
+
+	select {
+	case event := <-published:
+		t.Fatalf("la segunda publicación se confirmó antes que la primera: %+v", event)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// Mientras tanto nadie ve eventos posteriores a before
+	events, _, err := publisher.Replay(ctx, []uint{1}, before.ID)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("replay con una publicación abierta: %+v, %v", events, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+	var fast realtime.Event
+	select {
+	case fast = <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("la segunda publicación no terminó")
+	}
+	if fast.ID <= slow.ID {
+		t.Fatalf("IDs fuera de orden: %d antes que %d", fast.ID, slow.ID)
+	}
+
+	events, complete, err := publisher.Replay(ctx, []uint{1}, before.ID)
+	if err != nil || !complete || len(events) != 2 || events[0].ID != slow.ID || events[1].ID != fast.ID {
+		t.Fatalf("replay: %+v, completo %v, %v", events, complete, err)
+	}
+}
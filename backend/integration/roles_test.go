@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+// grantAdmin otorga el rol de administrador global al usuario
+func grantAdmin(t *testing.T, user models.User) models.UserRole {
+	t.Helper()
+	role, err := rbac.Grant(context.Background(), user.UserID, rbac.RoleAdmin, nil, nil)
+	if err != nil {
+		t.Fatalf("otorgando admin a %s: %v", user.Username, err)
+	}
+	return role
+}
+
+func TestRevokeRoleKeepsTheLastAdmin(t *testing.T) {
+	h := newHarness(t)
+	ana := h.createUser(t, "ana")
+	beto := h.createUser(t, "beto")
+	anaRole := grantAdmin(t, ana)
+	betoRole := grantAdmin(t, beto)
+
+	h.asUser(t, ana.UserID).delete(t, fmt.Sprintf("/admin/users/%d/roles/999", beto.UserID)).
+		expectError(t, http.StatusNotFound, "ROLE_NOT_FOUND")
+
+	// Cada uno le quita el rol al otro a la vez: solo una baja puede pasar. La
+	// otra choca con el último administrador o, si llega después, su autor ya
+	// no tiene permiso.
+	statuses := make(chan int, 2)
+	var wg sync.WaitGroup
+	for _, revoke := range []struct {
+		actor models.User
+		role  models.UserRole
+	}{{ana, betoRole}, {beto, anaRole}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := h.asUser(t, revoke.actor.UserID).delete(t, fmt.Sprintf("/admin/users/%d/roles/%d", revoke.role.UserID, revoke.role.UserRoleID))
+			statuses <- res.Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	got := map[int]int{}
+	for status := range statuses {
+		got[status]++
+	}
+	if got[http.StatusOK] != 1 || got[http.StatusConflict]+got[http.StatusForbidden] != 1 {
+		t.Fatalf("respuestas inesperadas: %v", got)
+	}
+
+	var admins int64
+	h.db.Model(&models.UserRole{}).Where("role = ?", rbac.RoleAdmin).Count(&admins)
+	if admins != 1 {
+		t.Fatalf("quedaron %d administradores", admins)
+	}
+}
+
+func TestConcurrentGrantsReportAlreadyGranted(t *testing.T) {
+	h := newHarness(t)
+	admin := h.createUser(t, "carla")
+	grantAdmin(t, admin)
+	user := h.createUser(t, "dario")
+	path := fmt.Sprintf("/admin/users/%d/roles", user.UserID)
+	body := gin.H{"role": rbac.RoleModerator, "university_id": h.universityID}
+
+	const grants = 5
+	statuses := make(chan int, grants)
+	var wg sync.WaitGroup
+	for i := 0; i < grants; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- h.asUser(t, admin.UserID).post(t, path, body).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	got := map[int]int{}
+	for status := range statuses {
+		got[status]++
+	}
+	if got[http.StatusCreated] != 1 || got[http.StatusConflict] != grants-1 {
+		t.Fatalf("respuestas inesperadas: %v", got)
+	}
+	h.asUser(t, admin.UserID).post(t, path, body).expectError(t, http.StatusConflict, "ROLE_ALREADY_GRANTED")
+	h.asUser(t, admin.UserID).post(t, path, gin.H{"role": rbac.RoleAdmin, "university_id": h.universityID}).
+		expectError(t, http.StatusBadRequest, "VALIDATION_FAILED")
+	h.asUser(t, admin.UserID).post(t, fmt.Sprintf("/admin/users/%d/roles", user.UserID+100), body).
+		expectError(t, http.StatusNotFound, "USER_NOT_FOUND")
+}
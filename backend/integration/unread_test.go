@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+// unreadCounts es lo que devuelve GET /channels sobre lo no leído
+type unreadCounts struct {
+	Unread map[string]struct {
+		Posts    int64 `json:"posts"`
+		Comments int64 `json:"comments"`
+	} `json:"unread"`
+	UnreadTotal int64 `json:"unread_total"`
+}
+
+func getUnread(t *testing.T, h *harness, userID uint) unreadCounts {
+	t.Helper()
+	var body unreadCounts
+	h.asUser(t, userID).get(t, "/channels").expect(t, http.StatusOK).decode(t, &body)
+	return body
+}
+
+// channelPostList es lo que devuelve GET /channels/:id/posts
+type channelPostList struct {
+	Posts             []models.ChannelPost `json:"posts"`
+	FirstUnreadPostID *uint                `json:"first_unread_post_id"`
+	UnreadPosts       int                  `json:"unread_posts"`
+}
+
+func TestChannelUnreadCounts(t *testing.T) {
+	h := newHarness(t)
+	owner := h.createUser(t, "ulises")
+	member := h.createUser(t, "vera")
+	outsider := h.createUser(t, "walter")
+	channelID := h.createChannel(t, owner.UserID, "Sistemas Operativos")
+	h.addMember(t, channelID, owner.UserID, member.UserID)
+	key := fmt.Sprint(channelID)
+	postsPath := fmt.Sprintf("/channels/%d/posts", channelID)
+
+	publish := func(content string) uint {
+		var created struct {
+			Post models.ChannelPost `json:"post"`
+		}
+		h.asUser(t, owner.UserID).post(t, postsPath, gin.H{"content": content}).expect(t, http.StatusCreated).decode(t, &created)
+		return created.Post.PostID
+	}
+	first := publish("Guía 1")
+	publish("Guía 2")
+	h.asUser(t, owner.UserID).post(t, fmt.Sprintf("/channels/posts/%d/comments", first), gin.H{"content": "Corregida"}).
+		expect(t, http.StatusCreated)
+
+	// Lo propio no cuenta como no leído
+	if own := getUnread(t, h, owner.UserID); own.UnreadTotal != 0 {
+		t.Fatalf("el autor tiene no leídos: %+v", own)
+	}
+	unread := getUnread(t, h, member.UserID)
+	if unread.Unread[key].Posts != 2 || unread.Unread[key].Comments != 1 || unread.UnreadTotal != 3 {
+		t.Fatalf("no leídos inesperados: %+v", unread)
+	}
+
+	var list channelPostList
+	h.asUser(t, member.UserID).get(t, postsPath).expect(t, http.StatusOK).decode(t, &list)
+	if list.FirstUnreadPostID == nil || *list.FirstUnreadPostID != first || list.UnreadPosts != 2 {
+		t.Fatalf("límite de no leídos inesperado: %+v", list)
+	}
+
+	readPath := fmt.Sprintf("/channels/%d/read", channelID)
+	h.asUser(t, outsider.UserID).post(t, readPath, nil).expectError(t, http.StatusForbidden, "CHANNEL_ACCESS_DENIED")
+	h.asUser(t, member.UserID).post(t, readPath, nil).expect(t, http.StatusOK)
+	if unread := getUnread(t, h, member.UserID); unread.UnreadTotal != 0 {
+		t.Fatalf("quedaron no leídos después de marcar: %+v", unread)
+	}
+	h.asUser(t, member.UserID).get(t, postsPath).expect(t, http.StatusOK).decode(t, &list)
+	if list.FirstUnreadPostID != nil {
+		t.Fatalf("el canal leído tiene límite: %+v", list)
+	}
+
+	third := publish("Guía 3")
+	h.asUser(t, member.UserID).get(t, postsPath).expect(t, http.StatusOK).decode(t, &list)
+	if list.FirstUnreadPostID == nil || *list.FirstUnreadPostID != third || list.UnreadPosts != 1 {
+		t.Fatalf("límite de no leídos inesperado: %+v", list)
+	}
+	if unread := getUnread(t, h, member.UserID); unread.UnreadTotal != 1 {
+		t.Fatalf("no leídos inesperados: %+v", unread)
+	}
+}
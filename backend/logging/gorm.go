@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold es a partir de cuánto una consulta se registra como lenta
+const slowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger envía los errores y las consultas lentas de GORM al logger del
+// contexto (con el request ID cuando la consulta usa WithContext)
+type GormLogger struct {
+	Level gormlogger.LogLevel
+}
+
+func NewGormLogger() *GormLogger {
+	return &GormLogger{Level: gormlogger.Warn}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &GormLogger{Level: level}
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Info {
+		FromContext(ctx).Info(msg, "args", args)
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Warn {
+		FromContext(ctx).Warn(msg, "args", args)
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Error {
+		FromContext(ctx).Error(msg, "args", args)
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	// No encontrar un registro es un resultado normal, lo decide el llamador
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.Level >= gormlogger.Error:
+		sql, rows := fc()
+		FromContext(ctx).Error("Error en consulta SQL", "error", err, "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds())
+	case elapsed > slowQueryThreshold && l.Level >= gormlogger.Warn:
+		sql, rows := fc()
+		FromContext(ctx).Warn("Consulta SQL lenta", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds())
+	case l.Level >= gormlogger.Info:
+		sql, rows := fc()
+		FromContext(ctx).Debug("Consulta SQL", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds())
+	}
+}
+
+// ParamsFilter deja los parámetros fuera del SQL registrado: pueden incluir
+// hashes de contraseñas, tokens o datos personales
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,81 @@
+// Package logging configura el logger estructurado (log/slog) del backend y
+// lo asocia a cada pedido para que todas las líneas lleven su request ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formatos de salida soportados
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type ctxKey struct{}
+
+// Setup crea el logger con el nivel (debug, info, warn, error) y el formato
+// indicados y lo deja como logger por defecto, también para el paquete log
+func Setup(level, format string) error {
+	logger, err := New(os.Stdout, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// New crea un logger que escribe en w
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("nivel de log inválido: %s", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("formato de log inválido: %s", format)
+	}
+}
+
+// WithLogger devuelve un contexto que lleva el logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext devuelve el logger del pedido (con su request ID y usuario) o
+// el logger por defecto si el contexto no tiene uno. Acepta directamente el
+// *gin.Context de los handlers.
+func FromContext(ctx context.Context) *slog.Logger {
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return slog.Default()
+		}
+		ctx = c.Request.Context()
+	}
+	if ctx != nil {
+		if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// With agrega atributos al logger del pedido, por ejemplo el usuario una vez
+// autenticado
+func With(c *gin.Context, args ...any) {
+	logger := FromContext(c).With(args...)
+	c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), logger))
+}
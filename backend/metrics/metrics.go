@@ -0,0 +1,125 @@
+// Package metrics define las métricas de Prometheus que expone /metrics
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "repositorio"
+
+// Resultados posibles en las etiquetas status de las métricas que no son HTTP
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Pedidos HTTP atendidos por ruta y código de respuesta.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duración de los pedidos HTTP por ruta y código de respuesta.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_queries_total",
+		Help:      "Consultas ejecutadas por GORM por operación y tabla.",
+	}, []string{"operation", "table", "status"})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duración de las consultas de GORM por operación y tabla.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	Uploads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_total",
+		Help:      "Archivos subidos por backend de almacenamiento y resultado.",
+	}, []string{"backend", "status"})
+
+	UploadBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes subidos correctamente por backend de almacenamiento.",
+	}, []string{"backend"})
+
+	UploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Duración de las subidas por backend de almacenamiento.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"backend", "status"})
+
+	EmailsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_sent_total",
+		Help:      "Emails enviados por tipo y resultado.",
+	}, []string{"kind", "status"})
+
+	PostsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "Posts creados, en el feed general o en canales.",
+	}, []string{"kind"})
+
+	Registrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Usuarios registrados por método de alta.",
+	}, []string{"method"})
+
+	ChannelInvites = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_invites_total",
+		Help:      "Invitaciones a canales enviadas.",
+	})
+
+	ChannelJoins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_joins_total",
+		Help:      "Usuarios que entraron a un canal, por vía de entrada.",
+	}, []string{"via"})
+)
+
+// Status traduce un error a la etiqueta status
+func Status(err error) string {
+	if err != nil {
+		return StatusError
+	}
+	return StatusOK
+}
+
+// ObserveUpload registra una subida al backend de almacenamiento indicado
+func ObserveUpload(backend string, size int64, start time.Time, err error) {
+	status := Status(err)
+	Uploads.WithLabelValues(backend, status).Inc()
+	UploadDuration.WithLabelValues(backend, status).Observe(time.Since(start).Seconds())
+	if err == nil {
+		UploadBytes.WithLabelValues(backend).Add(float64(size))
+	}
+}
+
+// ObserveEmail registra el resultado de un envío de email
+func ObserveEmail(kind string, err error) {
+	EmailsSent.WithLabelValues(kind, Status(err)).Inc()
+}
+
+// RegisterDBStats expone las estadísticas del pool de conexiones. Se puede
+// llamar una sola vez por proceso.
+func RegisterDBStats(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, "postgres"))
+}
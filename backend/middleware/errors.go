@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+// Errors escribe la respuesta de los errores que los handlers registran con
+// apperror.Abort. Si el handler ya respondió no hace nada.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		apperror.Render(c, c.Errors.Last().Err)
+	}
+}
+
+// NoRoute responde ROUTE_NOT_FOUND con el mismo formato que el resto de los
+// errores
+func NoRoute(c *gin.Context) {
+	apperror.Abort(c, apperror.New(apperror.CodeRouteNotFound))
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader es el encabezado con el que se propaga el ID del pedido
+const RequestIDHeader = "X-Request-ID"
+
+// Un request ID recibido solo se acepta si es corto y no tiene caracteres que
+// puedan romper los logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID asigna a cada pedido un ID (o respeta el que llega en
+// X-Request-ID), lo devuelve en la respuesta y deja en el contexto un logger
+// que lo incluye en cada línea
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		logger := slog.Default().With("request_id", requestID)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog registra una línea por pedido con la ruta, el estado, la latencia
+// y el usuario autenticado. Debe ir después de RequestID.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"ip", c.ClientIP(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		// El logger del pedido ya lleva el request ID y, si el pedido se
+		// autenticó, el user_id (ver AuthMiddleware)
+		logger := logging.FromContext(c)
+		status := c.Writer.Status()
+		switch {
+		case status >= 500:
+			logger.Error("Pedido HTTP", attrs...)
+		case status >= 400:
+			logger.Warn("Pedido HTTP", attrs...)
+		default:
+			logger.Info("Pedido HTTP", attrs...)
+		}
+	}
+}
+
+// Recovery responde 500 ante un panic y lo registra con el request ID
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		logging.FromContext(c).Error("Panic en handler", "panic", err, "route", c.FullPath())
+		apperror.Render(c, apperror.New(apperror.CodeInternal))
+	})
+}
@@ -1,13 +1,12 @@
 package middleware
 
 import (
-	"fmt"
-	"net/http"
-	"os"
 	"strings"
 
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // AuthMiddleware verifica el token JWT y agrega el ID del usuario al contexto
@@ -16,46 +15,47 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Obtener el token del header Authorization
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Se requiere token de autorización"})
+			apperror.Abort(c, apperror.New(apperror.CodeUnauthenticated))
 			return
 		}
 
 		// El formato esperado es "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Formato de token inválido"})
+			apperror.Abort(c, apperror.New(apperror.CodeInvalidToken))
 			return
 		}
 
-		tokenString := parts[1]
-
-		// Validar el token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Verificar el método de firma
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
-			}
-
-			// Usar la clave secreta para validar
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		authenticate(c, parts[1])
+	}
+}
 
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token inválido: " + err.Error()})
+// StreamAuthMiddleware es AuthMiddleware para los streams de eventos: como
+// EventSource no permite enviar encabezados, el token también se acepta en el
+// parámetro access_token
+func StreamAuthMiddleware() gin.HandlerFunc {
+	auth := AuthMiddleware()
+	return func(c *gin.Context) {
+		token := c.Query("access_token")
+		if c.GetHeader("Authorization") != "" || token == "" {
+			auth(c)
 			return
 		}
+		authenticate(c, token)
+	}
+}
 
-		// Verificar que el token sea válido
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Extraer el ID del usuario y agregarlo al contexto
-			userID := uint(claims["user_id"].(float64))
-			c.Set("userID", userID)
-
-			// Continuar con el siguiente handler
-			c.Next()
-		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
-			return
-		}
+// authenticate valida el token JWT y agrega el ID del usuario al contexto
+func authenticate(c *gin.Context, tokenString string) {
+	// Validar el token con el llavero de claves
+	claims, err := utils.ParseJWT(tokenString)
+	if err != nil {
+		apperror.Abort(c, apperror.New(apperror.CodeInvalidToken).Wrap(err))
+		return
 	}
+
+	// Agregar el ID del usuario al contexto y continuar con el siguiente handler
+	c.Set("userID", claims.UserID)
+	logging.With(c, "user_id", claims.UserID)
+	c.Next()
 }
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission exige que el usuario autenticado tenga el permiso de forma
+// global. Debe usarse después de AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			apperror.Abort(c, apperror.New(apperror.CodeUnauthenticated))
+			return
+		}
+
+		allowed, err := rbac.Can(userID.(uint), permission, nil)
+		if err != nil {
+			apperror.Abort(c, apperror.Internal(err))
+			return
+		}
+		if !allowed {
+			uid := userID.(uint)
+			security.Audit(c, models.AuditLog{
+				ActorID: &uid,
+				Action:  security.AuditPermissionDenied,
+				IP:      c.ClientIP(),
+				Details: permission + " " + c.Request.Method + " " + c.FullPath(),
+			})
+			apperror.Abort(c, apperror.New(apperror.CodeForbidden))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// TraceIDHeader devuelve el trace ID para poder buscar la traza de un pedido
+const TraceIDHeader = "X-Trace-ID"
+
+// Rutas que se consultan todo el tiempo y no aportan nada en las trazas. Los
+// streams de eventos quedan abiertos durante horas y darían spans eternos.
+var untracedRoutes = map[string]bool{
+	"/healthz":         true,
+	"/readyz":          true,
+	"/metrics":         true,
+	"/channels/events": true,
+}
+
+// Tracing crea un span por pedido, continuando la traza si llega un
+// encabezado traceparent
+func Tracing(serviceName string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !untracedRoutes[c.FullPath()]
+	}))
+}
+
+// TraceID expone el trace ID en X-Trace-ID y lo agrega al logger del pedido.
+// Debe ir después de Tracing y de RequestID.
+func TraceID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+			c.Header(TraceIDHeader, traceID)
+			logging.With(c, "trace_id", traceID)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// AuditLog registra eventos sensibles de seguridad (intentos fallidos de login,
+// bloqueos de cuenta, cambios de permisos, etc.)
+type AuditLog struct {
+	AuditID   uint   `gorm:"primaryKey"`
+	UserID    *uint  `gorm:"index"` // cuenta afectada, si se conoce
+	ActorID   *uint  // usuario que realizó la acción, si está autenticado
+	Action    string `gorm:"type:varchar(64);not null;index"`
+	IP        string `gorm:"type:varchar(64)"`
+	Details   string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+// AuthThrottle cuenta intentos recientes por clave (IP o cuenta) para aplicar
+// backoff exponencial y bloqueos temporales
+type AuthThrottle struct {
+	Key           string `gorm:"primaryKey;type:varchar(255)"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
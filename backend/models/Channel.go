@@ -1,6 +1,18 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Roles de los miembros de un canal, de mayor a menor
+const (
+	ChannelRoleOwner     = "owner"
+	ChannelRoleModerator = "moderator"
+	ChannelRoleMember    = "member"
+	ChannelRoleReadOnly  = "readonly"
+)
 
 type Channel struct {
 	ChannelID    uint   `gorm:"primaryKey"`
@@ -11,6 +23,9 @@ type Channel struct {
 	IsPrivate    bool `gorm:"default:false"`
 	UniversityID uint `gorm:"not null"`
 	CareerID     uint `gorm:"not null"`
+	// ArchivedAt marca el canal como de solo lectura; nil si está activo
+	ArchivedAt  *time.Time
+	Permissions ChannelPermissions `gorm:"embedded;embeddedPrefix:perm_"`
 
 	Creator     User       `gorm:"foreignKey:CreatedBy"`
 	University  University `gorm:"foreignKey:UniversityID"`
@@ -20,11 +35,21 @@ type Channel struct {
 	Posts       []ChannelPost
 }
 
+// ChannelPermissions guarda, para cada acción configurable, el rol mínimo que
+// necesita un miembro del canal para hacerla
+type ChannelPermissions struct {
+	Post        string `gorm:"type:varchar(20);not null;default:'member'"`
+	Comment     string `gorm:"type:varchar(20);not null;default:'member'"`
+	Invite      string `gorm:"type:varchar(20);not null;default:'moderator'"`
+	Pin         string `gorm:"type:varchar(20);not null;default:'moderator'"`
+	DeletePosts string `gorm:"type:varchar(20);not null;default:'moderator'"`
+}
+
 type ChannelMember struct {
-	MemberID   uint `gorm:"primaryKey"`
-	ChannelID  uint `gorm:"not null"`
-	UserID     uint `gorm:"not null"`
-	IsAdmin    bool `gorm:"default:false"`
+	MemberID   uint   `gorm:"primaryKey"`
+	ChannelID  uint   `gorm:"not null"`
+	UserID     uint   `gorm:"not null"`
+	Role       string `gorm:"type:varchar(20);not null;default:'member'"` // owner, moderator, member, readonly
 	JoinedAt   time.Time
 	LastSeenAt time.Time
 
@@ -37,7 +62,7 @@ type ChannelInvitation struct {
 	ChannelID    uint   `gorm:"not null"`
 	InvitedBy    uint   `gorm:"not null"`
 	InvitedUser  uint   `gorm:"not null"`
-	Status       string `gorm:"type:varchar(20);default:'pending'"` // pending, accepted, rejected
+	Status       string `gorm:"type:varchar(20);default:'pending'"` // pending, accepted, rejected, cancelled
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 
@@ -46,6 +71,68 @@ type ChannelInvitation struct {
 	Invitee User    `gorm:"foreignKey:InvitedUser"`
 }
 
+// ChannelJoinRequest es el pedido de un usuario para entrar a un canal
+// privado; lo aprueba o rechaza un administrador del canal
+type ChannelJoinRequest struct {
+	RequestID  uint   `gorm:"primaryKey"`
+	ChannelID  uint   `gorm:"not null"`
+	UserID     uint   `gorm:"not null"`
+	Message    string `gorm:"type:text"`
+	Status     string `gorm:"type:varchar(20);default:'pending'"` // pending, approved, denied
+	ReviewedBy *uint
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	Channel Channel `gorm:"foreignKey:ChannelID"`
+	User    User    `gorm:"foreignKey:UserID"`
+}
+
+// ChannelBan impide que el usuario vuelva a entrar al canal
+type ChannelBan struct {
+	BanID     uint `gorm:"primaryKey"`
+	ChannelID uint `gorm:"not null"`
+	UserID    uint `gorm:"not null"`
+	BannedBy  *uint
+	Reason    string `gorm:"type:text"`
+	CreatedAt time.Time
+
+	Channel Channel `gorm:"foreignKey:ChannelID"`
+	User    User    `gorm:"foreignKey:UserID"`
+}
+
+// ChannelInviteLink es un enlace para entrar al canal con un código. Email
+// solo está en las invitaciones enviadas a personas sin cuenta, que solo
+// puede canjear esa dirección.
+type ChannelInviteLink struct {
+	LinkID    uint   `gorm:"primaryKey"`
+	ChannelID uint   `gorm:"not null"`
+	Code      string `gorm:"type:varchar(32);not null;unique"`
+	Role      string `gorm:"type:varchar(20);not null;default:'member'"`
+	MaxUses   *int   // nil para usos ilimitados
+	Uses      int    `gorm:"not null;default:0"`
+	ExpiresAt *time.Time
+	Email     *string
+	CreatedBy *uint
+	RevokedAt *time.Time
+	CreatedAt time.Time
+
+	Channel Channel `gorm:"foreignKey:ChannelID"`
+	Creator *User   `gorm:"foreignKey:CreatedBy"`
+}
+
+// ChannelAuditLog registra un cambio hecho en un canal. ChannelID no tiene
+// clave foránea para conservar el registro de canales borrados.
+type ChannelAuditLog struct {
+	LogID     uint   `gorm:"primaryKey"`
+	ChannelID uint   `gorm:"not null"`
+	ActorID   *uint  // nil si el usuario que hizo el cambio ya no existe
+	Action    string `gorm:"type:varchar(64);not null"`
+	Details   string `gorm:"type:text"`
+	CreatedAt time.Time
+
+	Actor *User `gorm:"foreignKey:ActorID"`
+}
+
 type ChannelPost struct {
 	PostID    uint   `gorm:"primaryKey"`
 	ChannelID uint   `gorm:"not null"`
@@ -53,13 +140,32 @@ type ChannelPost struct {
 	Content   string `gorm:"type:text;not null"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Tags      []string `gorm:"type:text[]"`
+	// EditedAt es la última vez que el autor cambió el contenido o los tags
+	EditedAt *time.Time
+	// PinnedAt es nil si el post no está fijado
+	PinnedAt       *time.Time
+	PinnedBy       *uint
+	IsAnnouncement bool `gorm:"not null;default:false"`
 
 	Channel  Channel              `gorm:"foreignKey:ChannelID"`
 	User     User                 `gorm:"foreignKey:UserID"`
 	Comments []ChannelPostComment `gorm:"foreignKey:PostID"`
 	Likes    []ChannelPostLike    `gorm:"foreignKey:PostID"`
 	Files    []ChannelPostFile    `gorm:"foreignKey:PostID"`
+	Tags     []Tag                `gorm:"many2many:channel_post_tags;foreignKey:PostID;joinForeignKey:post_id;References:TagID;joinReferences:tag_id"`
+}
+
+// ChannelPostEdit guarda el contenido y los nombres de los tags que tenía un
+// post antes de una edición
+type ChannelPostEdit struct {
+	EditID    uint           `gorm:"primaryKey"`
+	PostID    uint           `gorm:"not null"`
+	EditorID  *uint          // nil si el usuario que editó ya no existe
+	Content   string         `gorm:"type:text;not null"`
+	Tags      pq.StringArray `gorm:"type:text[]"`
+	CreatedAt time.Time
+
+	Editor *User `gorm:"foreignKey:EditorID"`
 }
 
 type ChannelPostComment struct {
@@ -83,12 +189,17 @@ type ChannelPostLike struct {
 	User User        `gorm:"foreignKey:UserID"`
 }
 
+// ChannelPostFile es un archivo adjunto a un post de canal. Se guarda como
+// privado: FileURL no se expone y se descarga con DownloadURL, un enlace
+// firmado para el usuario que vence a los pocos minutos.
 type ChannelPostFile struct {
 	FileID   uint   `gorm:"primaryKey"`
 	PostID   uint   `gorm:"not null"`
-	FileURL  string `gorm:"not null"`
+	FileURL  string `gorm:"not null" json:"-"`
 	FileType string `gorm:"not null"`
 	FileName string `gorm:"not null"`
 
+	DownloadURL string `gorm:"-"`
+
 	Post ChannelPost `gorm:"foreignKey:PostID"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserRole asigna un rol a un usuario. Todos los usuarios tienen implícitamente
+// el rol "user"; acá solo se guardan los roles adicionales. Un moderador puede
+// estar limitado a una universidad (UniversityID) o ser global (nil).
+type UserRole struct {
+	UserRoleID   uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"not null;index"`
+	Role         string `gorm:"type:varchar(32);not null"`
+	UniversityID *uint
+	GrantedBy    *uint
+	CreatedAt    time.Time
+
+	User       User        `gorm:"foreignKey:UserID" json:"-"`
+	University *University `gorm:"foreignKey:UniversityID"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SigningKey es una clave del llavero usado para firmar los JWT. Se guarda en la
+// base de datos para que todas las réplicas compartan las mismas claves.
+type SigningKey struct {
+	KeyID      string `gorm:"primaryKey;type:varchar(64)"` // kid del header del JWT
+	Algorithm  string `gorm:"type:varchar(16);not null"`   // EdDSA o RS256
+	PrivateKey string `gorm:"type:text;not null" json:"-"` // PEM PKCS#8 cifrado con AES-GCM
+	PublicKey  string `gorm:"type:text;not null"`          // PEM PKIX
+	CreatedAt  time.Time
+	// SignFrom es el momento a partir del cual la clave firma tokens: antes
+	// solo se publica en el JWKS
+	SignFrom time.Time `gorm:"not null"`
+	// SignUntil es el momento a partir del cual la clave deja de firmar tokens
+	SignUntil time.Time `gorm:"not null"`
+	// VerifyUntil es el momento a partir del cual la clave deja de publicarse en
+	// el JWKS; debe cubrir la duración del último token firmado con ella
+	VerifyUntil time.Time `gorm:"not null;index"`
+}
@@ -20,3 +20,16 @@ type PostTag struct {
 func (PostTag) TableName() string {
 	return "post_tags"
 }
+
+type ChannelPostTag struct {
+	PostID uint `gorm:"primaryKey;column:post_id"`
+	TagID  uint `gorm:"primaryKey;column:tag_id"`
+
+	Post ChannelPost `gorm:"foreignKey:PostID"`
+	Tag  Tag         `gorm:"foreignKey:TagID"`
+}
+
+// TableName especifica el nombre de la tabla para ChannelPostTag
+func (ChannelPostTag) TableName() string {
+	return "channel_post_tags"
+}
@@ -7,22 +7,26 @@ import (
 )
 
 type User struct {
-	UserID               uint       `json:"user_id" gorm:"primaryKey"`
-	Username             string     `json:"username" gorm:"unique"`
-	Email                string     `json:"email" gorm:"unique"`
-	PasswordHash         string     `json:"-"`
-	AccountName          string     `json:"account_name"`
-	Img                  string     `json:"img"`
-	ResetPasswordToken   string     `json:"-"`
-	ResetPasswordExpires time.Time  `json:"-"`
-	CreatedAt            time.Time  `json:"created_at"`
-	UpdatedAt            time.Time  `json:"updated_at"`
-	UniversityID         uint       `gorm:"foreignKey:UniversityID"`
-	CareerID             uint       `gorm:"foreignKey:CareerID"`
-	University           University `gorm:"foreignKey:UniversityID"`
-	Career               Career     `gorm:"foreignKey:CareerID"`
-	Followers            []Follow   `gorm:"foreignKey:FollowedID"`
-	Following            []Follow   `gorm:"foreignKey:FollowerID"`
+	UserID   uint   `json:"user_id" gorm:"primaryKey"`
+	Username string `json:"username" gorm:"unique"`
+	Email    string `json:"email" gorm:"unique"`
+	// EmailCanonical es el email sin mayúsculas ni "+etiqueta", para que una
+	// misma casilla no pueda registrar varias cuentas
+	EmailCanonical *string `json:"-" gorm:"uniqueIndex"`
+	// EmailVerifiedAt es nil mientras no se compruebe que el usuario controla
+	// el email
+	EmailVerifiedAt *time.Time `json:"-"`
+	PasswordHash    string     `json:"-"`
+	AccountName     string     `json:"account_name"`
+	Img             string     `json:"img"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	UniversityID    uint       `gorm:"foreignKey:UniversityID"`
+	CareerID        uint       `gorm:"foreignKey:CareerID"`
+	University      University `gorm:"foreignKey:UniversityID"`
+	Career          Career     `gorm:"foreignKey:CareerID"`
+	Followers       []Follow   `gorm:"foreignKey:FollowedID"`
+	Following       []Follow   `gorm:"foreignKey:FollowerID"`
 
 	Posts    []Post    `gorm:"foreignKey:UserID"`
 	Comments []Comment `gorm:"foreignKey:UserID"`
@@ -35,6 +39,31 @@ type Follow struct {
 	CreatedAt  time.Time
 }
 
+// UserIdentity vincula un usuario con una cuenta de un proveedor OIDC externo
+type UserIdentity struct {
+	IdentityID uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Provider   string `gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject    string `gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Email      string
+	CreatedAt  time.Time
+
+	User User `gorm:"foreignKey:UserID"`
+}
+
+// PasswordResetToken es un token de recuperación de contraseña. Solo se guarda
+// el hash SHA-256 del token y se invalida al usarse una vez.
+type PasswordResetToken struct {
+	TokenID   uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	TokenHash string `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+
+	User User `gorm:"foreignKey:UserID"`
+}
+
 func (u *User) SetPassword(password string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
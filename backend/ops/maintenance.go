@@ -0,0 +1,91 @@
+package ops
+
+import (
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"gorm.io/gorm"
+)
+
+// ReindexSearch recalcula la columna tsv de todos los posts a partir del
+// contenido y los nombres de sus tags. Devuelve cuántos posts actualizó.
+func ReindexSearch() (int64, error) {
+	result := database.DB.Exec(`
+		UPDATE posts p
+		SET tsv = to_tsvector('spanish', p.content || ' ' || COALESCE((
+			SELECT string_agg(t.name, ' ')
+			FROM post_tags pt
+			JOIN tags t ON t.tag_id = pt.tag_id
+			WHERE pt.post_id = p.post_id
+		), ''))`)
+	return result.RowsAffected, result.Error
+}
+
+// purgeTarget es un tipo de fila que ya no se usa y puede borrarse
+type purgeTarget struct {
+	name  string
+	table string
+	where string
+	// olderThan indica si la condición usa el límite de antigüedad
+	olderThan bool
+}
+
+var purgeTargets = []purgeTarget{
+	{
+		name:      "tokens de recuperación usados o vencidos",
+		table:     "password_reset_tokens",
+		where:     "(used_at IS NOT NULL OR expires_at < now()) AND created_at < ?",
+		olderThan: true,
+	},
+	{
+		name:      "contadores de intentos de login sin actividad",
+		table:     "auth_throttles",
+		where:     "last_failure_at < ? AND (locked_until IS NULL OR locked_until < now())",
+		olderThan: true,
+	},
+	{
+		name:  "claves de firma JWT retiradas",
+		table: "signing_keys",
+		where: "verify_until < now()",
+	},
+	{
+		name:      "invitaciones a canales respondidas",
+		table:     "channel_invitations",
+		where:     "status <> 'pending' AND updated_at < ?",
+		olderThan: true,
+	},
+}
+
+// PurgeDeleted borra las filas que quedaron sin uso (tokens consumidos,
+// claves retiradas, invitaciones respondidas) con más antigüedad que
+// olderThan. Con dryRun solo las cuenta. Devuelve la cantidad por tipo.
+func PurgeDeleted(olderThan time.Duration, dryRun bool) (map[string]int64, error) {
+	cutoff := time.Now().Add(-olderThan)
+	counts := map[string]int64{}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, target := range purgeTargets {
+			var args []interface{}
+			if target.olderThan {
+				args = append(args, cutoff)
+			}
+
+			if dryRun {
+				var count int64
+				if err := tx.Table(target.table).Where(target.where, args...).Count(&count).Error; err != nil {
+					return err
+				}
+				counts[target.name] = count
+				continue
+			}
+
+			result := tx.Exec("DELETE FROM "+target.table+" WHERE "+target.where, args...)
+			if result.Error != nil {
+				return result.Error
+			}
+			counts[target.name] = result.RowsAffected
+		}
+		return nil
+	})
+	return counts, err
+}
@@ -0,0 +1,118 @@
+// Package ops reúne las tareas de operación que se ejecutan desde la línea de
+// comandos (ver cmd admin) en lugar de escribir SQL a mano.
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+	"gorm.io/gorm"
+)
+
+var ErrUserNotFound = errors.New("usuario no encontrado")
+
+// NewUser son los datos de un usuario creado por un operador
+type NewUser struct {
+	Username     string
+	Email        string
+	Password     string
+	AccountName  string
+	UniversityID uint
+	CareerID     uint
+}
+
+// CreateUser crea un usuario aplicando las mismas validaciones que el registro
+func CreateUser(input NewUser) (*models.User, error) {
+	username := validation.NormalizeUsername(input.Username)
+	email, canonicalEmail, emailErr := validation.NormalizeEmail(input.Email)
+
+	var errs validation.Errors
+	errs.Merge(validation.Username(username))
+	errs.Merge(emailErr)
+	errs.Merge(validation.AccountName(input.AccountName))
+	errs.Merge(validation.Password("password", input.Password, username, email))
+	if errs.Has() {
+		return nil, errs
+	}
+
+	var taken int64
+	if err := database.DB.Model(&models.User{}).
+		Where("LOWER(username) = ? OR LOWER(email) = ? OR email_canonical = ?", username, email, canonicalEmail).
+		Count(&taken).Error; err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, errors.New("ya existe un usuario con ese nombre de usuario o email")
+	}
+
+	user := models.User{
+		Username:       username,
+		Email:          email,
+		EmailCanonical: &canonicalEmail,
+		AccountName:    strings.TrimSpace(input.AccountName),
+		UniversityID:   input.UniversityID,
+		CareerID:       input.CareerID,
+	}
+	if err := user.SetPassword(input.Password); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindUser busca un usuario por email o nombre de usuario
+func FindUser(identifier string) (*models.User, error) {
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+
+	var user models.User
+	result := database.DB.Where("LOWER(email) = ? OR LOWER(username) = ?", identifier, identifier).Limit(1).Find(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// ResetPassword cambia la contraseña de un usuario, invalida sus tokens de
+// recuperación pendientes y desbloquea su cuenta
+func ResetPassword(ctx context.Context, user *models.User, password string) error {
+	if fe := validation.Password("password", password, user.Username, user.Email); fe != nil {
+		return validation.Errors{*fe}
+	}
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password_hash", user.PasswordHash).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.UserID).
+			Update("used_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := security.Reset(fmt.Sprintf("login:user:%d", user.UserID)); err != nil {
+		return err
+	}
+	security.Audit(ctx, models.AuditLog{
+		UserID:  &user.UserID,
+		Action:  security.AuditPasswordReset,
+		Details: "contraseña restablecida desde la línea de comandos",
+	})
+	return nil
+}
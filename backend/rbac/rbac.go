@@ -0,0 +1,169 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+)
+
+// Roles disponibles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permisos que se verifican en los controladores
+const (
+	PermTagsManage       = "tags.manage"
+	PermPostsModerate    = "posts.moderate"
+	PermCommentsModerate = "comments.moderate"
+	PermChannelsModerate = "channels.moderate"
+	PermRolesManage      = "roles.manage"
+	PermAuditRead        = "audit.read"
+)
+
+// rolePermissions define qué permisos otorga cada rol. El administrador tiene
+// todos los permisos, por eso no figura en el mapa.
+var rolePermissions = map[string][]string{
+	RoleUser: {},
+	RoleModerator: {
+		PermPostsModerate,
+		PermCommentsModerate,
+		PermChannelsModerate,
+	},
+}
+
+var (
+	ErrInvalidRole        = errors.New("rol inválido")
+	ErrScopedAdmin        = errors.New("el rol de administrador no puede limitarse a una universidad")
+	ErrRoleAlreadyGranted = repository.ErrRoleAlreadyGranted
+)
+
+// ValidRole indica si el rol puede asignarse (el rol "user" es implícito)
+func ValidRole(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
+}
+
+// RoleHasPermission indica si el rol otorga el permiso
+func RoleHasPermission(role, permission string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles devuelve los roles asignados al usuario
+func Roles(userID uint) ([]models.UserRole, error) {
+	var roles []models.UserRole
+	err := database.DB.Where("user_id = ?", userID).Preload("University").Find(&roles).Error
+	return roles, err
+}
+
+// Can indica si el usuario tiene el permiso. Si universityID no es nil también
+// cuentan los roles limitados a esa universidad; si es nil solo cuentan los
+// roles globales.
+func Can(userID uint, permission string, universityID *uint) (bool, error) {
+	roles, err := Roles(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range roles {
+		if !RoleHasPermission(r.Role, permission) {
+			continue
+		}
+		if r.UniversityID == nil {
+			return true, nil
+		}
+		if universityID != nil && *r.UniversityID == *universityID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CanInUniversity es un atajo de Can para recursos que pertenecen a una universidad
+func CanInUniversity(ctx context.Context, userID uint, permission string, universityID uint) bool {
+	ok, err := Can(userID, permission, &universityID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error verificando permiso", "permission", permission, "user_id", userID, "error", err)
+		return false
+	}
+	return ok
+}
+
+// CheckGrant verifica que el rol se pueda otorgar con ese alcance. Los
+// moderadores pueden limitarse a una universidad; los administradores siempre
+// son globales.
+func CheckGrant(role string, universityID *uint) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if role == RoleAdmin && universityID != nil {
+		return ErrScopedAdmin
+	}
+	return nil
+}
+
+// Grant otorga un rol a un usuario desde la línea de comandos o el arranque;
+// la API usa services.RoleService. Devuelve ErrRoleAlreadyGranted si ya lo
+// tiene con el mismo alcance. No audita: cada llamador registra la acción con
+// su contexto.
+func Grant(ctx context.Context, userID uint, role string, universityID *uint, grantedBy *uint) (models.UserRole, error) {
+	if err := CheckGrant(role, universityID); err != nil {
+		return models.UserRole{}, err
+	}
+	granted := models.UserRole{
+		UserID:       userID,
+		Role:         role,
+		UniversityID: universityID,
+		GrantedBy:    grantedBy,
+	}
+	if err := repository.NewRoleRepository(database.DB).Create(ctx, &granted); err != nil {
+		return models.UserRole{}, err
+	}
+	return granted, nil
+}
+
+// BootstrapAdmins otorga el rol de administrador a los usuarios con los emails
+// indicados si todavía no lo tienen. Permite crear el primer administrador.
+func BootstrapAdmins(ctx context.Context, emails []string) {
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+
+		var user models.User
+		if err := database.DB.WithContext(ctx).Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+			logging.FromContext(ctx).Warn("No se encontró el administrador inicial", "email", email)
+			continue
+		}
+
+		if _, err := Grant(ctx, user.UserID, RoleAdmin, nil, nil); err != nil {
+			if errors.Is(err, ErrRoleAlreadyGranted) {
+				continue
+			}
+			logging.FromContext(ctx).Error("Error otorgando rol de administrador", "email", email, "error", err)
+			continue
+		}
+		security.Audit(ctx, models.AuditLog{
+			UserID:  &user.UserID,
+			Action:  security.AuditRoleGranted,
+			Details: "rol admin otorgado por ADMIN_EMAILS",
+		})
+		logging.FromContext(ctx).Info("Rol de administrador otorgado", "email", email)
+	}
+}
@@ -0,0 +1,80 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// MemoryBroker guarda los últimos eventos en memoria. Solo sirve cuando hay
+// un único nodo: los eventos no se comparten entre procesos y se pierden al
+// reiniciar.
+type MemoryBroker struct {
+	hub *hub
+
+	mu     sync.Mutex
+	lastID uint64
+	// recent son los últimos eventos publicados, del más viejo al más nuevo
+	recent []Event
+	size   int
+}
+
+// NewMemoryBroker crea un MemoryBroker que conserva los últimos size eventos
+// para Replay
+func NewMemoryBroker(size int) *MemoryBroker {
+	return &MemoryBroker{hub: newHub(), size: size}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, channelID uint, eventType string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	b.mu.Lock()
+	b.lastID++
+	event := Event{ID: b.lastID, ChannelID: channelID, Type: eventType, Data: payload, CreatedAt: time.Now()}
+	b.recent = append(b.recent, event)
+	if len(b.recent) > b.size {
+		b.recent = b.recent[len(b.recent)-b.size:]
+	}
+	// Se entrega con el lock tomado para que los suscriptores reciban los
+	// eventos en el orden de sus ID
+	b.hub.dispatch(event)
+	b.mu.Unlock()
+	return event, nil
+}
+
+func (b *MemoryBroker) Subscribe(channelIDs []uint) *Subscription {
+	return b.hub.subscribe(channelIDs)
+}
+
+func (b *MemoryBroker) Replay(ctx context.Context, channelIDs []uint, afterID uint64) ([]Event, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Un ID mayor al último es de antes de un reinicio
+	if afterID > b.lastID {
+		return nil, false, nil
+	}
+	complete := len(b.recent) == 0 || b.recent[0].ID <= afterID+1
+
+	channels := channelSet(channelIDs)
+	var events []Event
+	for _, event := range b.recent {
+		if event.ID <= afterID || !channels[event.ChannelID] {
+			continue
+		}
+		if len(events) == ReplayLimit {
+			return events, false, nil
+		}
+		events = append(events, event)
+	}
+	return events, complete, nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.hub.close()
+	return nil
+}
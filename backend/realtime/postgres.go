@@ -0,0 +1,300 @@
+package realtime
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// notifyChannel es el canal de LISTEN/NOTIFY por el que se avisa el ID de
+// cada evento nuevo
+const notifyChannel = "channel_events"
+
+// publishLock serializa las publicaciones: el ID de un evento se asigna y se
+// confirma sin que otra publicación se intercale, así los eventos se vuelven
+// visibles en el orden de sus ID. Sin esto una transacción con un ID menor
+// podría confirmarse después de una con un ID mayor, y quien ya vio el mayor
+// (una réplica al volver a escuchar o un cliente con Last-Event-ID) pediría
+// "event_id > último" y no vería nunca el menor.
+const publishLock = "channel_events_publish"
+
+// cleanupInterval es cada cuánto se borran los eventos más viejos que la
+// retención
+const cleanupInterval = time.Hour
+
+// PostgresBroker guarda los eventos en la tabla channel_events y avisa a
+// todas las réplicas con NOTIFY. Cada réplica escucha en una conexión propia,
+// lee el evento avisado y lo entrega a sus suscriptores. Replay lee de la
+// tabla, así que un cliente puede reconectarse a cualquier réplica.
+type PostgresBroker struct {
+	db        *gorm.DB
+	hub       *hub
+	retention time.Duration
+
+	mu sync.Mutex
+	// lastID es el mayor evento entregado; al reconectar se entregan los
+	// publicados mientras no se escuchaba
+	lastID uint64
+}
+
+// NewPostgresBroker crea un PostgresBroker sobre db que conserva los eventos
+// durante retention. Los eventos de otras réplicas se reciben mientras corre
+// Run.
+func NewPostgresBroker(db *gorm.DB, retention time.Duration) *PostgresBroker {
+	return &PostgresBroker{db: db, hub: newHub(), retention: retention}
+}
+
+// eventRow es una fila de channel_events
+type eventRow struct {
+	EventID   uint64
+	ChannelID uint
+	Type      string
+	Data      string
+	CreatedAt time.Time
+}
+
+func (r eventRow) event() Event {
+	return Event{ID: r.EventID, ChannelID: r.ChannelID, Type: r.Type, Data: json.RawMessage(r.Data), CreatedAt: r.CreatedAt}
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, channelID uint, eventType string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	var event Event
+	err = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		event, err = b.PublishTx(tx, channelID, eventType, payload)
+		return err
+	})
+	if err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+// PublishTx guarda el evento dentro de la transacción tx, que tiene que
+// confirmarse cuanto antes: hasta entonces las demás publicaciones esperan.
+// NOTIFY se envía al confirmarla, cuando el evento ya es visible para las
+// demás réplicas.
+func (b *PostgresBroker) PublishTx(tx *gorm.DB, channelID uint, eventType string, payload json.RawMessage) (Event, error) {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", publishLock).Error; err != nil {
+		return Event{}, err
+	}
+	var row eventRow
+	err := tx.Raw(
+		"INSERT INTO channel_events (channel_id, type, data, created_at) VALUES (?, ?, CAST(? AS jsonb), now()) RETURNING event_id, channel_id, type, data::text AS data, created_at",
+		channelID, eventType, string(payload),
+	).Scan(&row).Error
+	if err != nil {
+		return Event{}, err
+	}
+	if err := tx.Exec("SELECT pg_notify(?, ?)", notifyChannel, strconv.FormatUint(row.EventID, 10)).Error; err != nil {
+		return Event{}, err
+	}
+	return row.event(), nil
+}
+
+func (b *PostgresBroker) Subscribe(channelIDs []uint) *Subscription {
+	return b.hub.subscribe(channelIDs)
+}
+
+func (b *PostgresBroker) Replay(ctx context.Context, channelIDs []uint, afterID uint64) ([]Event, bool, error) {
+	if len(channelIDs) == 0 {
+		return nil, true, nil
+	}
+
+	var bounds struct {
+		MinID uint64
+		MaxID uint64
+	}
+	err := b.db.WithContext(ctx).
+		Raw("SELECT COALESCE(MIN(event_id), 0) AS min_id, COALESCE(MAX(event_id), 0) AS max_id FROM channel_events").
+		Scan(&bounds).Error
+	if err != nil {
+		return nil, false, err
+	}
+	// Un ID mayor al último es de otra base; uno anterior al más viejo
+	// conservado puede haber perdido eventos en la limpieza
+	if afterID > bounds.MaxID {
+		return nil, false, nil
+	}
+	complete := bounds.MinID <= afterID+1
+
+	var rows []eventRow
+	err = b.db.WithContext(ctx).
+		Raw("SELECT event_id, channel_id, type, data::text AS data, created_at FROM channel_events WHERE event_id > ? AND channel_id IN ? ORDER BY event_id LIMIT ?",
+			afterID, channelIDs, ReplayLimit+1).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(rows) > ReplayLimit {
+		rows = rows[:ReplayLimit]
+		complete = false
+	}
+
+	events := make([]Event, len(rows))
+	for i, row := range rows {
+		events[i] = row.event()
+	}
+	return events, complete, nil
+}
+
+// Close corta las suscripciones de esta réplica. Run termina al cancelar su
+// contexto.
+func (b *PostgresBroker) Close() error {
+	b.hub.close()
+	return nil
+}
+
+// Run escucha los avisos de eventos nuevos hasta que se cancela ctx. Si se
+// pierde la conexión vuelve a escuchar y entrega los eventos publicados
+// mientras tanto. También borra periódicamente los eventos vencidos.
+func (b *PostgresBroker) Run(ctx context.Context) {
+	var maxID uint64
+	err := b.db.WithContext(ctx).Raw("SELECT COALESCE(MAX(event_id), 0) FROM channel_events").Scan(&maxID).Error
+	if err != nil {
+		slog.Error("Error leyendo el último evento de canales", "error", err)
+	}
+	b.mu.Lock()
+	b.lastID = maxID
+	b.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.runCleanup(ctx)
+	}()
+	defer wg.Wait()
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		slog.Warn("Se perdió la escucha de eventos de canales", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// listen toma una conexión del pool para LISTEN y entrega los eventos
+// avisados hasta que falla la conexión o se cancela ctx
+func (b *PostgresBroker) listen(ctx context.Context) error {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var listenErr error
+	// La conexión queda con LISTEN activo, así que se descarta en lugar de
+	// devolverla al pool
+	_ = conn.Raw(func(driverConn interface{}) error {
+		pg, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			listenErr = fmt.Errorf("conexión inesperada %T", driverConn)
+			return driver.ErrBadConn
+		}
+		if _, err := pg.Conn().Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+			listenErr = err
+			return driver.ErrBadConn
+		}
+		b.catchUp(ctx)
+
+		for {
+			notification, err := pg.Conn().WaitForNotification(ctx)
+			if err != nil {
+				listenErr = err
+				return driver.ErrBadConn
+			}
+			id, err := strconv.ParseUint(notification.Payload, 10, 64)
+			if err != nil {
+				slog.Warn("Aviso de evento de canal inválido", "payload", notification.Payload)
+				continue
+			}
+			b.deliver(ctx, "event_id = ?", id)
+		}
+	})
+	return listenErr
+}
+
+// catchUp entrega los eventos publicados mientras no se escuchaba. Como las
+// publicaciones se confirman en el orden de sus ID (ver publishLock), no
+// puede aparecer después uno menor a lastID.
+func (b *PostgresBroker) catchUp(ctx context.Context) {
+	b.mu.Lock()
+	lastID := b.lastID
+	b.mu.Unlock()
+	b.deliver(ctx, "event_id > ?", lastID)
+}
+
+// deliver lee los eventos que cumplen la condición y los entrega a los
+// suscriptores de esta réplica, en el orden de sus ID. Los que no superan
+// lastID ya se entregaron: un evento confirmado entre LISTEN y catchUp llega
+// por catchUp y después por su aviso.
+func (b *PostgresBroker) deliver(ctx context.Context, condition string, args ...interface{}) {
+	var rows []eventRow
+	err := b.db.WithContext(ctx).
+		Raw("SELECT event_id, channel_id, type, data::text AS data, created_at FROM channel_events WHERE "+condition+" ORDER BY event_id", args...).
+		Scan(&rows).Error
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			slog.Error("Error leyendo eventos de canales", "error", err)
+		}
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, row := range rows {
+		if row.EventID <= b.lastID {
+			continue
+		}
+		b.hub.dispatch(row.event())
+		b.lastID = row.EventID
+	}
+}
+
+func (b *PostgresBroker) runCleanup(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result := b.db.WithContext(ctx).Exec("DELETE FROM channel_events WHERE created_at < ?", time.Now().Add(-b.retention))
+		if result.Error != nil {
+			slog.Error("Error borrando eventos de canales vencidos", "error", result.Error)
+		} else if result.RowsAffected > 0 {
+			slog.Info("Eventos de canales vencidos borrados", "count", result.RowsAffected)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package realtime distribuye los eventos de los canales a los clientes
+// conectados. Un Broker publica los eventos y los entrega a las suscripciones
+// de este proceso; MemoryBroker sirve para un solo nodo y PostgresBroker
+// comparte los eventos entre réplicas con LISTEN/NOTIFY.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ReplayLimit es la cantidad máxima de eventos que devuelve Replay. Un cliente
+// que se perdió más eventos tiene que volver a cargar los canales.
+const ReplayLimit = 500
+
+// subscriptionBuffer es cuántos eventos puede tener pendientes una
+// suscripción antes de que se la corte por lenta
+const subscriptionBuffer = 64
+
+// Event es un cambio en un canal. Los ID son crecientes: un cliente que se
+// reconecta pide los eventos posteriores al último que recibió.
+type Event struct {
+	ID        uint64          `json:"id"`
+	ChannelID uint            `json:"channel_id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Broker publica eventos de canales y los entrega a las suscripciones
+type Broker interface {
+	// Publish guarda el evento con data serializada como JSON y lo entrega a
+	// los suscriptores del canal en todos los nodos
+	Publish(ctx context.Context, channelID uint, eventType string, data interface{}) (Event, error)
+	// Subscribe recibe los eventos de los canales publicados desde ahora
+	Subscribe(channelIDs []uint) *Subscription
+	// Replay devuelve, en orden, los eventos de los canales posteriores a
+	// afterID. complete es false si no se puede garantizar que estén todos
+	// porque pasaron más de ReplayLimit o ya se descartaron.
+	Replay(ctx context.Context, channelIDs []uint, afterID uint64) (events []Event, complete bool, err error)
+	// Close corta todas las suscripciones y libera los recursos del broker
+	Close() error
+}
+
+// Subscription recibe los eventos de un conjunto de canales. Events se cierra
+// cuando se cierra el broker o cuando el suscriptor no los consume a tiempo;
+// en ese caso Dropped devuelve true.
+type Subscription struct {
+	Events <-chan Event
+
+	events  chan Event
+	hub     *hub
+	mu      sync.RWMutex
+	filter  map[uint]bool
+	dropped bool
+	closed  bool
+}
+
+// SetChannels reemplaza los canales de la suscripción, por ejemplo cuando el
+// usuario deja de ser miembro de uno
+func (s *Subscription) SetChannels(channelIDs []uint) {
+	filter := channelSet(channelIDs)
+	s.mu.Lock()
+	s.filter = filter
+	s.mu.Unlock()
+}
+
+// Wants indica si la suscripción recibe los eventos del canal
+func (s *Subscription) Wants(channelID uint) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter[channelID]
+}
+
+// Dropped indica si la suscripción se cortó porque no consumía los eventos
+func (s *Subscription) Dropped() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dropped
+}
+
+// Close deja de recibir eventos. Se puede llamar más de una vez.
+func (s *Subscription) Close() {
+	s.hub.remove(s, false)
+}
+
+// hub reparte los eventos entre las suscripciones de este proceso
+type hub struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func newHub() *hub {
+	return &hub{subs: map[*Subscription]struct{}{}}
+}
+
+func (h *hub) subscribe(channelIDs []uint) *Subscription {
+	events := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{Events: events, events: events, hub: h, filter: channelSet(channelIDs)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.closed = true
+		close(events)
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// dispatch entrega el evento sin bloquear; la suscripción que tiene el buffer
+// lleno se corta para que un cliente lento no frene a los demás
+func (h *hub) dispatch(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if !sub.Wants(event.ChannelID) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			h.closeLocked(sub, true)
+		}
+	}
+}
+
+func (h *hub) remove(sub *Subscription, dropped bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeLocked(sub, dropped)
+}
+
+func (h *hub) closeLocked(sub *Subscription, dropped bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	sub.dropped = dropped
+	delete(h.subs, sub)
+	close(sub.events)
+}
+
+// close corta todas las suscripciones; las nuevas nacen cerradas
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		h.closeLocked(sub, false)
+	}
+}
+
+func channelSet(channelIDs []uint) map[uint]bool {
+	set := make(map[uint]bool, len(channelIDs))
+	for _, id := range channelIDs {
+		set[id] = true
+	}
+	return set
+}
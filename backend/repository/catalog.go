@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gorm.io/gorm"
+)
+
+// CatalogRepository resuelve datos de referencia: universidades, carreras y
+// tags
+type CatalogRepository interface {
+	// UniversityName devuelve "" si la universidad no existe
+	UniversityName(ctx context.Context, universityID uint) (string, error)
+	// CareerName devuelve "" si la carrera no existe
+	CareerName(ctx context.Context, careerID uint) (string, error)
+	// CareerInUniversity indica si la carrera existe y pertenece a la universidad
+	CareerInUniversity(ctx context.Context, careerID, universityID uint) (bool, error)
+	// FindTags devuelve los tags existentes entre tagIDs
+	FindTags(ctx context.Context, tagIDs []uint) ([]models.Tag, error)
+}
+
+type catalogRepository struct {
+	db *gorm.DB
+}
+
+// NewCatalogRepository crea un CatalogRepository sobre GORM
+func NewCatalogRepository(db *gorm.DB) CatalogRepository {
+	return catalogRepository{db: db}
+}
+
+func (r catalogRepository) UniversityName(ctx context.Context, universityID uint) (string, error) {
+	var names []string
+	err := conn(ctx, r.db).Model(&models.University{}).
+		Where("university_id = ?", universityID).
+		Pluck("name", &names).Error
+	if err != nil || len(names) == 0 {
+		return "", err
+	}
+	return names[0], nil
+}
+
+func (r catalogRepository) CareerName(ctx context.Context, careerID uint) (string, error) {
+	var names []string
+	err := conn(ctx, r.db).Model(&models.Career{}).
+		Where("career_id = ?", careerID).
+		Pluck("name", &names).Error
+	if err != nil || len(names) == 0 {
+		return "", err
+	}
+	return names[0], nil
+}
+
+func (r catalogRepository) CareerInUniversity(ctx context.Context, careerID, universityID uint) (bool, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&models.Career{}).
+		Where("career_id = ? AND university_id = ?", careerID, universityID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r catalogRepository) FindTags(ctx context.Context, tagIDs []uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(tagIDs) == 0 {
+		return tags, nil
+	}
+	err := conn(ctx, r.db).Where("tag_id IN ?", tagIDs).Find(&tags).Error
+	return tags, err
+}
@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gorm.io/gorm"
+)
+
+// ChannelFilter son los filtros del listado de canales públicos
+type ChannelFilter struct {
+	Query        string
+	UniversityID uint
+	CareerID     uint
+}
+
+// ChannelSummary es un canal público con su cantidad de miembros y la fecha
+// del último post, o nil si todavía no tiene
+type ChannelSummary struct {
+	Channel        models.Channel
+	MemberCount    int64
+	LastActivityAt *time.Time
+}
+
+// UnreadCount es lo que publicaron otros miembros en un canal después de la
+// marca de lectura del usuario
+type UnreadCount struct {
+	ChannelID  uint
+	LastSeenAt time.Time
+	Posts      int64
+	Comments   int64
+}
+
+// ChannelRepository accede a los canales, sus miembros e invitaciones
+type ChannelRepository interface {
+	Create(ctx context.Context, channel *models.Channel) error
+	// Update guarda los campos indicados del canal
+	Update(ctx context.Context, channel *models.Channel, fields map[string]interface{}) error
+	// Delete borra el canal; las claves foráneas borran en cascada miembros,
+	// invitaciones, solicitudes, bloqueos y posts con sus comentarios, likes
+	// y archivos
+	Delete(ctx context.Context, channel *models.Channel) error
+	// FileURLs devuelve las URLs de los archivos de todos los posts del canal
+	FileURLs(ctx context.Context, channelID uint) ([]string, error)
+	FindByID(ctx context.Context, channelID uint) (models.Channel, error)
+	// FindWithDetails precarga creador, universidad, carrera y miembros
+	FindWithDetails(ctx context.Context, channelID uint) (models.Channel, error)
+	// ListForUser devuelve los canales de los que el usuario es miembro
+	ListForUser(ctx context.Context, userID uint) ([]models.Channel, error)
+	// MemberChannelIDs devuelve los ID de los canales de los que el usuario es
+	// miembro
+	MemberChannelIDs(ctx context.Context, userID uint) ([]uint, error)
+	// MemberEmails devuelve los emails de los miembros del canal salvo el
+	// del usuario indicado
+	MemberEmails(ctx context.Context, channelID, exceptUserID uint) ([]string, error)
+	// Discover devuelve una página de canales públicos, los de actividad más
+	// reciente primero, y el total que cumple el filtro
+	Discover(ctx context.Context, filter ChannelFilter, page Page) ([]ChannelSummary, int64, error)
+
+	FindMember(ctx context.Context, channelID, userID uint) (models.ChannelMember, error)
+	// AddMember devuelve ErrAlreadyMember si el usuario ya era miembro
+	AddMember(ctx context.Context, member *models.ChannelMember) error
+	SaveMember(ctx context.Context, member *models.ChannelMember) error
+	// MarkRead adelanta la marca de lectura del miembro hasta at; nunca la
+	// atrasa
+	MarkRead(ctx context.Context, member *models.ChannelMember, at time.Time) error
+	// UnreadCounts devuelve, para cada canal del usuario, los posts y
+	// comentarios de otros posteriores a su marca de lectura
+	UnreadCounts(ctx context.Context, userID uint) ([]UnreadCount, error)
+	RemoveMember(ctx context.Context, member *models.ChannelMember) error
+	// CountAdmins cuenta los miembros que administran el canal: el creador y
+	// los moderadores
+	CountAdmins(ctx context.Context, channelID uint) (int64, error)
+	// SetOwner cambia el creador del canal, que es quien no puede ser
+	// expulsado ni perder la administración
+	SetOwner(ctx context.Context, channel *models.Channel, userID uint) error
+
+	FindInvitation(ctx context.Context, invitationID uint) (models.ChannelInvitation, error)
+	FindPendingInvitation(ctx context.Context, channelID, userID uint) (models.ChannelInvitation, error)
+	// PendingInvitations devuelve las invitaciones pendientes del usuario con
+	// el canal y quien invitó precargados
+	PendingInvitations(ctx context.Context, userID uint) ([]models.ChannelInvitation, error)
+	CreateInvitation(ctx context.Context, invitation *models.ChannelInvitation) error
+	SaveInvitation(ctx context.Context, invitation *models.ChannelInvitation) error
+	// CancelPendingInvitations marca como canceladas las invitaciones
+	// pendientes del usuario al canal
+	CancelPendingInvitations(ctx context.Context, channelID, userID uint) error
+
+	FindJoinRequest(ctx context.Context, requestID uint) (models.ChannelJoinRequest, error)
+	FindPendingJoinRequest(ctx context.Context, channelID, userID uint) (models.ChannelJoinRequest, error)
+	// PendingJoinRequests devuelve las solicitudes pendientes del canal con el
+	// usuario precargado, las más antiguas primero
+	PendingJoinRequests(ctx context.Context, channelID uint) ([]models.ChannelJoinRequest, error)
+	CreateJoinRequest(ctx context.Context, request *models.ChannelJoinRequest) error
+	SaveJoinRequest(ctx context.Context, request *models.ChannelJoinRequest) error
+	// DenyPendingJoinRequests rechaza las solicitudes pendientes del usuario
+	// al canal en nombre de reviewerID
+	DenyPendingJoinRequests(ctx context.Context, channelID, userID, reviewerID uint) error
+
+	FindBan(ctx context.Context, channelID, userID uint) (models.ChannelBan, error)
+	// Bans devuelve los bloqueos del canal con el usuario precargado, los más
+	// recientes primero
+	Bans(ctx context.Context, channelID uint) ([]models.ChannelBan, error)
+	CreateBan(ctx context.Context, ban *models.ChannelBan) error
+	DeleteBan(ctx context.Context, ban *models.ChannelBan) error
+
+	FindInviteLink(ctx context.Context, linkID uint) (models.ChannelInviteLink, error)
+	FindInviteLinkByCode(ctx context.Context, code string) (models.ChannelInviteLink, error)
+	// InviteLinks devuelve los enlaces no revocados del canal con su creador
+	// precargado, los más recientes primero
+	InviteLinks(ctx context.Context, channelID uint) ([]models.ChannelInviteLink, error)
+	CreateInviteLink(ctx context.Context, link *models.ChannelInviteLink) error
+	RevokeInviteLink(ctx context.Context, link *models.ChannelInviteLink) error
+	// UseInviteLink suma un uso al enlace si no alcanzó su límite. Devuelve
+	// false si ya no le quedaban usos; el chequeo y la suma son atómicos.
+	UseInviteLink(ctx context.Context, linkID uint) (bool, error)
+
+	CreateAuditLog(ctx context.Context, entry *models.ChannelAuditLog) error
+	// AuditLogs devuelve una página del registro del canal, lo más reciente
+	// primero, con el autor de cada cambio precargado
+	AuditLogs(ctx context.Context, channelID uint, page Page) ([]models.ChannelAuditLog, int64, error)
+}
+
+type channelRepository struct {
+	db *gorm.DB
+}
+
+// NewChannelRepository crea un ChannelRepository sobre GORM
+func NewChannelRepository(db *gorm.DB) ChannelRepository {
+	return channelRepository{db: db}
+}
+
+func (r channelRepository) Create(ctx context.Context, channel *models.Channel) error {
+	return conn(ctx, r.db).Create(channel).Error
+}
+
+func (r channelRepository) Update(ctx context.Context, channel *models.Channel, fields map[string]interface{}) error {
+	return conn(ctx, r.db).Model(channel).Updates(fields).Error
+}
+
+func (r channelRepository) Delete(ctx context.Context, channel *models.Channel) error {
+	return conn(ctx, r.db).Delete(channel).Error
+}
+
+func (r channelRepository) FileURLs(ctx context.Context, channelID uint) ([]string, error) {
+	var urls []string
+	err := conn(ctx, r.db).Model(&models.ChannelPostFile{}).
+		Joins("JOIN channel_posts ON channel_posts.post_id = channel_post_files.post_id").
+		Where("channel_posts.channel_id = ?", channelID).
+		Pluck("channel_post_files.file_url", &urls).Error
+	return urls, err
+}
+
+func (r channelRepository) FindByID(ctx context.Context, channelID uint) (models.Channel, error) {
+	var channel models.Channel
+	err := conn(ctx, r.db).First(&channel, channelID).Error
+	return channel, err
+}
+
+func (r channelRepository) FindWithDetails(ctx context.Context, channelID uint) (models.Channel, error) {
+	var channel models.Channel
+	err := conn(ctx, r.db).
+		Preload("Creator").
+		Preload("University").
+		Preload("Career").
+		Preload("Members.User").
+		First(&channel, channelID).Error
+	return channel, err
+}
+
+func (r channelRepository) ListForUser(ctx context.Context, userID uint) ([]models.Channel, error) {
+	var channels []models.Channel
+	err := conn(ctx, r.db).
+		Joins("JOIN channel_members ON channels.channel_id = channel_members.channel_id").
+		Where("channel_members.user_id = ?", userID).
+		Preload("Creator").
+		Preload("University").
+		Preload("Career").
+		Find(&channels).Error
+	return channels, err
+}
+
+func (r channelRepository) MemberChannelIDs(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := conn(ctx, r.db).
+		Model(&models.ChannelMember{}).
+		Where("user_id = ?", userID).
+		Order("channel_id").
+		Pluck("channel_id", &ids).Error
+	return ids, err
+}
+
+func (r channelRepository) MemberEmails(ctx context.Context, channelID, exceptUserID uint) ([]string, error) {
+	var emails []string
+	err := conn(ctx, r.db).
+		Model(&models.ChannelMember{}).
+		Joins("JOIN users ON users.user_id = channel_members.user_id").
+		Where("channel_members.channel_id = ? AND channel_members.user_id <> ?", channelID, exceptUserID).
+		Order("users.user_id").
+		Pluck("users.email", &emails).Error
+	return emails, err
+}
+
+func (r channelRepository) Discover(ctx context.Context, filter ChannelFilter, page Page) ([]ChannelSummary, int64, error) {
+	db := conn(ctx, r.db).Model(&models.Channel{}).Where("NOT channels.is_private AND channels.archived_at IS NULL")
+	if filter.Query != "" {
+		like := containsPattern(filter.Query)
+		db = db.Where(`(channels.name ILIKE ? ESCAPE '\' OR channels.description ILIKE ? ESCAPE '\')`, like, like)
+	}
+	if filter.UniversityID != 0 {
+		db = db.Where("channels.university_id = ?", filter.UniversityID)
+	}
+	if filter.CareerID != 0 {
+		db = db.Where("channels.career_id = ?", filter.CareerID)
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var stats []struct {
+		ChannelID      uint
+		MemberCount    int64
+		LastActivityAt *time.Time
+	}
+	err := db.
+		Select(`channels.channel_id,
+			(SELECT COUNT(*) FROM channel_members m WHERE m.channel_id = channels.channel_id) AS member_count,
+			(SELECT MAX(p.created_at) FROM channel_posts p WHERE p.channel_id = channels.channel_id) AS last_activity_at`).
+		Order("last_activity_at DESC NULLS LAST, channels.created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Size).
+		Scan(&stats).Error
+	if err != nil || len(stats) == 0 {
+		return nil, total, err
+	}
+
+	ids := make([]uint, len(stats))
+	for i, s := range stats {
+		ids[i] = s.ChannelID
+	}
+	var channels []models.Channel
+	err = conn(ctx, r.db).
+		Preload("Creator").
+		Preload("University").
+		Preload("Career").
+		Find(&channels, ids).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint]models.Channel, len(channels))
+	for _, channel := range channels {
+		byID[channel.ChannelID] = channel
+	}
+
+	summaries := make([]ChannelSummary, 0, len(stats))
+	for _, s := range stats {
+		if channel, ok := byID[s.ChannelID]; ok {
+			summaries = append(summaries, ChannelSummary{Channel: channel, MemberCount: s.MemberCount, LastActivityAt: s.LastActivityAt})
+		}
+	}
+	return summaries, total, nil
+}
+
+func (r channelRepository) FindMember(ctx context.Context, channelID, userID uint) (models.ChannelMember, error) {
+	var member models.ChannelMember
+	err := conn(ctx, r.db).Where("channel_id = ? AND user_id = ?", channelID, userID).First(&member).Error
+	return member, err
+}
+
+func (r channelRepository) AddMember(ctx context.Context, member *models.ChannelMember) error {
+	err := conn(ctx, r.db).Create(member).Error
+	if isUniqueViolation(err, "uni_channel_members_channel_user") {
+		return ErrAlreadyMember
+	}
+	return err
+}
+
+func (r channelRepository) SaveMember(ctx context.Context, member *models.ChannelMember) error {
+	return conn(ctx, r.db).Save(member).Error
+}
+
+func (r channelRepository) MarkRead(ctx context.Context, member *models.ChannelMember, at time.Time) error {
+	err := conn(ctx, r.db).
+		Model(member).
+		Update("last_seen_at", gorm.Expr("GREATEST(last_seen_at, ?)", at)).Error
+	if err != nil {
+		return err
+	}
+	if at.After(member.LastSeenAt) {
+		member.LastSeenAt = at
+	}
+	return nil
+}
+
+func (r channelRepository) UnreadCounts(ctx context.Context, userID uint) ([]UnreadCount, error) {
+	var counts []UnreadCount
+	err := conn(ctx, r.db).
+		Table("channel_members m").
+		Select(`m.channel_id, m.last_seen_at,
+			(SELECT COUNT(*) FROM channel_posts p
+				WHERE p.channel_id = m.channel_id AND p.user_id <> m.user_id AND p.created_at > m.last_seen_at) AS posts,
+			(SELECT COUNT(*) FROM channel_post_comments c JOIN channel_posts p ON p.post_id = c.post_id
+				WHERE p.channel_id = m.channel_id AND c.user_id <> m.user_id AND c.created_at > m.last_seen_at) AS comments`).
+		Where("m.user_id = ?", userID).
+		Order("m.channel_id").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r channelRepository) RemoveMember(ctx context.Context, member *models.ChannelMember) error {
+	return conn(ctx, r.db).Delete(member).Error
+}
+
+func (r channelRepository) CountAdmins(ctx context.Context, channelID uint) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&models.ChannelMember{}).
+		Where("channel_id = ? AND role IN ?", channelID, []string{models.ChannelRoleOwner, models.ChannelRoleModerator}).
+		Count(&count).Error
+	return count, err
+}
+
+func (r channelRepository) SetOwner(ctx context.Context, channel *models.Channel, userID uint) error {
+	return conn(ctx, r.db).Model(channel).Update("created_by", userID).Error
+}
+
+func (r channelRepository) FindInvitation(ctx context.Context, invitationID uint) (models.ChannelInvitation, error) {
+	var invitation models.ChannelInvitation
+	err := conn(ctx, r.db).First(&invitation, invitationID).Error
+	return invitation, err
+}
+
+func (r channelRepository) FindPendingInvitation(ctx context.Context, channelID, userID uint) (models.ChannelInvitation, error) {
+	var invitation models.ChannelInvitation
+	err := conn(ctx, r.db).
+		Where("channel_id = ? AND invited_user = ? AND status = ?", channelID, userID, "pending").
+		First(&invitation).Error
+	return invitation, err
+}
+
+func (r channelRepository) PendingInvitations(ctx context.Context, userID uint) ([]models.ChannelInvitation, error) {
+	var invitations []models.ChannelInvitation
+	err := conn(ctx, r.db).
+		Where("invited_user = ? AND status = ?", userID, "pending").
+		Preload("Channel").
+		Preload("Inviter").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r channelRepository) CreateInvitation(ctx context.Context, invitation *models.ChannelInvitation) error {
+	return conn(ctx, r.db).Create(invitation).Error
+}
+
+func (r channelRepository) SaveInvitation(ctx context.Context, invitation *models.ChannelInvitation) error {
+	return conn(ctx, r.db).Save(invitation).Error
+}
+
+func (r channelRepository) CancelPendingInvitations(ctx context.Context, channelID, userID uint) error {
+	return conn(ctx, r.db).Model(&models.ChannelInvitation{}).
+		Where("channel_id = ? AND invited_user = ? AND status = ?", channelID, userID, "pending").
+		Updates(map[string]interface{}{"status": "cancelled", "updated_at": time.Now()}).Error
+}
+
+func (r channelRepository) FindJoinRequest(ctx context.Context, requestID uint) (models.ChannelJoinRequest, error) {
+	var request models.ChannelJoinRequest
+	err := conn(ctx, r.db).First(&request, requestID).Error
+	return request, err
+}
+
+func (r channelRepository) FindPendingJoinRequest(ctx context.Context, channelID, userID uint) (models.ChannelJoinRequest, error) {
+	var request models.ChannelJoinRequest
+	err := conn(ctx, r.db).
+		Where("channel_id = ? AND user_id = ? AND status = ?", channelID, userID, "pending").
+		First(&request).Error
+	return request, err
+}
+
+func (r channelRepository) PendingJoinRequests(ctx context.Context, channelID uint) ([]models.ChannelJoinRequest, error) {
+	var requests []models.ChannelJoinRequest
+	err := conn(ctx, r.db).
+		Where("channel_id = ? AND status = ?", channelID, "pending").
+		Preload("User").
+		Order("created_at, request_id").
+		Find(&requests).Error
+	return requests, err
+}
+
+func (r channelRepository) CreateJoinRequest(ctx context.Context, request *models.ChannelJoinRequest) error {
+	return conn(ctx, r.db).Create(request).Error
+}
+
+func (r channelRepository) SaveJoinRequest(ctx context.Context, request *models.ChannelJoinRequest) error {
+	return conn(ctx, r.db).Save(request).Error
+}
+
+func (r channelRepository) DenyPendingJoinRequests(ctx context.Context, channelID, userID, reviewerID uint) error {
+	return conn(ctx, r.db).Model(&models.ChannelJoinRequest{}).
+		Where("channel_id = ? AND user_id = ? AND status = ?", channelID, userID, "pending").
+		Updates(map[string]interface{}{"status": "denied", "reviewed_by": reviewerID, "updated_at": time.Now()}).Error
+}
+
+func (r channelRepository) FindBan(ctx context.Context, channelID, userID uint) (models.ChannelBan, error) {
+	var ban models.ChannelBan
+	err := conn(ctx, r.db).Where("channel_id = ? AND user_id = ?", channelID, userID).First(&ban).Error
+	return ban, err
+}
+
+func (r channelRepository) Bans(ctx context.Context, channelID uint) ([]models.ChannelBan, error) {
+	var bans []models.ChannelBan
+	err := conn(ctx, r.db).
+		Where("channel_id = ?", channelID).
+		Preload("User").
+		Order("created_at DESC").
+		Find(&bans).Error
+	return bans, err
+}
+
+func (r channelRepository) CreateBan(ctx context.Context, ban *models.ChannelBan) error {
+	return conn(ctx, r.db).Create(ban).Error
+}
+
+func (r channelRepository) DeleteBan(ctx context.Context, ban *models.ChannelBan) error {
+	return conn(ctx, r.db).Delete(ban).Error
+}
+
+func (r channelRepository) FindInviteLink(ctx context.Context, linkID uint) (models.ChannelInviteLink, error) {
+	var link models.ChannelInviteLink
+	err := conn(ctx, r.db).First(&link, linkID).Error
+	return link, err
+}
+
+func (r channelRepository) FindInviteLinkByCode(ctx context.Context, code string) (models.ChannelInviteLink, error) {
+	var link models.ChannelInviteLink
+	err := conn(ctx, r.db).Where("code = ?", code).First(&link).Error
+	return link, err
+}
+
+func (r channelRepository) InviteLinks(ctx context.Context, channelID uint) ([]models.ChannelInviteLink, error) {
+	var links []models.ChannelInviteLink
+	err := conn(ctx, r.db).
+		Where("channel_id = ? AND revoked_at IS NULL", channelID).
+		Preload("Creator").
+		Order("created_at DESC").
+		Find(&links).Error
+	return links, err
+}
+
+func (r channelRepository) CreateInviteLink(ctx context.Context, link *models.ChannelInviteLink) error {
+	return conn(ctx, r.db).Create(link).Error
+}
+
+func (r channelRepository) RevokeInviteLink(ctx context.Context, link *models.ChannelInviteLink) error {
+	now := time.Now()
+	if err := conn(ctx, r.db).Model(link).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	link.RevokedAt = &now
+	return nil
+}
+
+func (r channelRepository) UseInviteLink(ctx context.Context, linkID uint) (bool, error) {
+	result := conn(ctx, r.db).Model(&models.ChannelInviteLink{}).
+		Where("link_id = ? AND (max_uses IS NULL OR uses < max_uses)", linkID).
+		Update("uses", gorm.Expr("uses + 1"))
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r channelRepository) CreateAuditLog(ctx context.Context, entry *models.ChannelAuditLog) error {
+	return conn(ctx, r.db).Create(entry).Error
+}
+
+func (r channelRepository) AuditLogs(ctx context.Context, channelID uint, page Page) ([]models.ChannelAuditLog, int64, error) {
+	db := conn(ctx, r.db).Model(&models.ChannelAuditLog{}).Where("channel_id = ?", channelID)
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.ChannelAuditLog
+	err := db.
+		Preload("Actor").
+		Order("created_at DESC, log_id DESC").
+		Offset(page.Offset()).
+		Limit(page.Size).
+		Find(&entries).Error
+	return entries, total, err
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChannelPostFilter son los filtros del listado de posts de un canal. Los
+// campos vacíos no filtran.
+type ChannelPostFilter struct {
+	// Query busca en el contenido y en los nombres de los tags
+	Query string
+	// TagIDs trae los posts que tengan al menos uno de los tags
+	TagIDs []uint
+}
+
+// ChannelPostRepository accede a los posts de los canales y a sus
+// comentarios y likes
+type ChannelPostRepository interface {
+	// ListByChannel devuelve los posts del canal que cumplen el filtro,
+	// primero los fijados (el último fijado primero) y después del más nuevo
+	// al más viejo, con autor, tags, archivos, comentarios y likes
+	// precargados
+	ListByChannel(ctx context.Context, channelID uint, filter ChannelPostFilter) ([]models.ChannelPost, error)
+	// FindByID devuelve el post con sus tags precargados
+	FindByID(ctx context.Context, postID uint) (models.ChannelPost, error)
+	// FindForUpdate es FindByID bloqueando la fila del post hasta el fin de la
+	// transacción de ctx
+	FindForUpdate(ctx context.Context, postID uint) (models.ChannelPost, error)
+	// Create guarda el post y lo recarga con su autor y sus tags. Los tags
+	// tienen que existir: solo se guarda la asociación.
+	Create(ctx context.Context, post *models.ChannelPost) error
+	// Update guarda los campos indicados del post
+	Update(ctx context.Context, post *models.ChannelPost, fields map[string]interface{}) error
+	ReplaceTags(ctx context.Context, post *models.ChannelPost, tags []models.Tag) error
+	// Delete borra el post; comentarios, likes, archivos y ediciones se
+	// borran en cascada
+	Delete(ctx context.Context, post *models.ChannelPost) error
+
+	// CreateEdit guarda una versión anterior del post
+	CreateEdit(ctx context.Context, edit *models.ChannelPostEdit) error
+	// Edits devuelve las versiones anteriores del post, de la más nueva a la
+	// más vieja, con su editor precargado
+	Edits(ctx context.Context, postID uint) ([]models.ChannelPostEdit, error)
+
+	// Pin fija el post si el canal tiene menos de max fijados. Devuelve false
+	// si alcanzó el límite. Bloquea el canal para que dos pedidos simultáneos
+	// no lo superen.
+	Pin(ctx context.Context, post *models.ChannelPost, userID uint, max int) (bool, error)
+	Unpin(ctx context.Context, post *models.ChannelPost) error
+
+	// Files devuelve los archivos del post
+	Files(ctx context.Context, postID uint) ([]models.ChannelPostFile, error)
+	AddFile(ctx context.Context, file *models.ChannelPostFile) error
+	// FindFile busca un archivo con su post precargado
+	FindFile(ctx context.Context, fileID uint) (models.ChannelPostFile, error)
+
+	// CreateComment guarda el comentario y lo recarga con su autor
+	CreateComment(ctx context.Context, comment *models.ChannelPostComment) error
+
+	FindLike(ctx context.Context, postID, userID uint) (models.ChannelPostLike, error)
+	// CreateLike devuelve ErrAlreadyLiked si el usuario ya dio like al post
+	CreateLike(ctx context.Context, like *models.ChannelPostLike) error
+	DeleteLike(ctx context.Context, like *models.ChannelPostLike) error
+}
+
+type channelPostRepository struct {
+	db *gorm.DB
+}
+
+// NewChannelPostRepository crea un ChannelPostRepository sobre GORM
+func NewChannelPostRepository(db *gorm.DB) ChannelPostRepository {
+	return channelPostRepository{db: db}
+}
+
+func (r channelPostRepository) ListByChannel(ctx context.Context, channelID uint, filter ChannelPostFilter) ([]models.ChannelPost, error) {
+	db := conn(ctx, r.db).Where("channel_id = ?", channelID)
+
+	if filter.Query != "" {
+		pattern := containsPattern(filter.Query)
+		db = db.Where(`(content ILIKE ? ESCAPE '\' OR EXISTS (
+			SELECT 1 FROM channel_post_tags pt JOIN tags t ON t.tag_id = pt.tag_id
+			WHERE pt.post_id = channel_posts.post_id AND t.name ILIKE ? ESCAPE '\'
+		))`, pattern, pattern)
+	}
+	if len(filter.TagIDs) > 0 {
+		db = db.Where("post_id IN (SELECT post_id FROM channel_post_tags WHERE tag_id IN ?)", filter.TagIDs)
+	}
+
+	var posts []models.ChannelPost
+	err := db.
+		Preload("User").
+		Preload("Tags").
+		Preload("Files").
+		Preload("Comments.User").
+		Preload("Likes").
+		Order("pinned_at DESC NULLS LAST, created_at DESC").
+		Find(&posts).Error
+	return posts, err
+}
+
+func (r channelPostRepository) FindByID(ctx context.Context, postID uint) (models.ChannelPost, error) {
+	var post models.ChannelPost
+	err := conn(ctx, r.db).Preload("Tags").First(&post, postID).Error
+	return post, err
+}
+
+func (r channelPostRepository) FindForUpdate(ctx context.Context, postID uint) (models.ChannelPost, error) {
+	var post models.ChannelPost
+	err := conn(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Tags").First(&post, postID).Error
+	return post, err
+}
+
+func (r channelPostRepository) Create(ctx context.Context, post *models.ChannelPost) error {
+	db := conn(ctx, r.db)
+	if err := db.Omit("Tags.*").Create(post).Error; err != nil {
+		return err
+	}
+	return db.Preload("User").Preload("Tags").First(post, post.PostID).Error
+}
+
+func (r channelPostRepository) Delete(ctx context.Context, post *models.ChannelPost) error {
+	return conn(ctx, r.db).Delete(post).Error
+}
+
+func (r channelPostRepository) Update(ctx context.Context, post *models.ChannelPost, fields map[string]interface{}) error {
+	return conn(ctx, r.db).Model(post).Updates(fields).Error
+}
+
+func (r channelPostRepository) ReplaceTags(ctx context.Context, post *models.ChannelPost, tags []models.Tag) error {
+	return conn(ctx, r.db).Model(post).Association("Tags").Replace(tags)
+}
+
+func (r channelPostRepository) CreateEdit(ctx context.Context, edit *models.ChannelPostEdit) error {
+	return conn(ctx, r.db).Create(edit).Error
+}
+
+func (r channelPostRepository) Edits(ctx context.Context, postID uint) ([]models.ChannelPostEdit, error) {
+	var edits []models.ChannelPostEdit
+	err := conn(ctx, r.db).
+		Where("post_id = ?", postID).
+		Preload("Editor").
+		Order("created_at DESC, edit_id DESC").
+		Find(&edits).Error
+	return edits, err
+}
+
+func (r channelPostRepository) Pin(ctx context.Context, post *models.ChannelPost, userID uint, max int) (bool, error) {
+	now := time.Now()
+	pinned := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT 1 FROM channels WHERE channel_id = ? FOR UPDATE", post.ChannelID).Error; err != nil {
+			return err
+		}
+		var count int64
+		err := tx.Model(&models.ChannelPost{}).
+			Where("channel_id = ? AND pinned_at IS NOT NULL", post.ChannelID).
+			Count(&count).Error
+		if err != nil || count >= int64(max) {
+			return err
+		}
+		pinned = true
+		return tx.Model(post).UpdateColumns(map[string]interface{}{"pinned_at": now, "pinned_by": userID}).Error
+	})
+	if err != nil || !pinned {
+		return false, err
+	}
+	post.PinnedAt = &now
+	post.PinnedBy = &userID
+	return true, nil
+}
+
+func (r channelPostRepository) Unpin(ctx context.Context, post *models.ChannelPost) error {
+	err := conn(ctx, r.db).Model(post).UpdateColumns(map[string]interface{}{"pinned_at": nil, "pinned_by": nil}).Error
+	if err != nil {
+		return err
+	}
+	post.PinnedAt = nil
+	post.PinnedBy = nil
+	return nil
+}
+
+func (r channelPostRepository) Files(ctx context.Context, postID uint) ([]models.ChannelPostFile, error) {
+	var files []models.ChannelPostFile
+	err := conn(ctx, r.db).Where("post_id = ?", postID).Find(&files).Error
+	return files, err
+}
+
+func (r channelPostRepository) AddFile(ctx context.Context, file *models.ChannelPostFile) error {
+	return conn(ctx, r.db).Create(file).Error
+}
+
+func (r channelPostRepository) FindFile(ctx context.Context, fileID uint) (models.ChannelPostFile, error) {
+	var file models.ChannelPostFile
+	err := conn(ctx, r.db).Preload("Post").First(&file, fileID).Error
+	return file, err
+}
+
+func (r channelPostRepository) CreateComment(ctx context.Context, comment *models.ChannelPostComment) error {
+	db := conn(ctx, r.db)
+	if err := db.Create(comment).Error; err != nil {
+		return err
+	}
+	return db.Preload("User").First(comment, comment.CommentID).Error
+}
+
+func (r channelPostRepository) FindLike(ctx context.Context, postID, userID uint) (models.ChannelPostLike, error) {
+	var like models.ChannelPostLike
+	err := conn(ctx, r.db).Where("post_id = ? AND user_id = ?", postID, userID).First(&like).Error
+	return like, err
+}
+
+func (r channelPostRepository) CreateLike(ctx context.Context, like *models.ChannelPostLike) error {
+	err := conn(ctx, r.db).Create(like).Error
+	if isUniqueViolation(err, "uni_channel_post_likes_post_user") {
+		return ErrAlreadyLiked
+	}
+	return err
+}
+
+func (r channelPostRepository) DeleteLike(ctx context.Context, like *models.ChannelPostLike) error {
+	return conn(ctx, r.db).Delete(like).Error
+}
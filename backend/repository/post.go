@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gorm.io/gorm"
+)
+
+// PostFilter son los filtros de la búsqueda de posts. Los campos vacíos no filtran.
+type PostFilter struct {
+	Query        string
+	UniversityID uint
+	CareerID     uint
+	// TagIDs trae los posts que tengan al menos uno de los tags
+	TagIDs []uint
+}
+
+// PostRepository accede a los posts del feed y a sus comentarios, likes,
+// archivos y tags. Los listados devuelven los posts con User, Comments.User,
+// Likes.User y Tags precargados.
+type PostRepository interface {
+	List(ctx context.Context, page Page) ([]models.Post, int64, error)
+	ListByUser(ctx context.Context, userID uint, page Page) ([]models.Post, int64, error)
+	Search(ctx context.Context, filter PostFilter) ([]models.Post, error)
+	// FindByID devuelve el post sin relaciones
+	FindByID(ctx context.Context, postID uint) (models.Post, error)
+	// FindWithRelations devuelve el post con las mismas relaciones que los listados
+	FindWithRelations(ctx context.Context, postID uint) (models.Post, error)
+	Create(ctx context.Context, post *models.Post) error
+	UpdateContent(ctx context.Context, post *models.Post, content string) error
+	// Delete borra el post junto con sus comentarios, likes, archivos y tags
+	Delete(ctx context.Context, post *models.Post) error
+
+	ReplaceTags(ctx context.Context, post *models.Post, tags []models.Tag) error
+
+	Files(ctx context.Context, postID uint) ([]models.PostFile, error)
+	AddFile(ctx context.Context, file *models.PostFile) error
+
+	FindLike(ctx context.Context, postID, userID uint) (models.PostLike, error)
+	CreateLike(ctx context.Context, like *models.PostLike) error
+	DeleteLike(ctx context.Context, like *models.PostLike) error
+
+	FindComment(ctx context.Context, postID, commentID uint) (models.Comment, error)
+	// CreateComment guarda el comentario y lo recarga con su autor
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	DeleteComment(ctx context.Context, comment *models.Comment) error
+
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	// LikesReceived cuenta los likes de todas las publicaciones del usuario
+	LikesReceived(ctx context.Context, userID uint) (int64, error)
+}
+
+type postRepository struct {
+	db *gorm.DB
+}
+
+// NewPostRepository crea un PostRepository sobre GORM
+func NewPostRepository(db *gorm.DB) PostRepository {
+	return postRepository{db: db}
+}
+
+func withPostRelations(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("User").
+		Preload("Comments").
+		Preload("Comments.User").
+		Preload("Likes").
+		Preload("Likes.User").
+		Preload("Tags")
+}
+
+func (r postRepository) List(ctx context.Context, page Page) ([]models.Post, int64, error) {
+	var total int64
+	if err := conn(ctx, r.db).Model(&models.Post{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	err := withPostRelations(conn(ctx, r.db)).
+		Order("created_at DESC").
+		Limit(page.Size).
+		Offset(page.Offset()).
+		Find(&posts).Error
+	return posts, total, err
+}
+
+func (r postRepository) ListByUser(ctx context.Context, userID uint, page Page) ([]models.Post, int64, error) {
+	total, err := r.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	err = withPostRelations(conn(ctx, r.db)).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(page.Size).
+		Offset(page.Offset()).
+		Find(&posts).Error
+	return posts, total, err
+}
+
+func (r postRepository) Search(ctx context.Context, filter PostFilter) ([]models.Post, error) {
+	db := withPostRelations(conn(ctx, r.db)).Model(&models.Post{})
+
+	if filter.Query != "" {
+		db = db.Where(`content ILIKE ? ESCAPE '\'`, containsPattern(filter.Query))
+	}
+	if filter.UniversityID != 0 {
+		db = db.Where("university_id = ?", filter.UniversityID)
+	}
+	if filter.CareerID != 0 {
+		db = db.Where("career_id = ?", filter.CareerID)
+	}
+	if len(filter.TagIDs) > 0 {
+		db = db.Joins("JOIN post_tags ON posts.post_id = post_tags.post_id").
+			Where("post_tags.tag_id IN ?", filter.TagIDs).
+			Group("posts.post_id")
+	}
+
+	var posts []models.Post
+	err := db.Order("created_at DESC").Find(&posts).Error
+	return posts, err
+}
+
+func (r postRepository) FindByID(ctx context.Context, postID uint) (models.Post, error) {
+	var post models.Post
+	err := conn(ctx, r.db).First(&post, postID).Error
+	return post, err
+}
+
+func (r postRepository) FindWithRelations(ctx context.Context, postID uint) (models.Post, error) {
+	var post models.Post
+	err := withPostRelations(conn(ctx, r.db)).First(&post, postID).Error
+	return post, err
+}
+
+func (r postRepository) Create(ctx context.Context, post *models.Post) error {
+	return conn(ctx, r.db).Omit("Tags.*").Create(post).Error
+}
+
+func (r postRepository) UpdateContent(ctx context.Context, post *models.Post, content string) error {
+	return conn(ctx, r.db).Model(post).Update("content", content).Error
+}
+
+func (r postRepository) Delete(ctx context.Context, post *models.Post) error {
+	db := conn(ctx, r.db)
+	for _, model := range []interface{}{&models.Comment{}, &models.PostLike{}, &models.PostFile{}, &models.PostTag{}} {
+		if err := db.Where("post_id = ?", post.PostID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return db.Delete(post).Error
+}
+
+func (r postRepository) ReplaceTags(ctx context.Context, post *models.Post, tags []models.Tag) error {
+	return conn(ctx, r.db).Model(post).Association("Tags").Replace(tags)
+}
+
+func (r postRepository) Files(ctx context.Context, postID uint) ([]models.PostFile, error) {
+	var files []models.PostFile
+	err := conn(ctx, r.db).Where("post_id = ?", postID).Find(&files).Error
+	return files, err
+}
+
+func (r postRepository) AddFile(ctx context.Context, file *models.PostFile) error {
+	return conn(ctx, r.db).Create(file).Error
+}
+
+func (r postRepository) FindLike(ctx context.Context, postID, userID uint) (models.PostLike, error) {
+	var like models.PostLike
+	err := conn(ctx, r.db).Where("post_id = ? AND user_id = ?", postID, userID).First(&like).Error
+	return like, err
+}
+
+func (r postRepository) CreateLike(ctx context.Context, like *models.PostLike) error {
+	return conn(ctx, r.db).Create(like).Error
+}
+
+func (r postRepository) DeleteLike(ctx context.Context, like *models.PostLike) error {
+	return conn(ctx, r.db).Delete(like).Error
+}
+
+func (r postRepository) FindComment(ctx context.Context, postID, commentID uint) (models.Comment, error) {
+	var comment models.Comment
+	err := conn(ctx, r.db).Where("comment_id = ? AND post_id = ?", commentID, postID).First(&comment).Error
+	return comment, err
+}
+
+func (r postRepository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	db := conn(ctx, r.db)
+	if err := db.Create(comment).Error; err != nil {
+		return err
+	}
+	return db.Preload("User").First(comment, comment.CommentID).Error
+}
+
+func (r postRepository) DeleteComment(ctx context.Context, comment *models.Comment) error {
+	return conn(ctx, r.db).Delete(comment).Error
+}
+
+func (r postRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&models.Post{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r postRepository) LikesReceived(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&models.PostLike{}).
+		Joins("JOIN posts ON post_likes.post_id = posts.post_id").
+		Where("posts.user_id = ?", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,86 @@
+// Package repository encapsula el acceso a la base de datos. Todos los métodos
+// reciben un context.Context: si el contexto trae una transacción abierta con
+// Transactor.Transaction, la consulta corre dentro de ella.
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyMember indica que el usuario ya es miembro del canal. Lo devuelve
+// AddMember cuando otro pedido lo agregó después del chequeo del servicio.
+var ErrAlreadyMember = errors.New("el usuario ya es miembro del canal")
+
+// ErrAlreadyLiked indica que el usuario ya dio like al post. Lo devuelve
+// CreateLike cuando otro pedido lo creó después de buscarlo.
+var ErrAlreadyLiked = errors.New("el usuario ya dio like al post")
+
+// ErrRoleAlreadyGranted indica que el usuario ya tiene el rol con el mismo
+// alcance
+var ErrRoleAlreadyGranted = errors.New("el usuario ya tiene ese rol")
+
+// uniqueViolation es el código de Postgres para una restricción única violada
+const uniqueViolation = "23505"
+
+type txKey struct{}
+
+// Page es una página de resultados; Number empieza en 1
+type Page struct {
+	Number int
+	Size   int
+}
+
+// Offset devuelve la cantidad de filas a saltear para llegar a la página
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.Size
+}
+
+// Transactor ejecuta fn dentro de una transacción. Los repositorios usados con
+// el ctx que recibe fn participan de esa transacción; si fn devuelve un error
+// se hace rollback. Las llamadas anidadas usan savepoints.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor crea un Transactor sobre la conexión de GORM
+func NewTransactor(db *gorm.DB) Transactor {
+	return gormTransactor{db: db}
+}
+
+func (t gormTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// isUniqueViolation indica si err viola la restricción única constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == constraint
+}
+
+// likeEscaper escapa los comodines de LIKE con la barra invertida
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern es el patrón de LIKE que busca query como texto literal en
+// cualquier parte. La consulta tiene que declarar ESCAPE '\'.
+func containsPattern(query string) string {
+	return "%" + likeEscaper.Replace(query) + "%"
+}
+
+// conn devuelve la transacción guardada en ctx o, si no hay, la conexión db
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import "testing"
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		query   string
+		pattern string
+	}{
+		{"parcial", `%parcial%`},
+		{"100%", `%100\%%`},
+		{"final_2024", `%final\_2024%`},
+		{`c:\apuntes`, `%c:\\apuntes%`},
+		{`\%_`, `%\\\%\_%`},
+	}
+	for _, tt := range tests {
+		if pattern := containsPattern(tt.query); pattern != tt.pattern {
+			t.Errorf("containsPattern(%q) = %q, se esperaba %q", tt.query, pattern, tt.pattern)
+		}
+	}
+}
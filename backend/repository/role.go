@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gorm.io/gorm"
+)
+
+// RoleRepository accede a los roles de rbac asignados a los usuarios
+type RoleRepository interface {
+	// LockAdmins serializa hasta el fin de la transacción de ctx los cambios
+	// que pueden dejar el sitio sin administradores globales
+	LockAdmins(ctx context.Context) error
+	FindUserRole(ctx context.Context, userID, roleID uint) (models.UserRole, error)
+	// CountGlobal cuenta las asignaciones del rol sin universidad
+	CountGlobal(ctx context.Context, role string) (int64, error)
+	// Create devuelve ErrRoleAlreadyGranted si el usuario ya tiene el rol con
+	// el mismo alcance
+	Create(ctx context.Context, role *models.UserRole) error
+	Delete(ctx context.Context, role *models.UserRole) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository crea un RoleRepository sobre GORM
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return roleRepository{db: db}
+}
+
+func (r roleRepository) LockAdmins(ctx context.Context) error {
+	return conn(ctx, r.db).Exec("SELECT pg_advisory_xact_lock(hashtext('user_roles_admin'))").Error
+}
+
+func (r roleRepository) FindUserRole(ctx context.Context, userID, roleID uint) (models.UserRole, error) {
+	var role models.UserRole
+	err := conn(ctx, r.db).Where("user_role_id = ? AND user_id = ?", roleID, userID).First(&role).Error
+	return role, err
+}
+
+func (r roleRepository) CountGlobal(ctx context.Context, role string) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&models.UserRole{}).Where("role = ? AND university_id IS NULL", role).Count(&count).Error
+	return count, err
+}
+
+func (r roleRepository) Create(ctx context.Context, role *models.UserRole) error {
+	err := conn(ctx, r.db).Create(role).Error
+	if isUniqueViolation(err, "idx_user_roles_scope") {
+		return ErrRoleAlreadyGranted
+	}
+	return err
+}
+
+func (r roleRepository) Delete(ctx context.Context, role *models.UserRole) error {
+	return conn(ctx, r.db).Delete(role).Error
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gorm.io/gorm"
+)
+
+// UserRepository accede a los usuarios
+type UserRepository interface {
+	FindByID(ctx context.Context, userID uint) (models.User, error)
+	FindByUsername(ctx context.Context, username string) (models.User, error)
+	// FindByEmail busca al usuario por email sin distinguir mayúsculas
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	Followers(ctx context.Context, user *models.User) ([]models.User, error)
+	Save(ctx context.Context, user *models.User) error
+	UpdatePasswordHash(ctx context.Context, user *models.User, hash string) error
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository crea un UserRepository sobre GORM
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return userRepository{db: db}
+}
+
+func (r userRepository) FindByID(ctx context.Context, userID uint) (models.User, error) {
+	var user models.User
+	err := conn(ctx, r.db).First(&user, userID).Error
+	return user, err
+}
+
+func (r userRepository) FindByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+	err := conn(ctx, r.db).Where("username = ?", username).First(&user).Error
+	return user, err
+}
+
+func (r userRepository) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := conn(ctx, r.db).Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	return user, err
+}
+
+func (r userRepository) Followers(ctx context.Context, user *models.User) ([]models.User, error) {
+	var followers []models.User
+	err := conn(ctx, r.db).Model(user).Association("Followers").Find(&followers)
+	return followers, err
+}
+
+func (r userRepository) Save(ctx context.Context, user *models.User) error {
+	return conn(ctx, r.db).Save(user).Error
+}
+
+func (r userRepository) UpdatePasswordHash(ctx context.Context, user *models.User, hash string) error {
+	return conn(ctx, r.db).Model(user).Update("password_hash", hash).Error
+}
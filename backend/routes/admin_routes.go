@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/controllers"
+	"github.com/LautaroRomano/repositorio-tecnologico/middleware"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(r *gin.Engine) {
+	admin := r.Group("/admin")
+	admin.Use(middleware.AuthMiddleware())
+	{
+		roles := admin.Group("/users/:id/roles")
+		roles.Use(middleware.RequirePermission(rbac.PermRolesManage))
+		{
+			roles.GET("", controllers.GetUserRoles)
+			roles.POST("", controllers.GrantRole)
+			roles.DELETE("/:roleId", controllers.RevokeRole)
+		}
+
+		admin.GET("/audit-logs", middleware.RequirePermission(rbac.PermAuditRead), controllers.GetAuditLogs)
+	}
+}
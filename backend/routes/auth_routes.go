@@ -12,5 +12,11 @@ func SetupAuthRoutes(router *gin.Engine) {
 		auth.POST("/login", controllers.Login)
 		auth.POST("/forgot-password", controllers.RequestPasswordReset)
 		auth.POST("/reset-password", controllers.ResetPassword)
+
+		// Login con proveedores OIDC (Google, SSO institucional)
+		auth.GET("/oidc/providers", controllers.GetOIDCProviders)
+		auth.GET("/oidc/:provider/login", controllers.OIDCLogin)
+		auth.GET("/oidc/:provider/callback", controllers.OIDCCallback)
+		auth.POST("/oidc/link", controllers.LinkOIDCIdentity)
 	}
 }
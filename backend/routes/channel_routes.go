@@ -7,6 +7,12 @@ import (
 )
 
 func SetupChannelRoutes(router *gin.Engine) {
+	// Eventos en tiempo real; acepta el token por query porque EventSource no
+	// envía encabezados
+	router.GET("/channels/events", middleware.StreamAuthMiddleware(), controllers.StreamChannelEvents)
+	// Descarga de archivos; el enlace lleva su propio token firmado
+	router.GET("/channels/files/:fileId/download", controllers.DownloadChannelFile)
+
 	// Grupo de rutas para canales
 	channelRoutes := router.Group("/channels")
 	channelRoutes.Use(middleware.AuthMiddleware())
@@ -14,16 +20,47 @@ func SetupChannelRoutes(router *gin.Engine) {
 		// Rutas para canales
 		channelRoutes.POST("", controllers.CreateChannel)
 		channelRoutes.GET("", controllers.GetChannels)
+		channelRoutes.GET("/discover", controllers.DiscoverChannels)
 		channelRoutes.GET("/:id", controllers.GetChannel)
+		channelRoutes.PATCH("/:id", controllers.UpdateChannel)
+		channelRoutes.DELETE("/:id", controllers.DeleteChannel)
+		channelRoutes.POST("/:id/archive", controllers.ArchiveChannel)
+		channelRoutes.DELETE("/:id/archive", controllers.UnarchiveChannel)
+		channelRoutes.GET("/:id/audit-log", controllers.GetChannelAuditLog)
+		channelRoutes.POST("/:id/read", controllers.MarkChannelRead)
+		channelRoutes.GET("/:id/permissions", controllers.GetChannelPermissions)
+		channelRoutes.PUT("/:id/permissions", controllers.UpdateChannelPermissions)
 		channelRoutes.POST("/:id/invite", controllers.InviteToChannel)
 		channelRoutes.GET("/invitations", controllers.GetPendingInvitations)
 		channelRoutes.POST("/invitations/:id", controllers.HandleInvitation)
+		channelRoutes.POST("/:id/join", controllers.JoinChannel)
+		channelRoutes.POST("/join/:code", controllers.JoinChannelWithCode)
+		channelRoutes.GET("/:id/invite-links", controllers.GetChannelInviteLinks)
+		channelRoutes.POST("/:id/invite-links", controllers.CreateChannelInviteLink)
+		channelRoutes.DELETE("/invite-links/:id", controllers.RevokeChannelInviteLink)
+		channelRoutes.POST("/:id/join-requests", controllers.RequestToJoinChannel)
+		channelRoutes.GET("/:id/join-requests", controllers.GetJoinRequests)
+		channelRoutes.POST("/join-requests/:id", controllers.HandleJoinRequest)
+
+		// Rutas para administrar miembros
+		channelRoutes.POST("/:id/leave", controllers.LeaveChannel)
+		channelRoutes.DELETE("/:id/members/:userId", controllers.RemoveChannelMember)
+		channelRoutes.PUT("/:id/members/:userId/role", controllers.SetChannelMemberRole)
+		channelRoutes.POST("/:id/transfer", controllers.TransferChannelOwnership)
+		channelRoutes.GET("/:id/bans", controllers.GetChannelBans)
+		channelRoutes.POST("/:id/bans", controllers.BanFromChannel)
+		channelRoutes.DELETE("/:id/bans/:userId", controllers.UnbanFromChannel)
 
 		// Rutas para posts de canales
 		channelRoutes.POST("/:id/posts", controllers.CreateChannelPost)
 		channelRoutes.GET("/:id/posts", controllers.GetChannelPosts)
 		channelRoutes.POST("/posts/:postId/comments", controllers.AddChannelPostComment)
 		channelRoutes.POST("/posts/:postId/like", controllers.LikeChannelPost)
+		channelRoutes.PATCH("/posts/:postId", controllers.UpdateChannelPost)
 		channelRoutes.DELETE("/posts/:postId", controllers.DeleteChannelPost)
+		channelRoutes.GET("/posts/:postId/edits", controllers.GetChannelPostEdits)
+		channelRoutes.POST("/posts/:postId/pin", controllers.PinChannelPost)
+		channelRoutes.DELETE("/posts/:postId/pin", controllers.UnpinChannelPost)
+		channelRoutes.GET("/files/:fileId", controllers.GetChannelFileLink)
 	}
 }
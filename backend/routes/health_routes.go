@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoutes(r *gin.Engine) {
+	r.GET("/healthz", controllers.Healthz)
+	r.GET("/readyz", controllers.Readyz)
+}
@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func MetricsRoutes(r *gin.Engine) {
+	r.GET("/metrics", controllers.Metrics)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/LautaroRomano/repositorio-tecnologico/controllers"
 	"github.com/LautaroRomano/repositorio-tecnologico/middleware"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
 	"github.com/gin-gonic/gin"
 
 )
@@ -24,9 +25,10 @@ func PostRoutes(r *gin.Engine) {
 			authorized.DELETE("/:id", controllers.DeletePost)
 			authorized.POST("/:id/likes", controllers.LikePost)
 			authorized.POST("/:id/comments", controllers.AddComment)
+			authorized.DELETE("/:id/comments/:commentId", controllers.DeleteComment)
+			authorized.POST("/tags/recreate", middleware.RequirePermission(rbac.PermTagsManage), controllers.RecreateTagTables)
 		}
 		posts.GET("/tags", controllers.GetTags)
-		posts.POST("/tags/recreate", controllers.RecreateTagTables)
 	}
 
 }
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter arma el router con los middlewares y todas las rutas de la API.
+// Las dependencias (base de datos, servicios, llavero JWT, email) se
+// inicializan aparte, antes de atender pedidos.
+func NewRouter(serviceName string) *gin.Engine {
+	router := gin.New()
+	// Con fallback el *gin.Context se puede pasar como context.Context (a GORM,
+	// por ejemplo) y conserva el span y el logger del pedido
+	router.ContextWithFallback = true
+	router.Use(
+		middleware.Tracing(serviceName),
+		middleware.RequestID(),
+		middleware.TraceID(),
+		middleware.AccessLog(),
+		middleware.Metrics(),
+		middleware.Errors(),
+		middleware.Recovery(),
+	)
+	router.NoRoute(middleware.NoRoute)
+	router.RedirectTrailingSlash = false
+
+	SetupAuthRoutes(router)
+	UserRoutes(router)
+	PostRoutes(router)
+	UniversityRoutes(router)
+	CareerRoutes(router)
+	SetupChannelRoutes(router)
+	WellKnownRoutes(router)
+	AdminRoutes(router)
+
+	router.GET("/ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "pong"})
+	})
+	HealthRoutes(router)
+	MetricsRoutes(router)
+
+	return router
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/LautaroRomano/repositorio-tecnologico/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func WellKnownRoutes(r *gin.Engine) {
+	wellKnown := r.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", controllers.GetJWKS)
+	}
+}
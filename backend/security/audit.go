@@ -0,0 +1,31 @@
+package security
+
+import (
+	"context"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+// Acciones registradas en el log de auditoría
+const (
+	AuditLoginFailed          = "login_failed"
+	AuditLoginThrottled       = "login_throttled"
+	AuditAccountLocked        = "account_locked"
+	AuditPasswordResetRequest = "password_reset_requested"
+	AuditPasswordResetFailed  = "password_reset_failed"
+	AuditPasswordReset        = "password_reset"
+	AuditRoleGranted          = "role_granted"
+	AuditRoleRevoked          = "role_revoked"
+	AuditPermissionDenied     = "permission_denied"
+	AuditContentModerated     = "content_moderated"
+)
+
+// Audit guarda una entrada en el log de auditoría. Un error al auditar no debe
+// cortar la operación en curso, por eso solo se registra en el log del servidor.
+func Audit(ctx context.Context, entry models.AuditLog) {
+	if err := database.DB.Create(&entry).Error; err != nil {
+		logging.FromContext(ctx).Error("Error guardando auditoría", "action", entry.Action, "error", err)
+	}
+}
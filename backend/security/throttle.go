@@ -0,0 +1,238 @@
+package security
+
+import (
+	"context"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/database"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Policy define cuántos intentos se toleran para una clave antes de aplicar
+// backoff exponencial y, eventualmente, un bloqueo temporal
+type Policy struct {
+	FreeAttempts int           // intentos permitidos sin demora
+	MaxDelay     time.Duration // tope de la demora exponencial entre intentos
+	LockoutAfter int           // cada cuántos intentos se bloquea la clave (0 = nunca)
+	LockoutFor   time.Duration // duración del primer bloqueo, se duplica en cada bloqueo siguiente
+	Window       time.Duration // sin intentos durante este tiempo el contador vuelve a cero
+}
+
+// maxLockout es el tope para la duración de un bloqueo
+const maxLockout = 24 * time.Hour
+
+var (
+	LoginAccountPolicy = Policy{FreeAttempts: 3, MaxDelay: 5 * time.Minute, LockoutAfter: 10, LockoutFor: 30 * time.Minute, Window: 24 * time.Hour}
+	LoginIPPolicy      = Policy{FreeAttempts: 10, MaxDelay: 15 * time.Minute, LockoutAfter: 50, LockoutFor: time.Hour, Window: time.Hour}
+
+	ResetRequestAccountPolicy = Policy{FreeAttempts: 3, MaxDelay: time.Hour, Window: 24 * time.Hour}
+	ResetRequestIPPolicy      = Policy{FreeAttempts: 5, MaxDelay: time.Hour, LockoutAfter: 30, LockoutFor: time.Hour, Window: time.Hour}
+	ResetTokenIPPolicy        = Policy{FreeAttempts: 5, MaxDelay: 15 * time.Minute, LockoutAfter: 30, LockoutFor: time.Hour, Window: time.Hour}
+)
+
+// delay calcula la espera exigida después de la cantidad de fallas dada
+func (p Policy) delay(failures int) time.Duration {
+	extra := failures - p.FreeAttempts
+	if extra <= 0 {
+		return 0
+	}
+	if extra > 30 {
+		return p.MaxDelay
+	}
+	d := time.Second << (extra - 1)
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// lockout calcula la duración del bloqueo para la cantidad de fallas dada
+func (p Policy) lockout(failures int) time.Duration {
+	d := p.LockoutFor
+	for i := failures/p.LockoutAfter - 1; i > 0 && d < maxLockout; i-- {
+		d *= 2
+	}
+	if d > maxLockout {
+		d = maxLockout
+	}
+	return d
+}
+
+// wait calcula cuánto le falta a la clave con el contador dado para poder
+// volver a intentar. Cero significa que el intento está permitido.
+func (p Policy) wait(throttle models.AuthThrottle, now time.Time) time.Duration {
+	if now.Sub(throttle.LastFailureAt) > p.Window {
+		return 0
+	}
+
+	var wait time.Duration
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+		wait = throttle.LockedUntil.Sub(now)
+	}
+	if next := throttle.LastFailureAt.Add(p.delay(throttle.Failures)); next.After(now) && next.Sub(now) > wait {
+		wait = next.Sub(now)
+	}
+	return wait
+}
+
+// Limit asocia una clave con la política que le corresponde
+type Limit struct {
+	Key    string
+	Policy Policy
+}
+
+// Attempt es un intento reservado con Reserve. Ya cuenta como fallido: si
+// sale bien hay que llamar a Release.
+type Attempt struct {
+	reservations []reservation
+}
+
+// reservation recuerda el contador de una clave antes de reservar el intento
+// para poder devolverlo
+type reservation struct {
+	key      string
+	at       time.Time
+	previous *models.AuthThrottle // nil si la clave no tenía contador
+	locked   *time.Time           // bloqueo que disparó este intento
+}
+
+// Reserve cuenta un intento para cada clave antes de hacerlo, así varios
+// pedidos simultáneos no pueden pasar todos el control antes de que se
+// registre alguna falla. Si alguna clave tiene que esperar no cuenta nada y
+// devuelve la espera más larga.
+//
+// Si el contador falla se deja pasar el intento: un problema en el contador
+// no debe impedir que los usuarios inicien sesión.
+func Reserve(ctx context.Context, limits ...Limit) (*Attempt, time.Duration) {
+	attempt := &Attempt{}
+	var wait time.Duration
+	for _, l := range limits {
+		r, w, err := reserve(ctx, l)
+		switch {
+		case err != nil:
+			logging.FromContext(ctx).Error("Error registrando intento", "key", l.Key, "error", err)
+		case w > 0:
+			if w > wait {
+				wait = w
+			}
+		default:
+			attempt.reservations = append(attempt.reservations, r)
+		}
+	}
+	if wait > 0 {
+		attempt.Release(ctx)
+		return nil, wait
+	}
+	return attempt, 0
+}
+
+// reserve cuenta el intento si la clave no tiene que esperar. Los pedidos a
+// una misma clave se serializan con un advisory lock.
+func reserve(ctx context.Context, l Limit) (reservation, time.Duration, error) {
+	var r reservation
+	var wait time.Duration
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockKey(tx, l.Key); err != nil {
+			return err
+		}
+		var throttle models.AuthThrottle
+		result := tx.Where("key = ?", l.Key).Limit(1).Find(&throttle)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Postgres guarda microsegundos; así Release puede reconocer el intento
+		now := time.Now().Truncate(time.Microsecond)
+		if result.RowsAffected > 0 {
+			if wait = l.Policy.wait(throttle, now); wait > 0 {
+				return nil
+			}
+			previous := throttle
+			r.previous = &previous
+		}
+		r.key = l.Key
+		r.at = now
+
+		// Pasada la ventana sin intentos el contador vuelve a cero
+		if now.Sub(throttle.LastFailureAt) > l.Policy.Window {
+			throttle.Failures = 0
+			throttle.LockedUntil = nil
+		}
+		throttle.Key = l.Key
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		if l.Policy.LockoutAfter > 0 && throttle.Failures%l.Policy.LockoutAfter == 0 {
+			until := now.Add(l.Policy.lockout(throttle.Failures))
+			throttle.LockedUntil = &until
+			r.locked = &until
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&throttle).Error
+	})
+	return r, wait, err
+}
+
+// Locked indica si este intento bloqueó la clave, para poder avisarle al
+// usuario, y hasta cuándo
+func (a *Attempt) Locked(key string) (time.Time, bool) {
+	for _, r := range a.reservations {
+		if r.key == key && r.locked != nil {
+			return *r.locked, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Release devuelve los intentos reservados porque no fallaron. Si después
+// hubo otros intentos solo descuenta este.
+func (a *Attempt) Release(ctx context.Context) {
+	for _, r := range a.reservations {
+		if err := r.release(ctx); err != nil {
+			logging.FromContext(ctx).Error("Error devolviendo intento", "key", r.key, "error", err)
+		}
+	}
+	a.reservations = nil
+}
+
+func (r reservation) release(ctx context.Context) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockKey(tx, r.key); err != nil {
+			return err
+		}
+		var throttle models.AuthThrottle
+		result := tx.Where("key = ?", r.key).Limit(1).Find(&throttle)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if throttle.LastFailureAt.Equal(r.at) {
+			if r.previous == nil {
+				return tx.Delete(&throttle).Error
+			}
+			return tx.Save(r.previous).Error
+		}
+
+		if throttle.Failures > 0 {
+			throttle.Failures--
+		}
+		if r.locked != nil && throttle.LockedUntil != nil && throttle.LockedUntil.Equal(*r.locked) {
+			throttle.LockedUntil = nil
+			if r.previous != nil {
+				throttle.LockedUntil = r.previous.LockedUntil
+			}
+		}
+		return tx.Save(&throttle).Error
+	})
+}
+
+// lockKey serializa dentro de la transacción los pedidos a una misma clave
+func lockKey(tx *gorm.DB, key string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "auth_throttle:"+key).Error
+}
+
+// Reset borra el contador de una clave, por ejemplo después de un login exitoso
+func Reset(key string) error {
+	return database.DB.Where("key = ?", key).Delete(&models.AuthThrottle{}).Error
+}
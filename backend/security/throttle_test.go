@@ -0,0 +1,74 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	p := Policy{FreeAttempts: 3, MaxDelay: 5 * time.Minute}
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{8, 16 * time.Second},
+		{12, 256 * time.Second},
+		{13, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := p.delay(c.failures); got != c.want {
+			t.Errorf("delay(%d) = %v, se esperaba %v", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestPolicyLockout(t *testing.T) {
+	p := Policy{LockoutAfter: 10, LockoutFor: 30 * time.Minute}
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{10, 30 * time.Minute},
+		{20, time.Hour},
+		{30, 2 * time.Hour},
+		{60, 16 * time.Hour},
+		{70, maxLockout},
+		{1000, maxLockout},
+	}
+	for _, c := range cases {
+		if got := p.lockout(c.failures); got != c.want {
+			t.Errorf("lockout(%d) = %v, se esperaba %v", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestPolicyWait(t *testing.T) {
+	p := Policy{FreeAttempts: 3, MaxDelay: time.Minute, Window: time.Hour}
+	now := time.Now()
+	lockedUntil := now.Add(10 * time.Minute)
+
+	cases := []struct {
+		name     string
+		throttle models.AuthThrottle
+		want     time.Duration
+	}{
+		{"sin fallas", models.AuthThrottle{}, 0},
+		{"dentro de las toleradas", models.AuthThrottle{Failures: 3, LastFailureAt: now}, 0},
+		{"con demora", models.AuthThrottle{Failures: 5, LastFailureAt: now.Add(-time.Second)}, time.Second},
+		{"demora cumplida", models.AuthThrottle{Failures: 5, LastFailureAt: now.Add(-3 * time.Second)}, 0},
+		{"bloqueada", models.AuthThrottle{Failures: 5, LastFailureAt: now, LockedUntil: &lockedUntil}, 10 * time.Minute},
+		{"ventana vencida", models.AuthThrottle{Failures: 50, LastFailureAt: now.Add(-2 * time.Hour), LockedUntil: &lockedUntil}, 0},
+	}
+	for _, c := range cases {
+		if got := p.wait(c.throttle, now); got != c.want {
+			t.Errorf("%s: espera %v, se esperaba %v", c.name, got, c.want)
+		}
+	}
+}
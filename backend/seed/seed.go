@@ -0,0 +1,206 @@
+package seed
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+//go:embed default.yaml
+var defaultDataset []byte
+
+// Dataset son los datos de referencia que se cargan con el comando seed
+type Dataset struct {
+	Universities []University `yaml:"universities"`
+	Tags         []string     `yaml:"tags"`
+}
+
+type University struct {
+	Name    string   `yaml:"name"`
+	Careers []string `yaml:"careers"`
+}
+
+// Result cuenta las filas creadas. Las que ya existían no se tocan.
+type Result struct {
+	Universities int
+	Careers      int
+	Tags         int
+}
+
+// Default devuelve el dataset incluido en el binario
+func Default() (*Dataset, error) {
+	return parseYAML(defaultDataset)
+}
+
+// Load lee un dataset en YAML (.yaml, .yml) o CSV (.csv).
+//
+// El CSV tiene las columnas tipo,nombre,universidad, por ejemplo:
+//
+//	university,Universidad Nacional de Tucumán,
+//	career,Ingeniería en Computación,Universidad Nacional de Tucumán
+//	tag,Apuntes,
+func Load(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		return parseYAML(data)
+	case ".csv":
+		return parseCSV(f)
+	default:
+		return nil, fmt.Errorf("formato de dataset no soportado: %s", path)
+	}
+}
+
+func parseYAML(data []byte) (*Dataset, error) {
+	var dataset Dataset
+	if err := yaml.Unmarshal(data, &dataset); err != nil {
+		return nil, fmt.Errorf("dataset inválido: %w", err)
+	}
+	return &dataset, nil
+}
+
+func parseCSV(r io.Reader) (*Dataset, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	dataset := &Dataset{}
+	universities := map[string]int{}
+	addUniversity := func(name string) int {
+		if i, ok := universities[name]; ok {
+			return i
+		}
+		dataset.Universities = append(dataset.Universities, University{Name: name})
+		universities[name] = len(dataset.Universities) - 1
+		return universities[name]
+	}
+
+	line := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("dataset inválido: %w", err)
+		}
+		line++
+		for len(record) < 3 {
+			record = append(record, "")
+		}
+		kind := strings.ToLower(strings.TrimSpace(record[0]))
+		name := strings.TrimSpace(record[1])
+		university := strings.TrimSpace(record[2])
+
+		switch {
+		case line == 1 && (kind == "tipo" || kind == "type"):
+			// Encabezado
+		case name == "":
+			return nil, fmt.Errorf("línea %d: falta el nombre", line)
+		case kind == "university":
+			addUniversity(name)
+		case kind == "career":
+			if university == "" {
+				return nil, fmt.Errorf("línea %d: la carrera %s no indica la universidad", line, name)
+			}
+			i := addUniversity(university)
+			dataset.Universities[i].Careers = append(dataset.Universities[i].Careers, name)
+		case kind == "tag":
+			dataset.Tags = append(dataset.Tags, name)
+		default:
+			return nil, fmt.Errorf("línea %d: tipo desconocido %q", line, record[0])
+		}
+	}
+	return dataset, nil
+}
+
+// Apply carga el dataset en una transacción. Es idempotente: las
+// universidades, carreras y tags se buscan por nombre y solo se crean los que
+// faltan.
+func Apply(db *gorm.DB, dataset *Dataset) (Result, error) {
+	var result Result
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, u := range dataset.Universities {
+			university := models.University{Name: strings.TrimSpace(u.Name)}
+			if university.Name == "" {
+				return errors.New("hay una universidad sin nombre")
+			}
+			created, err := firstOrCreate(tx, &university, "name = ?", university.Name)
+			if err != nil {
+				return err
+			}
+			if created {
+				result.Universities++
+			}
+
+			for _, name := range u.Careers {
+				career := models.Career{Name: strings.TrimSpace(name), UniversityID: university.UniversityID}
+				if career.Name == "" {
+					return fmt.Errorf("hay una carrera sin nombre en %s", university.Name)
+				}
+				created, err := firstOrCreate(tx, &career, "name = ? AND university_id = ?", career.Name, career.UniversityID)
+				if err != nil {
+					return err
+				}
+				if created {
+					result.Careers++
+				}
+			}
+		}
+
+		tags, err := ApplyTags(tx, dataset.Tags)
+		result.Tags = tags
+		return err
+	})
+	return result, err
+}
+
+// ApplyTags crea los tags que todavía no existen y devuelve cuántos creó
+func ApplyTags(db *gorm.DB, names []string) (int, error) {
+	created := 0
+	for _, name := range names {
+		tag := models.Tag{Name: strings.TrimSpace(name)}
+		if tag.Name == "" {
+			continue
+		}
+		ok, err := firstOrCreate(db, &tag, "name = ?", tag.Name)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// firstOrCreate busca la fila con la condición y la crea si no existe.
+// Devuelve si la creó.
+func firstOrCreate(db *gorm.DB, row interface{}, query string, args ...interface{}) (bool, error) {
+	result := db.Where(query, args...).Limit(1).Find(row)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return false, nil
+	}
+	return true, db.Create(row).Error
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AnnouncementQueueSize es cuántos anuncios pueden esperar su envío. Si la
+// cola se llena, los avisos de los anuncios nuevos se descartan.
+const AnnouncementQueueSize = 100
+
+// announcementTimeout limita el envío de los avisos de un anuncio
+const announcementTimeout = 2 * time.Minute
+
+// Announcer recibe los anuncios cuyos avisos por email se envían en segundo
+// plano
+type Announcer interface {
+	Enqueue(ctx context.Context, channel models.Channel, post models.ChannelPost)
+}
+
+// announcement es un anuncio esperando que se avise a los miembros
+type announcement struct {
+	ctx     context.Context
+	channel models.Channel
+	post    models.ChannelPost
+}
+
+// AnnouncementWorker avisa por email a los miembros de los anuncios de los
+// canales sin demorar el pedido que publica el post. Lo arranca runServe
+// con las demás tareas en segundo plano.
+type AnnouncementWorker struct {
+	channels repository.ChannelRepository
+	mailer   Mailer
+	queue    chan announcement
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAnnouncementWorker crea un AnnouncementWorker
+func NewAnnouncementWorker(channels repository.ChannelRepository, mailer Mailer) *AnnouncementWorker {
+	return &AnnouncementWorker{
+		channels: channels,
+		mailer:   mailer,
+		queue:    make(chan announcement, AnnouncementQueueSize),
+	}
+}
+
+// Enqueue encola el anuncio sin bloquear. El envío usa un contexto nuevo con
+// el logger y la traza del pedido: ctx puede ser un *gin.Context, que gin
+// reutiliza cuando termina el pedido.
+func (w *AnnouncementWorker) Enqueue(ctx context.Context, channel models.Channel, post models.ChannelPost) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		logging.FromContext(ctx).Error("Anuncio publicado durante el apagado, no se avisa a los miembros", "post_id", post.PostID)
+		return
+	}
+	select {
+	case w.queue <- announcement{ctx: detach(ctx), channel: channel, post: post}:
+	default:
+		logging.FromContext(ctx).Error("Cola de anuncios llena, no se avisa a los miembros", "post_id", post.PostID)
+	}
+}
+
+// detach copia el logger y la traza de ctx a un contexto que no se cancela
+func detach(ctx context.Context) context.Context {
+	detached := logging.WithLogger(context.Background(), logging.FromContext(ctx))
+	return trace.ContextWithSpanContext(detached, trace.SpanContextFromContext(ctx))
+}
+
+// Run envía los avisos encolados hasta que se llama a Close y la cola queda
+// vacía
+func (w *AnnouncementWorker) Run() {
+	for job := range w.queue {
+		w.send(job)
+	}
+}
+
+// Close deja de aceptar anuncios. Run termina después de enviar los que ya
+// estaban en la cola.
+func (w *AnnouncementWorker) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+}
+
+// send avisa a los demás miembros del canal. Como record, un error solo se
+// registra.
+func (w *AnnouncementWorker) send(job announcement) {
+	ctx, cancel := context.WithTimeout(job.ctx, announcementTimeout)
+	defer cancel()
+
+	emails, err := w.channels.MemberEmails(ctx, job.channel.ChannelID, job.post.UserID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error buscando los miembros a avisar del anuncio", "channel_id", job.channel.ChannelID, "error", err)
+		return
+	}
+	if len(emails) == 0 {
+		return
+	}
+	if err := w.mailer.SendChannelAnnouncement(ctx, emails, job.post.User.Username, job.channel.Name, job.channel.ChannelID, job.post.Content); err != nil {
+		logging.FromContext(ctx).Error("Error enviando los avisos del anuncio", "channel_id", job.channel.ChannelID, "post_id", job.post.PostID, "error", err)
+	}
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/realtime"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+)
+
+// NewChannel son los datos para crear un canal
+type NewChannel struct {
+	Name         string
+	Description  string
+	IsPrivate    bool
+	UniversityID uint
+	CareerID     uint
+}
+
+// ChannelUpdate son los cambios a un canal; los campos nil no se modifican
+type ChannelUpdate struct {
+	Name        *string
+	Description *string
+	IsPrivate   *bool
+	CareerID    *uint
+}
+
+// Acciones del registro de cambios de un canal
+const (
+	ChannelAuditCreated       = "channel_created"
+	ChannelAuditUpdated       = "channel_updated"
+	ChannelAuditArchived      = "channel_archived"
+	ChannelAuditUnarchived    = "channel_unarchived"
+	ChannelAuditDeleted       = "channel_deleted"
+	ChannelAuditMemberJoined  = "member_joined"
+	ChannelAuditMemberLeft    = "member_left"
+	ChannelAuditMemberRemoved = "member_removed"
+	ChannelAuditRoleChanged   = "role_changed"
+	ChannelAuditOwnerChanged  = "ownership_transferred"
+	ChannelAuditUserBanned    = "user_banned"
+	ChannelAuditUserUnbanned  = "user_unbanned"
+
+	ChannelAuditPermissionsChanged = "permissions_changed"
+	ChannelAuditInviteLinkCreated  = "invite_link_created"
+	ChannelAuditInviteLinkRevoked  = "invite_link_revoked"
+	ChannelAuditPostPinned         = "post_pinned"
+	ChannelAuditPostUnpinned       = "post_unpinned"
+)
+
+// ChannelService es la lógica de los canales, sus invitaciones y sus posts.
+// Salvo que se indique otra cosa, las operaciones sobre un canal requieren
+// que el actor sea miembro, y lo que puede hacer depende de su rol y de los
+// permisos del canal (ver ChannelAuthorizer). "Administradores" son el
+// creador y los moderadores. Los canales archivados son de solo lectura: no
+// admiten posts, comentarios, likes ni miembros nuevos.
+type ChannelService interface {
+	// Create crea el canal con el actor como creador y los permisos por defecto
+	Create(ctx context.Context, actor Actor, input NewChannel) (models.Channel, error)
+	// ListForUser devuelve los canales de los que el usuario es miembro
+	ListForUser(ctx context.Context, userID uint) ([]models.Channel, error)
+	// UnreadCounts devuelve los posts y comentarios no leídos de cada canal
+	// del usuario
+	UnreadCounts(ctx context.Context, userID uint) ([]repository.UnreadCount, error)
+	// MarkRead marca como leído todo lo publicado en el canal hasta ahora
+	MarkRead(ctx context.Context, actor Actor, channelID uint) (models.ChannelMember, error)
+	Get(ctx context.Context, actor Actor, channelID uint) (models.Channel, error)
+	// Update modifica nombre, descripción, privacidad o carrera; solo para
+	// administradores
+	Update(ctx context.Context, actor Actor, channelID uint, input ChannelUpdate) (models.Channel, error)
+	// SetArchived archiva o reactiva el canal; solo para administradores
+	SetArchived(ctx context.Context, actor Actor, channelID uint, archived bool) (models.Channel, error)
+	// Delete borra el canal con todo su contenido y sus archivos; solo lo
+	// puede hacer el creador
+	Delete(ctx context.Context, actor Actor, channelID uint) error
+	// AuditLog devuelve el registro de cambios del canal; solo para
+	// administradores
+	AuditLog(ctx context.Context, actor Actor, channelID uint, page repository.Page) ([]models.ChannelAuditLog, int64, error)
+
+	// Discover lista los canales públicos; no requiere ser miembro
+	Discover(ctx context.Context, filter repository.ChannelFilter, page repository.Page) ([]repository.ChannelSummary, int64, error)
+
+	// Join agrega al actor como miembro de un canal público
+	Join(ctx context.Context, actor Actor, channelID uint) (models.ChannelMember, error)
+	// RequestJoin pide entrar a un canal privado
+	RequestJoin(ctx context.Context, actor Actor, channelID uint, message string) (models.ChannelJoinRequest, error)
+	// JoinRequests devuelve las solicitudes pendientes; solo para administradores
+	JoinRequests(ctx context.Context, actor Actor, channelID uint) ([]models.ChannelJoinRequest, error)
+	// RespondJoinRequest aprueba o rechaza una solicitud; solo para
+	// administradores del canal
+	RespondJoinRequest(ctx context.Context, actor Actor, requestID uint, approve bool) (models.ChannelJoinRequest, error)
+
+	// Leave saca al actor del canal. El creador tiene que transferir la
+	// propiedad antes de salir y el último administrador no puede irse.
+	Leave(ctx context.Context, actor Actor, channelID uint) error
+	// RemoveMember expulsa a un miembro. Lo hacen los administradores, pero
+	// solo el creador puede expulsar a otro administrador y a él no se lo
+	// puede expulsar.
+	RemoveMember(ctx context.Context, actor Actor, channelID, userID uint) error
+	// SetRole cambia el rol de un miembro a moderator, member o readonly.
+	// Cualquier administrador puede cambiar el rol de los miembros; cambiar el
+	// de otro moderador es solo del creador, cuyo rol no se puede cambiar.
+	SetRole(ctx context.Context, actor Actor, channelID, userID uint, role string) (models.ChannelMember, error)
+	// TransferOwnership pasa la propiedad del canal a otro miembro; el creador
+	// anterior queda como moderador. Solo la puede transferir el creador actual.
+	TransferOwnership(ctx context.Context, actor Actor, channelID, userID uint) (models.Channel, error)
+
+	// Permissions devuelve el rol del actor, la configuración del canal y qué
+	// permisos tiene
+	Permissions(ctx context.Context, actor Actor, channelID uint) (ChannelPermissionSummary, error)
+	// UpdatePermissions cambia el rol mínimo de los permisos configurables;
+	// solo para administradores
+	UpdatePermissions(ctx context.Context, actor Actor, channelID uint, input ChannelPermissionsUpdate) (models.Channel, error)
+
+	// Ban bloquea al usuario en el canal: lo saca si es miembro y cancela sus
+	// invitaciones y solicitudes pendientes. Rige lo mismo que en RemoveMember.
+	Ban(ctx context.Context, actor Actor, channelID, userID uint, reason string) (models.ChannelBan, error)
+	Unban(ctx context.Context, actor Actor, channelID, userID uint) error
+	Bans(ctx context.Context, actor Actor, channelID uint) ([]models.ChannelBan, error)
+
+	// Invite invita a un usuario al canal; requiere el permiso invite
+	Invite(ctx context.Context, actor Actor, channelID, invitedUserID uint) (models.ChannelInvitation, error)
+	// InviteByEmail invita al usuario con ese email o, si no tiene cuenta, le
+	// envía un enlace de un solo uso que vence en EmailInviteTTL
+	InviteByEmail(ctx context.Context, actor Actor, channelID uint, email string) (EmailInvitation, error)
+	// RespondInvitation acepta o rechaza una invitación dirigida al actor
+	RespondInvitation(ctx context.Context, actor Actor, invitationID uint, accept bool) (models.ChannelInvitation, error)
+	PendingInvitations(ctx context.Context, userID uint) ([]models.ChannelInvitation, error)
+
+	// CreateInviteLink crea un enlace de invitación; requiere el permiso
+	// invite y no puede dar un rol mayor que el del actor
+	CreateInviteLink(ctx context.Context, actor Actor, channelID uint, input NewInviteLink) (models.ChannelInviteLink, error)
+	// InviteLinks devuelve los enlaces vigentes; solo para administradores
+	InviteLinks(ctx context.Context, actor Actor, channelID uint) ([]models.ChannelInviteLink, error)
+	// RevokeInviteLink invalida el enlace; solo para administradores
+	RevokeInviteLink(ctx context.Context, actor Actor, linkID uint) error
+	// RedeemInviteLink agrega al actor al canal con el rol del enlace. No
+	// requiere ser miembro y vale también para canales privados.
+	RedeemInviteLink(ctx context.Context, actor Actor, code string) (models.ChannelMember, error)
+
+	// CreatePost requiere el permiso post, y publicar un anuncio también el
+	// permiso pin. Los archivos se guardan como privados, hasta
+	// MaxChannelPostFiles por post.
+	CreatePost(ctx context.Context, actor Actor, channelID uint, input NewChannelPost) (models.ChannelPost, error)
+	// Posts devuelve primero los fijados y no mueve la marca de lectura; eso
+	// lo hace MarkRead. Cada archivo trae su enlace de descarga para el actor.
+	// Con un filtro, los no leídos se cuentan solo entre los posts que lo
+	// cumplen.
+	Posts(ctx context.Context, actor Actor, channelID uint, filter repository.ChannelPostFilter) (ChannelPostList, error)
+	// UpdatePost cambia el contenido o los tags y guarda la versión anterior
+	// en el historial. Solo lo puede hacer el autor, si conserva el permiso
+	// post. Si nada cambia devuelve el post sin marcarlo como editado.
+	UpdatePost(ctx context.Context, actor Actor, postID uint, input ChannelPostUpdate) (models.ChannelPost, error)
+	// PostEdits devuelve las versiones anteriores del post, de la más nueva a
+	// la más vieja
+	PostEdits(ctx context.Context, actor Actor, postID uint) ([]models.ChannelPostEdit, error)
+	// SetPinned fija o desfija el post; requiere el permiso pin y un canal
+	// admite hasta MaxPinnedChannelPosts fijados
+	SetPinned(ctx context.Context, actor Actor, postID uint, pinned bool) (models.ChannelPost, error)
+	// FileLink devuelve un enlace de descarga nuevo para un archivo del canal;
+	// sirve cuando venció el que vino con los posts
+	FileLink(ctx context.Context, actor Actor, fileID uint) (FileLink, error)
+	// DownloadFile valida el token de un enlace de FileLink y devuelve una URL
+	// del almacenamiento que vence en un minuto. El token identifica al
+	// usuario, que tiene que seguir siendo miembro del canal.
+	DownloadFile(ctx context.Context, fileID uint, token string) (string, error)
+	// AddComment requiere el permiso comment
+	AddComment(ctx context.Context, actor Actor, postID uint, content string) (models.ChannelPostComment, error)
+	// ToggleLike agrega el like del actor o lo quita si ya existía. Devuelve
+	// el like creado, o nil si se quitó. Si otro pedido lo agrega a la vez,
+	// devuelve ese like. Requiere el permiso comment.
+	ToggleLike(ctx context.Context, actor Actor, postID uint) (*models.ChannelPostLike, error)
+	// DeletePost lo puede hacer el autor, quien tenga el permiso delete_posts
+	// o un moderador de canales de la universidad (auditado). En un canal
+	// archivado solo este último. También borra sus archivos del
+	// almacenamiento.
+	DeletePost(ctx context.Context, actor Actor, postID uint) error
+
+	// Stream suscribe al actor a los eventos en tiempo real de los canales
+	// pedidos, que tiene que poder ver, o de todos sus canales si no pide
+	// ninguno. Con lastEventID incluye los eventos posteriores a ese. El
+	// llamador tiene que cerrar la suscripción.
+	Stream(ctx context.Context, actor Actor, channelIDs []uint, lastEventID *uint64) (*ChannelStream, error)
+	// RefreshStream actualiza los canales del stream con las membresías
+	// actuales del actor
+	RefreshStream(ctx context.Context, actor Actor, stream *ChannelStream) error
+}
+
+type channelService struct {
+	tx        repository.Transactor
+	channels  repository.ChannelRepository
+	posts     repository.ChannelPostRepository
+	catalog   repository.CatalogRepository
+	users     repository.UserRepository
+	access    ChannelAuthorizer
+	audit     Auditor
+	storage   Storage
+	files     FileSigner
+	mailer    Mailer
+	announcer Announcer
+	events    realtime.Broker
+}
+
+// NewChannelService crea un ChannelService
+func NewChannelService(tx repository.Transactor, channels repository.ChannelRepository, posts repository.ChannelPostRepository, catalog repository.CatalogRepository, users repository.UserRepository, authz Authorizer, audit Auditor, storage Storage, files FileSigner, mailer Mailer, announcer Announcer, events realtime.Broker) ChannelService {
+	return &channelService{
+		tx:        tx,
+		channels:  channels,
+		posts:     posts,
+		catalog:   catalog,
+		users:     users,
+		access:    NewChannelAuthorizer(channels, authz),
+		audit:     audit,
+		storage:   storage,
+		files:     files,
+		mailer:    mailer,
+		announcer: announcer,
+		events:    events,
+	}
+}
+
+func (s *channelService) Create(ctx context.Context, actor Actor, input NewChannel) (models.Channel, error) {
+	channel := models.Channel{
+		Name:         input.Name,
+		Description:  input.Description,
+		IsPrivate:    input.IsPrivate,
+		CreatedBy:    actor.UserID,
+		UniversityID: input.UniversityID,
+		CareerID:     input.CareerID,
+		Permissions:  DefaultChannelPermissions,
+	}
+
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.channels.Create(ctx, &channel); err != nil {
+			return err
+		}
+		return s.channels.AddMember(ctx, &models.ChannelMember{
+			ChannelID:  channel.ChannelID,
+			UserID:     actor.UserID,
+			Role:       models.ChannelRoleOwner,
+			JoinedAt:   time.Now(),
+			LastSeenAt: time.Now(),
+		})
+	})
+	if err != nil {
+		return models.Channel{}, apperror.Internal(err)
+	}
+	s.record(ctx, actor, channel.ChannelID, ChannelAuditCreated, fmt.Sprintf("canal %q creado", channel.Name))
+	return channel, nil
+}
+
+func (s *channelService) ListForUser(ctx context.Context, userID uint) ([]models.Channel, error) {
+	channels, err := s.channels.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return channels, nil
+}
+
+func (s *channelService) UnreadCounts(ctx context.Context, userID uint) ([]repository.UnreadCount, error) {
+	counts, err := s.channels.UnreadCounts(ctx, userID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return counts, nil
+}
+
+func (s *channelService) MarkRead(ctx context.Context, actor Actor, channelID uint) (models.ChannelMember, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermView)
+	if err != nil {
+		return models.ChannelMember{}, err
+	}
+
+	member := access.Member
+	if err := s.channels.MarkRead(ctx, &member, time.Now()); err != nil {
+		return models.ChannelMember{}, apperror.Internal(err)
+	}
+	return member, nil
+}
+
+func (s *channelService) Get(ctx context.Context, actor Actor, channelID uint) (models.Channel, error) {
+	if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermView); err != nil {
+		return models.Channel{}, err
+	}
+
+	channel, err := s.channels.FindWithDetails(ctx, channelID)
+	if err != nil {
+		return models.Channel{}, apperror.NotFound(apperror.CodeChannelNotFound, err)
+	}
+	return channel, nil
+}
+
+func (s *channelService) Update(ctx context.Context, actor Actor, channelID uint, input ChannelUpdate) (models.Channel, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage)
+	if err != nil {
+		return models.Channel{}, err
+	}
+	channel := access.Channel
+	if channel.ArchivedAt != nil {
+		return channel, apperror.New(apperror.CodeChannelArchived)
+	}
+
+	fields := map[string]interface{}{}
+	var changes []string
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return channel, apperror.InvalidField("name", "required")
+		}
+		if name != channel.Name {
+			fields["name"] = name
+			changes = append(changes, fmt.Sprintf("nombre: %q → %q", channel.Name, name))
+		}
+	}
+	if input.Description != nil && *input.Description != channel.Description {
+		fields["description"] = *input.Description
+		changes = append(changes, "descripción modificada")
+	}
+	if input.IsPrivate != nil && *input.IsPrivate != channel.IsPrivate {
+		fields["is_private"] = *input.IsPrivate
+		changes = append(changes, fmt.Sprintf("privado: %t → %t", channel.IsPrivate, *input.IsPrivate))
+	}
+	if input.CareerID != nil && *input.CareerID != channel.CareerID {
+		ok, err := s.catalog.CareerInUniversity(ctx, *input.CareerID, channel.UniversityID)
+		if err != nil {
+			return channel, apperror.Internal(err)
+		}
+		if !ok {
+			return channel, apperror.InvalidField("career_id", "unknown_career")
+		}
+		fields["career_id"] = *input.CareerID
+		changes = append(changes, fmt.Sprintf("carrera: %d → %d", channel.CareerID, *input.CareerID))
+	}
+	if len(fields) == 0 {
+		return channel, nil
+	}
+
+	if err := s.channels.Update(ctx, &channel, fields); err != nil {
+		return channel, apperror.Internal(err)
+	}
+	if input.Name != nil {
+		channel.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		channel.Description = *input.Description
+	}
+	if input.IsPrivate != nil {
+		channel.IsPrivate = *input.IsPrivate
+	}
+	if input.CareerID != nil {
+		channel.CareerID = *input.CareerID
+	}
+	s.record(ctx, actor, channelID, ChannelAuditUpdated, strings.Join(changes, "; "))
+	return channel, nil
+}
+
+func (s *channelService) SetArchived(ctx context.Context, actor Actor, channelID uint, archived bool) (models.Channel, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage)
+	if err != nil {
+		return models.Channel{}, err
+	}
+	channel := access.Channel
+	if (channel.ArchivedAt != nil) == archived {
+		return channel, nil
+	}
+
+	var archivedAt *time.Time
+	action := ChannelAuditUnarchived
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+		action = ChannelAuditArchived
+	}
+	if err := s.channels.Update(ctx, &channel, map[string]interface{}{"archived_at": archivedAt}); err != nil {
+		return channel, apperror.Internal(err)
+	}
+	channel.ArchivedAt = archivedAt
+	s.record(ctx, actor, channelID, action, "")
+	return channel, nil
+}
+
+func (s *channelService) Delete(ctx context.Context, actor Actor, channelID uint) error {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermOwn)
+	if err != nil {
+		return err
+	}
+	channel := access.Channel
+
+	urls, err := s.channels.FileURLs(ctx, channelID)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.channels.Delete(ctx, &channel); err != nil {
+		return apperror.Internal(err)
+	}
+	s.record(ctx, actor, channelID, ChannelAuditDeleted,
+		fmt.Sprintf("canal %q eliminado con %d archivos", channel.Name, len(urls)))
+
+	// Los archivos se borran después de confirmar el borrado en la base. Si
+	// alguno falla el canal ya no existe, así que solo se registra.
+	for _, url := range urls {
+		if err := s.storage.Delete(ctx, url); err != nil {
+			logging.FromContext(ctx).Error("Error borrando archivo del canal", "channel_id", channelID, "url", url, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *channelService) AuditLog(ctx context.Context, actor Actor, channelID uint, page repository.Page) ([]models.ChannelAuditLog, int64, error) {
+	if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage); err != nil {
+		return nil, 0, err
+	}
+
+	entries, total, err := s.channels.AuditLogs(ctx, channelID, page)
+	if err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+	return entries, total, nil
+}
+
+func (s *channelService) Discover(ctx context.Context, filter repository.ChannelFilter, page repository.Page) ([]repository.ChannelSummary, int64, error) {
+	channels, total, err := s.channels.Discover(ctx, filter, page)
+	if err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+	return channels, total, nil
+}
+
+// activeChannel busca el canal y devuelve CHANNEL_ARCHIVED si está archivado
+func (s *channelService) activeChannel(ctx context.Context, channelID uint) (models.Channel, error) {
+	channel, err := s.channels.FindByID(ctx, channelID)
+	if err != nil {
+		return channel, apperror.NotFound(apperror.CodeChannelNotFound, err)
+	}
+	if channel.ArchivedAt != nil {
+		return channel, apperror.New(apperror.CodeChannelArchived)
+	}
+	return channel, nil
+}
+
+// record agrega una entrada al registro de cambios del canal. Igual que la
+// auditoría de seguridad, un error al guardarla no corta la operación y solo
+// se registra en el log del servidor.
+func (s *channelService) record(ctx context.Context, actor Actor, channelID uint, action, details string) {
+	entry := models.ChannelAuditLog{
+		ChannelID: channelID,
+		ActorID:   &actor.UserID,
+		Action:    action,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if err := s.channels.CreateAuditLog(ctx, &entry); err != nil {
+		logging.FromContext(ctx).Error("Error guardando el registro del canal", "channel_id", channelID, "action", action, "error", err)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"gorm.io/gorm"
+)
+
+// Permisos sobre un canal. Post, comment, invite, pin y delete_posts se
+// configuran por canal; view lo tiene cualquier miembro, manage los
+// moderadores y own solo el creador.
+const (
+	ChannelPermView        = "view"
+	ChannelPermPost        = "post"
+	ChannelPermComment     = "comment"
+	ChannelPermInvite      = "invite"
+	ChannelPermPin         = "pin"
+	ChannelPermDeletePosts = "delete_posts"
+	ChannelPermManage      = "manage"
+	ChannelPermOwn         = "own"
+)
+
+// ConfigurablePermissions son los permisos cuyo rol mínimo elige cada canal
+var ConfigurablePermissions = []string{
+	ChannelPermPost,
+	ChannelPermComment,
+	ChannelPermInvite,
+	ChannelPermPin,
+	ChannelPermDeletePosts,
+}
+
+// DefaultChannelPermissions es la configuración de los canales nuevos
+var DefaultChannelPermissions = models.ChannelPermissions{
+	Post:        models.ChannelRoleMember,
+	Comment:     models.ChannelRoleMember,
+	Invite:      models.ChannelRoleModerator,
+	Pin:         models.ChannelRoleModerator,
+	DeletePosts: models.ChannelRoleModerator,
+}
+
+// roleRank ordena los roles de un canal; un rol tiene los permisos de todos
+// los de rango menor o igual
+var roleRank = map[string]int{
+	models.ChannelRoleReadOnly:  1,
+	models.ChannelRoleMember:    2,
+	models.ChannelRoleModerator: 3,
+	models.ChannelRoleOwner:     4,
+}
+
+// ValidChannelRole indica si role es un rol de canal
+func ValidChannelRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// writePermissions son los permisos que un canal archivado no concede
+var writePermissions = map[string]bool{
+	ChannelPermPost:    true,
+	ChannelPermComment: true,
+	ChannelPermInvite:  true,
+	ChannelPermPin:     true,
+}
+
+// ChannelAccess es el resultado de una autorización: el canal, la membresía
+// del usuario y si accede como moderador de rbac sin un rol del canal que lo
+// habilite, en cuyo caso la acción se audita
+type ChannelAccess struct {
+	Channel    models.Channel
+	Member     models.ChannelMember
+	IsMember   bool
+	Moderating bool
+}
+
+// ChannelAuthorizer decide qué puede hacer un usuario en un canal según su
+// rol y la configuración del canal. Todas las verificaciones de permisos de
+// canales pasan por acá.
+type ChannelAuthorizer interface {
+	// Authorize devuelve el acceso del usuario si tiene el permiso. Si no lo
+	// tiene devuelve CHANNEL_OWNER_REQUIRED o CHANNEL_ADMIN_REQUIRED para own
+	// y manage, CHANNEL_ACCESS_DENIED si no es miembro y
+	// CHANNEL_PERMISSION_DENIED si su rol no alcanza. En un canal archivado los
+	// permisos de escritura devuelven CHANNEL_ARCHIVED.
+	Authorize(ctx context.Context, channelID, userID uint, permission string) (ChannelAccess, error)
+	// Permissions indica qué permisos tiene la membresía en el canal
+	Permissions(channel models.Channel, member models.ChannelMember) map[string]bool
+}
+
+type channelAuthorizer struct {
+	channels repository.ChannelRepository
+	authz    Authorizer
+}
+
+// NewChannelAuthorizer crea un ChannelAuthorizer. authz se usa para que los
+// moderadores de rbac de la universidad del canal puedan borrar posts aunque
+// no sean miembros.
+func NewChannelAuthorizer(channels repository.ChannelRepository, authz Authorizer) ChannelAuthorizer {
+	return channelAuthorizer{channels: channels, authz: authz}
+}
+
+func (a channelAuthorizer) Authorize(ctx context.Context, channelID, userID uint, permission string) (ChannelAccess, error) {
+	var access ChannelAccess
+	member, err := a.channels.FindMember(ctx, channelID, userID)
+	switch {
+	case err == nil:
+		access.Member = member
+		access.IsMember = true
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return access, apperror.Internal(err)
+	case permission != ChannelPermDeletePosts:
+		// A quien no es miembro no se le confirma si el canal existe
+		return access, deny(access, permission)
+	}
+
+	channel, err := a.channels.FindByID(ctx, channelID)
+	if err != nil {
+		return access, apperror.NotFound(apperror.CodeChannelNotFound, err)
+	}
+	access.Channel = channel
+
+	if access.IsMember && channel.ArchivedAt != nil && writePermissions[permission] {
+		return access, apperror.New(apperror.CodeChannelArchived)
+	}
+	if access.IsMember && roleRank[member.Role] >= roleRank[requiredRole(channel, permission)] {
+		return access, nil
+	}
+
+	if permission == ChannelPermDeletePosts && a.authz.CanInUniversity(ctx, userID, rbac.PermChannelsModerate, channel.UniversityID) {
+		access.Moderating = true
+		return access, nil
+	}
+
+	return access, deny(access, permission)
+}
+
+// deny devuelve el error para un permiso que el usuario no tiene
+func deny(access ChannelAccess, permission string) error {
+	switch {
+	case permission == ChannelPermOwn:
+		return apperror.New(apperror.CodeChannelOwnerRequired)
+	case permission == ChannelPermManage:
+		return apperror.New(apperror.CodeChannelAdminRequired)
+	case !access.IsMember:
+		return apperror.New(apperror.CodeChannelAccessDenied)
+	}
+	return apperror.New(apperror.CodeChannelPermissionDenied).WithParam("permission", permission)
+}
+
+func (a channelAuthorizer) Permissions(channel models.Channel, member models.ChannelMember) map[string]bool {
+	permissions := map[string]bool{}
+	for _, permission := range append([]string{ChannelPermView, ChannelPermManage, ChannelPermOwn}, ConfigurablePermissions...) {
+		allowed := roleRank[member.Role] >= roleRank[requiredRole(channel, permission)]
+		if channel.ArchivedAt != nil && writePermissions[permission] {
+			allowed = false
+		}
+		permissions[permission] = allowed
+	}
+	return permissions
+}
+
+// requiredRole devuelve el rol mínimo para el permiso en el canal. Un permiso
+// desconocido o una configuración vacía exigen el rol de creador.
+func requiredRole(channel models.Channel, permission string) string {
+	var role string
+	switch permission {
+	case ChannelPermView:
+		role = models.ChannelRoleReadOnly
+	case ChannelPermPost:
+		role = channel.Permissions.Post
+	case ChannelPermComment:
+		role = channel.Permissions.Comment
+	case ChannelPermInvite:
+		role = channel.Permissions.Invite
+	case ChannelPermPin:
+		role = channel.Permissions.Pin
+	case ChannelPermDeletePosts:
+		role = channel.Permissions.DeletePosts
+	case ChannelPermManage:
+		role = models.ChannelRoleModerator
+	}
+	if !ValidChannelRole(role) {
+		return models.ChannelRoleOwner
+	}
+	return role
+}
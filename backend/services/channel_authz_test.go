@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"gorm.io/gorm"
+)
+
+// fakeChannels es un ChannelRepository en memoria con lo que usan los tests.
+// Los métodos que no redefine entran en pánico.
+type fakeChannels struct {
+	repository.ChannelRepository
+
+	channels map[uint]models.Channel
+	members  []models.ChannelMember
+	bans     []models.ChannelBan
+	links    []models.ChannelInviteLink
+	audit    []models.ChannelAuditLog
+	// noUses hace que UseInviteLink no encuentre cupo, como si otro canje
+	// hubiera usado el último
+	noUses bool
+}
+
+func (f *fakeChannels) FindByID(ctx context.Context, channelID uint) (models.Channel, error) {
+	channel, ok := f.channels[channelID]
+	if !ok {
+		return models.Channel{}, gorm.ErrRecordNotFound
+	}
+	return channel, nil
+}
+
+func (f *fakeChannels) FindMember(ctx context.Context, channelID, userID uint) (models.ChannelMember, error) {
+	for _, member := range f.members {
+		if member.ChannelID == channelID && member.UserID == userID {
+			return member, nil
+		}
+	}
+	return models.ChannelMember{}, gorm.ErrRecordNotFound
+}
+
+func (f *fakeChannels) AddMember(ctx context.Context, member *models.ChannelMember) error {
+	if _, err := f.FindMember(ctx, member.ChannelID, member.UserID); err == nil {
+		return repository.ErrAlreadyMember
+	}
+	f.members = append(f.members, *member)
+	return nil
+}
+
+func (f *fakeChannels) FindBan(ctx context.Context, channelID, userID uint) (models.ChannelBan, error) {
+	for _, ban := range f.bans {
+		if ban.ChannelID == channelID && ban.UserID == userID {
+			return ban, nil
+		}
+	}
+	return models.ChannelBan{}, gorm.ErrRecordNotFound
+}
+
+func (f *fakeChannels) FindInviteLinkByCode(ctx context.Context, code string) (models.ChannelInviteLink, error) {
+	for _, link := range f.links {
+		if link.Code == code {
+			return link, nil
+		}
+	}
+	return models.ChannelInviteLink{}, gorm.ErrRecordNotFound
+}
+
+func (f *fakeChannels) UseInviteLink(ctx context.Context, linkID uint) (bool, error) {
+	for i, link := range f.links {
+		if link.LinkID != linkID {
+			continue
+		}
+		if f.noUses || (link.MaxUses != nil && link.Uses >= *link.MaxUses) {
+			return false, nil
+		}
+		f.links[i].Uses++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (f *fakeChannels) CreateAuditLog(ctx context.Context, entry *models.ChannelAuditLog) error {
+	f.audit = append(f.audit, *entry)
+	return nil
+}
+
+// fakeAuthz concede los permisos de rbac de moderates en cualquier universidad
+type fakeAuthz struct {
+	moderates bool
+}
+
+func (a fakeAuthz) CanInUniversity(ctx context.Context, userID uint, permission string, universityID uint) bool {
+	return a.moderates
+}
+
+// expectCode falla si err no es un *apperror.Error con code
+func expectCode(t *testing.T, err error, code apperror.Code) {
+	t.Helper()
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code != code {
+		t.Fatalf("se esperaba %s: %v", code, err)
+	}
+}
+
+func TestRequiredRole(t *testing.T) {
+	channel := models.Channel{Permissions: models.ChannelPermissions{
+		Post:        models.ChannelRoleReadOnly,
+		Comment:     models.ChannelRoleMember,
+		Invite:      models.ChannelRoleModerator,
+		Pin:         "desconocido",
+		DeletePosts: "",
+	}}
+
+	tests := []struct {
+		permission string
+		role       string
+	}{
+		{ChannelPermView, models.ChannelRoleReadOnly},
+		{ChannelPermPost, models.ChannelRoleReadOnly},
+		{ChannelPermComment, models.ChannelRoleMember},
+		{ChannelPermInvite, models.ChannelRoleModerator},
+		{ChannelPermManage, models.ChannelRoleModerator},
+		{ChannelPermOwn, models.ChannelRoleOwner},
+		// Una configuración inválida o vacía exige el rol de creador
+		{ChannelPermPin, models.ChannelRoleOwner},
+		{ChannelPermDeletePosts, models.ChannelRoleOwner},
+		{"otro", models.ChannelRoleOwner},
+	}
+	for _, tt := range tests {
+		if role := requiredRole(channel, tt.permission); role != tt.role {
+			t.Errorf("requiredRole(%s) = %s, se esperaba %s", tt.permission, role, tt.role)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	archivedAt := time.Now()
+	channels := &fakeChannels{
+		channels: map[uint]models.Channel{
+			1: {ChannelID: 1, UniversityID: 10, Permissions: DefaultChannelPermissions},
+			2: {ChannelID: 2, UniversityID: 10, Permissions: DefaultChannelPermissions, ArchivedAt: &archivedAt},
+		},
+		members: []models.ChannelMember{
+			{ChannelID: 1, UserID: 1, Role: models.ChannelRoleOwner},
+			{ChannelID: 1, UserID: 2, Role: models.ChannelRoleModerator},
+			{ChannelID: 1, UserID: 3, Role: models.ChannelRoleMember},
+			{ChannelID: 1, UserID: 4, Role: models.ChannelRoleReadOnly},
+			{ChannelID: 2, UserID: 3, Role: models.ChannelRoleMember},
+			// Miembro de un canal que ya no existe
+			{ChannelID: 3, UserID: 3, Role: models.ChannelRoleMember},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		channelID  uint
+		userID     uint
+		permission string
+		moderates  bool
+		code       apperror.Code
+		moderating bool
+	}{
+		{name: "creador", channelID: 1, userID: 1, permission: ChannelPermOwn},
+		{name: "moderador administra", channelID: 1, userID: 2, permission: ChannelPermManage},
+		{name: "moderador no es creador", channelID: 1, userID: 2, permission: ChannelPermOwn, code: apperror.CodeChannelOwnerRequired},
+		{name: "miembro publica", channelID: 1, userID: 3, permission: ChannelPermPost},
+		{name: "miembro no fija", channelID: 1, userID: 3, permission: ChannelPermPin, code: apperror.CodeChannelPermissionDenied},
+		{name: "miembro no administra", channelID: 1, userID: 3, permission: ChannelPermManage, code: apperror.CodeChannelAdminRequired},
+		{name: "solo lectura ve", channelID: 1, userID: 4, permission: ChannelPermView},
+		{name: "solo lectura no publica", channelID: 1, userID: 4, permission: ChannelPermPost, code: apperror.CodeChannelPermissionDenied},
+		{name: "no miembro", channelID: 1, userID: 5, permission: ChannelPermView, code: apperror.CodeChannelAccessDenied},
+		{name: "no miembro en canal inexistente", channelID: 9, userID: 5, permission: ChannelPermView, code: apperror.CodeChannelAccessDenied},
+		{name: "miembro de canal inexistente", channelID: 3, userID: 3, permission: ChannelPermView, code: apperror.CodeChannelNotFound},
+		{name: "archivado se lee", channelID: 2, userID: 3, permission: ChannelPermView},
+		{name: "archivado no admite posts", channelID: 2, userID: 3, permission: ChannelPermPost, code: apperror.CodeChannelArchived},
+		{name: "moderador de rbac borra posts", channelID: 1, userID: 5, permission: ChannelPermDeletePosts, moderates: true, moderating: true},
+		{name: "moderador de rbac no publica", channelID: 1, userID: 5, permission: ChannelPermPost, moderates: true, code: apperror.CodeChannelAccessDenied},
+		{name: "sin rbac no borra posts", channelID: 1, userID: 5, permission: ChannelPermDeletePosts, code: apperror.CodeChannelAccessDenied},
+		{name: "miembro borra con rbac", channelID: 1, userID: 3, permission: ChannelPermDeletePosts, moderates: true, moderating: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorizer := NewChannelAuthorizer(channels, fakeAuthz{moderates: tt.moderates})
+			access, err := authorizer.Authorize(context.Background(), tt.channelID, tt.userID, tt.permission)
+			if tt.code != "" {
+				expectCode(t, err, tt.code)
+				return
+			}
+			if err != nil {
+				t.Fatalf("se esperaba autorizar: %v", err)
+			}
+			if access.Channel.ChannelID != tt.channelID || access.Moderating != tt.moderating {
+				t.Fatalf("acceso inesperado: %+v", access)
+			}
+		})
+	}
+}
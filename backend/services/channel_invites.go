@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+	"gorm.io/gorm"
+)
+
+// Estados de una invitación a un canal
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRejected = "rejected"
+	// InvitationCancelled es el estado de las invitaciones pendientes de un
+	// usuario que fue bloqueado en el canal
+	InvitationCancelled = "cancelled"
+)
+
+// EmailInviteTTL es lo que dura la invitación enviada a un email sin cuenta
+const EmailInviteTTL = 7 * 24 * time.Hour
+
+// NewInviteLink son los datos de un enlace de invitación. Role vacío es
+// member; MaxUses y ExpiresAt nil no ponen límite.
+type NewInviteLink struct {
+	Role      string
+	MaxUses   *int
+	ExpiresAt *time.Time
+}
+
+// EmailInvitation es el resultado de invitar por email: la invitación, si la
+// dirección ya tiene cuenta, o el enlace que se le envió si no
+type EmailInvitation struct {
+	Invitation *models.ChannelInvitation
+	Link       *models.ChannelInviteLink
+}
+
+func (s *channelService) Invite(ctx context.Context, actor Actor, channelID, invitedUserID uint) (models.ChannelInvitation, error) {
+	if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermInvite); err != nil {
+		return models.ChannelInvitation{}, err
+	}
+	if err := s.checkNotBanned(ctx, channelID, invitedUserID); err != nil {
+		return models.ChannelInvitation{}, err
+	}
+
+	if ok, err := s.isMember(ctx, channelID, invitedUserID); err != nil {
+		return models.ChannelInvitation{}, err
+	} else if ok {
+		return models.ChannelInvitation{}, apperror.New(apperror.CodeAlreadyChannelMember)
+	}
+
+	_, err := s.channels.FindPendingInvitation(ctx, channelID, invitedUserID)
+	switch {
+	case err == nil:
+		return models.ChannelInvitation{}, apperror.New(apperror.CodeInvitationPending)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return models.ChannelInvitation{}, apperror.Internal(err)
+	}
+
+	invitation := models.ChannelInvitation{
+		ChannelID:   channelID,
+		InvitedBy:   actor.UserID,
+		InvitedUser: invitedUserID,
+		Status:      InvitationPending,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.channels.CreateInvitation(ctx, &invitation); err != nil {
+		return models.ChannelInvitation{}, apperror.Internal(err)
+	}
+	metrics.ChannelInvites.Inc()
+	return invitation, nil
+}
+
+func (s *channelService) RespondInvitation(ctx context.Context, actor Actor, invitationID uint, accept bool) (models.ChannelInvitation, error) {
+	invitation, err := s.channels.FindInvitation(ctx, invitationID)
+	if err != nil {
+		return invitation, apperror.NotFound(apperror.CodeInvitationNotFound, err)
+	}
+	if invitation.InvitedUser != actor.UserID {
+		return invitation, apperror.New(apperror.CodeForbidden)
+	}
+	if invitation.Status != InvitationPending {
+		return invitation, apperror.New(apperror.CodeInvitationProcessed)
+	}
+	if accept {
+		if _, err := s.activeChannel(ctx, invitation.ChannelID); err != nil {
+			return invitation, err
+		}
+	}
+
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		invitation.Status = InvitationRejected
+		if accept {
+			invitation.Status = InvitationAccepted
+			// Pudo haber entrado por otra vía mientras la invitación esperaba
+			ok, err := s.isMember(ctx, invitation.ChannelID, actor.UserID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				err := s.channels.AddMember(ctx, &models.ChannelMember{
+					ChannelID:  invitation.ChannelID,
+					UserID:     actor.UserID,
+					Role:       models.ChannelRoleMember,
+					JoinedAt:   time.Now(),
+					LastSeenAt: time.Now(),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		invitation.UpdatedAt = time.Now()
+		return s.channels.SaveInvitation(ctx, &invitation)
+	})
+	if err != nil {
+		return invitation, memberError(err)
+	}
+	if accept {
+		metrics.ChannelJoins.WithLabelValues("invitation").Inc()
+		s.record(ctx, actor, invitation.ChannelID, ChannelAuditMemberJoined, fmt.Sprintf("invitación %d", invitation.InvitationID))
+	}
+	return invitation, nil
+}
+
+func (s *channelService) PendingInvitations(ctx context.Context, userID uint) ([]models.ChannelInvitation, error) {
+	invitations, err := s.channels.PendingInvitations(ctx, userID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return invitations, nil
+}
+
+func (s *channelService) InviteByEmail(ctx context.Context, actor Actor, channelID uint, email string) (EmailInvitation, error) {
+	email, _, fe := validation.NormalizeEmail(email)
+	if fe != nil {
+		return EmailInvitation{}, validation.Errors{*fe}
+	}
+
+	user, err := s.users.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		invitation, err := s.Invite(ctx, actor, channelID, user.UserID)
+		if err != nil {
+			return EmailInvitation{}, err
+		}
+		return EmailInvitation{Invitation: &invitation}, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return EmailInvitation{}, apperror.Internal(err)
+	}
+
+	expiresAt := time.Now().Add(EmailInviteTTL)
+	maxUses := 1
+	link, err := s.createInviteLink(ctx, actor, channelID, NewInviteLink{MaxUses: &maxUses, ExpiresAt: &expiresAt}, &email)
+	if err != nil {
+		return EmailInvitation{}, err
+	}
+
+	inviter, err := s.users.FindByID(ctx, actor.UserID)
+	if err != nil {
+		return EmailInvitation{}, apperror.Internal(err)
+	}
+	channel, err := s.channels.FindByID(ctx, channelID)
+	if err != nil {
+		return EmailInvitation{}, apperror.NotFound(apperror.CodeChannelNotFound, err)
+	}
+	if err := s.mailer.SendChannelInvite(ctx, email, inviter.Username, channel.Name, link.Code, expiresAt); err != nil {
+		// Un enlace que nadie recibió no tiene que quedar vigente
+		if err := s.channels.RevokeInviteLink(ctx, &link); err != nil {
+			logging.FromContext(ctx).Error("Error revocando el enlace no enviado", "link_id", link.LinkID, "error", err)
+		}
+		return EmailInvitation{}, apperror.New(apperror.CodeEmailFailed).Wrap(err)
+	}
+	metrics.ChannelInvites.Inc()
+	s.record(ctx, actor, channelID, ChannelAuditInviteLinkCreated, fmt.Sprintf("enlace %d enviado por email", link.LinkID))
+	return EmailInvitation{Link: &link}, nil
+}
+
+func (s *channelService) CreateInviteLink(ctx context.Context, actor Actor, channelID uint, input NewInviteLink) (models.ChannelInviteLink, error) {
+	link, err := s.createInviteLink(ctx, actor, channelID, input, nil)
+	if err != nil {
+		return link, err
+	}
+	s.record(ctx, actor, channelID, ChannelAuditInviteLinkCreated, fmt.Sprintf("enlace %d con rol %s", link.LinkID, link.Role))
+	return link, nil
+}
+
+// createInviteLink valida y guarda el enlace; email restringe quién lo canjea
+func (s *channelService) createInviteLink(ctx context.Context, actor Actor, channelID uint, input NewInviteLink, email *string) (models.ChannelInviteLink, error) {
+	role := input.Role
+	if role == "" {
+		role = models.ChannelRoleMember
+	}
+	switch {
+	case role == models.ChannelRoleOwner || !ValidChannelRole(role):
+		return models.ChannelInviteLink{}, apperror.InvalidField("role", "invalid_role")
+	case input.MaxUses != nil && *input.MaxUses < 1:
+		return models.ChannelInviteLink{}, apperror.InvalidField("max_uses", "not_positive")
+	case input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()):
+		return models.ChannelInviteLink{}, apperror.InvalidField("expires_at", "not_future")
+	}
+
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermInvite)
+	if err != nil {
+		return models.ChannelInviteLink{}, err
+	}
+	if roleRank[role] > roleRank[access.Member.Role] {
+		return models.ChannelInviteLink{}, apperror.New(apperror.CodeChannelAdminRequired)
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		return models.ChannelInviteLink{}, apperror.Internal(err)
+	}
+	link := models.ChannelInviteLink{
+		ChannelID: channelID,
+		Code:      code,
+		Role:      role,
+		MaxUses:   input.MaxUses,
+		ExpiresAt: input.ExpiresAt,
+		Email:     email,
+		CreatedBy: &actor.UserID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.channels.CreateInviteLink(ctx, &link); err != nil {
+		return models.ChannelInviteLink{}, apperror.Internal(err)
+	}
+	return link, nil
+}
+
+func (s *channelService) InviteLinks(ctx context.Context, actor Actor, channelID uint) ([]models.ChannelInviteLink, error) {
+	if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage); err != nil {
+		return nil, err
+	}
+
+	links, err := s.channels.InviteLinks(ctx, channelID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return links, nil
+}
+
+func (s *channelService) RevokeInviteLink(ctx context.Context, actor Actor, linkID uint) error {
+	link, err := s.channels.FindInviteLink(ctx, linkID)
+	if err != nil {
+		return apperror.NotFound(apperror.CodeInviteLinkNotFound, err)
+	}
+	if _, err := s.access.Authorize(ctx, link.ChannelID, actor.UserID, ChannelPermManage); err != nil {
+		return err
+	}
+	if link.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.channels.RevokeInviteLink(ctx, &link); err != nil {
+		return apperror.Internal(err)
+	}
+	s.record(ctx, actor, link.ChannelID, ChannelAuditInviteLinkRevoked, fmt.Sprintf("enlace %d", link.LinkID))
+	return nil
+}
+
+func (s *channelService) RedeemInviteLink(ctx context.Context, actor Actor, code string) (models.ChannelMember, error) {
+	link, err := s.channels.FindInviteLinkByCode(ctx, code)
+	if err != nil {
+		return models.ChannelMember{}, apperror.NotFound(apperror.CodeInviteLinkNotFound, err)
+	}
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now())) {
+		return models.ChannelMember{}, apperror.New(apperror.CodeInviteLinkExpired)
+	}
+	if link.MaxUses != nil && link.Uses >= *link.MaxUses {
+		return models.ChannelMember{}, apperror.New(apperror.CodeInviteLinkExhausted)
+	}
+	if link.Email != nil {
+		user, err := s.users.FindByID(ctx, actor.UserID)
+		if err != nil {
+			return models.ChannelMember{}, apperror.NotFound(apperror.CodeUserNotFound, err)
+		}
+		if !strings.EqualFold(user.Email, *link.Email) {
+			return models.ChannelMember{}, apperror.New(apperror.CodeInviteLinkEmail)
+		}
+	}
+
+	if _, err := s.activeChannel(ctx, link.ChannelID); err != nil {
+		return models.ChannelMember{}, err
+	}
+	if err := s.checkNotBanned(ctx, link.ChannelID, actor.UserID); err != nil {
+		return models.ChannelMember{}, err
+	}
+	if ok, err := s.isMember(ctx, link.ChannelID, actor.UserID); err != nil {
+		return models.ChannelMember{}, err
+	} else if ok {
+		return models.ChannelMember{}, apperror.New(apperror.CodeAlreadyChannelMember)
+	}
+
+	member := models.ChannelMember{
+		ChannelID:  link.ChannelID,
+		UserID:     actor.UserID,
+		Role:       link.Role,
+		JoinedAt:   time.Now(),
+		LastSeenAt: time.Now(),
+	}
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		// Otro canje pudo usar el último cupo después del chequeo de arriba
+		ok, err := s.channels.UseInviteLink(ctx, link.LinkID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apperror.New(apperror.CodeInviteLinkExhausted)
+		}
+		return s.channels.AddMember(ctx, &member)
+	})
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		return models.ChannelMember{}, appErr
+	case err != nil:
+		return models.ChannelMember{}, memberError(err)
+	}
+	metrics.ChannelJoins.WithLabelValues("link").Inc()
+	s.record(ctx, actor, link.ChannelID, ChannelAuditMemberJoined, fmt.Sprintf("enlace %d con rol %s", link.LinkID, link.Role))
+	return member, nil
+}
+
+// newInviteCode genera el código de un enlace de invitación: 12 caracteres
+// aptos para URL
+func newInviteCode() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
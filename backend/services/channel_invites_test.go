@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"gorm.io/gorm"
+)
+
+// fakeTx ejecuta la transacción sin base de datos
+type fakeTx struct{}
+
+func (fakeTx) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeUsers es un UserRepository en memoria con lo que usan los tests
+type fakeUsers struct {
+	repository.UserRepository
+
+	users map[uint]models.User
+}
+
+func (f fakeUsers) FindByID(ctx context.Context, userID uint) (models.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func TestRedeemInviteLinkLimits(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	one, two := 1, 2
+	email := "Invitada@Example.com"
+
+	tests := []struct {
+		name   string
+		link   models.ChannelInviteLink
+		noUses bool
+		code   apperror.Code
+	}{
+		{name: "sin límites", link: models.ChannelInviteLink{}},
+		{name: "con cupo", link: models.ChannelInviteLink{MaxUses: &two, Uses: 1, ExpiresAt: &future}},
+		{name: "vencido", link: models.ChannelInviteLink{ExpiresAt: &past}, code: apperror.CodeInviteLinkExpired},
+		{name: "revocado", link: models.ChannelInviteLink{RevokedAt: &past}, code: apperror.CodeInviteLinkExpired},
+		{name: "agotado", link: models.ChannelInviteLink{MaxUses: &one, Uses: 1}, code: apperror.CodeInviteLinkExhausted},
+		// Otro canje usó el último cupo entre el chequeo y la transacción
+		{name: "agotado al canjear", link: models.ChannelInviteLink{MaxUses: &one}, noUses: true, code: apperror.CodeInviteLinkExhausted},
+		{name: "para el email del usuario", link: models.ChannelInviteLink{Email: &email}},
+		{name: "para otro email", link: models.ChannelInviteLink{Email: new(string)}, code: apperror.CodeInviteLinkEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := tt.link
+			link.LinkID, link.ChannelID, link.Code, link.Role = 1, 1, "codigo", models.ChannelRoleMember
+			channels := &fakeChannels{
+				channels: map[uint]models.Channel{1: {ChannelID: 1, Permissions: DefaultChannelPermissions}},
+				links:    []models.ChannelInviteLink{link},
+				noUses:   tt.noUses,
+			}
+			users := fakeUsers{users: map[uint]models.User{7: {UserID: 7, Email: "invitada@example.com"}}}
+			service := NewChannelService(fakeTx{}, channels, nil, nil, users, fakeAuthz{}, nil, nil, nil, nil, nil, nil)
+
+			member, err := service.RedeemInviteLink(context.Background(), Actor{UserID: 7}, "codigo")
+			if tt.code != "" {
+				expectCode(t, err, tt.code)
+				if len(channels.members) != 0 {
+					t.Fatalf("se agregó el miembro: %+v", channels.members)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("se esperaba canjear el enlace: %v", err)
+			}
+			if member.UserID != 7 || member.Role != models.ChannelRoleMember || channels.links[0].Uses != link.Uses+1 {
+				t.Fatalf("canje inesperado: %+v, %d usos", member, channels.links[0].Uses)
+			}
+		})
+	}
+
+	t.Run("ya es miembro", func(t *testing.T) {
+		channels := &fakeChannels{
+			channels: map[uint]models.Channel{1: {ChannelID: 1}},
+			members:  []models.ChannelMember{{ChannelID: 1, UserID: 7, Role: models.ChannelRoleMember}},
+			links:    []models.ChannelInviteLink{{LinkID: 1, ChannelID: 1, Code: "codigo", Role: models.ChannelRoleMember}},
+		}
+		service := NewChannelService(fakeTx{}, channels, nil, nil, fakeUsers{}, fakeAuthz{}, nil, nil, nil, nil, nil, nil)
+		_, err := service.RedeemInviteLink(context.Background(), Actor{UserID: 7}, "codigo")
+		expectCode(t, err, apperror.CodeAlreadyChannelMember)
+		if channels.links[0].Uses != 0 {
+			t.Fatal("se consumió un uso del enlace")
+		}
+	})
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"gorm.io/gorm"
+)
+
+// Estados de una solicitud para entrar a un canal privado
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestDenied   = "denied"
+)
+
+// ChannelPermissionsUpdate cambia el rol mínimo de cada permiso configurable;
+// los campos nil no se modifican
+type ChannelPermissionsUpdate struct {
+	Post        *string
+	Comment     *string
+	Invite      *string
+	Pin         *string
+	DeletePosts *string
+}
+
+// ChannelPermissionSummary es lo que un miembro puede hacer en el canal
+type ChannelPermissionSummary struct {
+	Role     string
+	Settings models.ChannelPermissions
+	Allowed  map[string]bool
+}
+
+func (s *channelService) Join(ctx context.Context, actor Actor, channelID uint) (models.ChannelMember, error) {
+	channel, err := s.activeChannel(ctx, channelID)
+	if err != nil {
+		return models.ChannelMember{}, err
+	}
+	if channel.IsPrivate {
+		return models.ChannelMember{}, apperror.New(apperror.CodeChannelPrivate)
+	}
+	if err := s.checkNotBanned(ctx, channelID, actor.UserID); err != nil {
+		return models.ChannelMember{}, err
+	}
+	if ok, err := s.isMember(ctx, channelID, actor.UserID); err != nil {
+		return models.ChannelMember{}, err
+	} else if ok {
+		return models.ChannelMember{}, apperror.New(apperror.CodeAlreadyChannelMember)
+	}
+
+	member := models.ChannelMember{
+		ChannelID:  channelID,
+		UserID:     actor.UserID,
+		Role:       models.ChannelRoleMember,
+		JoinedAt:   time.Now(),
+		LastSeenAt: time.Now(),
+	}
+	if err := s.channels.AddMember(ctx, &member); err != nil {
+		return models.ChannelMember{}, memberError(err)
+	}
+	metrics.ChannelJoins.WithLabelValues("public").Inc()
+	s.record(ctx, actor, channelID, ChannelAuditMemberJoined, "canal público")
+	return member, nil
+}
+
+func (s *channelService) RequestJoin(ctx context.Context, actor Actor, channelID uint, message string) (models.ChannelJoinRequest, error) {
+	channel, err := s.activeChannel(ctx, channelID)
+	if err != nil {
+		return models.ChannelJoinRequest{}, err
+	}
+	if !channel.IsPrivate {
+		return models.ChannelJoinRequest{}, apperror.New(apperror.CodeChannelPublic)
+	}
+	if err := s.checkNotBanned(ctx, channelID, actor.UserID); err != nil {
+		return models.ChannelJoinRequest{}, err
+	}
+	if ok, err := s.isMember(ctx, channelID, actor.UserID); err != nil {
+		return models.ChannelJoinRequest{}, err
+	} else if ok {
+		return models.ChannelJoinRequest{}, apperror.New(apperror.CodeAlreadyChannelMember)
+	}
+
+	_, err = s.channels.FindPendingJoinRequest(ctx, channelID, actor.UserID)
+	switch {
+	case err == nil:
+		return models.ChannelJoinRequest{}, apperror.New(apperror.CodeJoinRequestPending)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return models.ChannelJoinRequest{}, apperror.Internal(err)
+	}
+
+	request := models.ChannelJoinRequest{
+		ChannelID: channelID,
+		UserID:    actor.UserID,
+		Message:   message,
+		Status:    JoinRequestPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.channels.CreateJoinRequest(ctx, &request); err != nil {
+		return models.ChannelJoinRequest{}, apperror.Internal(err)
+	}
+	return request, nil
+}
+
+func (s *channelService) JoinRequests(ctx context.Context, actor Actor, channelID uint) ([]models.ChannelJoinRequest, error) {
+	if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.channels.PendingJoinRequests(ctx, channelID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return requests, nil
+}
+
+func (s *channelService) RespondJoinRequest(ctx context.Context, actor Actor, requestID uint, approve bool) (models.ChannelJoinRequest, error) {
+	request, err := s.channels.FindJoinRequest(ctx, requestID)
+	if err != nil {
+		return request, apperror.NotFound(apperror.CodeJoinRequestNotFound, err)
+	}
+	if _, err := s.access.Authorize(ctx, request.ChannelID, actor.UserID, ChannelPermManage); err != nil {
+		return request, err
+	}
+	if request.Status != JoinRequestPending {
+		return request, apperror.New(apperror.CodeJoinRequestProcessed)
+	}
+	if approve {
+		if _, err := s.activeChannel(ctx, request.ChannelID); err != nil {
+			return request, err
+		}
+	}
+
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		request.Status = JoinRequestDenied
+		if approve {
+			request.Status = JoinRequestApproved
+			// Pudo haber entrado por una invitación mientras esperaba
+			ok, err := s.isMember(ctx, request.ChannelID, request.UserID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				err := s.channels.AddMember(ctx, &models.ChannelMember{
+					ChannelID:  request.ChannelID,
+					UserID:     request.UserID,
+					Role:       models.ChannelRoleMember,
+					JoinedAt:   time.Now(),
+					LastSeenAt: time.Now(),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		request.ReviewedBy = &actor.UserID
+		request.UpdatedAt = time.Now()
+		return s.channels.SaveJoinRequest(ctx, &request)
+	})
+	if err != nil {
+		return request, memberError(err)
+	}
+	if approve {
+		metrics.ChannelJoins.WithLabelValues("request").Inc()
+		s.record(ctx, actor, request.ChannelID, ChannelAuditMemberJoined, fmt.Sprintf("solicitud %d de usuario %d aprobada", request.RequestID, request.UserID))
+	}
+	return request, nil
+}
+
+func (s *channelService) Leave(ctx context.Context, actor Actor, channelID uint) error {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermView)
+	if err != nil {
+		return err
+	}
+	member := access.Member
+	if member.Role == models.ChannelRoleOwner {
+		return apperror.New(apperror.CodeChannelOwnerCannotLeave)
+	}
+	if member.Role == models.ChannelRoleModerator {
+		if err := s.checkOtherAdmins(ctx, channelID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.channels.RemoveMember(ctx, &member); err != nil {
+		return apperror.Internal(err)
+	}
+	s.record(ctx, actor, channelID, ChannelAuditMemberLeft, "")
+	return nil
+}
+
+func (s *channelService) RemoveMember(ctx context.Context, actor Actor, channelID, userID uint) error {
+	if userID == actor.UserID {
+		return s.Leave(ctx, actor, channelID)
+	}
+
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage)
+	if err != nil {
+		return err
+	}
+	target, err := s.channels.FindMember(ctx, channelID, userID)
+	if err != nil {
+		return apperror.NotFound(apperror.CodeMemberNotFound, err)
+	}
+	if err := checkOutranks(access.Member, target); err != nil {
+		return err
+	}
+
+	if err := s.channels.RemoveMember(ctx, &target); err != nil {
+		return apperror.Internal(err)
+	}
+	s.record(ctx, actor, channelID, ChannelAuditMemberRemoved, fmt.Sprintf("usuario %d", userID))
+	return nil
+}
+
+func (s *channelService) SetRole(ctx context.Context, actor Actor, channelID, userID uint, role string) (models.ChannelMember, error) {
+	if role == models.ChannelRoleOwner || !ValidChannelRole(role) {
+		return models.ChannelMember{}, apperror.InvalidField("role", "invalid_role")
+	}
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage)
+	if err != nil {
+		return models.ChannelMember{}, err
+	}
+	target, err := s.channels.FindMember(ctx, channelID, userID)
+	if err != nil {
+		return target, apperror.NotFound(apperror.CodeMemberNotFound, err)
+	}
+	if target.Role == role {
+		return target, nil
+	}
+
+	// Un moderador puede dejar de serlo por su cuenta, siempre que quede otro
+	if userID != actor.UserID {
+		if err := checkOutranks(access.Member, target); err != nil {
+			return target, err
+		}
+	} else if target.Role == models.ChannelRoleOwner {
+		return target, apperror.New(apperror.CodeChannelOwnerProtected)
+	}
+	if target.Role == models.ChannelRoleModerator {
+		if err := s.checkOtherAdmins(ctx, channelID); err != nil {
+			return target, err
+		}
+	}
+
+	previous := target.Role
+	target.Role = role
+	if err := s.channels.SaveMember(ctx, &target); err != nil {
+		return target, apperror.Internal(err)
+	}
+	s.record(ctx, actor, channelID, ChannelAuditRoleChanged, fmt.Sprintf("usuario %d: %s → %s", userID, previous, role))
+	return target, nil
+}
+
+func (s *channelService) TransferOwnership(ctx context.Context, actor Actor, channelID, userID uint) (models.Channel, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermOwn)
+	if err != nil {
+		return models.Channel{}, err
+	}
+	channel := access.Channel
+	target, err := s.channels.FindMember(ctx, channelID, userID)
+	if err != nil {
+		return channel, apperror.NotFound(apperror.CodeMemberNotFound, err)
+	}
+	if userID == actor.UserID {
+		return channel, nil
+	}
+
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		// El índice único de creador obliga a degradar primero al anterior
+		previous := access.Member
+		previous.Role = models.ChannelRoleModerator
+		if err := s.channels.SaveMember(ctx, &previous); err != nil {
+			return err
+		}
+		target.Role = models.ChannelRoleOwner
+		if err := s.channels.SaveMember(ctx, &target); err != nil {
+			return err
+		}
+		return s.channels.SetOwner(ctx, &channel, userID)
+	})
+	if err != nil {
+		return channel, apperror.Internal(err)
+	}
+	channel.CreatedBy = userID
+	s.record(ctx, actor, channelID, ChannelAuditOwnerChanged, fmt.Sprintf("usuario %d", userID))
+	return channel, nil
+}
+
+func (s *channelService) Permissions(ctx context.Context, actor Actor, channelID uint) (ChannelPermissionSummary, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermView)
+	if err != nil {
+		return ChannelPermissionSummary{}, err
+	}
+	return ChannelPermissionSummary{
+		Role:     access.Member.Role,
+		Settings: access.Channel.Permissions,
+		Allowed:  s.access.Permissions(access.Channel, access.Member),
+	}, nil
+}
+
+func (s *channelService) UpdatePermissions(ctx context.Context, actor Actor, channelID uint, input ChannelPermissionsUpdate) (models.Channel, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage)
+	if err != nil {
+		return models.Channel{}, err
+	}
+	channel := access.Channel
+
+	settings := channel.Permissions
+	fields := map[string]interface{}{}
+	var changes []string
+	for _, change := range []struct {
+		permission string
+		role       *string
+		current    *string
+	}{
+		{ChannelPermPost, input.Post, &settings.Post},
+		{ChannelPermComment, input.Comment, &settings.Comment},
+		{ChannelPermInvite, input.Invite, &settings.Invite},
+		{ChannelPermPin, input.Pin, &settings.Pin},
+		{ChannelPermDeletePosts, input.DeletePosts, &settings.DeletePosts},
+	} {
+		if change.role == nil {
+			continue
+		}
+		if !ValidChannelRole(*change.role) {
+			return channel, apperror.InvalidField(change.permission, "invalid_role")
+		}
+		if *change.role != *change.current {
+			fields["perm_"+change.permission] = *change.role
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", change.permission, *change.current, *change.role))
+			*change.current = *change.role
+		}
+	}
+	if len(fields) == 0 {
+		return channel, nil
+	}
+
+	if err := s.channels.Update(ctx, &channel, fields); err != nil {
+		return channel, apperror.Internal(err)
+	}
+	channel.Permissions = settings
+	s.record(ctx, actor, channelID, ChannelAuditPermissionsChanged, strings.Join(changes, "; "))
+	return channel, nil
+}
+
+func (s *channelService) Ban(ctx context.Context, actor Actor, channelID, userID uint, reason string) (models.ChannelBan, error) {
+	if userID == actor.UserID {
+		return models.ChannelBan{}, apperror.New(apperror.CodeForbidden)
+	}
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage)
+	if err != nil {
+		return models.ChannelBan{}, err
+	}
+
+	target, err := s.channels.FindMember(ctx, channelID, userID)
+	isMember := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ChannelBan{}, apperror.Internal(err)
+	}
+	if isMember {
+		if err := checkOutranks(access.Member, target); err != nil {
+			return models.ChannelBan{}, err
+		}
+	}
+
+	_, err = s.channels.FindBan(ctx, channelID, userID)
+	switch {
+	case err == nil:
+		return models.ChannelBan{}, apperror.New(apperror.CodeAlreadyBanned)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return models.ChannelBan{}, apperror.Internal(err)
+	}
+
+	ban := models.ChannelBan{
+		ChannelID: channelID,
+		UserID:    userID,
+		BannedBy:  &actor.UserID,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if isMember {
+			if err := s.channels.RemoveMember(ctx, &target); err != nil {
+				return err
+			}
+		}
+		if err := s.channels.CancelPendingInvitations(ctx, channelID, userID); err != nil {
+			return err
+		}
+		if err := s.channels.DenyPendingJoinRequests(ctx, channelID, userID, actor.UserID); err != nil {
+			return err
+		}
+		return s.channels.CreateBan(ctx, &ban)
+	})
+	if err != nil {
+		return models.ChannelBan{}, apperror.Internal(err)
+	}
+	details := fmt.Sprintf("usuario %d", userID)
+	if reason != "" {
+		details += ": " + reason
+	}
+	s.record(ctx, actor, channelID, ChannelAuditUserBanned, details)
+	return ban, nil
+}
+
+func (s *channelService) Unban(ctx context.Context, actor Actor, channelID, userID uint) error {
+	if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage); err != nil {
+		return err
+	}
+	ban, err := s.channels.FindBan(ctx, channelID, userID)
+	if err != nil {
+		return apperror.NotFound(apperror.CodeBanNotFound, err)
+	}
+
+	if err := s.channels.DeleteBan(ctx, &ban); err != nil {
+		return apperror.Internal(err)
+	}
+	s.record(ctx, actor, channelID, ChannelAuditUserUnbanned, fmt.Sprintf("usuario %d", userID))
+	return nil
+}
+
+func (s *channelService) Bans(ctx context.Context, actor Actor, channelID uint) ([]models.ChannelBan, error) {
+	if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermManage); err != nil {
+		return nil, err
+	}
+
+	bans, err := s.channels.Bans(ctx, channelID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return bans, nil
+}
+
+// checkOutranks verifica que actor pueda expulsar, bloquear o cambiar el rol
+// de target: al creador no se lo puede tocar y a un moderador solo el creador
+func checkOutranks(actor, target models.ChannelMember) error {
+	switch {
+	case target.Role == models.ChannelRoleOwner:
+		return apperror.New(apperror.CodeChannelOwnerProtected)
+	case target.Role == models.ChannelRoleModerator && actor.Role != models.ChannelRoleOwner:
+		return apperror.New(apperror.CodeChannelOwnerRequired)
+	}
+	return nil
+}
+
+// checkOtherAdmins devuelve LAST_CHANNEL_ADMIN si el canal se quedaría sin
+// administradores al perder uno
+func (s *channelService) checkOtherAdmins(ctx context.Context, channelID uint) error {
+	admins, err := s.channels.CountAdmins(ctx, channelID)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if admins <= 1 {
+		return apperror.New(apperror.CodeLastChannelAdmin)
+	}
+	return nil
+}
+
+// checkNotBanned devuelve CHANNEL_USER_BANNED si el usuario está bloqueado
+func (s *channelService) checkNotBanned(ctx context.Context, channelID, userID uint) error {
+	_, err := s.channels.FindBan(ctx, channelID, userID)
+	switch {
+	case err == nil:
+		return apperror.New(apperror.CodeChannelBanned)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (s *channelService) isMember(ctx context.Context, channelID, userID uint) (bool, error) {
+	_, err := s.channels.FindMember(ctx, channelID, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return false, nil
+	case err != nil:
+		return false, apperror.Internal(err)
+	}
+	return true, nil
+}
+
+// memberError traduce el error de agregar un miembro: si otro pedido lo
+// agregó primero, el usuario ya es miembro
+func memberError(err error) error {
+	var appErr *apperror.Error
+	switch {
+	case errors.Is(err, repository.ErrAlreadyMember):
+		return apperror.New(apperror.CodeAlreadyChannelMember)
+	case errors.As(err, &appErr):
+		return appErr
+	}
+	return apperror.Internal(err)
+}
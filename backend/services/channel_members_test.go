@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+func TestCheckOutranks(t *testing.T) {
+	tests := []struct {
+		actor  string
+		target string
+		code   apperror.Code
+	}{
+		{models.ChannelRoleOwner, models.ChannelRoleModerator, ""},
+		{models.ChannelRoleOwner, models.ChannelRoleMember, ""},
+		{models.ChannelRoleModerator, models.ChannelRoleMember, ""},
+		{models.ChannelRoleModerator, models.ChannelRoleReadOnly, ""},
+		{models.ChannelRoleModerator, models.ChannelRoleModerator, apperror.CodeChannelOwnerRequired},
+		{models.ChannelRoleModerator, models.ChannelRoleOwner, apperror.CodeChannelOwnerProtected},
+		// Al creador no lo toca nadie, ni otro con su mismo rol
+		{models.ChannelRoleOwner, models.ChannelRoleOwner, apperror.CodeChannelOwnerProtected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.actor+"/"+tt.target, func(t *testing.T) {
+			err := checkOutranks(models.ChannelMember{Role: tt.actor}, models.ChannelMember{Role: tt.target})
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("se esperaba permitir: %v", err)
+				}
+				return
+			}
+			expectCode(t, err, tt.code)
+		})
+	}
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// ChannelPostList son los posts de un canal, del más nuevo al más viejo, con
+// el límite de lo nuevo desde la última visita del actor: LastSeenAt es su
+// marca de lectura y FirstUnreadPostID el post no leído más viejo, o nil si
+// leyó todo
+type ChannelPostList struct {
+	Posts             []models.ChannelPost
+	LastSeenAt        time.Time
+	FirstUnreadPostID *uint
+	UnreadPosts       int
+}
+
+// NewChannelPost son los datos para publicar en un canal. Announcement
+// publica el post como anuncio y avisa por email a todos los miembros.
+type NewChannelPost struct {
+	Content      string
+	TagIDs       []uint
+	Files        []File
+	Announcement bool
+}
+
+// ChannelPostUpdate son los cambios a un post de canal; los campos nil no se
+// modifican
+type ChannelPostUpdate struct {
+	Content *string
+	TagIDs  *[]uint
+}
+
+// MaxPinnedChannelPosts es la cantidad máxima de posts fijados de un canal
+const MaxPinnedChannelPosts = 5
+
+// ChannelFileFolder es la carpeta del almacenamiento donde se guardan los
+// archivos de los canales
+const ChannelFileFolder = "channel_files"
+
+// MaxChannelPostFiles es la cantidad máxima de archivos de un post de canal
+const MaxChannelPostFiles = 10
+
+// ChannelFileLinkTTL es lo que dura un enlace de descarga de un archivo de
+// canal. El enlace redirige a una URL del almacenamiento que vence en
+// channelFileRedirectTTL, así que abrirlo requiere seguir siendo miembro.
+const ChannelFileLinkTTL = 15 * time.Minute
+
+const channelFileRedirectTTL = time.Minute
+
+// FileLink es un enlace de descarga firmado para un usuario. URL es relativa
+// a la API.
+type FileLink struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+func (s *channelService) CreatePost(ctx context.Context, actor Actor, channelID uint, input NewChannelPost) (models.ChannelPost, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermPost)
+	if err != nil {
+		return models.ChannelPost{}, err
+	}
+	// Igual que al editar, el contenido no puede ser solo espacios
+	if strings.TrimSpace(input.Content) == "" {
+		return models.ChannelPost{}, apperror.InvalidField("content", "required")
+	}
+	if input.Announcement {
+		if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermPin); err != nil {
+			return models.ChannelPost{}, err
+		}
+	}
+	if len(input.Files) > MaxChannelPostFiles {
+		return models.ChannelPost{}, apperror.InvalidField("files", "too_many_files").WithParam("max", MaxChannelPostFiles)
+	}
+	tags, err := s.tags(ctx, input.TagIDs)
+	if err != nil {
+		return models.ChannelPost{}, err
+	}
+
+	// Igual que en los posts públicos, los archivos se suben antes de abrir
+	// la transacción
+	files := make([]models.ChannelPostFile, 0, len(input.Files))
+	for _, file := range input.Files {
+		url, err := s.storage.UploadPrivate(ctx, ChannelFileFolder, file)
+		if err != nil {
+			s.deleteFiles(ctx, channelID, files)
+			return models.ChannelPost{}, apperror.New(apperror.CodeUploadFailed).WithParam("file", file.Name).Wrap(err)
+		}
+		files = append(files, models.ChannelPostFile{
+			FileURL:  url,
+			FileType: fileType(file.Name),
+			FileName: file.Name,
+		})
+	}
+
+	post := models.ChannelPost{
+		ChannelID:      channelID,
+		UserID:         actor.UserID,
+		Content:        input.Content,
+		Tags:           tags,
+		IsAnnouncement: input.Announcement,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.posts.Create(ctx, &post); err != nil {
+			return err
+		}
+		for i := range files {
+			files[i].PostID = post.PostID
+			if err := s.posts.AddFile(ctx, &files[i]); err != nil {
+				return fmt.Errorf("guardando el archivo %s del post %d: %w", files[i].FileName, post.PostID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.deleteFiles(ctx, channelID, files)
+		return models.ChannelPost{}, apperror.Internal(err)
+	}
+	post.Files = files
+	metrics.PostsCreated.WithLabelValues("channel").Inc()
+	s.publish(ctx, channelID, ChannelEventPostCreated, post)
+	if post.IsAnnouncement {
+		s.announcer.Enqueue(ctx, access.Channel, post)
+	}
+	return post, nil
+}
+
+func (s *channelService) Posts(ctx context.Context, actor Actor, channelID uint, filter repository.ChannelPostFilter) (ChannelPostList, error) {
+	access, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermView)
+	if err != nil {
+		return ChannelPostList{}, err
+	}
+
+	posts, err := s.posts.ListByChannel(ctx, channelID, filter)
+	if err != nil {
+		return ChannelPostList{}, apperror.Internal(err)
+	}
+
+	expiresAt := time.Now().Add(ChannelFileLinkTTL)
+	for i := range posts {
+		for j := range posts[i].Files {
+			file := &posts[i].Files[j]
+			link, err := s.fileLink(file.FileID, actor.UserID, expiresAt)
+			if err != nil {
+				return ChannelPostList{}, apperror.Internal(err)
+			}
+			file.DownloadURL = link.URL
+		}
+	}
+
+	list := ChannelPostList{Posts: posts, LastSeenAt: access.Member.LastSeenAt}
+	var firstUnread *models.ChannelPost
+	for i := range posts {
+		if posts[i].UserID != actor.UserID && posts[i].CreatedAt.After(list.LastSeenAt) {
+			// Los fijados van primero, así que el límite es el no leído más
+			// viejo y no el último de la lista
+			if firstUnread == nil || posts[i].CreatedAt.Before(firstUnread.CreatedAt) {
+				firstUnread = &posts[i]
+			}
+			list.UnreadPosts++
+		}
+	}
+	if firstUnread != nil {
+		list.FirstUnreadPostID = &firstUnread.PostID
+	}
+	return list, nil
+}
+
+func (s *channelService) UpdatePost(ctx context.Context, actor Actor, postID uint, input ChannelPostUpdate) (models.ChannelPost, error) {
+	post, _, err := s.postAccess(ctx, actor, postID, ChannelPermPost)
+	if err != nil {
+		return post, err
+	}
+	if post.UserID != actor.UserID {
+		return post, apperror.New(apperror.CodeForbidden)
+	}
+	if input.Content != nil && strings.TrimSpace(*input.Content) == "" {
+		return post, apperror.InvalidField("content", "required")
+	}
+	var tags []models.Tag
+	if input.TagIDs != nil {
+		if tags, err = s.tags(ctx, *input.TagIDs); err != nil {
+			return post, err
+		}
+	}
+
+	var now time.Time
+	changed := false
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		// La versión anterior se lee con la fila bloqueada: dos ediciones
+		// simultáneas guardan cada una la que reemplazan
+		var err error
+		if post, err = s.posts.FindForUpdate(ctx, postID); err != nil {
+			return apperror.NotFound(apperror.CodePostNotFound, err)
+		}
+		if changed = postChanged(post, input, tags); !changed {
+			return nil
+		}
+		now = time.Now()
+
+		// El historial guarda los nombres de los tags para que se lean igual
+		// aunque después se borre alguno
+		previous := make(pq.StringArray, len(post.Tags))
+		for i, tag := range post.Tags {
+			previous[i] = tag.Name
+		}
+		edit := models.ChannelPostEdit{
+			PostID:    post.PostID,
+			EditorID:  &actor.UserID,
+			Content:   post.Content,
+			Tags:      previous,
+			CreatedAt: now,
+		}
+		fields := map[string]interface{}{"edited_at": now}
+		if input.Content != nil {
+			fields["content"] = *input.Content
+		}
+
+		if err := s.posts.CreateEdit(ctx, &edit); err != nil {
+			return err
+		}
+		if input.TagIDs != nil {
+			if err := s.posts.ReplaceTags(ctx, &post, tags); err != nil {
+				return err
+			}
+		}
+		return s.posts.Update(ctx, &post, fields)
+	})
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		return post, appErr
+	case err != nil:
+		return post, apperror.Internal(err)
+	case !changed:
+		return post, nil
+	}
+	if input.Content != nil {
+		post.Content = *input.Content
+	}
+	if input.TagIDs != nil {
+		post.Tags = tags
+	}
+	post.EditedAt = &now
+	s.publish(ctx, post.ChannelID, ChannelEventPostUpdated, post)
+	return post, nil
+}
+
+// postChanged indica si la edición cambia el contenido o los tags del post.
+// Los tags se comparan como conjunto.
+func postChanged(post models.ChannelPost, input ChannelPostUpdate, tags []models.Tag) bool {
+	if input.Content != nil && *input.Content != post.Content {
+		return true
+	}
+	if input.TagIDs == nil {
+		return false
+	}
+	current := map[uint]bool{}
+	for _, tag := range post.Tags {
+		current[tag.TagID] = true
+	}
+	updated := map[uint]bool{}
+	for _, tag := range tags {
+		if !current[tag.TagID] {
+			return true
+		}
+		updated[tag.TagID] = true
+	}
+	return len(updated) != len(current)
+}
+
+func (s *channelService) PostEdits(ctx context.Context, actor Actor, postID uint) ([]models.ChannelPostEdit, error) {
+	if _, _, err := s.postAccess(ctx, actor, postID, ChannelPermView); err != nil {
+		return nil, err
+	}
+	edits, err := s.posts.Edits(ctx, postID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return edits, nil
+}
+
+func (s *channelService) SetPinned(ctx context.Context, actor Actor, postID uint, pinned bool) (models.ChannelPost, error) {
+	post, _, err := s.postAccess(ctx, actor, postID, ChannelPermPin)
+	if err != nil {
+		return post, err
+	}
+	if pinned == (post.PinnedAt != nil) {
+		return post, nil
+	}
+
+	action, eventType := ChannelAuditPostUnpinned, ChannelEventPostUnpinned
+	if pinned {
+		action, eventType = ChannelAuditPostPinned, ChannelEventPostPinned
+		ok, err := s.posts.Pin(ctx, &post, actor.UserID, MaxPinnedChannelPosts)
+		if err != nil {
+			return post, apperror.Internal(err)
+		}
+		if !ok {
+			return post, apperror.New(apperror.CodeChannelPinLimit).WithParam("max", MaxPinnedChannelPosts)
+		}
+	} else if err := s.posts.Unpin(ctx, &post); err != nil {
+		return post, apperror.Internal(err)
+	}
+
+	s.record(ctx, actor, post.ChannelID, action, fmt.Sprintf("post %d", post.PostID))
+	s.publish(ctx, post.ChannelID, eventType, postEvent{PostID: post.PostID, UserID: actor.UserID})
+	return post, nil
+}
+
+func (s *channelService) FileLink(ctx context.Context, actor Actor, fileID uint) (FileLink, error) {
+	file, err := s.posts.FindFile(ctx, fileID)
+	if err != nil {
+		return FileLink{}, apperror.NotFound(apperror.CodeFileNotFound, err)
+	}
+	if _, err := s.access.Authorize(ctx, file.Post.ChannelID, actor.UserID, ChannelPermView); err != nil {
+		return FileLink{}, err
+	}
+
+	link, err := s.fileLink(fileID, actor.UserID, time.Now().Add(ChannelFileLinkTTL))
+	if err != nil {
+		return FileLink{}, apperror.Internal(err)
+	}
+	return link, nil
+}
+
+func (s *channelService) DownloadFile(ctx context.Context, fileID uint, token string) (string, error) {
+	signedID, userID, err := s.files.VerifyFile(token)
+	switch {
+	case errors.Is(err, utils.ErrTokenExpired):
+		return "", apperror.New(apperror.CodeFileLinkExpired)
+	case err != nil:
+		return "", apperror.New(apperror.CodeFileLinkInvalid).Wrap(err)
+	case signedID != fileID:
+		return "", apperror.New(apperror.CodeFileLinkInvalid)
+	}
+
+	file, err := s.posts.FindFile(ctx, fileID)
+	if err != nil {
+		return "", apperror.NotFound(apperror.CodeFileNotFound, err)
+	}
+	// El enlace vale mientras el usuario siga pudiendo ver el canal
+	if _, err := s.access.Authorize(ctx, file.Post.ChannelID, userID, ChannelPermView); err != nil {
+		return "", err
+	}
+
+	signed, err := s.storage.SignedURL(ctx, file.FileURL, time.Now().Add(channelFileRedirectTTL))
+	if err != nil {
+		return "", apperror.Internal(err)
+	}
+	return signed, nil
+}
+
+// fileLink firma el enlace de descarga del archivo para el usuario
+func (s *channelService) fileLink(fileID, userID uint, expiresAt time.Time) (FileLink, error) {
+	token, err := s.files.SignFile(fileID, userID, expiresAt)
+	if err != nil {
+		return FileLink{}, err
+	}
+	return FileLink{
+		URL:       fmt.Sprintf("/channels/files/%d/download?token=%s", fileID, url.QueryEscape(token)),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// tags busca los tags de un post de canal; son los mismos que usan los posts
+// públicos, así que tienen que existir
+func (s *channelService) tags(ctx context.Context, tagIDs []uint) ([]models.Tag, error) {
+	tags, err := s.catalog.FindTags(ctx, tagIDs)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if id, ok := missingTag(tagIDs, tags); ok {
+		return nil, apperror.InvalidField("tag_ids", "unknown_tag").WithParam("id", id)
+	}
+	return tags, nil
+}
+
+// deleteFiles borra archivos del almacenamiento después de borrarlos de la
+// base, o de no llegar a guardarlos. Un error solo se registra.
+func (s *channelService) deleteFiles(ctx context.Context, channelID uint, files []models.ChannelPostFile) {
+	for _, file := range files {
+		if err := s.storage.Delete(ctx, file.FileURL); err != nil {
+			logging.FromContext(ctx).Error("Error borrando archivo del canal", "channel_id", channelID, "url", file.FileURL, "error", err)
+		}
+	}
+}
+
+func (s *channelService) AddComment(ctx context.Context, actor Actor, postID uint, content string) (models.ChannelPostComment, error) {
+	post, _, err := s.postAccess(ctx, actor, postID, ChannelPermComment)
+	if err != nil {
+		return models.ChannelPostComment{}, err
+	}
+
+	comment := models.ChannelPostComment{
+		PostID:    postID,
+		UserID:    actor.UserID,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	if err := s.posts.CreateComment(ctx, &comment); err != nil {
+		return models.ChannelPostComment{}, apperror.Internal(err)
+	}
+	s.publish(ctx, post.ChannelID, ChannelEventCommentCreated, comment)
+	return comment, nil
+}
+
+func (s *channelService) ToggleLike(ctx context.Context, actor Actor, postID uint) (*models.ChannelPostLike, error) {
+	post, _, err := s.postAccess(ctx, actor, postID, ChannelPermComment)
+	if err != nil {
+		return nil, err
+	}
+
+	like, err := s.posts.FindLike(ctx, postID, actor.UserID)
+	switch {
+	case err == nil:
+		if err := s.posts.DeleteLike(ctx, &like); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		s.publish(ctx, post.ChannelID, ChannelEventLikeRemoved, postEvent{PostID: postID, UserID: actor.UserID})
+		return nil, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, apperror.Internal(err)
+	}
+
+	like = models.ChannelPostLike{
+		PostID:  postID,
+		UserID:  actor.UserID,
+		LikedAt: time.Now(),
+	}
+	switch err := s.posts.CreateLike(ctx, &like); {
+	case errors.Is(err, repository.ErrAlreadyLiked):
+		// Otro pedido del actor, como un doble clic, lo agregó recién: el
+		// like queda puesto
+		if like, err = s.posts.FindLike(ctx, postID, actor.UserID); err != nil {
+			return nil, apperror.Internal(err)
+		}
+		return &like, nil
+	case err != nil:
+		return nil, apperror.Internal(err)
+	}
+	s.publish(ctx, post.ChannelID, ChannelEventLikeAdded, postEvent{PostID: postID, UserID: actor.UserID})
+	return &like, nil
+}
+
+func (s *channelService) DeletePost(ctx context.Context, actor Actor, postID uint) error {
+	post, err := s.posts.FindByID(ctx, postID)
+	if err != nil {
+		return apperror.NotFound(apperror.CodePostNotFound, err)
+	}
+
+	// El autor borra sus posts con solo ser miembro; los ajenos requieren
+	// delete_posts
+	permission := ChannelPermDeletePosts
+	if post.UserID == actor.UserID {
+		permission = ChannelPermView
+	}
+	access, err := s.access.Authorize(ctx, post.ChannelID, actor.UserID, permission)
+	if err != nil {
+		return err
+	}
+	// Un canal archivado es de solo lectura; solo los moderadores de rbac
+	// pueden sacar contenido
+	if access.Channel.ArchivedAt != nil && !access.Moderating {
+		return apperror.New(apperror.CodeChannelArchived)
+	}
+
+	files, err := s.posts.Files(ctx, post.PostID)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.posts.Delete(ctx, &post); err != nil {
+		return apperror.Internal(err)
+	}
+	s.publish(ctx, post.ChannelID, ChannelEventPostDeleted, postEvent{PostID: post.PostID})
+	s.deleteFiles(ctx, post.ChannelID, files)
+
+	if access.Moderating {
+		s.audit.Audit(ctx, models.AuditLog{
+			UserID:  &post.UserID,
+			ActorID: &actor.UserID,
+			Action:  security.AuditContentModerated,
+			IP:      actor.IP,
+			Details: fmt.Sprintf("post %d del canal %d eliminado", post.PostID, post.ChannelID),
+		})
+	}
+	return nil
+}
+
+// postAccess busca un post de canal y autoriza el permiso en su canal
+func (s *channelService) postAccess(ctx context.Context, actor Actor, postID uint, permission string) (models.ChannelPost, ChannelAccess, error) {
+	post, err := s.posts.FindByID(ctx, postID)
+	if err != nil {
+		return post, ChannelAccess{}, apperror.NotFound(apperror.CodePostNotFound, err)
+	}
+	access, err := s.access.Authorize(ctx, post.ChannelID, actor.UserID, permission)
+	return post, access, err
+}
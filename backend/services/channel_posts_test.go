@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+)
+
+func TestPostChanged(t *testing.T) {
+	post := models.ChannelPost{Content: "Resumen", Tags: []models.Tag{{TagID: 1}, {TagID: 2}}}
+	content := func(s string) *string { return &s }
+	tagIDs := func(ids ...uint) *[]uint { return &ids }
+	tags := func(ids ...uint) []models.Tag {
+		tags := make([]models.Tag, len(ids))
+		for i, id := range ids {
+			tags[i] = models.Tag{TagID: id}
+		}
+		return tags
+	}
+
+	tests := []struct {
+		name    string
+		input   ChannelPostUpdate
+		tags    []models.Tag
+		changed bool
+	}{
+		{"sin campos", ChannelPostUpdate{}, nil, false},
+		{"mismo contenido", ChannelPostUpdate{Content: content("Resumen")}, nil, false},
+		{"mismos tags en otro orden", ChannelPostUpdate{TagIDs: tagIDs(2, 1)}, tags(2, 1), false},
+		{"contenido nuevo", ChannelPostUpdate{Content: content("Resumen corregido")}, nil, true},
+		{"tag de más", ChannelPostUpdate{TagIDs: tagIDs(1, 2, 3)}, tags(1, 2, 3), true},
+		{"tag de menos", ChannelPostUpdate{TagIDs: tagIDs(1)}, tags(1), true},
+		{"sin tags", ChannelPostUpdate{TagIDs: tagIDs()}, nil, true},
+	}
+	for _, tt := range tests {
+		if changed := postChanged(post, tt.input, tt.tags); changed != tt.changed {
+			t.Errorf("%s: postChanged = %v, se esperaba %v", tt.name, changed, tt.changed)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/realtime"
+)
+
+// Tipos de los eventos en tiempo real de un canal
+const (
+	ChannelEventPostCreated    = "post.created"
+	ChannelEventPostUpdated    = "post.updated"
+	ChannelEventPostDeleted    = "post.deleted"
+	ChannelEventPostPinned     = "post.pinned"
+	ChannelEventPostUnpinned   = "post.unpinned"
+	ChannelEventCommentCreated = "comment.created"
+	ChannelEventLikeAdded      = "like.added"
+	ChannelEventLikeRemoved    = "like.removed"
+)
+
+// ChannelStreamRefresh es cada cuánto conviene llamar a RefreshStream para
+// que quien deja un canal deje de recibir sus eventos
+const ChannelStreamRefresh = 30 * time.Second
+
+// ChannelStream son los eventos en tiempo real de los canales de un usuario.
+// Backlog son los eventos que se perdió desde el último que recibió; si
+// Resync es true no se pudieron recuperar todos y tiene que volver a cargar
+// los canales. Los eventos de Backlog también pueden llegar por la
+// suscripción y se deben descartar.
+type ChannelStream struct {
+	Subscription *realtime.Subscription
+	Backlog      []realtime.Event
+	Resync       bool
+	Channels     []uint
+
+	// requested son los canales pedidos; vacío sigue todos los del usuario
+	requested []uint
+}
+
+// postEvent son los datos de los eventos de like y de post borrado
+type postEvent struct {
+	PostID uint `json:"post_id"`
+	UserID uint `json:"user_id,omitempty"`
+}
+
+func (s *channelService) Stream(ctx context.Context, actor Actor, channelIDs []uint, lastEventID *uint64) (*ChannelStream, error) {
+	for _, channelID := range channelIDs {
+		if _, err := s.access.Authorize(ctx, channelID, actor.UserID, ChannelPermView); err != nil {
+			return nil, err
+		}
+	}
+	stream := &ChannelStream{requested: channelIDs}
+	channels, err := s.streamChannels(ctx, actor, stream)
+	if err != nil {
+		return nil, err
+	}
+	stream.Channels = channels
+
+	// Se suscribe antes de leer los eventos perdidos para no perder los que
+	// se publiquen en el medio; los repetidos los descarta el llamador
+	stream.Subscription = s.events.Subscribe(channels)
+	if lastEventID != nil {
+		backlog, complete, err := s.events.Replay(ctx, channels, *lastEventID)
+		if err != nil {
+			stream.Subscription.Close()
+			return nil, apperror.Internal(err)
+		}
+		stream.Backlog = backlog
+		stream.Resync = !complete
+	}
+	return stream, nil
+}
+
+func (s *channelService) RefreshStream(ctx context.Context, actor Actor, stream *ChannelStream) error {
+	channels, err := s.streamChannels(ctx, actor, stream)
+	if err != nil {
+		return err
+	}
+	stream.Channels = channels
+	stream.Subscription.SetChannels(channels)
+	return nil
+}
+
+// streamChannels devuelve los canales del stream de los que el actor sigue
+// siendo miembro
+func (s *channelService) streamChannels(ctx context.Context, actor Actor, stream *ChannelStream) ([]uint, error) {
+	member, err := s.channels.MemberChannelIDs(ctx, actor.UserID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if len(stream.requested) == 0 {
+		return member, nil
+	}
+
+	isMember := make(map[uint]bool, len(member))
+	for _, channelID := range member {
+		isMember[channelID] = true
+	}
+	channels := []uint{}
+	for _, channelID := range stream.requested {
+		if isMember[channelID] {
+			channels = append(channels, channelID)
+		}
+	}
+	return channels, nil
+}
+
+// publish envía un evento en tiempo real a los miembros del canal. Como
+// record, un error no corta la operación: los clientes se enteran del cambio
+// al recargar.
+func (s *channelService) publish(ctx context.Context, channelID uint, eventType string, data interface{}) {
+	if _, err := s.events.Publish(ctx, channelID, eventType, data); err != nil {
+		logging.FromContext(ctx).Error("Error publicando el evento del canal", "channel_id", channelID, "type", eventType, "error", err)
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"gorm.io/gorm"
+)
+
+// PostFolder es la carpeta del almacenamiento donde se guardan los archivos
+// adjuntos a los posts
+const PostFolder = "post_files"
+
+// PostDetails es un post con los datos que se muestran junto a él
+type PostDetails struct {
+	Post       models.Post
+	University string
+	Career     string
+	Files      []models.PostFile
+}
+
+// NewPost son los datos para crear un post del feed
+type NewPost struct {
+	Content      string
+	UniversityID uint
+	CareerID     uint
+	TagIDs       []uint
+	Files        []File
+}
+
+// PostService es la lógica de los posts del feed
+type PostService interface {
+	List(ctx context.Context, page repository.Page) ([]PostDetails, int64, error)
+	ListByUser(ctx context.Context, userID uint, page repository.Page) ([]PostDetails, int64, error)
+	Search(ctx context.Context, filter repository.PostFilter) ([]PostDetails, error)
+	Get(ctx context.Context, postID uint) (PostDetails, error)
+	Create(ctx context.Context, actor Actor, input NewPost) (models.Post, error)
+	// Update y Delete los puede hacer el autor o un moderador de la universidad
+	// del post; las acciones de moderación quedan auditadas
+	Update(ctx context.Context, actor Actor, postID uint, input PostUpdate) error
+	Delete(ctx context.Context, actor Actor, postID uint) error
+	// ToggleLike agrega el like del actor o lo quita si ya existía; devuelve
+	// si el post quedó con like
+	ToggleLike(ctx context.Context, actor Actor, postID uint) (bool, error)
+	AddComment(ctx context.Context, actor Actor, postID uint, content string) (models.Comment, error)
+	// DeleteComment lo puede hacer el autor del comentario o un moderador de
+	// comentarios de la universidad del post
+	DeleteComment(ctx context.Context, actor Actor, postID, commentID uint) error
+}
+
+type postService struct {
+	tx      repository.Transactor
+	posts   repository.PostRepository
+	catalog repository.CatalogRepository
+	authz   Authorizer
+	audit   Auditor
+	storage Storage
+}
+
+// NewPostService crea un PostService
+func NewPostService(tx repository.Transactor, posts repository.PostRepository, catalog repository.CatalogRepository, authz Authorizer, audit Auditor, storage Storage) PostService {
+	return &postService{tx: tx, posts: posts, catalog: catalog, authz: authz, audit: audit, storage: storage}
+}
+
+func (s *postService) List(ctx context.Context, page repository.Page) ([]PostDetails, int64, error) {
+	posts, total, err := s.posts.List(ctx, page)
+	if err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+	details, err := s.details(ctx, posts)
+	return details, total, err
+}
+
+func (s *postService) ListByUser(ctx context.Context, userID uint, page repository.Page) ([]PostDetails, int64, error) {
+	posts, total, err := s.posts.ListByUser(ctx, userID, page)
+	if err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+	details, err := s.details(ctx, posts)
+	return details, total, err
+}
+
+func (s *postService) Search(ctx context.Context, filter repository.PostFilter) ([]PostDetails, error) {
+	posts, err := s.posts.Search(ctx, filter)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return s.details(ctx, posts)
+}
+
+func (s *postService) Get(ctx context.Context, postID uint) (PostDetails, error) {
+	post, err := s.posts.FindWithRelations(ctx, postID)
+	if err != nil {
+		return PostDetails{}, apperror.NotFound(apperror.CodePostNotFound, err)
+	}
+	return s.detail(ctx, post)
+}
+
+func (s *postService) Create(ctx context.Context, actor Actor, input NewPost) (models.Post, error) {
+	tags, err := s.catalog.FindTags(ctx, input.TagIDs)
+	if err != nil {
+		return models.Post{}, apperror.Internal(err)
+	}
+	if id, ok := missingTag(input.TagIDs, tags); ok {
+		return models.Post{}, apperror.InvalidField("tag_ids", "unknown_tag").WithParam("id", id)
+	}
+
+	// Los archivos se suben antes de abrir la transacción para no tenerla
+	// abierta durante las subidas
+	files := make([]models.PostFile, 0, len(input.Files))
+	for _, file := range input.Files {
+		url, err := s.storage.Upload(ctx, PostFolder, file)
+		if err != nil {
+			return models.Post{}, apperror.New(apperror.CodeUploadFailed).WithParam("file", file.Name).Wrap(err)
+		}
+		files = append(files, models.PostFile{
+			FileURL:  url,
+			FileType: fileType(file.Name),
+			FileName: file.Name,
+		})
+	}
+
+	post := models.Post{
+		Content:      input.Content,
+		CareerID:     input.CareerID,
+		UniversityID: input.UniversityID,
+		UserID:       actor.UserID,
+		Tags:         tags,
+	}
+
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.posts.Create(ctx, &post); err != nil {
+			return err
+		}
+		for i := range files {
+			files[i].PostID = post.PostID
+			if err := s.posts.AddFile(ctx, &files[i]); err != nil {
+				return fmt.Errorf("guardando el archivo %s del post %d: %w", files[i].FileName, post.PostID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.deleteFiles(ctx, files)
+		return models.Post{}, apperror.Internal(err)
+	}
+
+	metrics.PostsCreated.WithLabelValues("feed").Inc()
+	return post, nil
+}
+
+func (s *postService) ToggleLike(ctx context.Context, actor Actor, postID uint) (bool, error) {
+	if _, err := s.posts.FindByID(ctx, postID); err != nil {
+		return false, apperror.NotFound(apperror.CodePostNotFound, err)
+	}
+
+	like, err := s.posts.FindLike(ctx, postID, actor.UserID)
+	switch {
+	case err == nil:
+		if err := s.posts.DeleteLike(ctx, &like); err != nil {
+			return false, apperror.Internal(err)
+		}
+		return false, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return false, apperror.Internal(err)
+	}
+
+	like = models.PostLike{
+		PostID:  postID,
+		UserID:  actor.UserID,
+		LikedAt: time.Now(),
+	}
+	if err := s.posts.CreateLike(ctx, &like); err != nil {
+		return false, apperror.Internal(err)
+	}
+	return true, nil
+}
+
+func (s *postService) AddComment(ctx context.Context, actor Actor, postID uint, content string) (models.Comment, error) {
+	if _, err := s.posts.FindByID(ctx, postID); err != nil {
+		return models.Comment{}, apperror.NotFound(apperror.CodePostNotFound, err)
+	}
+
+	comment := models.Comment{
+		PostID:    postID,
+		UserID:    actor.UserID,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	if err := s.posts.CreateComment(ctx, &comment); err != nil {
+		return models.Comment{}, apperror.Internal(err)
+	}
+	return comment, nil
+}
+
+func (s *postService) details(ctx context.Context, posts []models.Post) ([]PostDetails, error) {
+	details := make([]PostDetails, 0, len(posts))
+	for _, post := range posts {
+		detail, err := s.detail(ctx, post)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+	}
+	return details, nil
+}
+
+func (s *postService) detail(ctx context.Context, post models.Post) (PostDetails, error) {
+	university, err := s.catalog.UniversityName(ctx, post.UniversityID)
+	if err != nil {
+		return PostDetails{}, apperror.Internal(err)
+	}
+	career, err := s.catalog.CareerName(ctx, post.CareerID)
+	if err != nil {
+		return PostDetails{}, apperror.Internal(err)
+	}
+	files, err := s.posts.Files(ctx, post.PostID)
+	if err != nil {
+		return PostDetails{}, apperror.Internal(err)
+	}
+	return PostDetails{Post: post, University: university, Career: career, Files: files}, nil
+}
+
+// missingTag devuelve el primer id de ids que no está entre los tags encontrados
+func missingTag(ids []uint, tags []models.Tag) (uint, bool) {
+	found := make(map[uint]bool, len(tags))
+	for _, tag := range tags {
+		found[tag.TagID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return id, true
+		}
+	}
+	return 0, false
+}
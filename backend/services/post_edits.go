@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/logging"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+)
+
+// PostUpdate son los cambios a un post; los campos nil no se modifican
+type PostUpdate struct {
+	Content *string
+	TagIDs  *[]uint
+}
+
+func (s *postService) Update(ctx context.Context, actor Actor, postID uint, input PostUpdate) error {
+	post, moderating, err := s.authorOrModerator(ctx, actor, postID)
+	if err != nil {
+		return err
+	}
+
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if input.Content != nil {
+			if err := s.posts.UpdateContent(ctx, &post, *input.Content); err != nil {
+				return err
+			}
+		}
+		if input.TagIDs != nil {
+			tags, err := s.catalog.FindTags(ctx, *input.TagIDs)
+			if err != nil {
+				return err
+			}
+			if err := s.posts.ReplaceTags(ctx, &post, tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	if moderating {
+		s.moderated(ctx, actor, post.UserID, fmt.Sprintf("post %d editado", post.PostID))
+	}
+	return nil
+}
+
+func (s *postService) Delete(ctx context.Context, actor Actor, postID uint) error {
+	post, moderating, err := s.authorOrModerator(ctx, actor, postID)
+	if err != nil {
+		return err
+	}
+
+	var files []models.PostFile
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if files, err = s.posts.Files(ctx, post.PostID); err != nil {
+			return err
+		}
+		return s.posts.Delete(ctx, &post)
+	})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	// Los archivos se borran después de confirmar: si la transacción fallaba
+	// el post quedaba apuntando a archivos inexistentes
+	s.deleteFiles(ctx, files)
+
+	if moderating {
+		s.moderated(ctx, actor, post.UserID, fmt.Sprintf("post %d eliminado", post.PostID))
+	}
+	return nil
+}
+
+// deleteFiles borra del almacenamiento los archivos de un post. Como
+// moderated, un error solo se registra.
+func (s *postService) deleteFiles(ctx context.Context, files []models.PostFile) {
+	for _, file := range files {
+		if err := s.storage.Delete(ctx, file.FileURL); err != nil {
+			logging.FromContext(ctx).Error("Error borrando archivo del post", "post_id", file.PostID, "url", file.FileURL, "error", err)
+		}
+	}
+}
+
+func (s *postService) DeleteComment(ctx context.Context, actor Actor, postID, commentID uint) error {
+	comment, err := s.posts.FindComment(ctx, postID, commentID)
+	if err != nil {
+		return apperror.NotFound(apperror.CodeCommentNotFound, err)
+	}
+
+	moderating := comment.UserID != actor.UserID
+	if moderating {
+		post, err := s.posts.FindByID(ctx, comment.PostID)
+		if err != nil {
+			return apperror.NotFound(apperror.CodePostNotFound, err)
+		}
+		if !s.authz.CanInUniversity(ctx, actor.UserID, rbac.PermCommentsModerate, post.UniversityID) {
+			return apperror.New(apperror.CodeForbidden)
+		}
+	}
+
+	if err := s.posts.DeleteComment(ctx, &comment); err != nil {
+		return apperror.Internal(err)
+	}
+
+	if moderating {
+		s.moderated(ctx, actor, comment.UserID, fmt.Sprintf("comentario %d del post %d eliminado", comment.CommentID, comment.PostID))
+	}
+	return nil
+}
+
+// authorOrModerator busca el post y verifica que el actor sea su autor o un
+// moderador de posts de su universidad; moderating indica el segundo caso
+func (s *postService) authorOrModerator(ctx context.Context, actor Actor, postID uint) (post models.Post, moderating bool, err error) {
+	post, err = s.posts.FindByID(ctx, postID)
+	if err != nil {
+		return post, false, apperror.NotFound(apperror.CodePostNotFound, err)
+	}
+
+	moderating = post.UserID != actor.UserID
+	if moderating && !s.authz.CanInUniversity(ctx, actor.UserID, rbac.PermPostsModerate, post.UniversityID) {
+		return post, false, apperror.New(apperror.CodeForbidden)
+	}
+	return post, moderating, nil
+}
+
+func (s *postService) moderated(ctx context.Context, actor Actor, authorID uint, details string) {
+	s.audit.Audit(ctx, models.AuditLog{
+		UserID:  &authorID,
+		ActorID: &actor.UserID,
+		Action:  security.AuditContentModerated,
+		IP:      actor.IP,
+		Details: details,
+	})
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+)
+
+// RoleService es la administración de los roles de rbac
+type RoleService interface {
+	// Grant otorga un rol al usuario. Los moderadores pueden limitarse a una
+	// universidad; los administradores siempre son globales.
+	Grant(ctx context.Context, actor Actor, userID uint, role string, universityID *uint) (models.UserRole, error)
+	// Revoke quita un rol al usuario. No quita el último administrador
+	// global: nadie podría volver a otorgar roles.
+	Revoke(ctx context.Context, actor Actor, userID, roleID uint) (models.UserRole, error)
+}
+
+type roleService struct {
+	tx      repository.Transactor
+	roles   repository.RoleRepository
+	users   repository.UserRepository
+	catalog repository.CatalogRepository
+	audit   Auditor
+}
+
+// NewRoleService crea un RoleService
+func NewRoleService(tx repository.Transactor, roles repository.RoleRepository, users repository.UserRepository, catalog repository.CatalogRepository, audit Auditor) RoleService {
+	return &roleService{tx: tx, roles: roles, users: users, catalog: catalog, audit: audit}
+}
+
+func (s *roleService) Grant(ctx context.Context, actor Actor, userID uint, role string, universityID *uint) (models.UserRole, error) {
+	switch err := rbac.CheckGrant(role, universityID); {
+	case errors.Is(err, rbac.ErrInvalidRole):
+		return models.UserRole{}, apperror.InvalidField("role", "invalid_role")
+	case errors.Is(err, rbac.ErrScopedAdmin):
+		return models.UserRole{}, apperror.InvalidField("university_id", "admin_not_scoped")
+	}
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		return models.UserRole{}, apperror.NotFound(apperror.CodeUserNotFound, err)
+	}
+	if universityID != nil {
+		name, err := s.catalog.UniversityName(ctx, *universityID)
+		if err != nil {
+			return models.UserRole{}, apperror.Internal(err)
+		}
+		if name == "" {
+			return models.UserRole{}, apperror.New(apperror.CodeUniversityNotFound)
+		}
+	}
+
+	// El índice único resuelve dos otorgamientos simultáneos del mismo rol
+	granted := models.UserRole{
+		UserID:       userID,
+		Role:         role,
+		UniversityID: universityID,
+		GrantedBy:    &actor.UserID,
+	}
+	switch err := s.roles.Create(ctx, &granted); {
+	case errors.Is(err, repository.ErrRoleAlreadyGranted):
+		return models.UserRole{}, apperror.New(apperror.CodeRoleAlreadyGranted)
+	case err != nil:
+		return models.UserRole{}, apperror.Internal(err)
+	}
+
+	s.audit.Audit(ctx, models.AuditLog{
+		UserID:  &userID,
+		ActorID: &actor.UserID,
+		Action:  security.AuditRoleGranted,
+		IP:      actor.IP,
+		Details: DescribeRole(granted),
+	})
+	return granted, nil
+}
+
+func (s *roleService) Revoke(ctx context.Context, actor Actor, userID, roleID uint) (models.UserRole, error) {
+	var role models.UserRole
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		// Con el lock, dos bajas simultáneas de administradores no pueden
+		// contar cada una al otro como el que queda
+		if err := s.roles.LockAdmins(ctx); err != nil {
+			return err
+		}
+		var err error
+		role, err = s.roles.FindUserRole(ctx, userID, roleID)
+		if err != nil {
+			return apperror.NotFound(apperror.CodeRoleNotFound, err)
+		}
+		if role.Role == rbac.RoleAdmin {
+			admins, err := s.roles.CountGlobal(ctx, rbac.RoleAdmin)
+			if err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return apperror.New(apperror.CodeLastAdmin)
+			}
+		}
+		return s.roles.Delete(ctx, &role)
+	})
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		return role, appErr
+	case err != nil:
+		return role, apperror.Internal(err)
+	}
+
+	s.audit.Audit(ctx, models.AuditLog{
+		UserID:  &role.UserID,
+		ActorID: &actor.UserID,
+		Action:  security.AuditRoleRevoked,
+		IP:      actor.IP,
+		Details: DescribeRole(role),
+	})
+	return role, nil
+}
+
+// DescribeRole describe el rol para el log de auditoría
+func DescribeRole(role models.UserRole) string {
+	if role.UniversityID != nil {
+		return fmt.Sprintf("rol %s (universidad %d)", role.Role, *role.UniversityID)
+	}
+	return "rol " + role.Role
+}
@@ -0,0 +1,208 @@
+// Package services implementa la lógica de dominio de posts, canales y
+// usuarios. Los controladores solo traducen HTTP a llamadas a los servicios;
+// los servicios acceden a la base de datos a través de los repositorios y
+// reciben sus dependencias como interfaces, así se pueden probar con fakes.
+//
+// Los errores que devuelven son *apperror.Error o validation.Errors, listos
+// para pasar a apperror.Abort.
+package services
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/config"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/rbac"
+	"github.com/LautaroRomano/repositorio-tecnologico/realtime"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/security"
+	"github.com/LautaroRomano/repositorio-tecnologico/utils"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"gorm.io/gorm"
+)
+
+// Services agrupa los servicios que usan los controladores
+type Services struct {
+	Posts    PostService
+	Channels ChannelService
+	Users    UserService
+	Roles    RoleService
+	// Announcements envía los avisos de los anuncios de los canales; hay que
+	// correrlo con Run y cerrarlo al apagar el servidor
+	Announcements *AnnouncementWorker
+}
+
+// New arma los servicios con repositorios sobre db, los permisos de rbac, el
+// log de auditoría de security, storage para los archivos subidos, los
+// enlaces de descarga y los emails de utils y events para los eventos en tiempo real de los canales
+func New(db *gorm.DB, storage Storage, events realtime.Broker) *Services {
+	tx := repository.NewTransactor(db)
+	posts := repository.NewPostRepository(db)
+	catalog := repository.NewCatalogRepository(db)
+	users := repository.NewUserRepository(db)
+	channels := repository.NewChannelRepository(db)
+	announcements := NewAnnouncementWorker(channels, emailMailer{})
+
+	return &Services{
+		Posts: NewPostService(tx, posts, catalog, rbacAuthorizer{}, securityAuditor{}, storage),
+		Channels: NewChannelService(tx,
+			channels,
+			repository.NewChannelPostRepository(db),
+			catalog, users, rbacAuthorizer{}, securityAuditor{}, storage, jwtFileSigner{}, emailMailer{}, announcements, events),
+		Users:         NewUserService(users, posts, catalog, storage),
+		Roles:         NewRoleService(tx, repository.NewRoleRepository(db), users, catalog, securityAuditor{}),
+		Announcements: announcements,
+	}
+}
+
+// Actor es el usuario que ejecuta una acción, con la IP desde la que la hizo
+// para el log de auditoría
+type Actor struct {
+	UserID uint
+	IP     string
+}
+
+// File es un archivo subido por el usuario
+type File struct {
+	Name    string
+	Size    int64
+	Content io.Reader
+}
+
+// Authorizer decide si un usuario tiene un permiso de rbac en una universidad
+type Authorizer interface {
+	CanInUniversity(ctx context.Context, userID uint, permission string, universityID uint) bool
+}
+
+// Auditor registra acciones en el log de auditoría
+type Auditor interface {
+	Audit(ctx context.Context, entry models.AuditLog)
+}
+
+// Storage guarda archivos y devuelve la URL pública
+type Storage interface {
+	Upload(ctx context.Context, folder string, file File) (string, error)
+	// UploadPrivate guarda un archivo que solo se puede descargar con una URL
+	// de SignedURL
+	UploadPrivate(ctx context.Context, folder string, file File) (string, error)
+	// SignedURL devuelve una URL que descarga hasta expiresAt el archivo de
+	// una URL devuelta por UploadPrivate
+	SignedURL(ctx context.Context, url string, expiresAt time.Time) (string, error)
+	// Delete borra el archivo de una URL devuelta por Upload o UploadPrivate
+	Delete(ctx context.Context, url string) error
+}
+
+// FileSigner firma los tokens de los enlaces de descarga de archivos privados
+type FileSigner interface {
+	SignFile(fileID, userID uint, expiresAt time.Time) (string, error)
+	// VerifyFile devuelve el archivo y el usuario del token; si venció
+	// devuelve un error que cumple errors.Is(err, utils.ErrTokenExpired)
+	VerifyFile(token string) (fileID, userID uint, err error)
+}
+
+// Mailer envía los emails que disparan los servicios
+type Mailer interface {
+	// SendChannelInvite invita a una persona sin cuenta a entrar al canal con
+	// el código de un enlace de invitación
+	SendChannelInvite(ctx context.Context, to, inviter, channel, code string, expiresAt time.Time) error
+	// SendChannelAnnouncement avisa a los miembros de un anuncio del canal
+	SendChannelAnnouncement(ctx context.Context, to []string, author, channel string, channelID uint, content string) error
+}
+
+type rbacAuthorizer struct{}
+
+func (rbacAuthorizer) CanInUniversity(ctx context.Context, userID uint, permission string, universityID uint) bool {
+	return rbac.CanInUniversity(ctx, userID, permission, universityID)
+}
+
+type securityAuditor struct{}
+
+func (securityAuditor) Audit(ctx context.Context, entry models.AuditLog) {
+	security.Audit(ctx, entry)
+}
+
+type jwtFileSigner struct{}
+
+func (jwtFileSigner) SignFile(fileID, userID uint, expiresAt time.Time) (string, error) {
+	return utils.GenerateFileToken(fileID, userID, expiresAt)
+}
+
+func (jwtFileSigner) VerifyFile(token string) (uint, uint, error) {
+	claims, err := utils.ParseFileToken(token)
+	if err != nil {
+		return 0, 0, err
+	}
+	return claims.FileID, claims.UserID, nil
+}
+
+type emailMailer struct{}
+
+func (emailMailer) SendChannelInvite(ctx context.Context, to, inviter, channel, code string, expiresAt time.Time) error {
+	return utils.SendChannelInviteEmail(ctx, to, inviter, channel, code, expiresAt)
+}
+
+func (emailMailer) SendChannelAnnouncement(ctx context.Context, to []string, author, channel string, channelID uint, content string) error {
+	return utils.SendChannelAnnouncementEmails(ctx, to, author, channel, channelID, content)
+}
+
+// CloudinaryStorage guarda los archivos en Cloudinary
+type CloudinaryStorage struct{}
+
+func (CloudinaryStorage) Upload(ctx context.Context, folder string, file File) (string, error) {
+	result, err := config.Upload(ctx, file.Content, file.Size, uploader.UploadParams{
+		Folder:       folder,
+		ResourceType: "auto",
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.SecureURL, nil
+}
+
+func (CloudinaryStorage) UploadPrivate(ctx context.Context, folder string, file File) (string, error) {
+	result, err := config.Upload(ctx, file.Content, file.Size, uploader.UploadParams{
+		Folder:       folder,
+		ResourceType: "auto",
+		Type:         "authenticated",
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.SecureURL, nil
+}
+
+func (CloudinaryStorage) SignedURL(ctx context.Context, url string, expiresAt time.Time) (string, error) {
+	return config.PrivateDownloadURL(url, expiresAt)
+}
+
+func (CloudinaryStorage) Delete(ctx context.Context, url string) error {
+	return config.Destroy(ctx, url)
+}
+
+// fileType determina el tipo de archivo a partir de la extensión del nombre
+func fileType(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp":
+		return "image/jpeg"
+	case ".pdf":
+		return "application/pdf"
+	case ".doc", ".docx":
+		return "application/msword"
+	case ".xls", ".xlsx":
+		return "application/vnd.ms-excel"
+	case ".ppt", ".pptx":
+		return "application/vnd.ms-powerpoint"
+	case ".mp4", ".avi", ".mov", ".wmv", ".flv", ".mkv":
+		return "video/mp4"
+	case ".mp3", ".wav", ".ogg", ".flac", ".aac":
+		return "audio/mpeg"
+	case ".zip", ".rar", ".7z", ".tar", ".gz":
+		return "application/zip"
+	default:
+		return "application/octet-stream"
+	}
+}
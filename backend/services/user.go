@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/apperror"
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/LautaroRomano/repositorio-tecnologico/repository"
+	"github.com/LautaroRomano/repositorio-tecnologico/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AvatarFolder es la carpeta del almacenamiento donde se guardan los avatares
+const AvatarFolder = "avatars"
+
+// Profile es el perfil público de un usuario
+type Profile struct {
+	User          models.User
+	University    string
+	Career        string
+	PostsCount    int64
+	LikesReceived int64
+}
+
+// ProfileUpdate son los datos que el usuario puede cambiar de su perfil
+type ProfileUpdate struct {
+	Avatar       File
+	UniversityID uint
+	CareerID     uint
+}
+
+// UserService es la lógica de los perfiles de usuario
+type UserService interface {
+	Profile(ctx context.Context, userID uint) (Profile, error)
+	Followers(ctx context.Context, username string) ([]models.User, error)
+	// UpdateProfile sube el nuevo avatar (solo imágenes) y actualiza la
+	// universidad y la carrera del actor
+	UpdateProfile(ctx context.Context, actor Actor, input ProfileUpdate) (models.User, error)
+	// ChangePassword verifica la contraseña actual y aplica la política de
+	// contraseñas a la nueva
+	ChangePassword(ctx context.Context, actor Actor, currentPassword, newPassword string) error
+}
+
+type userService struct {
+	users   repository.UserRepository
+	posts   repository.PostRepository
+	catalog repository.CatalogRepository
+	storage Storage
+}
+
+// NewUserService crea un UserService
+func NewUserService(users repository.UserRepository, posts repository.PostRepository, catalog repository.CatalogRepository, storage Storage) UserService {
+	return &userService{users: users, posts: posts, catalog: catalog, storage: storage}
+}
+
+func (s *userService) Profile(ctx context.Context, userID uint) (Profile, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return Profile{}, apperror.NotFound(apperror.CodeUserNotFound, err)
+	}
+
+	profile := Profile{User: user}
+	if user.UniversityID > 0 {
+		if profile.University, err = s.catalog.UniversityName(ctx, user.UniversityID); err != nil {
+			return Profile{}, apperror.Internal(err)
+		}
+	}
+	if user.CareerID > 0 {
+		if profile.Career, err = s.catalog.CareerName(ctx, user.CareerID); err != nil {
+			return Profile{}, apperror.Internal(err)
+		}
+	}
+	if profile.PostsCount, err = s.posts.CountByUser(ctx, userID); err != nil {
+		return Profile{}, apperror.Internal(err)
+	}
+	if profile.LikesReceived, err = s.posts.LikesReceived(ctx, userID); err != nil {
+		return Profile{}, apperror.Internal(err)
+	}
+	return profile, nil
+}
+
+func (s *userService) Followers(ctx context.Context, username string) ([]models.User, error) {
+	user, err := s.users.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, apperror.NotFound(apperror.CodeUserNotFound, err)
+	}
+
+	followers, err := s.users.Followers(ctx, &user)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return followers, nil
+}
+
+func (s *userService) UpdateProfile(ctx context.Context, actor Actor, input ProfileUpdate) (models.User, error) {
+	if fileType(input.Avatar.Name) != "image/jpeg" {
+		return models.User{}, apperror.InvalidField("avatar", "not_image")
+	}
+
+	user, err := s.users.FindByID(ctx, actor.UserID)
+	if err != nil {
+		return models.User{}, apperror.NotFound(apperror.CodeUserNotFound, err)
+	}
+
+	url, err := s.storage.Upload(ctx, AvatarFolder, input.Avatar)
+	if err != nil {
+		return models.User{}, apperror.New(apperror.CodeUploadFailed).WithParam("file", input.Avatar.Name).Wrap(err)
+	}
+
+	user.Img = url
+	user.UniversityID = input.UniversityID
+	user.CareerID = input.CareerID
+	if err := s.users.Save(ctx, &user); err != nil {
+		return models.User{}, apperror.Internal(err)
+	}
+	return user, nil
+}
+
+func (s *userService) ChangePassword(ctx context.Context, actor Actor, currentPassword, newPassword string) error {
+	user, err := s.users.FindByID(ctx, actor.UserID)
+	if err != nil {
+		return apperror.NotFound(apperror.CodeUserNotFound, err)
+	}
+
+	if !user.CheckPassword(currentPassword) {
+		return apperror.InvalidField("current_password", "password_incorrect")
+	}
+
+	if fe := validation.Password("new_password", newPassword, user.Username, user.Email); fe != nil {
+		return validation.Errors{*fe}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if err := s.users.UpdatePasswordHash(ctx, &user, string(hash)); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
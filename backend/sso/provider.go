@@ -0,0 +1,208 @@
+package sso
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ProviderConfig describe un proveedor OIDC (Google, SSO institucional, etc.)
+type ProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+	// TrustEmail considera verificados los emails del proveedor aunque no
+	// envíe el claim email_verified (útil para SSO universitarios)
+	TrustEmail bool
+}
+
+// Provider es un proveedor configurado. El documento de discovery se obtiene
+// de forma perezosa para que un proveedor caído no impida iniciar el servidor.
+type Provider struct {
+	Config     ProviderConfig
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	oidc     *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+	oauth2   *oauth2.Config
+}
+
+var (
+	ErrUnknownProvider = errors.New("proveedor desconocido")
+
+	providers = map[string]*Provider{}
+)
+
+// Register agrega un proveedor al registro global
+func Register(p *Provider) {
+	providers[p.Config.Name] = p
+}
+
+// Get busca un proveedor registrado por nombre
+func Get(name string) (*Provider, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// List devuelve los proveedores registrados ordenados por nombre
+func List() []*Provider {
+	list := make([]*Provider, 0, len(providers))
+	for _, p := range providers {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Config.Name < list[j].Config.Name })
+	return list
+}
+
+// Configure registra los proveedores configurados. Los valores se leen de las
+// variables OIDC_<NOMBRE>_* (ver config.Load).
+func Configure(configs []ProviderConfig) {
+	for _, cfg := range configs {
+		Register(&Provider{Config: cfg})
+	}
+}
+
+// context agrega el cliente HTTP del proveedor (si lo tiene) al contexto,
+// lo que permite apuntar a un proveedor OIDC de prueba local
+func (p *Provider) context(ctx context.Context) context.Context {
+	if p.HTTPClient != nil {
+		return oidc.ClientContext(ctx, p.HTTPClient)
+	}
+	return ctx
+}
+
+// init realiza el discovery del proveedor la primera vez que se usa
+func (p *Provider) init(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oidc != nil {
+		return nil
+	}
+
+	provider, err := oidc.NewProvider(p.context(ctx), p.Config.Issuer)
+	if err != nil {
+		return fmt.Errorf("discovery de %s: %w", p.Config.Name, err)
+	}
+
+	scopes := p.Config.Scopes
+	hasOpenID := false
+	for _, s := range scopes {
+		if s == oidc.ScopeOpenID {
+			hasOpenID = true
+		}
+	}
+	if !hasOpenID {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+
+	p.oidc = provider
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.Config.ClientID})
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.Config.ClientID,
+		ClientSecret: p.Config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.Config.RedirectURL,
+		Scopes:       scopes,
+	}
+	return nil
+}
+
+// AuthCodeURL inicia el flujo authorization code con PKCE. Devuelve la URL
+// del proveedor a la que hay que redirigir al usuario y el login pendiente,
+// que hay que pasarle a Exchange en el callback.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectTo string) (string, PendingAuth, error) {
+	if err := p.init(ctx); err != nil {
+		return "", PendingAuth{}, err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", PendingAuth{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", PendingAuth{}, err
+	}
+	auth := PendingAuth{
+		Provider:   p.Config.Name,
+		State:      state,
+		Verifier:   oauth2.GenerateVerifier(),
+		Nonce:      nonce,
+		RedirectTo: redirectTo,
+	}
+
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(auth.Verifier)), auth, nil
+}
+
+// Claims son los datos de identidad que interesan del ID token
+type Claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Picture           string `json:"picture"`
+}
+
+var (
+	ErrInvalidState = errors.New("estado de autenticación inválido o expirado")
+	ErrNoIDToken    = errors.New("el proveedor no devolvió un id_token")
+	ErrInvalidNonce = errors.New("nonce inválido")
+)
+
+// Exchange completa el flujo del login pendiente auth: valida el state,
+// canjea el código usando el verificador PKCE y verifica el ID token
+func (p *Provider) Exchange(ctx context.Context, auth PendingAuth, state, code string) (*Claims, error) {
+	if !auth.matches(p.Config.Name, state) {
+		return nil, ErrInvalidState
+	}
+
+	if err := p.init(ctx); err != nil {
+		return nil, err
+	}
+	ctx = p.context(ctx)
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(auth.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("canje del código: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrNoIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verificación del id_token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(auth.Nonce)) != 1 {
+		return nil, ErrInvalidNonce
+	}
+
+	var claims Claims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("lectura de claims: %w", err)
+	}
+	if p.Config.TrustEmail && claims.Email != "" {
+		claims.EmailVerified = true
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,37 @@
+package sso
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+)
+
+// StateTTL es el tiempo que tiene el usuario para completar el login en el proveedor
+const StateTTL = 10 * time.Minute
+
+// PendingAuth son los datos de un login iniciado y todavía no completado. No
+// se guardan en el servidor: quien inicia el login los deja firmados en una
+// cookie del navegador, así el callback solo se acepta en ese navegador y no
+// depende de qué réplica lo atienda.
+type PendingAuth struct {
+	Provider   string
+	State      string
+	Verifier   string
+	Nonce      string
+	RedirectTo string
+}
+
+// matches indica si el callback del proveedor corresponde a este login
+func (a PendingAuth) matches(provider, state string) bool {
+	return a.Provider == provider && state != "" &&
+		subtle.ConstantTimeCompare([]byte(a.State), []byte(state)) == 1
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -1,37 +1,243 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/metrics"
+	"github.com/LautaroRomano/repositorio-tecnologico/tracing"
 	"github.com/resendlabs/resend-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EmailConfig define el remitente de los emails y la URL del frontend que se
+// usa en los enlaces
+type EmailConfig struct {
+	APIKey      string
+	From        string
+	FrontendURL string
+}
+
+// EmailTransport entrega los emails. En producción es Resend; los tests de
+// integración usan uno en memoria.
+type EmailTransport interface {
+	Send(ctx context.Context, params *resend.SendEmailRequest) error
+	// SendBatch entrega hasta EmailBatchSize emails en un solo pedido
+	SendBatch(ctx context.Context, params []*resend.SendEmailRequest) error
+}
+
+// EmailBatchSize es cuántos emails acepta Resend en un pedido a /emails/batch
+const EmailBatchSize = 100
+
+var (
+	resendClient   *resend.Client
+	emailTransport EmailTransport
+	emailConfig    EmailConfig
 )
 
-var resendClient *resend.Client
+func InitResendClient(cfg EmailConfig) {
+	resendClient = resend.NewClient(cfg.APIKey)
+	UseEmailTransport(cfg, resendTransport{client: resendClient})
+}
 
-func InitResendClient(apiKey string) {
-	resendClient = resend.NewClient(apiKey)
+// UseEmailTransport configura el remitente y el transporte de los emails
+func UseEmailTransport(cfg EmailConfig, transport EmailTransport) {
+	emailConfig = cfg
+	emailTransport = transport
 }
 
-func SendPasswordResetEmail(to, resetToken string) error {
+type resendTransport struct {
+	client *resend.Client
+}
+
+// Send envía el email con la API de Resend, que no acepta contexto
+func (t resendTransport) Send(_ context.Context, params *resend.SendEmailRequest) error {
+	_, err := t.client.Emails.Send(params)
+	return err
+}
+
+// SendBatch envía los emails con /emails/batch, que el cliente de Resend no
+// implementa
+func (t resendTransport) SendBatch(ctx context.Context, params []*resend.SendEmailRequest) error {
+	req, err := t.client.NewRequest(http.MethodPost, "emails/batch", params)
+	if err != nil {
+		return err
+	}
+	_, err = t.client.Perform(req.WithContext(ctx), nil)
+	return err
+}
+
+func SendPasswordResetEmail(ctx context.Context, to, resetToken string) error {
 	params := &resend.SendEmailRequest{
-		From:    "noreply@redapuntes.com",
+		From:    emailConfig.From,
 		To:      []string{to},
 		Subject: "Recuperación de contraseña",
 		Html:    generatePasswordResetEmailHTML(resetToken),
 	}
 
-	_, err := resendClient.Emails.Send(params)
+	return sendEmail(ctx, "password_reset", params)
+}
+
+// sendEmail envía el email y registra el resultado en las métricas y en un
+// span por tipo
+func sendEmail(ctx context.Context, kind string, params *resend.SendEmailRequest) error {
+	ctx, span := tracing.Start(ctx, "email.send", attribute.String("email.kind", kind))
+	err := emailTransport.Send(ctx, params)
+	tracing.End(span, err)
+	metrics.ObserveEmail(kind, err)
 	return err
 }
 
+// sendEmailBatch envía los emails en pedidos de a EmailBatchSize. Sigue con
+// los demás pedidos si uno falla y devuelve el primer error.
+func sendEmailBatch(ctx context.Context, kind string, params []*resend.SendEmailRequest) error {
+	var first error
+	for start := 0; start < len(params); start += EmailBatchSize {
+		batch := params[start:min(start+EmailBatchSize, len(params))]
+		ctx, span := tracing.Start(ctx, "email.send_batch", attribute.String("email.kind", kind), attribute.Int("email.count", len(batch)))
+		err := emailTransport.SendBatch(ctx, batch)
+		tracing.End(span, err)
+		for range batch {
+			metrics.ObserveEmail(kind, err)
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("enviando %d emails: %w", len(batch), err)
+		}
+	}
+	return first
+}
+
 func generatePasswordResetEmailHTML(token string) string {
 	return `
 		<html>
 			<body>
 				<h2>Recuperación de contraseña</h2>
 				<p>Has solicitado restablecer tu contraseña. Haz clic en el siguiente enlace para continuar:</p>
-				<a href="http://localhost:3000/reset-password?token=` + token + `">Restablecer contraseña</a>
+				<a href="` + emailConfig.FrontendURL + `/reset-password?token=` + token + `">Restablecer contraseña</a>
 				<p>Si no solicitaste este cambio, puedes ignorar este correo.</p>
 				<p>El enlace expirará en 1 hora.</p>
 			</body>
 		</html>
 	`
 }
+
+// SendAccountLockedEmail avisa al usuario que su cuenta fue bloqueada
+// temporalmente por demasiados intentos fallidos de inicio de sesión
+func SendAccountLockedEmail(ctx context.Context, to string, until time.Time) error {
+	params := &resend.SendEmailRequest{
+		From:    emailConfig.From,
+		To:      []string{to},
+		Subject: "Tu cuenta fue bloqueada temporalmente",
+		Html:    generateAccountLockedEmailHTML(until),
+	}
+
+	return sendEmail(ctx, "account_locked", params)
+}
+
+func generateAccountLockedEmailHTML(until time.Time) string {
+	return `
+		<html>
+			<body>
+				<h2>Cuenta bloqueada temporalmente</h2>
+				<p>Detectamos demasiados intentos fallidos de inicio de sesión en tu cuenta, por lo que la bloqueamos hasta el ` + until.Format("02/01/2006 15:04") + `.</p>
+				<p>Si no fuiste vos, te recomendamos restablecer tu contraseña:</p>
+				<a href="` + emailConfig.FrontendURL + `/forgot-password">Restablecer contraseña</a>
+			</body>
+		</html>
+	`
+}
+
+// SendChannelInviteEmail invita a alguien sin cuenta a un canal. El enlace
+// lleva al frontend, que pide registrarse antes de canjear el código.
+func SendChannelInviteEmail(ctx context.Context, to, inviter, channel, code string, expiresAt time.Time) error {
+	params := &resend.SendEmailRequest{
+		From:    emailConfig.From,
+		To:      []string{to},
+		Subject: "Te invitaron a un canal",
+		Html:    generateChannelInviteEmailHTML(inviter, channel, code, expiresAt),
+	}
+
+	return sendEmail(ctx, "channel_invite", params)
+}
+
+func generateChannelInviteEmailHTML(inviter, channel, code string, expiresAt time.Time) string {
+	return `
+		<html>
+			<body>
+				<h2>Te invitaron a un canal</h2>
+				<p>` + html.EscapeString(inviter) + ` te invitó a unirte al canal <strong>` + html.EscapeString(channel) + `</strong>.</p>
+				<p>Creá tu cuenta con este email y después abrí el siguiente enlace:</p>
+				<a href="` + emailConfig.FrontendURL + `/channels/join/` + url.PathEscape(code) + `">Unirme al canal</a>
+				<p>La invitación vence el ` + expiresAt.Format("02/01/2006 15:04") + `.</p>
+			</body>
+		</html>
+	`
+}
+
+// announcementExcerpt es cuántos caracteres del anuncio se incluyen en el email
+const announcementExcerpt = 300
+
+// SendChannelAnnouncementEmails avisa a los miembros de un anuncio publicado
+// en un canal, con el comienzo del texto y un enlace al canal. Cada miembro
+// recibe su propio email y se envían en lotes.
+func SendChannelAnnouncementEmails(ctx context.Context, to []string, author, channel string, channelID uint, content string) error {
+	body := generateChannelAnnouncementEmailHTML(author, channel, channelID, content)
+	params := make([]*resend.SendEmailRequest, 0, len(to))
+	for _, address := range to {
+		params = append(params, &resend.SendEmailRequest{
+			From:    emailConfig.From,
+			To:      []string{address},
+			Subject: "Nuevo anuncio en " + channel,
+			Html:    body,
+		})
+	}
+
+	return sendEmailBatch(ctx, "channel_announcement", params)
+}
+
+func generateChannelAnnouncementEmailHTML(author, channel string, channelID uint, content string) string {
+	if excerpt := []rune(content); len(excerpt) > announcementExcerpt {
+		content = string(excerpt[:announcementExcerpt]) + "…"
+	}
+	return `
+		<html>
+			<body>
+				<h2>Nuevo anuncio en ` + html.EscapeString(channel) + `</h2>
+				<p>` + html.EscapeString(author) + ` publicó un anuncio:</p>
+				<blockquote>` + html.EscapeString(content) + `</blockquote>
+				<a href="` + emailConfig.FrontendURL + `/channels/` + strconv.FormatUint(uint64(channelID), 10) + `">Ver en el canal</a>
+			</body>
+		</html>
+	`
+}
+
+// CheckEmailTransport verifica que la API de Resend responda. No envía
+// ningún email: alcanza con que el servicio conteste por HTTP.
+func CheckEmailTransport(ctx context.Context) error {
+	if resendClient == nil {
+		return errors.New("el cliente de email no fue inicializado")
+	}
+	if emailConfig.APIKey == "" {
+		return errors.New("RESEND_API_KEY no está configurada")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, resendClient.BaseURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("la API de email respondió %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -1,25 +1,237 @@
 package utils
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenTTL es la duración de los tokens de sesión
+const TokenTTL = 72 * time.Hour
+
 type Claims struct {
 	UserID uint `json:"user_id"`
 	jwt.RegisteredClaims
 }
 
+// DefaultIssuer es el claim iss cuando el llavero no configura otro
+const DefaultIssuer = "repositorio-tecnologico"
+
+// tokenIssuer identifica a este backend en el claim iss, para que otros
+// servicios puedan validar de dónde viene el token
+func tokenIssuer() string {
+	if Keys != nil && Keys.config.Issuer != "" {
+		return Keys.config.Issuer
+	}
+	return DefaultIssuer
+}
+
+// FileTokenAudience es el claim aud de los tokens de descarga de archivos.
+// Los tokens de sesión no tienen audiencia, así que uno de descarga no sirve
+// para autenticarse.
+const FileTokenAudience = "file-download"
+
+// OIDCStateAudience es el claim aud de la cookie que ata un login OIDC al
+// navegador que lo inició
+const OIDCStateAudience = "oidc-state"
+
+// OIDCLinkAudience es el claim aud de los tokens para vincular una identidad
+// OIDC a una cuenta existente confirmando la contraseña
+const OIDCLinkAudience = "oidc-link"
+
+// ErrTokenExpired es el error de un token vencido
+var ErrTokenExpired = jwt.ErrTokenExpired
+
+// FileClaims autorizan a un usuario a descargar un archivo
+type FileClaims struct {
+	FileID uint `json:"file_id"`
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// OIDCStateClaims son los datos de un login OIDC en curso: el state que tiene
+// que volver en el callback, el verificador PKCE, el nonce y la ruta del
+// frontend a la que volver
+type OIDCStateClaims struct {
+	Provider   string `json:"provider"`
+	State      string `json:"state"`
+	Verifier   string `json:"verifier"`
+	Nonce      string `json:"nonce"`
+	RedirectTo string `json:"redirect_to"`
+	jwt.RegisteredClaims
+}
+
+// OIDCLinkClaims identifican una identidad OIDC que se puede vincular a la
+// cuenta UserID si el usuario confirma su contraseña
+type OIDCLinkClaims struct {
+	UserID   uint   `json:"user_id"`
+	Provider string `json:"provider"`
+	Subject  string `json:"oidc_sub"`
+	Email    string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// GenerateJWT firma un token para el usuario con la clave activa del llavero
 func GenerateJWT(userID uint) (string, error) {
-	claims := &Claims{
+	now := time.Now()
+	return sign(&Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(72 * time.Hour)),
+			Issuer:    tokenIssuer(),
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenTTL)),
 		},
+	})
+}
+
+// GenerateFileToken firma un token que permite al usuario descargar el
+// archivo hasta expiresAt
+func GenerateFileToken(fileID, userID uint, expiresAt time.Time) (string, error) {
+	return sign(&FileClaims{
+		FileID:           fileID,
+		UserID:           userID,
+		RegisteredClaims: audienceClaims(strconv.FormatUint(uint64(userID), 10), FileTokenAudience, expiresAt),
+	})
+}
+
+// GenerateOIDCStateToken firma los datos de un login OIDC en curso para
+// guardarlos en una cookie hasta expiresAt
+func GenerateOIDCStateToken(claims OIDCStateClaims, expiresAt time.Time) (string, error) {
+	claims.RegisteredClaims = audienceClaims(claims.Provider, OIDCStateAudience, expiresAt)
+	return sign(&claims)
+}
+
+// GenerateOIDCLinkToken firma una identidad OIDC pendiente de vincular
+func GenerateOIDCLinkToken(claims OIDCLinkClaims, expiresAt time.Time) (string, error) {
+	claims.RegisteredClaims = audienceClaims(strconv.FormatUint(uint64(claims.UserID), 10), OIDCLinkAudience, expiresAt)
+	return sign(&claims)
+}
+
+// audienceClaims son los claims registrados de los tokens que no son de
+// sesión: la audiencia impide usarlos para autenticarse
+func audienceClaims(subject, audience string, expiresAt time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    tokenIssuer(),
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+}
+
+// sign firma los claims con la clave activa del llavero
+func sign(claims jwt.Claims) (string, error) {
+	key, err := Keys.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.algorithm), claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// ParseJWT valida la firma y los claims de un token y devuelve sus claims
+func ParseJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc,
+		jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256, jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token inválido")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	// Los tokens HS256 previos al llavero no tienen issuer
+	if _, legacy := token.Method.(*jwt.SigningMethodHMAC); !legacy && claims.Issuer != tokenIssuer() {
+		return nil, errors.New("emisor del token inválido")
+	}
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("el token no es de sesión")
+	}
+
+	return claims, nil
+}
+
+// ParseFileToken valida un token de GenerateFileToken y devuelve sus claims
+func ParseFileToken(tokenString string) (*FileClaims, error) {
+	claims := &FileClaims{}
+	if err := parseAudience(tokenString, claims, FileTokenAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseOIDCStateToken valida un token de GenerateOIDCStateToken y devuelve
+// sus claims
+func ParseOIDCStateToken(tokenString string) (*OIDCStateClaims, error) {
+	claims := &OIDCStateClaims{}
+	if err := parseAudience(tokenString, claims, OIDCStateAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseOIDCLinkToken valida un token de GenerateOIDCLinkToken y devuelve sus
+// claims
+func ParseOIDCLinkToken(tokenString string) (*OIDCLinkClaims, error) {
+	claims := &OIDCLinkClaims{}
+	if err := parseAudience(tokenString, claims, OIDCLinkAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// parseAudience valida un token del llavero emitido para audience. Los
+// tokens HS256 anteriores al llavero nunca tienen audiencia.
+func parseAudience(tokenString string, claims jwt.Claims, audience string) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc,
+		jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(audience),
+		jwt.WithIssuer(tokenIssuer()),
+	)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("token inválido")
+	}
+	return nil
+}
+
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	// Tokens HS256 emitidos antes de migrar al llavero: se aceptan mientras
+	// JWT_SECRET siga configurado y hasta que pudo vencer el último. Uno que
+	// vence después no lo emitió el backend anterior.
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || Keys == nil {
+			return nil, errors.New("el token no indica la clave de firma")
+		}
+		if !Keys.acceptsLegacy(time.Now()) {
+			return nil, errors.New("los tokens HS256 ya no se aceptan")
+		}
+		if exp, err := token.Claims.GetExpirationTime(); err != nil || exp == nil || exp.After(Keys.legacyDeadline()) {
+			return nil, errors.New("token HS256 posterior al llavero")
+		}
+		return []byte(Keys.config.LegacySecret), nil
+	}
+
+	key, err := Keys.verificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
+	}
+	return key.public, nil
 }
@@ -0,0 +1,456 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LautaroRomano/repositorio-tecnologico/models"
+	"github.com/go-jose/go-jose/v4"
+	"gorm.io/gorm"
+)
+
+// Algoritmos de firma soportados por el llavero
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+// KeyRingConfig define el algoritmo y cada cuánto se rotan las claves
+type KeyRingConfig struct {
+	Algorithm        string
+	RotationInterval time.Duration
+	// TokenTTL es la duración de los tokens; una clave retirada se sigue
+	// publicando este tiempo para poder verificar los tokens que firmó
+	TokenTTL time.Duration
+	// Issuer es el claim iss de los tokens emitidos
+	Issuer string
+	// LegacySecret valida los tokens HS256 emitidos antes del llavero, hasta
+	// TokenTTL después de que se generó la primera clave
+	LegacySecret string
+	// EncryptionKey es la clave AES-256 con la que se cifran las claves
+	// privadas guardadas en la base de datos
+	EncryptionKey []byte
+}
+
+type ringKey struct {
+	id          string
+	algorithm   string
+	private     crypto.Signer
+	public      crypto.PublicKey
+	signFrom    time.Time
+	signUntil   time.Time
+	verifyUntil time.Time
+}
+
+// KeyRing mantiene en memoria las claves de firma vigentes
+type KeyRing struct {
+	db     *gorm.DB
+	config KeyRingConfig
+	aead   cipher.AEAD
+
+	mu         sync.RWMutex
+	keys       map[string]*ringKey
+	lastReload time.Time
+	// legacyUntil es hasta cuándo se aceptan tokens HS256: la creación de la
+	// clave más antigua guardada más TokenTTL
+	legacyUntil time.Time
+}
+
+// JWKSMaxAge es cuánto pueden guardar el JWKS en caché quienes verifican los
+// tokens. Una clave nueva se publica durante este tiempo antes de firmar.
+const JWKSMaxAge = 5 * time.Minute
+
+// rotationLead es cuánto antes del vencimiento de la clave activa se genera la
+// siguiente, para que nunca haya un momento sin clave para firmar. Tiene que
+// ser mayor que JWKSMaxAge.
+const rotationLead = time.Hour
+
+// encryptedKeyPrefix marca las claves privadas cifradas; las anteriores al
+// cifrado se guardaban como PEM y se cifran al cargarlas
+const encryptedKeyPrefix = "v1:"
+
+// minReloadInterval limita las recargas provocadas por tokens con kid desconocido
+const minReloadInterval = 10 * time.Second
+
+var (
+	Keys *KeyRing
+
+	ErrUnknownKey = errors.New("clave de firma desconocida")
+	ErrNoKeyRing  = errors.New("el llavero de claves JWT no fue inicializado")
+)
+
+// InitKeyRing carga las claves desde la base de datos y genera una nueva si no
+// hay ninguna activa
+func InitKeyRing(db *gorm.DB, config KeyRingConfig) error {
+	if config.Algorithm != AlgorithmEdDSA && config.Algorithm != AlgorithmRS256 {
+		return fmt.Errorf("algoritmo de firma no soportado: %s", config.Algorithm)
+	}
+
+	if len(config.EncryptionKey) != 32 {
+		return errors.New("la clave de cifrado del llavero debe tener 32 bytes")
+	}
+	block, err := aes.NewCipher(config.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	ring := &KeyRing{db: db, config: config, aead: aead, keys: map[string]*ringKey{}}
+	if err := ring.Rotate(); err != nil {
+		return err
+	}
+	if config.LegacySecret != "" && !ring.acceptsLegacy(time.Now()) {
+		slog.Warn("JWT_SECRET ya no se usa: los tokens HS256 vencieron y se puede quitar", "since", ring.legacyUntil)
+	}
+
+	Keys = ring
+	return nil
+}
+
+// Rotate recarga las claves y genera una nueva si la clave activa está por
+// vencer. Es seguro llamarlo desde varias réplicas a la vez: la generación se
+// serializa con un advisory lock de Postgres.
+func (r *KeyRing) Rotate() error {
+	if err := r.reload(); err != nil {
+		return err
+	}
+	if r.hasNextKey() {
+		return nil
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('jwt_signing_key_rotation'))").Error; err != nil {
+			return err
+		}
+
+		// Otra réplica pudo haber rotado mientras esperábamos el lock
+		var count int64
+		if err := tx.Model(&models.SigningKey{}).
+			Where("algorithm = ? AND sign_until > ?", r.config.Algorithm, time.Now().Add(rotationLead)).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		// La clave nueva firma después de estar publicada JWKSMaxAge, salvo que
+		// no haya ninguna otra con la que firmar mientras tanto
+		signFrom := time.Now().Add(JWKSMaxAge)
+		if _, err := r.signingKey(); err != nil {
+			signFrom = time.Now()
+		}
+		key, err := r.generate(signFrom)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		slog.Info("Nueva clave de firma JWT generada", "kid", key.KeyID, "algorithm", key.Algorithm, "sign_from", key.SignFrom)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("rotando claves de firma: %w", err)
+	}
+
+	return r.reload()
+}
+
+// RunRotation revisa periódicamente si hace falta rotar la clave activa y
+// recarga las claves generadas por otras réplicas. Bloquea hasta que se
+// cancela el contexto; una rotación en curso se completa antes de volver.
+func (r *KeyRing) RunRotation(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Rotate(); err != nil {
+				slog.Error("Error rotando claves JWT", "error", err)
+			}
+		}
+	}
+}
+
+// hasNextKey indica si hay una clave que sigue firmando después de
+// rotationLead, aunque todavía no haya empezado
+func (r *KeyRing) hasNextKey() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	limit := time.Now().Add(rotationLead)
+	for _, key := range r.keys {
+		if key.algorithm == r.config.Algorithm && key.signUntil.After(limit) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload lee de la base de datos todas las claves que todavía sirven para
+// verificar y cifra las que estaban guardadas en texto plano
+func (r *KeyRing) reload() error {
+	var rows []models.SigningKey
+	if err := r.db.Where("verify_until > ?", time.Now()).Order("created_at ASC").Find(&rows).Error; err != nil {
+		return err
+	}
+	// Las claves retiradas se borran recién cuando venció el último token que
+	// firmaron, así que la más antigua que queda nunca es posterior al llavero
+	// más TokenTTL
+	var oldest sql.NullTime
+	if err := r.db.Model(&models.SigningKey{}).Select("MIN(created_at)").Row().Scan(&oldest); err != nil {
+		return err
+	}
+
+	keys := map[string]*ringKey{}
+	now := time.Now()
+	for _, row := range rows {
+		key, err := r.parseRingKey(row)
+		if err != nil {
+			slog.Warn("Ignorando clave de firma", "kid", row.KeyID, "error", err)
+			continue
+		}
+		keys[key.id] = key
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.lastReload = now
+	r.legacyUntil = time.Time{}
+	if oldest.Valid {
+		r.legacyUntil = oldest.Time.Add(r.config.TokenTTL)
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// generate crea una clave nueva con el algoritmo configurado que firma a
+// partir de signFrom
+func (r *KeyRing) generate(signFrom time.Time) (*models.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch r.config.Algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	kidBytes := make([]byte, 8)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	keyID := now.UTC().Format("20060102") + "-" + hex.EncodeToString(kidBytes)
+	encrypted, err := r.encrypt(keyID, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	if err != nil {
+		return nil, err
+	}
+	signUntil := signFrom.Add(r.config.RotationInterval)
+	return &models.SigningKey{
+		KeyID:       keyID,
+		Algorithm:   r.config.Algorithm,
+		PrivateKey:  encrypted,
+		PublicKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		CreatedAt:   now,
+		SignFrom:    signFrom,
+		SignUntil:   signUntil,
+		VerifyUntil: signUntil.Add(r.config.TokenTTL),
+	}, nil
+}
+
+// encrypt cifra la clave privada en PEM. El kid va como dato adicional para
+// que no se pueda copiar el cifrado de una fila a otra.
+func (r *KeyRing) encrypt(keyID string, privatePEM []byte) (string, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := r.aead.Seal(nonce, nonce, privatePEM, []byte(keyID))
+	return encryptedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt devuelve el PEM de la clave privada de la fila. Una clave en texto
+// plano, de antes del cifrado, se cifra en la base de datos.
+func (r *KeyRing) decrypt(row models.SigningKey) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(row.PrivateKey, encryptedKeyPrefix)
+	if !ok {
+		encrypted, err := r.encrypt(row.KeyID, []byte(row.PrivateKey))
+		if err != nil {
+			return nil, err
+		}
+		err = r.db.Model(&models.SigningKey{}).
+			Where("key_id = ? AND private_key = ?", row.KeyID, row.PrivateKey).
+			Update("private_key", encrypted).Error
+		if err != nil {
+			return nil, fmt.Errorf("cifrando la clave guardada en texto plano: %w", err)
+		}
+		return []byte(row.PrivateKey), nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < r.aead.NonceSize() {
+		return nil, errors.New("clave cifrada inválida")
+	}
+	nonce, ciphertext := sealed[:r.aead.NonceSize()], sealed[r.aead.NonceSize():]
+	privatePEM, err := r.aead.Open(nil, nonce, ciphertext, []byte(row.KeyID))
+	if err != nil {
+		return nil, errors.New("no se pudo descifrar la clave: ¿cambió JWT_KEY_ENCRYPTION_KEY?")
+	}
+	return privatePEM, nil
+}
+
+func (r *KeyRing) parseRingKey(row models.SigningKey) (*ringKey, error) {
+	privatePEM, err := r.decrypt(row)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, errors.New("PEM inválido")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tipo de clave no soportado")
+	}
+
+	return &ringKey{
+		id:          row.KeyID,
+		algorithm:   row.Algorithm,
+		private:     private,
+		public:      private.Public(),
+		signFrom:    row.SignFrom,
+		signUntil:   row.SignUntil,
+		verifyUntil: row.VerifyUntil,
+	}, nil
+}
+
+// signingKey devuelve la clave activa para firmar: de las que ya empezaron a
+// firmar y no terminaron, la más nueva
+func (r *KeyRing) signingKey() (*ringKey, error) {
+	if r == nil {
+		return nil, ErrNoKeyRing
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := time.Now()
+	var signing *ringKey
+	for _, key := range r.keys {
+		if key.algorithm != r.config.Algorithm || key.signFrom.After(now) || !key.signUntil.After(now) {
+			continue
+		}
+		if signing == nil || key.signFrom.After(signing.signFrom) {
+			signing = key
+		}
+	}
+	if signing == nil {
+		return nil, errors.New("no hay una clave de firma activa")
+	}
+	return signing, nil
+}
+
+// legacyDeadline es hasta cuándo se aceptan tokens HS256
+func (r *KeyRing) legacyDeadline() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.legacyUntil
+}
+
+// acceptsLegacy indica si en now todavía se aceptan tokens HS256
+func (r *KeyRing) acceptsLegacy(now time.Time) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.LegacySecret != "" && now.Before(r.legacyUntil)
+}
+
+// verificationKey busca una clave por kid. Si no está en memoria se recarga
+// una vez, por si otra réplica acaba de rotar.
+func (r *KeyRing) verificationKey(kid string) (*ringKey, error) {
+	if r == nil {
+		return nil, ErrNoKeyRing
+	}
+	r.mu.RLock()
+	key, ok := r.keys[kid]
+	recent := time.Since(r.lastReload) < minReloadInterval
+	r.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if recent {
+		return nil, ErrUnknownKey
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if key, ok := r.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS devuelve las claves públicas vigentes en formato JSON Web Key Set
+func (r *KeyRing) JWKS() jose.JSONWebKeySet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	now := time.Now()
+	for _, key := range r.keys {
+		if key.verifyUntil.Before(now) {
+			continue
+		}
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.public,
+			KeyID:     key.id,
+			Algorithm: key.algorithm,
+			Use:       "sig",
+		})
+	}
+	return set
+}
@@ -0,0 +1,111 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	PasswordMinLength = 8
+	// bcrypt ignora todo lo que supere los 72 bytes
+	PasswordMaxBytes = 72
+)
+
+//go:embed breached_passwords.txt
+var embeddedBreachedPasswords string
+
+var (
+	breachedOnce sync.Once
+	breached     map[string]bool
+	breachedFile string
+)
+
+// UseBreachedPasswordsFile agrega a la lista incluida en el binario un archivo
+// más grande (una contraseña por línea). Debe llamarse antes de la primera
+// validación.
+func UseBreachedPasswordsFile(path string) {
+	breachedFile = path
+}
+
+// LoadBreachedPasswords carga la lista local de contraseñas filtradas
+func LoadBreachedPasswords() {
+	breachedOnce.Do(func() {
+		breached = map[string]bool{}
+		addBreachedPasswords(strings.NewReader(embeddedBreachedPasswords))
+
+		if breachedFile != "" {
+			if f, err := os.Open(breachedFile); err == nil {
+				addBreachedPasswords(f)
+				f.Close()
+			}
+		}
+	})
+}
+
+func addBreachedPasswords(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			breached[line] = true
+		}
+	}
+}
+
+// IsBreached indica si la contraseña figura en la lista de contraseñas filtradas
+func IsBreached(password string) bool {
+	LoadBreachedPasswords()
+	return breached[strings.ToLower(password)]
+}
+
+// Password valida la política de contraseñas. El campo se recibe por parámetro
+// porque cambia según el formulario (password, new_password). Los datos
+// personales se usan para rechazar contraseñas que los contengan.
+func Password(field, password string, personal ...string) *FieldError {
+	if password == "" {
+		return fieldError(field, "password_required", "La contraseña es obligatoria")
+	}
+	if len([]rune(password)) < PasswordMinLength {
+		return fieldError(field, "password_too_short", "La contraseña debe tener al menos 8 caracteres")
+	}
+	if len(password) > PasswordMaxBytes {
+		return fieldError(field, "password_too_long", "La contraseña puede tener como máximo 72 caracteres")
+	}
+
+	var hasLetter, hasDigit, hasOther bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasOther = true
+		}
+	}
+	if !hasLetter || (!hasDigit && !hasOther) {
+		return fieldError(field, "password_too_weak", "La contraseña debe combinar letras con números o símbolos")
+	}
+
+	lower := strings.ToLower(password)
+	for _, p := range personal {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if i := strings.Index(p, "@"); i >= 0 {
+			p = p[:i]
+		}
+		if len(p) >= 3 && strings.Contains(lower, p) {
+			return fieldError(field, "password_contains_personal_data", "La contraseña no puede contener tu nombre de usuario o email")
+		}
+	}
+
+	if IsBreached(password) {
+		return fieldError(field, "password_breached", "Esa contraseña aparece en filtraciones conocidas, elegí otra")
+	}
+
+	return nil
+}
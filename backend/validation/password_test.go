@@ -0,0 +1,46 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		code     string
+	}{
+		{"letras y números", "apuntes2024", nil, ""},
+		{"letras y símbolos", "apuntes#final", nil, ""},
+		{"mínimo en runas", "ñandú#12", nil, ""},
+		{"máximo en bytes", strings.Repeat("a", PasswordMaxBytes-1) + "1", nil, ""},
+		{"vacía", "", nil, "password_required"},
+		{"corta", "abc#123", nil, "password_too_short"},
+		// La longitud máxima es en bytes porque bcrypt ignora el resto
+		{"larga", strings.Repeat("ñ", PasswordMaxBytes/2) + "1", nil, "password_too_long"},
+		{"solo letras", "apuntesfinales", nil, "password_too_weak"},
+		{"solo números", "1234509876", nil, "password_too_weak"},
+		{"solo símbolos", "#$%&/()=?", nil, "password_too_weak"},
+		{"contiene el usuario", "Mariela#2024", []string{"mariela", "otra@example.com"}, "password_contains_personal_data"},
+		{"contiene el email", "xjuanp#2024", []string{"usuario", "JuanP@example.com"}, "password_contains_personal_data"},
+		{"dato personal corto", "ana#apuntes1", []string{"an"}, ""},
+		{"filtrada", "Password123", nil, "password_breached"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fe := Password("new_password", tt.password, tt.personal...)
+			if tt.code == "" {
+				if fe != nil {
+					t.Fatalf("error inesperado: %+v", fe)
+				}
+				return
+			}
+			// El error es del campo del formulario que se valida
+			if fe == nil || fe.Field != "new_password" || fe.Code != tt.code {
+				t.Fatalf("se esperaba new_password/%s: %+v", tt.code, fe)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 30
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9_.]*[a-z0-9_])?$`)
+
+// reservedUsernames no pueden registrarse porque se confunden con rutas,
+// roles del sistema o cuentas oficiales
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrador": true, "administrator": true, "root": true,
+	"system": true, "sistema": true, "soporte": true, "support": true,
+	"moderador": true, "moderator": true, "staff": true, "equipo": true,
+	"api": true, "auth": true, "login": true, "register": true, "registro": true,
+	"me": true, "users": true, "usuarios": true, "posts": true, "channels": true,
+	"canales": true, "search": true, "settings": true, "myprofile": true,
+	"null": true, "undefined": true, "anonymous": true, "anonimo": true,
+	"redapuntes": true, "noreply": true, "no-reply": true,
+}
+
+// NormalizeUsername pasa el nombre de usuario a minúsculas y quita espacios
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Username valida un nombre de usuario ya normalizado
+func Username(username string) *FieldError {
+	switch {
+	case username == "":
+		return fieldError("username", "username_required", "El nombre de usuario es obligatorio")
+	case len(username) < UsernameMinLength:
+		return fieldError("username", "username_too_short", "El nombre de usuario debe tener al menos 3 caracteres")
+	case len(username) > UsernameMaxLength:
+		return fieldError("username", "username_too_long", "El nombre de usuario puede tener como máximo 30 caracteres")
+	case !usernamePattern.MatchString(username) || strings.Contains(username, ".."):
+		return fieldError("username", "username_invalid_chars", "El nombre de usuario solo puede tener letras, números, puntos y guiones bajos, y no puede empezar con un símbolo")
+	case reservedUsernames[username]:
+		return fieldError("username", "username_reserved", "Ese nombre de usuario está reservado")
+	}
+	return nil
+}
+
+// NormalizeEmail valida el email y devuelve dos formas:
+//   - normalized: sin espacios y en minúsculas, es la que se guarda
+//   - canonical: además sin el sufijo "+etiqueta", se usa para evitar que la
+//     misma casilla registre varias cuentas
+func NormalizeEmail(email string) (normalized, canonical string, fe *FieldError) {
+	normalized = strings.ToLower(strings.TrimSpace(email))
+	if normalized == "" {
+		return "", "", fieldError("email", "email_required", "El email es obligatorio")
+	}
+
+	addr, err := mail.ParseAddress(normalized)
+	if err != nil || addr.Address != normalized || len(normalized) > 254 {
+		return "", "", fieldError("email", "email_invalid", "Ingresá un email válido")
+	}
+
+	at := strings.LastIndex(normalized, "@")
+	local, domain := normalized[:at], normalized[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", "", fieldError("email", "email_invalid", "Ingresá un email válido")
+	}
+
+	if i := strings.Index(local, "+"); i >= 0 {
+		local = local[:i]
+	}
+	if local == "" {
+		return "", "", fieldError("email", "email_invalid", "Ingresá un email válido")
+	}
+
+	return normalized, local + "@" + domain, nil
+}
+
+// AccountName valida el nombre visible de la cuenta
+func AccountName(name string) *FieldError {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return fieldError("account_name", "account_name_required", "El nombre de la cuenta es obligatorio")
+	case len([]rune(name)) > 60:
+		return fieldError("account_name", "account_name_too_long", "El nombre de la cuenta puede tener como máximo 60 caracteres")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email      string
+		normalized string
+		canonical  string
+		code       string
+	}{
+		{"ana@example.com", "ana@example.com", "ana@example.com", ""},
+		{"  Ana.Perez@Example.COM ", "ana.perez@example.com", "ana.perez@example.com", ""},
+		// La etiqueta se guarda pero no cuenta para detectar la misma casilla
+		{"ana+apuntes@example.com", "ana+apuntes@example.com", "ana@example.com", ""},
+		{"Ana+Uno+Dos@Example.com", "ana+uno+dos@example.com", "ana@example.com", ""},
+		{"", "", "", "email_required"},
+		{"   ", "", "", "email_required"},
+		{"ana", "", "", "email_invalid"},
+		{"ana@example", "", "", "email_invalid"},
+		{"ana@.example.com", "", "", "email_invalid"},
+		{"ana@example.com.", "", "", "email_invalid"},
+		{"+apuntes@example.com", "", "", "email_invalid"},
+		{"Ana <ana@example.com>", "", "", "email_invalid"},
+		{strings.Repeat("a", 250) + "@example.com", "", "", "email_invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			normalized, canonical, fe := NormalizeEmail(tt.email)
+			if tt.code != "" {
+				if fe == nil || fe.Field != "email" || fe.Code != tt.code {
+					t.Fatalf("se esperaba email/%s: %+v", tt.code, fe)
+				}
+				return
+			}
+			if fe != nil {
+				t.Fatalf("error inesperado: %+v", fe)
+			}
+			if normalized != tt.normalized || canonical != tt.canonical {
+				t.Fatalf("NormalizeEmail = %q, %q; se esperaba %q, %q", normalized, canonical, tt.normalized, tt.canonical)
+			}
+		})
+	}
+}
+
+func TestUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		code     string
+	}{
+		{"ana_perez", ""},
+		{"ana.perez", ""},
+		{"a1_", ""},
+		{"", "username_required"},
+		{"ab", "username_too_short"},
+		{strings.Repeat("a", UsernameMaxLength+1), "username_too_long"},
+		{".ana", "username_invalid_chars"},
+		{"_ana", "username_invalid_chars"},
+		{"ana.", "username_invalid_chars"},
+		{"ana..perez", "username_invalid_chars"},
+		{"ana perez", "username_invalid_chars"},
+		{"ana-perez", "username_invalid_chars"},
+		{"admin", "username_reserved"},
+		{"soporte", "username_reserved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			fe := Username(tt.username)
+			if tt.code == "" {
+				if fe != nil {
+					t.Fatalf("error inesperado: %+v", fe)
+				}
+				return
+			}
+			if fe == nil || fe.Field != "username" || fe.Code != tt.code {
+				t.Fatalf("se esperaba username/%s: %+v", tt.code, fe)
+			}
+		})
+	}
+
+	if username := NormalizeUsername("  Ana_Perez "); username != "ana_perez" {
+		t.Fatalf("NormalizeUsername = %q", username)
+	}
+}
+
+func TestAccountName(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"Ana Pérez", ""},
+		{strings.Repeat("ñ", 60), ""},
+		{"", "account_name_required"},
+		{"   ", "account_name_required"},
+		{strings.Repeat("ñ", 61), "account_name_too_long"},
+	}
+	for _, tt := range tests {
+		fe := AccountName(tt.name)
+		if tt.code == "" && fe != nil {
+			t.Errorf("AccountName(%q): error inesperado %+v", tt.name, fe)
+		}
+		if tt.code != "" && (fe == nil || fe.Field != "account_name" || fe.Code != tt.code) {
+			t.Errorf("AccountName(%q): se esperaba %s, se obtuvo %+v", tt.name, tt.code, fe)
+		}
+	}
+}
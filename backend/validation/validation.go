@@ -0,0 +1,42 @@
+package validation
+
+import "strings"
+
+// FieldError describe un error de validación de un campo puntual del formulario
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors agrupa los errores de validación de una petición
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fe := range e {
+		messages = append(messages, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add agrega un error para el campo indicado
+func (e *Errors) Add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Merge agrega un error de campo si no es nil
+func (e *Errors) Merge(fe *FieldError) {
+	if fe != nil {
+		*e = append(*e, *fe)
+	}
+}
+
+// Has indica si hay algún error
+func (e Errors) Has() bool {
+	return len(e) > 0
+}
+
+func fieldError(field, code, message string) *FieldError {
+	return &FieldError{Field: field, Code: code, Message: message}
+}
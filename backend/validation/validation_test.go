@@ -0,0 +1,35 @@
+package validation
+
+import "testing"
+
+func TestErrorsByField(t *testing.T) {
+	var errs Errors
+	errs.Merge(Username("ana"))
+	if errs.Has() {
+		t.Fatalf("un campo válido agregó errores: %v", errs)
+	}
+
+	_, _, emailErr := NormalizeEmail("no-es-un-email")
+	errs.Merge(Username(""))
+	errs.Merge(emailErr)
+	errs.Merge(Password("password", "corta"))
+	errs.Add("username", "username_taken", "Ese nombre de usuario ya está en uso")
+
+	want := []struct{ field, code string }{
+		{"username", "username_required"},
+		{"email", "email_invalid"},
+		{"password", "password_too_short"},
+		{"username", "username_taken"},
+	}
+	if !errs.Has() || len(errs) != len(want) {
+		t.Fatalf("errores inesperados: %+v", errs)
+	}
+	for i, w := range want {
+		if errs[i].Field != w.field || errs[i].Code != w.code || errs[i].Message == "" {
+			t.Errorf("error %d = %+v, se esperaba %s/%s", i, errs[i], w.field, w.code)
+		}
+	}
+	if msg := errs.Error(); msg != "username: El nombre de usuario es obligatorio; email: Ingresá un email válido; password: La contraseña debe tener al menos 8 caracteres; username: Ese nombre de usuario ya está en uso" {
+		t.Errorf("Error() = %q", msg)
+	}
+}
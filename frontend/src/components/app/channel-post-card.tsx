@@ -95,6 +95,21 @@ export function ChannelPostCard({
     }
   };
 
+  // Pide un enlace nuevo porque el que vino con los posts puede haber vencido
+  const handleDownload = async (fileID: number) => {
+    try {
+      const response = await axios.get(`/api/channels/files/${fileID}`, {
+        headers: {
+          Authorization: `Bearer ${localStorage.getItem("token")}`,
+        },
+      });
+      window.open(`/api${response.data.url}`, "_blank");
+    } catch (error) {
+      toast.error("Error al descargar el archivo");
+      console.error(error);
+    }
+  };
+
   const handleDelete = async () => {
     if (!confirm("¿Estás seguro de que deseas eliminar este post?")) return;
 
@@ -169,7 +184,7 @@ export function ChannelPostCard({
                 <Button
                   variant="ghost"
                   size="sm"
-                  onClick={() => window.open(file.FileURL, "_blank")}
+                  onClick={() => handleDownload(file.FileID)}
                 >
                   <Download className="h-4 w-4" />
                 </Button>
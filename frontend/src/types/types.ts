@@ -92,13 +92,24 @@ export interface Channel {
   University?: University;
   Career?: Career;
   Members?: ChannelMember[];
+  Permissions?: ChannelPermissions;
+}
+
+export type ChannelRole = "owner" | "moderator" | "member" | "readonly";
+
+export interface ChannelPermissions {
+  Post: ChannelRole;
+  Comment: ChannelRole;
+  Invite: ChannelRole;
+  Pin: ChannelRole;
+  DeletePosts: ChannelRole;
 }
 
 export interface ChannelMember {
   MemberID: number;
   ChannelID: number;
   UserID: number;
-  IsAdmin: boolean;
+  Role: ChannelRole;
   JoinedAt: string;
   LastSeenAt: string;
   User?: User;
@@ -116,6 +127,21 @@ export interface ChannelInvitation {
   Inviter?: User;
 }
 
+export interface ChannelInviteLink {
+  LinkID: number;
+  ChannelID: number;
+  Code: string;
+  Role: Exclude<ChannelRole, "owner">;
+  MaxUses: number | null;
+  Uses: number;
+  ExpiresAt: string | null;
+  Email: string | null;
+  CreatedBy: number | null;
+  RevokedAt: string | null;
+  CreatedAt: string;
+  Creator?: User;
+}
+
 export interface ChannelPost {
   PostID: number;
   ChannelID: number;
@@ -123,19 +149,35 @@ export interface ChannelPost {
   Content: string;
   CreatedAt: string;
   UpdatedAt: string;
-  Tags: string[];
+  Tags: Tag[];
+  EditedAt: string | null;
+  PinnedAt: string | null;
+  PinnedBy: number | null;
+  IsAnnouncement: boolean;
   User?: User;
   Files?: ChannelPostFile[];
   Comments?: ChannelPostComment[];
   Likes?: ChannelPostLike[];
 }
 
+// Versión anterior de un post en GET /channels/posts/:postId/edits
+export interface ChannelPostEdit {
+  EditID: number;
+  PostID: number;
+  EditorID: number | null;
+  Content: string;
+  Tags: string[] | null;
+  CreatedAt: string;
+  Editor?: User;
+}
+
 export interface ChannelPostFile {
   FileID: number;
   PostID: number;
-  FileURL: string;
   FileType: string;
   FileName: string;
+  // Enlace firmado relativo a la API; vence a los 15 minutos
+  DownloadURL?: string;
 }
 
 export interface ChannelPostComment {
@@ -153,3 +195,29 @@ export interface ChannelPostLike {
   PostID: number;
   UserID: number;
 }
+
+export type ChannelEventType =
+  | "post.created"
+  | "post.updated"
+  | "post.deleted"
+  | "post.pinned"
+  | "post.unpinned"
+  | "comment.created"
+  | "like.added"
+  | "like.removed";
+
+// Evento recibido por GET /channels/events (Server-Sent Events)
+export interface ChannelEvent<T = unknown> {
+  id: number;
+  channel_id: number;
+  type: ChannelEventType;
+  data: T;
+  created_at: string;
+}
+
+// No leídos de un canal en GET /channels (unread, por ChannelID)
+export interface ChannelUnread {
+  posts: number;
+  comments: number;
+  last_seen_at: string;
+}